@@ -0,0 +1,337 @@
+// Package astest provides a recording implementation of the
+// ApplicationServerService for testing code which uses the
+// as.ApplicationServerServiceClient.
+//
+// The Server records every call in the order in which it was received. Errors
+// can be injected per method, and the Wait functions block until a matching
+// call has been received:
+//
+//	srv := astest.NewServer()
+//	defer srv.Close()
+//
+//	client, err := srv.Client(ctx)
+//	...
+//	req, err := srv.WaitForUplink(devEUI, time.Second)
+package astest
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/golang/protobuf/ptypes/empty"
+	"google.golang.org/grpc"
+
+	"github.com/brocaar/chirpstack-api/go/as"
+	"github.com/brocaar/chirpstack-api/go/internal/grpctest"
+)
+
+var _ as.ApplicationServerServiceServer = (*Server)(nil)
+
+// Method names, as used for recording calls and injecting errors.
+const (
+	MethodHandleUplinkData        = "HandleUplinkData"
+	MethodHandleProprietaryUplink = "HandleProprietaryUplink"
+	MethodHandleError             = "HandleError"
+	MethodHandleDownlinkACK       = "HandleDownlinkACK"
+	MethodHandleGatewayStats      = "HandleGatewayStats"
+	MethodSetDeviceStatus         = "SetDeviceStatus"
+	MethodSetDeviceLocation       = "SetDeviceLocation"
+)
+
+// Call is a recorded call.
+type Call struct {
+	// Method holds the method name (e.g. MethodHandleUplinkData).
+	Method string
+
+	// Request holds a copy of the request.
+	Request proto.Message
+
+	// Err holds the injected error returned for the call, if any.
+	Err error
+
+	// ReceivedAt holds the time at which the call was received.
+	ReceivedAt time.Time
+}
+
+// Server implements a recording as.ApplicationServerServiceServer.
+type Server struct {
+	grpc *grpctest.Server
+
+	mu       sync.Mutex
+	calls    []Call
+	consumed []bool
+	errs     map[string]error
+	nextErrs map[string][]error
+
+	// changed is closed and replaced when a call has been recorded.
+	changed chan struct{}
+}
+
+// NewServer returns a new Server.
+func NewServer() *Server {
+	s := &Server{
+		errs:     make(map[string]error),
+		nextErrs: make(map[string][]error),
+		changed:  make(chan struct{}),
+	}
+
+	s.grpc = grpctest.NewServer(func(gs *grpc.Server) {
+		as.RegisterApplicationServerServiceServer(gs, s)
+	})
+
+	return s
+}
+
+// Dial returns a connection to the Server, served over an in-memory
+// listener.
+func (s *Server) Dial(ctx context.Context, opts ...grpc.DialOption) (*grpc.ClientConn, error) {
+	return s.grpc.Dial(ctx, opts...)
+}
+
+// Client returns an ApplicationServerService client connected to the Server.
+func (s *Server) Client(ctx context.Context, opts ...grpc.DialOption) (as.ApplicationServerServiceClient, error) {
+	conn, err := s.Dial(ctx, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return as.NewApplicationServerServiceClient(conn), nil
+}
+
+// Close closes the connections returned by Dial and stops serving.
+func (s *Server) Close() error {
+	return s.grpc.Close()
+}
+
+// SetError sets the error returned by every call of the given method. A nil
+// error removes the error.
+func (s *Server) SetError(method string, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err == nil {
+		delete(s.errs, method)
+	} else {
+		s.errs[method] = err
+	}
+}
+
+// FailNext queues errors to return for the next calls of the given method,
+// one error per call. Queued errors take precedence over SetError.
+func (s *Server) FailNext(method string, errs ...error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.nextErrs[method] = append(s.nextErrs[method], errs...)
+}
+
+// Calls returns the recorded calls in the order in which they were received.
+func (s *Server) Calls() []Call {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	out := make([]Call, len(s.calls))
+	for i, c := range s.calls {
+		out[i] = c
+		out[i].Request = proto.Clone(c.Request)
+	}
+	return out
+}
+
+// CallsFor returns the recorded calls of the given method, in the order in
+// which they were received.
+func (s *Server) CallsFor(method string) []Call {
+	var out []Call
+	for _, c := range s.Calls() {
+		if c.Method == method {
+			out = append(out, c)
+		}
+	}
+	return out
+}
+
+// Reset removes the recorded calls and injected errors.
+func (s *Server) Reset() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.calls = nil
+	s.consumed = nil
+	s.errs = make(map[string]error)
+	s.nextErrs = make(map[string][]error)
+}
+
+// HandleUplinkData records the call.
+func (s *Server) HandleUplinkData(ctx context.Context, req *as.HandleUplinkDataRequest) (*empty.Empty, error) {
+	return s.record(MethodHandleUplinkData, req)
+}
+
+// HandleProprietaryUplink records the call.
+func (s *Server) HandleProprietaryUplink(ctx context.Context, req *as.HandleProprietaryUplinkRequest) (*empty.Empty, error) {
+	return s.record(MethodHandleProprietaryUplink, req)
+}
+
+// HandleError records the call.
+func (s *Server) HandleError(ctx context.Context, req *as.HandleErrorRequest) (*empty.Empty, error) {
+	return s.record(MethodHandleError, req)
+}
+
+// HandleDownlinkACK records the call.
+func (s *Server) HandleDownlinkACK(ctx context.Context, req *as.HandleDownlinkACKRequest) (*empty.Empty, error) {
+	return s.record(MethodHandleDownlinkACK, req)
+}
+
+// HandleGatewayStats records the call.
+func (s *Server) HandleGatewayStats(ctx context.Context, req *as.HandleGatewayStatsRequest) (*empty.Empty, error) {
+	return s.record(MethodHandleGatewayStats, req)
+}
+
+// SetDeviceStatus records the call.
+func (s *Server) SetDeviceStatus(ctx context.Context, req *as.SetDeviceStatusRequest) (*empty.Empty, error) {
+	return s.record(MethodSetDeviceStatus, req)
+}
+
+// SetDeviceLocation records the call.
+func (s *Server) SetDeviceLocation(ctx context.Context, req *as.SetDeviceLocationRequest) (*empty.Empty, error) {
+	return s.record(MethodSetDeviceLocation, req)
+}
+
+// record records the call and returns the injected error, if any.
+func (s *Server) record(method string, req proto.Message) (*empty.Empty, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	err := s.errs[method]
+	if next := s.nextErrs[method]; len(next) != 0 {
+		err = next[0]
+		s.nextErrs[method] = next[1:]
+	}
+
+	s.calls = append(s.calls, Call{
+		Method:     method,
+		Request:    proto.Clone(req),
+		Err:        err,
+		ReceivedAt: time.Now(),
+	})
+	s.consumed = append(s.consumed, false)
+
+	close(s.changed)
+	s.changed = make(chan struct{})
+
+	if err != nil {
+		return nil, err
+	}
+	return &empty.Empty{}, nil
+}
+
+// Wait blocks until a call of the given method is received for which match
+// returns true, and returns its request. Calls returned by a previous Wait
+// are skipped, so that consecutive Waits return consecutive matching calls.
+// Calls received before Wait was called are taken into account. A nil match
+// matches any call of the method.
+func (s *Server) Wait(method string, match func(proto.Message) bool, timeout time.Duration) (proto.Message, error) {
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+
+	for {
+		s.mu.Lock()
+		for i, c := range s.calls {
+			if s.consumed[i] || c.Method != method {
+				continue
+			}
+			if match == nil || match(c.Request) {
+				s.consumed[i] = true
+				s.mu.Unlock()
+				return proto.Clone(c.Request), nil
+			}
+		}
+		changed := s.changed
+		s.mu.Unlock()
+
+		select {
+		case <-changed:
+		case <-timer.C:
+			return nil, fmt.Errorf("astest: timeout waiting for %s", method)
+		}
+	}
+}
+
+// WaitForUplink waits for a HandleUplinkData call for the given DevEUI.
+func (s *Server) WaitForUplink(devEUI []byte, timeout time.Duration) (*as.HandleUplinkDataRequest, error) {
+	msg, err := s.Wait(MethodHandleUplinkData, func(m proto.Message) bool {
+		return string(m.(*as.HandleUplinkDataRequest).DevEui) == string(devEUI)
+	}, timeout)
+	if err != nil {
+		return nil, err
+	}
+	return msg.(*as.HandleUplinkDataRequest), nil
+}
+
+// WaitForProprietaryUplink waits for a HandleProprietaryUplink call.
+func (s *Server) WaitForProprietaryUplink(timeout time.Duration) (*as.HandleProprietaryUplinkRequest, error) {
+	msg, err := s.Wait(MethodHandleProprietaryUplink, nil, timeout)
+	if err != nil {
+		return nil, err
+	}
+	return msg.(*as.HandleProprietaryUplinkRequest), nil
+}
+
+// WaitForError waits for a HandleError call for the given DevEUI.
+func (s *Server) WaitForError(devEUI []byte, timeout time.Duration) (*as.HandleErrorRequest, error) {
+	msg, err := s.Wait(MethodHandleError, func(m proto.Message) bool {
+		return string(m.(*as.HandleErrorRequest).DevEui) == string(devEUI)
+	}, timeout)
+	if err != nil {
+		return nil, err
+	}
+	return msg.(*as.HandleErrorRequest), nil
+}
+
+// WaitForDownlinkACK waits for a HandleDownlinkACK call for the given
+// DevEUI.
+func (s *Server) WaitForDownlinkACK(devEUI []byte, timeout time.Duration) (*as.HandleDownlinkACKRequest, error) {
+	msg, err := s.Wait(MethodHandleDownlinkACK, func(m proto.Message) bool {
+		return string(m.(*as.HandleDownlinkACKRequest).DevEui) == string(devEUI)
+	}, timeout)
+	if err != nil {
+		return nil, err
+	}
+	return msg.(*as.HandleDownlinkACKRequest), nil
+}
+
+// WaitForGatewayStats waits for a HandleGatewayStats call for the given
+// gateway ID.
+func (s *Server) WaitForGatewayStats(gatewayID []byte, timeout time.Duration) (*as.HandleGatewayStatsRequest, error) {
+	msg, err := s.Wait(MethodHandleGatewayStats, func(m proto.Message) bool {
+		return string(m.(*as.HandleGatewayStatsRequest).GatewayId) == string(gatewayID)
+	}, timeout)
+	if err != nil {
+		return nil, err
+	}
+	return msg.(*as.HandleGatewayStatsRequest), nil
+}
+
+// WaitForDeviceStatus waits for a SetDeviceStatus call for the given DevEUI.
+func (s *Server) WaitForDeviceStatus(devEUI []byte, timeout time.Duration) (*as.SetDeviceStatusRequest, error) {
+	msg, err := s.Wait(MethodSetDeviceStatus, func(m proto.Message) bool {
+		return string(m.(*as.SetDeviceStatusRequest).DevEui) == string(devEUI)
+	}, timeout)
+	if err != nil {
+		return nil, err
+	}
+	return msg.(*as.SetDeviceStatusRequest), nil
+}
+
+// WaitForDeviceLocation waits for a SetDeviceLocation call for the given
+// DevEUI.
+func (s *Server) WaitForDeviceLocation(devEUI []byte, timeout time.Duration) (*as.SetDeviceLocationRequest, error) {
+	msg, err := s.Wait(MethodSetDeviceLocation, func(m proto.Message) bool {
+		return string(m.(*as.SetDeviceLocationRequest).DevEui) == string(devEUI)
+	}, timeout)
+	if err != nil {
+		return nil, err
+	}
+	return msg.(*as.SetDeviceLocationRequest), nil
+}
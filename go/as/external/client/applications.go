@@ -0,0 +1,265 @@
+package client
+
+import (
+	"context"
+
+	"github.com/brocaar/chirpstack-api/go/as/external/api"
+)
+
+// Application defines an application.
+type Application struct {
+	ID                   int64
+	Name                 string
+	Description          string
+	OrganizationID       int64
+	ServiceProfileID     string
+	PayloadCodec         string
+	PayloadEncoderScript string
+	PayloadDecoderScript string
+}
+
+// ApplicationListItem defines an application within a list result-set.
+type ApplicationListItem struct {
+	ID                 int64
+	Name               string
+	Description        string
+	OrganizationID     int64
+	ServiceProfileID   string
+	ServiceProfileName string
+}
+
+// ApplicationFilter defines the filters for listing applications.
+type ApplicationFilter struct {
+	OrganizationID int64
+	Search         string
+}
+
+// ApplicationService wraps the external API application service.
+// The integration messages do not contain any encoded fields and are therefore
+// used as-is.
+type ApplicationService struct {
+	client api.ApplicationServiceClient
+}
+
+// API returns the underlying generated service client.
+func (s *ApplicationService) API() api.ApplicationServiceClient {
+	return s.client
+}
+
+// Create creates the given application and returns its ID.
+func (s *ApplicationService) Create(ctx context.Context, app Application) (int64, error) {
+	resp, err := s.client.Create(ctx, &api.CreateApplicationRequest{
+		Application: app.toProto(),
+	})
+	if err != nil {
+		return 0, err
+	}
+	return resp.Id, nil
+}
+
+// Get returns the application matching the given ID.
+func (s *ApplicationService) Get(ctx context.Context, id int64) (*Application, error) {
+	resp, err := s.client.Get(ctx, &api.GetApplicationRequest{
+		Id: id,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	pb := resp.GetApplication()
+	return &Application{
+		ID:                   pb.GetId(),
+		Name:                 pb.GetName(),
+		Description:          pb.GetDescription(),
+		OrganizationID:       pb.GetOrganizationId(),
+		ServiceProfileID:     pb.GetServiceProfileId(),
+		PayloadCodec:         pb.GetPayloadCodec(),
+		PayloadEncoderScript: pb.GetPayloadEncoderScript(),
+		PayloadDecoderScript: pb.GetPayloadDecoderScript(),
+	}, nil
+}
+
+// Update updates the given application.
+func (s *ApplicationService) Update(ctx context.Context, app Application) error {
+	_, err := s.client.Update(ctx, &api.UpdateApplicationRequest{
+		Application: app.toProto(),
+	})
+	return err
+}
+
+// Delete deletes the application matching the given ID.
+func (s *ApplicationService) Delete(ctx context.Context, id int64) error {
+	_, err := s.client.Delete(ctx, &api.DeleteApplicationRequest{
+		Id: id,
+	})
+	return err
+}
+
+// List returns a page of applications matching the given filter, together
+// with the total number of matching applications.
+func (s *ApplicationService) List(ctx context.Context, filter ApplicationFilter, limit, offset int64) ([]ApplicationListItem, int64, error) {
+	resp, err := s.client.List(ctx, &api.ListApplicationRequest{
+		Limit:          limit,
+		Offset:         offset,
+		OrganizationId: filter.OrganizationID,
+		Search:         filter.Search,
+	})
+	if err != nil {
+		return nil, 0, err
+	}
+
+	out := make([]ApplicationListItem, 0, len(resp.Result))
+	for _, item := range resp.Result {
+		out = append(out, ApplicationListItem{
+			ID:                 item.Id,
+			Name:               item.Name,
+			Description:        item.Description,
+			OrganizationID:     item.OrganizationId,
+			ServiceProfileID:   item.ServiceProfileId,
+			ServiceProfileName: item.ServiceProfileName,
+		})
+	}
+
+	return out, resp.TotalCount, nil
+}
+
+// ListIntegrations returns the integration kinds configured for the given
+// application.
+func (s *ApplicationService) ListIntegrations(ctx context.Context, applicationID int64) ([]api.IntegrationKind, error) {
+	resp, err := s.client.ListIntegrations(ctx, &api.ListIntegrationRequest{
+		ApplicationId: applicationID,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	out := make([]api.IntegrationKind, 0, len(resp.Result))
+	for _, item := range resp.Result {
+		out = append(out, item.Kind)
+	}
+
+	return out, nil
+}
+
+// CreateHTTPIntegration creates the given HTTP integration.
+func (s *ApplicationService) CreateHTTPIntegration(ctx context.Context, i *api.HTTPIntegration) error {
+	_, err := s.client.CreateHTTPIntegration(ctx, &api.CreateHTTPIntegrationRequest{
+		Integration: i,
+	})
+	return err
+}
+
+// GetHTTPIntegration returns the HTTP integration of the given application.
+func (s *ApplicationService) GetHTTPIntegration(ctx context.Context, applicationID int64) (*api.HTTPIntegration, error) {
+	resp, err := s.client.GetHTTPIntegration(ctx, &api.GetHTTPIntegrationRequest{
+		ApplicationId: applicationID,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return resp.Integration, nil
+}
+
+// UpdateHTTPIntegration updates the given HTTP integration.
+func (s *ApplicationService) UpdateHTTPIntegration(ctx context.Context, i *api.HTTPIntegration) error {
+	_, err := s.client.UpdateHTTPIntegration(ctx, &api.UpdateHTTPIntegrationRequest{
+		Integration: i,
+	})
+	return err
+}
+
+// DeleteHTTPIntegration deletes the HTTP integration of the given
+// application.
+func (s *ApplicationService) DeleteHTTPIntegration(ctx context.Context, applicationID int64) error {
+	_, err := s.client.DeleteHTTPIntegration(ctx, &api.DeleteHTTPIntegrationRequest{
+		ApplicationId: applicationID,
+	})
+	return err
+}
+
+// CreateInfluxDBIntegration creates the given InfluxDB integration.
+func (s *ApplicationService) CreateInfluxDBIntegration(ctx context.Context, i *api.InfluxDBIntegration) error {
+	_, err := s.client.CreateInfluxDBIntegration(ctx, &api.CreateInfluxDBIntegrationRequest{
+		Integration: i,
+	})
+	return err
+}
+
+// GetInfluxDBIntegration returns the InfluxDB integration of the given
+// application.
+func (s *ApplicationService) GetInfluxDBIntegration(ctx context.Context, applicationID int64) (*api.InfluxDBIntegration, error) {
+	resp, err := s.client.GetInfluxDBIntegration(ctx, &api.GetInfluxDBIntegrationRequest{
+		ApplicationId: applicationID,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return resp.Integration, nil
+}
+
+// UpdateInfluxDBIntegration updates the given InfluxDB integration.
+func (s *ApplicationService) UpdateInfluxDBIntegration(ctx context.Context, i *api.InfluxDBIntegration) error {
+	_, err := s.client.UpdateInfluxDBIntegration(ctx, &api.UpdateInfluxDBIntegrationRequest{
+		Integration: i,
+	})
+	return err
+}
+
+// DeleteInfluxDBIntegration deletes the InfluxDB integration of the given
+// application.
+func (s *ApplicationService) DeleteInfluxDBIntegration(ctx context.Context, applicationID int64) error {
+	_, err := s.client.DeleteInfluxDBIntegration(ctx, &api.DeleteInfluxDBIntegrationRequest{
+		ApplicationId: applicationID,
+	})
+	return err
+}
+
+// CreateThingsBoardIntegration creates the given ThingsBoard integration.
+func (s *ApplicationService) CreateThingsBoardIntegration(ctx context.Context, i *api.ThingsBoardIntegration) error {
+	_, err := s.client.CreateThingsBoardIntegration(ctx, &api.CreateThingsBoardIntegrationRequest{
+		Integration: i,
+	})
+	return err
+}
+
+// GetThingsBoardIntegration returns the ThingsBoard integration of the given
+// application.
+func (s *ApplicationService) GetThingsBoardIntegration(ctx context.Context, applicationID int64) (*api.ThingsBoardIntegration, error) {
+	resp, err := s.client.GetThingsBoardIntegration(ctx, &api.GetThingsBoardIntegrationRequest{
+		ApplicationId: applicationID,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return resp.Integration, nil
+}
+
+// UpdateThingsBoardIntegration updates the given ThingsBoard integration.
+func (s *ApplicationService) UpdateThingsBoardIntegration(ctx context.Context, i *api.ThingsBoardIntegration) error {
+	_, err := s.client.UpdateThingsBoardIntegration(ctx, &api.UpdateThingsBoardIntegrationRequest{
+		Integration: i,
+	})
+	return err
+}
+
+// DeleteThingsBoardIntegration deletes the ThingsBoard integration of the
+// given application.
+func (s *ApplicationService) DeleteThingsBoardIntegration(ctx context.Context, applicationID int64) error {
+	_, err := s.client.DeleteThingsBoardIntegration(ctx, &api.DeleteThingsBoardIntegrationRequest{
+		ApplicationId: applicationID,
+	})
+	return err
+}
+
+func (app Application) toProto() *api.Application {
+	return &api.Application{
+		Id:                   app.ID,
+		Name:                 app.Name,
+		Description:          app.Description,
+		OrganizationId:       app.OrganizationID,
+		ServiceProfileId:     app.ServiceProfileID,
+		PayloadCodec:         app.PayloadCodec,
+		PayloadEncoderScript: app.PayloadEncoderScript,
+		PayloadDecoderScript: app.PayloadDecoderScript,
+	}
+}
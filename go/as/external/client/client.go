@@ -0,0 +1,235 @@
+// Package client provides a typed client for the ChirpStack Application Server
+// external API. It wraps the generated gRPC service clients of the api package
+// and exposes them using Go-native types.
+package client
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+
+	"github.com/brocaar/chirpstack-api/go/as/external/api"
+)
+
+// Option configures the Client.
+type Option func(*options)
+
+type options struct {
+	tlsConfig   *tls.Config
+	insecure    bool
+	apiToken    string
+	username    string
+	password    string
+	dialOptions []grpc.DialOption
+}
+
+// WithTLSConfig sets the TLS configuration used for the connection.
+func WithTLSConfig(conf *tls.Config) Option {
+	return func(o *options) {
+		o.tlsConfig = conf
+		o.insecure = false
+	}
+}
+
+// WithInsecure disables transport security for the connection.
+func WithInsecure() Option {
+	return func(o *options) {
+		o.tlsConfig = nil
+		o.insecure = true
+	}
+}
+
+// WithAPIToken sets the (JWT) API token used to authenticate each call.
+func WithAPIToken(token string) Option {
+	return func(o *options) {
+		o.apiToken = token
+	}
+}
+
+// WithLogin sets the username and password used to obtain a JWT token through
+// InternalService.Login. The login is performed on the first call and is
+// repeated when the token has expired.
+func WithLogin(username, password string) Option {
+	return func(o *options) {
+		o.username = username
+		o.password = password
+	}
+}
+
+// WithDialOptions appends additional gRPC dial options.
+func WithDialOptions(opts ...grpc.DialOption) Option {
+	return func(o *options) {
+		o.dialOptions = append(o.dialOptions, opts...)
+	}
+}
+
+// Client implements a client for the external API.
+type Client struct {
+	conn *grpc.ClientConn
+
+	applications     *ApplicationService
+	devices          *DeviceService
+	deviceProfiles   *DeviceProfileService
+	deviceQueue      *DeviceQueueService
+	fuotaDeployments *FUOTADeploymentService
+	gateways         *GatewayService
+	gatewayProfiles  *GatewayProfileService
+	internal         *InternalService
+	multicastGroups  *MulticastGroupService
+	networkServers   *NetworkServerService
+	organizations    *OrganizationService
+	serviceProfiles  *ServiceProfileService
+	users            *UserService
+}
+
+// New dials the given target and returns a new Client. Unless WithInsecure is
+// given, the connection is secured using TLS.
+func New(ctx context.Context, target string, opts ...Option) (*Client, error) {
+	var o options
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	var dialOpts []grpc.DialOption
+	if o.insecure {
+		dialOpts = append(dialOpts, grpc.WithInsecure())
+	} else {
+		conf := o.tlsConfig
+		if conf == nil {
+			conf = &tls.Config{}
+		}
+		dialOpts = append(dialOpts, grpc.WithTransportCredentials(credentials.NewTLS(conf)))
+	}
+
+	var creds *JWTCredentials
+	if o.apiToken != "" {
+		creds = NewAPITokenCredentials(o.apiToken)
+	} else if o.username != "" {
+		creds = NewLoginCredentials(o.username, o.password)
+	}
+	if creds != nil {
+		creds.SetRequireTransportSecurity(!o.insecure)
+		dialOpts = append(dialOpts,
+			grpc.WithPerRPCCredentials(creds),
+			grpc.WithChainUnaryInterceptor(creds.UnaryClientInterceptor()),
+			grpc.WithChainStreamInterceptor(creds.StreamClientInterceptor()),
+		)
+	}
+
+	dialOpts = append(dialOpts, o.dialOptions...)
+
+	conn, err := grpc.DialContext(ctx, target, dialOpts...)
+	if err != nil {
+		return nil, fmt.Errorf("client: dial %s: %w", target, err)
+	}
+
+	c := NewFromConn(conn)
+	if creds != nil {
+		creds.SetLoginClient(c.internal.client)
+		c.devices.creds = creds
+		c.gateways.creds = creds
+	}
+
+	return c, nil
+}
+
+// NewFromConn returns a new Client using the given connection. Closing the
+// Client will close the connection. As the credentials of the connection are
+// not known, stream subscriptions end on codes.Unauthenticated.
+func NewFromConn(conn *grpc.ClientConn) *Client {
+	return &Client{
+		conn: conn,
+
+		applications:     &ApplicationService{client: api.NewApplicationServiceClient(conn)},
+		devices:          &DeviceService{client: api.NewDeviceServiceClient(conn)},
+		deviceProfiles:   &DeviceProfileService{client: api.NewDeviceProfileServiceClient(conn)},
+		deviceQueue:      &DeviceQueueService{client: api.NewDeviceQueueServiceClient(conn)},
+		fuotaDeployments: &FUOTADeploymentService{client: api.NewFUOTADeploymentServiceClient(conn)},
+		gateways:         &GatewayService{client: api.NewGatewayServiceClient(conn)},
+		gatewayProfiles:  &GatewayProfileService{client: api.NewGatewayProfileServiceClient(conn)},
+		internal:         &InternalService{client: api.NewInternalServiceClient(conn)},
+		multicastGroups:  &MulticastGroupService{client: api.NewMulticastGroupServiceClient(conn)},
+		networkServers:   &NetworkServerService{client: api.NewNetworkServerServiceClient(conn)},
+		organizations:    &OrganizationService{client: api.NewOrganizationServiceClient(conn)},
+		serviceProfiles:  &ServiceProfileService{client: api.NewServiceProfileServiceClient(conn)},
+		users:            &UserService{client: api.NewUserServiceClient(conn)},
+	}
+}
+
+// Conn returns the underlying gRPC connection.
+func (c *Client) Conn() *grpc.ClientConn {
+	return c.conn
+}
+
+// Close closes the underlying gRPC connection.
+func (c *Client) Close() error {
+	return c.conn.Close()
+}
+
+// Applications returns the application service.
+func (c *Client) Applications() *ApplicationService {
+	return c.applications
+}
+
+// Devices returns the device service.
+func (c *Client) Devices() *DeviceService {
+	return c.devices
+}
+
+// DeviceProfiles returns the device-profile service.
+func (c *Client) DeviceProfiles() *DeviceProfileService {
+	return c.deviceProfiles
+}
+
+// DeviceQueue returns the device-queue service.
+func (c *Client) DeviceQueue() *DeviceQueueService {
+	return c.deviceQueue
+}
+
+// FUOTADeployments returns the FUOTA deployment service.
+func (c *Client) FUOTADeployments() *FUOTADeploymentService {
+	return c.fuotaDeployments
+}
+
+// Gateways returns the gateway service.
+func (c *Client) Gateways() *GatewayService {
+	return c.gateways
+}
+
+// GatewayProfiles returns the gateway-profile service.
+func (c *Client) GatewayProfiles() *GatewayProfileService {
+	return c.gatewayProfiles
+}
+
+// Internal returns the internal service.
+func (c *Client) Internal() *InternalService {
+	return c.internal
+}
+
+// MulticastGroups returns the multicast-group service.
+func (c *Client) MulticastGroups() *MulticastGroupService {
+	return c.multicastGroups
+}
+
+// NetworkServers returns the network-server service.
+func (c *Client) NetworkServers() *NetworkServerService {
+	return c.networkServers
+}
+
+// Organizations returns the organization service.
+func (c *Client) Organizations() *OrganizationService {
+	return c.organizations
+}
+
+// ServiceProfiles returns the service-profile service.
+func (c *Client) ServiceProfiles() *ServiceProfileService {
+	return c.serviceProfiles
+}
+
+// Users returns the user service.
+func (c *Client) Users() *UserService {
+	return c.users
+}
@@ -0,0 +1,39 @@
+package client
+
+import (
+	"context"
+	"net"
+	"testing"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+// newTestClient serves the services registered by the given function over an
+// in-memory listener and returns a Client connected to it. The server and
+// client are stopped by calling the returned function.
+func newTestClient(t *testing.T, register func(*grpc.Server), opts ...Option) (*Client, func()) {
+	t.Helper()
+
+	lis := bufconn.Listen(1024 * 1024)
+	server := grpc.NewServer()
+	register(server)
+	go server.Serve(lis)
+
+	opts = append([]Option{
+		WithInsecure(),
+		WithDialOptions(grpc.WithContextDialer(func(context.Context, string) (net.Conn, error) {
+			return lis.Dial()
+		})),
+	}, opts...)
+
+	c, err := New(context.Background(), "bufnet", opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return c, func() {
+		c.Close()
+		server.Stop()
+	}
+}
@@ -0,0 +1,273 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/brocaar/chirpstack-api/go/as/external/api"
+)
+
+// loginTimeout defines the timeout of the login call. The login is shared by
+// all calls waiting for a token, thus it does not use the context of any of
+// these calls.
+const loginTimeout = 30 * time.Second
+
+// skipAuthKey marks a context for which no authorization metadata must be
+// added (e.g. the login call itself).
+type skipAuthKey struct{}
+
+// JWTCredentials implements grpc.PerRPCCredentials using a JWT token. The
+// token is either a static API token, or is obtained by logging in with a
+// username and password through InternalService.Login.
+//
+// When using a username and password, the login is performed lazily on the
+// first call. Use the UnaryClientInterceptor and StreamClientInterceptor to
+// transparently log in again when a call fails with codes.Unauthenticated
+// (e.g. because the token has expired).
+//
+// Concurrent calls which need a new token share a single login. Each call
+// stops waiting for it when its own context is done.
+type JWTCredentials struct {
+	mu          sync.Mutex
+	token       string
+	login       *loginCall
+	username    string
+	password    string
+	loginClient api.InternalServiceClient
+	insecure    bool
+}
+
+// NewAPITokenCredentials returns credentials for the given static API token.
+func NewAPITokenCredentials(token string) *JWTCredentials {
+	return &JWTCredentials{
+		token: token,
+	}
+}
+
+// NewLoginCredentials returns credentials which log in using the given
+// username and password. SetLoginClient must be called before the first call
+// is made.
+func NewLoginCredentials(username, password string) *JWTCredentials {
+	return &JWTCredentials{
+		username: username,
+		password: password,
+	}
+}
+
+// SetLoginClient sets the client used for logging in. This is typically a
+// client using the same connection as the one these credentials are
+// attached to.
+func (c *JWTCredentials) SetLoginClient(client api.InternalServiceClient) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.loginClient = client
+}
+
+// SetRequireTransportSecurity sets if the credentials require a secure
+// connection (the default).
+func (c *JWTCredentials) SetRequireTransportSecurity(b bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.insecure = !b
+}
+
+// GetRequestMetadata returns the authorization metadata, logging in first
+// when needed.
+func (c *JWTCredentials) GetRequestMetadata(ctx context.Context, uri ...string) (map[string]string, error) {
+	if ctx.Value(skipAuthKey{}) != nil {
+		return nil, nil
+	}
+
+	token, err := c.Token(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return map[string]string{
+		"authorization": "Bearer " + token,
+	}, nil
+}
+
+// RequireTransportSecurity indicates whether the credentials require
+// transport security.
+func (c *JWTCredentials) RequireTransportSecurity() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return !c.insecure
+}
+
+// Token returns the current token, logging in first when needed.
+func (c *JWTCredentials) Token(ctx context.Context) (string, error) {
+	c.mu.Lock()
+
+	if c.token != "" {
+		token := c.token
+		c.mu.Unlock()
+		return token, nil
+	}
+
+	if c.username == "" {
+		c.mu.Unlock()
+		return "", errors.New("client: no API token or login credentials configured")
+	}
+
+	if c.loginClient == nil {
+		c.mu.Unlock()
+		return "", errors.New("client: login client is not set")
+	}
+
+	call := c.login
+	if call == nil {
+		call = &loginCall{done: make(chan struct{})}
+		c.login = call
+		go c.doLogin(call, c.loginClient, &api.LoginRequest{
+			Username: c.username,
+			Password: c.password,
+		})
+	}
+	c.mu.Unlock()
+
+	select {
+	case <-call.done:
+		return call.token, call.err
+	case <-ctx.Done():
+		return "", ctx.Err()
+	}
+}
+
+// loginCall holds the result of an in-flight login. The token and err fields
+// are set before done is closed.
+type loginCall struct {
+	done  chan struct{}
+	token string
+	err   error
+}
+
+// doLogin performs the login and stores the token.
+func (c *JWTCredentials) doLogin(call *loginCall, client api.InternalServiceClient, req *api.LoginRequest) {
+	ctx, cancel := context.WithTimeout(context.WithValue(context.Background(), skipAuthKey{}, true), loginTimeout)
+	defer cancel()
+
+	resp, err := client.Login(ctx, req)
+
+	c.mu.Lock()
+	c.login = nil
+	if err != nil {
+		call.err = err
+	} else {
+		c.token = resp.Jwt
+		call.token = resp.Jwt
+	}
+	c.mu.Unlock()
+
+	close(call.done)
+}
+
+// UnaryClientInterceptor returns an interceptor which logs in again and
+// retries the call once when it fails with codes.Unauthenticated.
+func (c *JWTCredentials) UnaryClientInterceptor() grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		if ctx.Value(skipAuthKey{}) != nil {
+			return invoker(ctx, method, req, reply, cc, opts...)
+		}
+
+		token := c.currentToken()
+		err := invoker(ctx, method, req, reply, cc, opts...)
+		if !c.shouldRetry(token, err) {
+			return err
+		}
+
+		return invoker(ctx, method, req, reply, cc, opts...)
+	}
+}
+
+// StreamClientInterceptor returns an interceptor which logs in again and
+// retries opening the stream once when this fails with
+// codes.Unauthenticated. When an already opened stream fails with
+// codes.Unauthenticated, the token is invalidated so that re-opening the
+// stream performs a new login.
+func (c *JWTCredentials) StreamClientInterceptor() grpc.StreamClientInterceptor {
+	return func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+		if ctx.Value(skipAuthKey{}) != nil {
+			return streamer(ctx, desc, cc, method, opts...)
+		}
+
+		token := c.currentToken()
+		stream, err := streamer(ctx, desc, cc, method, opts...)
+		if c.shouldRetry(token, err) {
+			stream, err = streamer(ctx, desc, cc, method, opts...)
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		return &jwtClientStream{ClientStream: stream, creds: c, token: c.currentToken()}, nil
+	}
+}
+
+// currentToken returns the current token without logging in.
+func (c *JWTCredentials) currentToken() string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.token
+}
+
+// invalidate clears the given token, so that the next call performs a new
+// login. This is a no-op for static API tokens, or when the token has already
+// been replaced by a concurrent call.
+func (c *JWTCredentials) invalidate(token string) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.username == "" || token == "" {
+		return false
+	}
+
+	if c.token == token {
+		c.token = ""
+	}
+	return true
+}
+
+// canRefresh returns true when the credentials can obtain a new token by
+// logging in again. It returns false for nil credentials.
+func (c *JWTCredentials) canRefresh() bool {
+	if c == nil {
+		return false
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.username != ""
+}
+
+// shouldRetry returns true when the given error was caused by an expired (or
+// revoked) token and a new login could resolve it.
+func (c *JWTCredentials) shouldRetry(token string, err error) bool {
+	if status.Code(err) != codes.Unauthenticated {
+		return false
+	}
+
+	// When no token was set before the call, the login was performed as part
+	// of this call and a retry will not help.
+	return c.invalidate(token)
+}
+
+type jwtClientStream struct {
+	grpc.ClientStream
+	creds *JWTCredentials
+	token string
+}
+
+func (s *jwtClientStream) RecvMsg(m interface{}) error {
+	err := s.ClientStream.RecvMsg(m)
+	if status.Code(err) == codes.Unauthenticated {
+		s.creds.invalidate(s.token)
+	}
+	return err
+}
@@ -0,0 +1,149 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/golang/protobuf/ptypes/empty"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	"github.com/brocaar/chirpstack-api/go/as/external/api"
+)
+
+// loginServer implements the Login and Profile calls of the internal
+// service. Each login issues a new token, which replaces the previous one.
+type loginServer struct {
+	api.UnimplementedInternalServiceServer
+
+	mu     sync.Mutex
+	logins int
+	valid  string
+	block  chan struct{}
+}
+
+func (s *loginServer) Login(ctx context.Context, req *api.LoginRequest) (*api.LoginResponse, error) {
+	if s.block != nil {
+		<-s.block
+	}
+
+	// Make sure concurrent calls pile up behind the login.
+	time.Sleep(50 * time.Millisecond)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if req.Username != "admin" || req.Password != "secret" {
+		return nil, status.Error(codes.Unauthenticated, "invalid username or password")
+	}
+
+	s.logins++
+	s.valid = fmt.Sprintf("token-%d", s.logins)
+	return &api.LoginResponse{Jwt: s.valid}, nil
+}
+
+func (s *loginServer) Profile(ctx context.Context, req *empty.Empty) (*api.ProfileResponse, error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if auth := md.Get("authorization"); len(auth) != 1 || s.valid == "" || auth[0] != "Bearer "+s.valid {
+		return nil, status.Error(codes.Unauthenticated, "authentication failed")
+	}
+	return &api.ProfileResponse{}, nil
+}
+
+// expire invalidates the current token.
+func (s *loginServer) expire() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.valid = ""
+}
+
+func (s *loginServer) loginCount() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.logins
+}
+
+func TestConcurrentLogin(t *testing.T) {
+	srv := &loginServer{}
+	c, stop := newTestClient(t, func(s *grpc.Server) {
+		api.RegisterInternalServiceServer(s, srv)
+	}, WithLogin("admin", "secret"))
+	defer stop()
+
+	ctx := context.Background()
+
+	if _, err := c.Internal().Profile(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if n := srv.loginCount(); n != 1 {
+		t.Fatalf("expected 1 login, got %d", n)
+	}
+
+	srv.expire()
+
+	const calls = 20
+	var wg sync.WaitGroup
+	errs := make(chan error, calls)
+	for i := 0; i < calls; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := c.Internal().Profile(ctx)
+			errs <- err
+		}()
+	}
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		if err != nil {
+			t.Errorf("unexpected error: %s", err)
+		}
+	}
+	if n := srv.loginCount(); n != 2 {
+		t.Errorf("expected a single login for %d calls with an expired token, got %d", calls, n-1)
+	}
+}
+
+func TestLoginContextDone(t *testing.T) {
+	srv := &loginServer{block: make(chan struct{})}
+	c, stop := newTestClient(t, func(s *grpc.Server) {
+		api.RegisterInternalServiceServer(s, srv)
+	}, WithLogin("admin", "secret"))
+	defer stop()
+	defer close(srv.block)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	_, err := c.Internal().Profile(ctx)
+	if status.Code(err) != codes.DeadlineExceeded {
+		t.Errorf("expected DeadlineExceeded, got %v", err)
+	}
+	if d := time.Since(start); d > time.Second {
+		t.Errorf("call did not give up waiting for the login (took %s)", d)
+	}
+}
+
+func TestLoginInvalidCredentials(t *testing.T) {
+	srv := &loginServer{}
+	c, stop := newTestClient(t, func(s *grpc.Server) {
+		api.RegisterInternalServiceServer(s, srv)
+	}, WithLogin("admin", "wrong"))
+	defer stop()
+
+	_, err := c.Internal().Profile(context.Background())
+	if status.Code(err) != codes.Unauthenticated {
+		t.Errorf("expected Unauthenticated, got %v", err)
+	}
+}
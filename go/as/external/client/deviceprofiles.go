@@ -0,0 +1,114 @@
+package client
+
+import (
+	"context"
+	"time"
+
+	"github.com/brocaar/chirpstack-api/go/as/external/api"
+)
+
+// DeviceProfileDetails contains a device-profile together with its
+// timestamps. The device-profile message does not contain any encoded fields
+// and is therefore used as-is.
+type DeviceProfileDetails struct {
+	DeviceProfile *api.DeviceProfile
+	CreatedAt     time.Time
+	UpdatedAt     time.Time
+}
+
+// DeviceProfileListItem defines a device-profile within a list result-set.
+type DeviceProfileListItem struct {
+	ID              string
+	Name            string
+	OrganizationID  int64
+	NetworkServerID int64
+	CreatedAt       time.Time
+	UpdatedAt       time.Time
+}
+
+// DeviceProfileFilter defines the filters for listing device-profiles.
+type DeviceProfileFilter struct {
+	OrganizationID int64
+	ApplicationID  int64
+}
+
+// DeviceProfileService wraps the external API device-profile service.
+type DeviceProfileService struct {
+	client api.DeviceProfileServiceClient
+}
+
+// API returns the underlying generated service client.
+func (s *DeviceProfileService) API() api.DeviceProfileServiceClient {
+	return s.client
+}
+
+// Create creates the given device-profile and returns its ID.
+func (s *DeviceProfileService) Create(ctx context.Context, dp *api.DeviceProfile) (string, error) {
+	resp, err := s.client.Create(ctx, &api.CreateDeviceProfileRequest{
+		DeviceProfile: dp,
+	})
+	if err != nil {
+		return "", err
+	}
+	return resp.Id, nil
+}
+
+// Get returns the device-profile matching the given ID.
+func (s *DeviceProfileService) Get(ctx context.Context, id string) (*DeviceProfileDetails, error) {
+	resp, err := s.client.Get(ctx, &api.GetDeviceProfileRequest{
+		Id: id,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &DeviceProfileDetails{
+		DeviceProfile: resp.DeviceProfile,
+		CreatedAt:     fromTimestamp(resp.CreatedAt),
+		UpdatedAt:     fromTimestamp(resp.UpdatedAt),
+	}, nil
+}
+
+// Update updates the given device-profile.
+func (s *DeviceProfileService) Update(ctx context.Context, dp *api.DeviceProfile) error {
+	_, err := s.client.Update(ctx, &api.UpdateDeviceProfileRequest{
+		DeviceProfile: dp,
+	})
+	return err
+}
+
+// Delete deletes the device-profile matching the given ID.
+func (s *DeviceProfileService) Delete(ctx context.Context, id string) error {
+	_, err := s.client.Delete(ctx, &api.DeleteDeviceProfileRequest{
+		Id: id,
+	})
+	return err
+}
+
+// List returns a page of device-profiles matching the given filter, together
+// with the total number of matching device-profiles.
+func (s *DeviceProfileService) List(ctx context.Context, filter DeviceProfileFilter, limit, offset int64) ([]DeviceProfileListItem, int64, error) {
+	resp, err := s.client.List(ctx, &api.ListDeviceProfileRequest{
+		Limit:          limit,
+		Offset:         offset,
+		OrganizationId: filter.OrganizationID,
+		ApplicationId:  filter.ApplicationID,
+	})
+	if err != nil {
+		return nil, 0, err
+	}
+
+	out := make([]DeviceProfileListItem, 0, len(resp.Result))
+	for _, item := range resp.Result {
+		out = append(out, DeviceProfileListItem{
+			ID:              item.Id,
+			Name:            item.Name,
+			OrganizationID:  item.OrganizationId,
+			NetworkServerID: item.NetworkServerId,
+			CreatedAt:       fromTimestamp(item.CreatedAt),
+			UpdatedAt:       fromTimestamp(item.UpdatedAt),
+		})
+	}
+
+	return out, resp.TotalCount, nil
+}
@@ -0,0 +1,79 @@
+package client
+
+import (
+	"context"
+
+	"github.com/brocaar/chirpstack-api/go/as/external/api"
+	"github.com/brocaar/chirpstack-api/go/lorawan"
+)
+
+// DeviceQueueItem defines a device-queue item.
+type DeviceQueueItem struct {
+	DevEUI     lorawan.EUI64
+	Confirmed  bool
+	FCnt       uint32
+	FPort      uint32
+	Data       []byte
+	JSONObject string
+}
+
+// DeviceQueueService wraps the external API device-queue service.
+type DeviceQueueService struct {
+	client api.DeviceQueueServiceClient
+}
+
+// API returns the underlying generated service client.
+func (s *DeviceQueueService) API() api.DeviceQueueServiceClient {
+	return s.client
+}
+
+// Enqueue adds the given item to the device-queue and returns the
+// frame-counter used for the enqueued payload.
+func (s *DeviceQueueService) Enqueue(ctx context.Context, item DeviceQueueItem) (uint32, error) {
+	resp, err := s.client.Enqueue(ctx, &api.EnqueueDeviceQueueItemRequest{
+		DeviceQueueItem: &api.DeviceQueueItem{
+			DevEui:     item.DevEUI.String(),
+			Confirmed:  item.Confirmed,
+			FCnt:       item.FCnt,
+			FPort:      item.FPort,
+			Data:       item.Data,
+			JsonObject: item.JSONObject,
+		},
+	})
+	if err != nil {
+		return 0, err
+	}
+	return resp.FCnt, nil
+}
+
+// Flush flushes the downlink device-queue.
+func (s *DeviceQueueService) Flush(ctx context.Context, devEUI lorawan.EUI64) error {
+	_, err := s.client.Flush(ctx, &api.FlushDeviceQueueRequest{
+		DevEui: devEUI.String(),
+	})
+	return err
+}
+
+// List lists the items in the device-queue.
+func (s *DeviceQueueService) List(ctx context.Context, devEUI lorawan.EUI64) ([]DeviceQueueItem, error) {
+	resp, err := s.client.List(ctx, &api.ListDeviceQueueItemsRequest{
+		DevEui: devEUI.String(),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	out := make([]DeviceQueueItem, 0, len(resp.DeviceQueueItems))
+	for _, item := range resp.DeviceQueueItems {
+		out = append(out, DeviceQueueItem{
+			DevEUI:     devEUI,
+			Confirmed:  item.Confirmed,
+			FCnt:       item.FCnt,
+			FPort:      item.FPort,
+			Data:       item.Data,
+			JSONObject: item.JsonObject,
+		})
+	}
+
+	return out, nil
+}
@@ -0,0 +1,362 @@
+package client
+
+import (
+	"context"
+	"time"
+
+	"github.com/brocaar/chirpstack-api/go/as/external/api"
+	"github.com/brocaar/chirpstack-api/go/common"
+	"github.com/brocaar/chirpstack-api/go/lorawan"
+)
+
+// Device defines a device.
+type Device struct {
+	DevEUI            lorawan.EUI64
+	Name              string
+	ApplicationID     int64
+	Description       string
+	DeviceProfileID   string
+	SkipFCntCheck     bool
+	ReferenceAltitude float64
+	Variables         map[string]string
+	Tags              map[string]string
+}
+
+// DeviceDetails contains a device together with its state.
+type DeviceDetails struct {
+	Device
+
+	LastSeenAt          time.Time
+	DeviceStatusBattery uint32
+	DeviceStatusMargin  int32
+	Location            *common.Location
+}
+
+// DeviceListItem defines a device within a list result-set.
+type DeviceListItem struct {
+	DevEUI                              lorawan.EUI64
+	Name                                string
+	ApplicationID                       int64
+	Description                         string
+	DeviceProfileID                     string
+	DeviceProfileName                   string
+	DeviceStatusBattery                 uint32
+	DeviceStatusMargin                  int32
+	DeviceStatusExternalPowerSource     bool
+	DeviceStatusBatteryLevelUnavailable bool
+	DeviceStatusBatteryLevel            float32
+	LastSeenAt                          time.Time
+}
+
+// DeviceFilter defines the filters for listing devices.
+type DeviceFilter struct {
+	ApplicationID    int64
+	Search           string
+	MulticastGroupID string
+	ServiceProfileID string
+}
+
+// DeviceKeys defines the device root-keys.
+// For LoRaWAN 1.0.x devices, NwkKey holds the LoRaWAN 1.0.x AppKey.
+type DeviceKeys struct {
+	DevEUI    lorawan.EUI64
+	NwkKey    lorawan.AES128Key
+	AppKey    *lorawan.AES128Key
+	GenAppKey *lorawan.AES128Key
+}
+
+// DeviceActivation defines the activation state of a device.
+type DeviceActivation struct {
+	DevEUI      lorawan.EUI64
+	DevAddr     lorawan.DevAddr
+	AppSKey     lorawan.AES128Key
+	NwkSEncKey  lorawan.AES128Key
+	SNwkSIntKey lorawan.AES128Key
+	FNwkSIntKey lorawan.AES128Key
+	FCntUp      uint32
+	NFCntDown   uint32
+	AFCntDown   uint32
+}
+
+// DeviceService wraps the external API device service.
+type DeviceService struct {
+	client api.DeviceServiceClient
+
+	// creds holds the credentials of the client, used for deciding if a
+	// stream can be re-opened after an authentication error.
+	creds *JWTCredentials
+}
+
+// API returns the underlying generated service client.
+func (s *DeviceService) API() api.DeviceServiceClient {
+	return s.client
+}
+
+// Create creates the given device.
+func (s *DeviceService) Create(ctx context.Context, d Device) error {
+	_, err := s.client.Create(ctx, &api.CreateDeviceRequest{
+		Device: d.toProto(),
+	})
+	return err
+}
+
+// Get returns the device matching the given DevEUI.
+func (s *DeviceService) Get(ctx context.Context, devEUI lorawan.EUI64) (*DeviceDetails, error) {
+	resp, err := s.client.Get(ctx, &api.GetDeviceRequest{
+		DevEui: devEUI.String(),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	d, err := deviceFromProto(resp.GetDevice())
+	if err != nil {
+		return nil, err
+	}
+
+	return &DeviceDetails{
+		Device:              d,
+		LastSeenAt:          fromTimestamp(resp.LastSeenAt),
+		DeviceStatusBattery: resp.DeviceStatusBattery,
+		DeviceStatusMargin:  resp.DeviceStatusMargin,
+		Location:            resp.Location,
+	}, nil
+}
+
+// Update updates the given device.
+func (s *DeviceService) Update(ctx context.Context, d Device) error {
+	_, err := s.client.Update(ctx, &api.UpdateDeviceRequest{
+		Device: d.toProto(),
+	})
+	return err
+}
+
+// Delete deletes the device matching the given DevEUI.
+func (s *DeviceService) Delete(ctx context.Context, devEUI lorawan.EUI64) error {
+	_, err := s.client.Delete(ctx, &api.DeleteDeviceRequest{
+		DevEui: devEUI.String(),
+	})
+	return err
+}
+
+// List returns a page of devices matching the given filter, together with
+// the total number of matching devices.
+func (s *DeviceService) List(ctx context.Context, filter DeviceFilter, limit, offset int64) ([]DeviceListItem, int64, error) {
+	resp, err := s.client.List(ctx, &api.ListDeviceRequest{
+		Limit:            limit,
+		Offset:           offset,
+		ApplicationId:    filter.ApplicationID,
+		Search:           filter.Search,
+		MulticastGroupId: filter.MulticastGroupID,
+		ServiceProfileId: filter.ServiceProfileID,
+	})
+	if err != nil {
+		return nil, 0, err
+	}
+
+	out := make([]DeviceListItem, 0, len(resp.Result))
+	for _, item := range resp.Result {
+		devEUI, err := lorawan.ParseEUI64(item.DevEui)
+		if err != nil {
+			return nil, 0, err
+		}
+
+		out = append(out, DeviceListItem{
+			DevEUI:                              devEUI,
+			Name:                                item.Name,
+			ApplicationID:                       item.ApplicationId,
+			Description:                         item.Description,
+			DeviceProfileID:                     item.DeviceProfileId,
+			DeviceProfileName:                   item.DeviceProfileName,
+			DeviceStatusBattery:                 item.DeviceStatusBattery,
+			DeviceStatusMargin:                  item.DeviceStatusMargin,
+			DeviceStatusExternalPowerSource:     item.DeviceStatusExternalPowerSource,
+			DeviceStatusBatteryLevelUnavailable: item.DeviceStatusBatteryLevelUnavailable,
+			DeviceStatusBatteryLevel:            item.DeviceStatusBatteryLevel,
+			LastSeenAt:                          fromTimestamp(item.LastSeenAt),
+		})
+	}
+
+	return out, resp.TotalCount, nil
+}
+
+// CreateKeys creates the given device-keys.
+func (s *DeviceService) CreateKeys(ctx context.Context, k DeviceKeys) error {
+	_, err := s.client.CreateKeys(ctx, &api.CreateDeviceKeysRequest{
+		DeviceKeys: k.toProto(),
+	})
+	return err
+}
+
+// GetKeys returns the device-keys for the given DevEUI.
+func (s *DeviceService) GetKeys(ctx context.Context, devEUI lorawan.EUI64) (*DeviceKeys, error) {
+	resp, err := s.client.GetKeys(ctx, &api.GetDeviceKeysRequest{
+		DevEui: devEUI.String(),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	pb := resp.GetDeviceKeys()
+	k := DeviceKeys{
+		DevEUI: devEUI,
+	}
+	if k.NwkKey, err = lorawan.ParseAES128Key(pb.GetNwkKey()); err != nil {
+		return nil, err
+	}
+	if k.AppKey, err = parseOptionalKey(pb.GetAppKey()); err != nil {
+		return nil, err
+	}
+	if k.GenAppKey, err = parseOptionalKey(pb.GetGenAppKey()); err != nil {
+		return nil, err
+	}
+
+	return &k, nil
+}
+
+// UpdateKeys updates the given device-keys.
+func (s *DeviceService) UpdateKeys(ctx context.Context, k DeviceKeys) error {
+	_, err := s.client.UpdateKeys(ctx, &api.UpdateDeviceKeysRequest{
+		DeviceKeys: k.toProto(),
+	})
+	return err
+}
+
+// DeleteKeys deletes the device-keys for the given DevEUI.
+func (s *DeviceService) DeleteKeys(ctx context.Context, devEUI lorawan.EUI64) error {
+	_, err := s.client.DeleteKeys(ctx, &api.DeleteDeviceKeysRequest{
+		DevEui: devEUI.String(),
+	})
+	return err
+}
+
+// Activate (re)activates the device (only when ABP is set to true).
+func (s *DeviceService) Activate(ctx context.Context, a DeviceActivation) error {
+	_, err := s.client.Activate(ctx, &api.ActivateDeviceRequest{
+		DeviceActivation: &api.DeviceActivation{
+			DevEui:      a.DevEUI.String(),
+			DevAddr:     a.DevAddr.String(),
+			AppSKey:     a.AppSKey.String(),
+			NwkSEncKey:  a.NwkSEncKey.String(),
+			SNwkSIntKey: a.SNwkSIntKey.String(),
+			FNwkSIntKey: a.FNwkSIntKey.String(),
+			FCntUp:      a.FCntUp,
+			NFCntDown:   a.NFCntDown,
+			AFCntDown:   a.AFCntDown,
+		},
+	})
+	return err
+}
+
+// Deactivate de-activates the device.
+func (s *DeviceService) Deactivate(ctx context.Context, devEUI lorawan.EUI64) error {
+	_, err := s.client.Deactivate(ctx, &api.DeactivateDeviceRequest{
+		DevEui: devEUI.String(),
+	})
+	return err
+}
+
+// GetActivation returns the current activation details of the device.
+func (s *DeviceService) GetActivation(ctx context.Context, devEUI lorawan.EUI64) (*DeviceActivation, error) {
+	resp, err := s.client.GetActivation(ctx, &api.GetDeviceActivationRequest{
+		DevEui: devEUI.String(),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	pb := resp.GetDeviceActivation()
+	a := DeviceActivation{
+		DevEUI:    devEUI,
+		FCntUp:    pb.GetFCntUp(),
+		NFCntDown: pb.GetNFCntDown(),
+		AFCntDown: pb.GetAFCntDown(),
+	}
+	if a.DevAddr, err = lorawan.ParseDevAddr(pb.GetDevAddr()); err != nil {
+		return nil, err
+	}
+	for _, k := range []struct {
+		dst *lorawan.AES128Key
+		src string
+	}{
+		{&a.AppSKey, pb.GetAppSKey()},
+		{&a.NwkSEncKey, pb.GetNwkSEncKey()},
+		{&a.SNwkSIntKey, pb.GetSNwkSIntKey()},
+		{&a.FNwkSIntKey, pb.GetFNwkSIntKey()},
+	} {
+		if *k.dst, err = lorawan.ParseAES128Key(k.src); err != nil {
+			return nil, err
+		}
+	}
+
+	return &a, nil
+}
+
+// GetRandomDevAddr returns a random DevAddr taking the NwkID prefix into
+// account.
+func (s *DeviceService) GetRandomDevAddr(ctx context.Context, devEUI lorawan.EUI64) (lorawan.DevAddr, error) {
+	resp, err := s.client.GetRandomDevAddr(ctx, &api.GetRandomDevAddrRequest{
+		DevEui: devEUI.String(),
+	})
+	if err != nil {
+		return lorawan.DevAddr{}, err
+	}
+	return lorawan.ParseDevAddr(resp.DevAddr)
+}
+
+// StreamFrameLogs opens a stream of uplink and downlink frame-logs for the
+// given DevEUI.
+func (s *DeviceService) StreamFrameLogs(ctx context.Context, devEUI lorawan.EUI64) (api.DeviceService_StreamFrameLogsClient, error) {
+	return s.client.StreamFrameLogs(ctx, &api.StreamDeviceFrameLogsRequest{
+		DevEui: devEUI.String(),
+	})
+}
+
+// StreamEventLogs opens a stream of device events for the given DevEUI.
+func (s *DeviceService) StreamEventLogs(ctx context.Context, devEUI lorawan.EUI64) (api.DeviceService_StreamEventLogsClient, error) {
+	return s.client.StreamEventLogs(ctx, &api.StreamDeviceEventLogsRequest{
+		DevEui: devEUI.String(),
+	})
+}
+
+func (d Device) toProto() *api.Device {
+	return &api.Device{
+		DevEui:            d.DevEUI.String(),
+		Name:              d.Name,
+		ApplicationId:     d.ApplicationID,
+		Description:       d.Description,
+		DeviceProfileId:   d.DeviceProfileID,
+		SkipFCntCheck:     d.SkipFCntCheck,
+		ReferenceAltitude: d.ReferenceAltitude,
+		Variables:         d.Variables,
+		Tags:              d.Tags,
+	}
+}
+
+func deviceFromProto(pb *api.Device) (Device, error) {
+	devEUI, err := lorawan.ParseEUI64(pb.GetDevEui())
+	if err != nil {
+		return Device{}, err
+	}
+
+	return Device{
+		DevEUI:            devEUI,
+		Name:              pb.GetName(),
+		ApplicationID:     pb.GetApplicationId(),
+		Description:       pb.GetDescription(),
+		DeviceProfileID:   pb.GetDeviceProfileId(),
+		SkipFCntCheck:     pb.GetSkipFCntCheck(),
+		ReferenceAltitude: pb.GetReferenceAltitude(),
+		Variables:         pb.GetVariables(),
+		Tags:              pb.GetTags(),
+	}, nil
+}
+
+func (k DeviceKeys) toProto() *api.DeviceKeys {
+	return &api.DeviceKeys{
+		DevEui:    k.DevEUI.String(),
+		NwkKey:    k.NwkKey.String(),
+		AppKey:    keyString(k.AppKey),
+		GenAppKey: keyString(k.GenAppKey),
+	}
+}
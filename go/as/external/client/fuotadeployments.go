@@ -0,0 +1,211 @@
+package client
+
+import (
+	"context"
+	"time"
+
+	"github.com/brocaar/chirpstack-api/go/as/external/api"
+	"github.com/brocaar/chirpstack-api/go/lorawan"
+)
+
+// FUOTADeployment defines a FUOTA deployment.
+type FUOTADeployment struct {
+	ID               string
+	Name             string
+	GroupType        api.MulticastGroupType
+	DR               uint32
+	Frequency        uint32
+	Payload          []byte
+	Redundancy       uint32
+	MulticastTimeout uint32
+	UnicastTimeout   time.Duration
+
+	// State and NextStepAfter are set by the application-server.
+	State         string
+	NextStepAfter time.Time
+}
+
+// FUOTADeploymentDetails contains a FUOTA deployment together with its
+// timestamps.
+type FUOTADeploymentDetails struct {
+	FUOTADeployment
+
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+// FUOTADeploymentListItem defines a FUOTA deployment within a list result-set.
+type FUOTADeploymentListItem struct {
+	ID            string
+	CreatedAt     time.Time
+	UpdatedAt     time.Time
+	Name          string
+	State         string
+	NextStepAfter time.Time
+}
+
+// FUOTADeploymentFilter defines the filters for listing FUOTA deployments.
+type FUOTADeploymentFilter struct {
+	ApplicationID int64
+	DevEUI        *lorawan.EUI64
+}
+
+// FUOTADeploymentDevice defines a device within a FUOTA deployment.
+type FUOTADeploymentDevice struct {
+	DevEUI       lorawan.EUI64
+	DeviceName   string
+	State        api.FUOTADeploymentDeviceState
+	ErrorMessage string
+	CreatedAt    time.Time
+	UpdatedAt    time.Time
+}
+
+// FUOTADeploymentService wraps the external API FUOTA deployment service.
+type FUOTADeploymentService struct {
+	client api.FUOTADeploymentServiceClient
+}
+
+// API returns the underlying generated service client.
+func (s *FUOTADeploymentService) API() api.FUOTADeploymentServiceClient {
+	return s.client
+}
+
+// CreateForDevice creates the given FUOTA deployment for a single device and
+// returns its ID.
+func (s *FUOTADeploymentService) CreateForDevice(ctx context.Context, devEUI lorawan.EUI64, d FUOTADeployment) (string, error) {
+	resp, err := s.client.CreateForDevice(ctx, &api.CreateFUOTADeploymentForDeviceRequest{
+		DevEui: devEUI.String(),
+		FuotaDeployment: &api.FUOTADeployment{
+			Name:             d.Name,
+			GroupType:        d.GroupType,
+			Dr:               d.DR,
+			Frequency:        d.Frequency,
+			Payload:          d.Payload,
+			Redundancy:       d.Redundancy,
+			MulticastTimeout: d.MulticastTimeout,
+			UnicastTimeout:   toDuration(d.UnicastTimeout),
+		},
+	})
+	if err != nil {
+		return "", err
+	}
+	return resp.Id, nil
+}
+
+// Get returns the FUOTA deployment matching the given ID.
+func (s *FUOTADeploymentService) Get(ctx context.Context, id string) (*FUOTADeploymentDetails, error) {
+	resp, err := s.client.Get(ctx, &api.GetFUOTADeploymentRequest{
+		Id: id,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	pb := resp.GetFuotaDeployment()
+	return &FUOTADeploymentDetails{
+		FUOTADeployment: FUOTADeployment{
+			ID:               pb.GetId(),
+			Name:             pb.GetName(),
+			GroupType:        pb.GetGroupType(),
+			DR:               pb.GetDr(),
+			Frequency:        pb.GetFrequency(),
+			Payload:          pb.GetPayload(),
+			Redundancy:       pb.GetRedundancy(),
+			MulticastTimeout: pb.GetMulticastTimeout(),
+			UnicastTimeout:   fromDuration(pb.GetUnicastTimeout()),
+			State:            pb.GetState(),
+			NextStepAfter:    fromTimestamp(pb.GetNextStepAfter()),
+		},
+		CreatedAt: fromTimestamp(resp.CreatedAt),
+		UpdatedAt: fromTimestamp(resp.UpdatedAt),
+	}, nil
+}
+
+// List returns a page of FUOTA deployments matching the given filter,
+// together with the total number of matching deployments.
+func (s *FUOTADeploymentService) List(ctx context.Context, filter FUOTADeploymentFilter, limit, offset int64) ([]FUOTADeploymentListItem, int64, error) {
+	req := api.ListFUOTADeploymentRequest{
+		Limit:         limit,
+		Offset:        offset,
+		ApplicationId: filter.ApplicationID,
+	}
+	if filter.DevEUI != nil {
+		req.DevEui = filter.DevEUI.String()
+	}
+
+	resp, err := s.client.List(ctx, &req)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	out := make([]FUOTADeploymentListItem, 0, len(resp.Result))
+	for _, item := range resp.Result {
+		out = append(out, FUOTADeploymentListItem{
+			ID:            item.Id,
+			CreatedAt:     fromTimestamp(item.CreatedAt),
+			UpdatedAt:     fromTimestamp(item.UpdatedAt),
+			Name:          item.Name,
+			State:         item.State,
+			NextStepAfter: fromTimestamp(item.NextStepAfter),
+		})
+	}
+
+	return out, resp.TotalCount, nil
+}
+
+// GetDeploymentDevice returns the given device of the FUOTA deployment.
+func (s *FUOTADeploymentService) GetDeploymentDevice(ctx context.Context, fuotaDeploymentID string, devEUI lorawan.EUI64) (*FUOTADeploymentDevice, error) {
+	resp, err := s.client.GetDeploymentDevice(ctx, &api.GetFUOTADeploymentDeviceRequest{
+		FuotaDeploymentId: fuotaDeploymentID,
+		DevEui:            devEUI.String(),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	d, err := fuotaDeploymentDeviceFromProto(resp.GetDeploymentDevice())
+	if err != nil {
+		return nil, err
+	}
+	return &d, nil
+}
+
+// ListDeploymentDevices returns a page of devices of the FUOTA deployment,
+// together with the total number of devices.
+func (s *FUOTADeploymentService) ListDeploymentDevices(ctx context.Context, fuotaDeploymentID string, limit, offset int64) ([]FUOTADeploymentDevice, int64, error) {
+	resp, err := s.client.ListDeploymentDevices(ctx, &api.ListFUOTADeploymentDevicesRequest{
+		FuotaDeploymentId: fuotaDeploymentID,
+		Limit:             limit,
+		Offset:            offset,
+	})
+	if err != nil {
+		return nil, 0, err
+	}
+
+	out := make([]FUOTADeploymentDevice, 0, len(resp.Result))
+	for _, item := range resp.Result {
+		d, err := fuotaDeploymentDeviceFromProto(item)
+		if err != nil {
+			return nil, 0, err
+		}
+		out = append(out, d)
+	}
+
+	return out, resp.TotalCount, nil
+}
+
+func fuotaDeploymentDeviceFromProto(pb *api.FUOTADeploymentDeviceListItem) (FUOTADeploymentDevice, error) {
+	devEUI, err := lorawan.ParseEUI64(pb.GetDevEui())
+	if err != nil {
+		return FUOTADeploymentDevice{}, err
+	}
+
+	return FUOTADeploymentDevice{
+		DevEUI:       devEUI,
+		DeviceName:   pb.GetDeviceName(),
+		State:        pb.GetState(),
+		ErrorMessage: pb.GetErrorMessage(),
+		CreatedAt:    fromTimestamp(pb.GetCreatedAt()),
+		UpdatedAt:    fromTimestamp(pb.GetUpdatedAt()),
+	}, nil
+}
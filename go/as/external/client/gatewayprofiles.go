@@ -0,0 +1,112 @@
+package client
+
+import (
+	"context"
+	"time"
+
+	"github.com/brocaar/chirpstack-api/go/as/external/api"
+)
+
+// GatewayProfileDetails contains a gateway-profile together with its
+// timestamps. The gateway-profile message does not contain any encoded fields
+// and is therefore used as-is.
+type GatewayProfileDetails struct {
+	GatewayProfile *api.GatewayProfile
+	CreatedAt      time.Time
+	UpdatedAt      time.Time
+}
+
+// GatewayProfileListItem defines a gateway-profile within a list result-set.
+type GatewayProfileListItem struct {
+	ID                string
+	Name              string
+	NetworkServerID   int64
+	NetworkServerName string
+	CreatedAt         time.Time
+	UpdatedAt         time.Time
+}
+
+// GatewayProfileFilter defines the filters for listing gateway-profiles.
+type GatewayProfileFilter struct {
+	NetworkServerID int64
+}
+
+// GatewayProfileService wraps the external API gateway-profile service.
+type GatewayProfileService struct {
+	client api.GatewayProfileServiceClient
+}
+
+// API returns the underlying generated service client.
+func (s *GatewayProfileService) API() api.GatewayProfileServiceClient {
+	return s.client
+}
+
+// Create creates the given gateway-profile and returns its ID.
+func (s *GatewayProfileService) Create(ctx context.Context, gp *api.GatewayProfile) (string, error) {
+	resp, err := s.client.Create(ctx, &api.CreateGatewayProfileRequest{
+		GatewayProfile: gp,
+	})
+	if err != nil {
+		return "", err
+	}
+	return resp.Id, nil
+}
+
+// Get returns the gateway-profile matching the given ID.
+func (s *GatewayProfileService) Get(ctx context.Context, id string) (*GatewayProfileDetails, error) {
+	resp, err := s.client.Get(ctx, &api.GetGatewayProfileRequest{
+		Id: id,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &GatewayProfileDetails{
+		GatewayProfile: resp.GatewayProfile,
+		CreatedAt:      fromTimestamp(resp.CreatedAt),
+		UpdatedAt:      fromTimestamp(resp.UpdatedAt),
+	}, nil
+}
+
+// Update updates the given gateway-profile.
+func (s *GatewayProfileService) Update(ctx context.Context, gp *api.GatewayProfile) error {
+	_, err := s.client.Update(ctx, &api.UpdateGatewayProfileRequest{
+		GatewayProfile: gp,
+	})
+	return err
+}
+
+// Delete deletes the gateway-profile matching the given ID.
+func (s *GatewayProfileService) Delete(ctx context.Context, id string) error {
+	_, err := s.client.Delete(ctx, &api.DeleteGatewayProfileRequest{
+		Id: id,
+	})
+	return err
+}
+
+// List returns a page of gateway-profiles matching the given filter, together
+// with the total number of matching gateway-profiles.
+func (s *GatewayProfileService) List(ctx context.Context, filter GatewayProfileFilter, limit, offset int64) ([]GatewayProfileListItem, int64, error) {
+	resp, err := s.client.List(ctx, &api.ListGatewayProfilesRequest{
+		Limit:           limit,
+		Offset:          offset,
+		NetworkServerId: filter.NetworkServerID,
+	})
+	if err != nil {
+		return nil, 0, err
+	}
+
+	out := make([]GatewayProfileListItem, 0, len(resp.Result))
+	for _, item := range resp.Result {
+		out = append(out, GatewayProfileListItem{
+			ID:                item.Id,
+			Name:              item.Name,
+			NetworkServerID:   item.NetworkServerId,
+			NetworkServerName: item.NetworkServerName,
+			CreatedAt:         fromTimestamp(item.CreatedAt),
+			UpdatedAt:         fromTimestamp(item.UpdatedAt),
+		})
+	}
+
+	return out, resp.TotalCount, nil
+}
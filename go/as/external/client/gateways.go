@@ -0,0 +1,312 @@
+package client
+
+import (
+	"context"
+	"encoding/hex"
+	"fmt"
+	"time"
+
+	"github.com/brocaar/chirpstack-api/go/as/external/api"
+	"github.com/brocaar/chirpstack-api/go/common"
+	"github.com/brocaar/chirpstack-api/go/lorawan"
+)
+
+// Gateway defines a gateway.
+type Gateway struct {
+	ID               lorawan.EUI64
+	Name             string
+	Description      string
+	Location         *common.Location
+	OrganizationID   int64
+	DiscoveryEnabled bool
+	NetworkServerID  int64
+	GatewayProfileID string
+	Boards           []GatewayBoard
+}
+
+// GatewayBoard defines the configuration of a gateway board.
+type GatewayBoard struct {
+	FPGAID           []byte
+	FineTimestampKey *lorawan.AES128Key
+}
+
+// GatewayDetails contains a gateway together with its timestamps.
+type GatewayDetails struct {
+	Gateway
+
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+	FirstSeenAt time.Time
+	LastSeenAt  time.Time
+}
+
+// GatewayListItem defines a gateway within a list result-set.
+type GatewayListItem struct {
+	ID              lorawan.EUI64
+	Name            string
+	Description     string
+	CreatedAt       time.Time
+	UpdatedAt       time.Time
+	FirstSeenAt     time.Time
+	LastSeenAt      time.Time
+	OrganizationID  int64
+	NetworkServerID int64
+	Location        *common.Location
+}
+
+// GatewayFilter defines the filters for listing gateways.
+type GatewayFilter struct {
+	OrganizationID int64
+	Search         string
+}
+
+// GatewayStats defines the (aggregated) gateway stats for an interval.
+type GatewayStats struct {
+	Timestamp           time.Time
+	RxPacketsReceived   int32
+	RxPacketsReceivedOK int32
+	TxPacketsReceived   int32
+	TxPacketsEmitted    int32
+}
+
+// GatewayPing defines the last gateway ping.
+type GatewayPing struct {
+	CreatedAt time.Time
+	Frequency uint32
+	DR        uint32
+	PingRX    []GatewayPingRX
+}
+
+// GatewayPingRX defines the reception of a ping by a gateway.
+type GatewayPingRX struct {
+	GatewayID lorawan.EUI64
+	RSSI      int32
+	LoRaSNR   float64
+	Latitude  float64
+	Longitude float64
+	Altitude  float64
+}
+
+// GatewayService wraps the external API gateway service.
+type GatewayService struct {
+	client api.GatewayServiceClient
+
+	// creds holds the credentials of the client, used for deciding if a
+	// stream can be re-opened after an authentication error.
+	creds *JWTCredentials
+}
+
+// API returns the underlying generated service client.
+func (s *GatewayService) API() api.GatewayServiceClient {
+	return s.client
+}
+
+// Create creates the given gateway.
+func (s *GatewayService) Create(ctx context.Context, gw Gateway) error {
+	_, err := s.client.Create(ctx, &api.CreateGatewayRequest{
+		Gateway: gw.toProto(),
+	})
+	return err
+}
+
+// Get returns the gateway matching the given ID.
+func (s *GatewayService) Get(ctx context.Context, id lorawan.EUI64) (*GatewayDetails, error) {
+	resp, err := s.client.Get(ctx, &api.GetGatewayRequest{
+		Id: id.String(),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	gw, err := gatewayFromProto(resp.GetGateway())
+	if err != nil {
+		return nil, err
+	}
+
+	return &GatewayDetails{
+		Gateway:     gw,
+		CreatedAt:   fromTimestamp(resp.CreatedAt),
+		UpdatedAt:   fromTimestamp(resp.UpdatedAt),
+		FirstSeenAt: fromTimestamp(resp.FirstSeenAt),
+		LastSeenAt:  fromTimestamp(resp.LastSeenAt),
+	}, nil
+}
+
+// Update updates the given gateway.
+func (s *GatewayService) Update(ctx context.Context, gw Gateway) error {
+	_, err := s.client.Update(ctx, &api.UpdateGatewayRequest{
+		Gateway: gw.toProto(),
+	})
+	return err
+}
+
+// Delete deletes the gateway matching the given ID.
+func (s *GatewayService) Delete(ctx context.Context, id lorawan.EUI64) error {
+	_, err := s.client.Delete(ctx, &api.DeleteGatewayRequest{
+		Id: id.String(),
+	})
+	return err
+}
+
+// List returns a page of gateways matching the given filter, together with
+// the total number of matching gateways.
+func (s *GatewayService) List(ctx context.Context, filter GatewayFilter, limit, offset int64) ([]GatewayListItem, int64, error) {
+	limit32, offset32, err := int32Page(limit, offset)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	resp, err := s.client.List(ctx, &api.ListGatewayRequest{
+		Limit:          limit32,
+		Offset:         offset32,
+		OrganizationId: filter.OrganizationID,
+		Search:         filter.Search,
+	})
+	if err != nil {
+		return nil, 0, err
+	}
+
+	out := make([]GatewayListItem, 0, len(resp.Result))
+	for _, item := range resp.Result {
+		id, err := lorawan.ParseEUI64(item.Id)
+		if err != nil {
+			return nil, 0, err
+		}
+
+		out = append(out, GatewayListItem{
+			ID:              id,
+			Name:            item.Name,
+			Description:     item.Description,
+			CreatedAt:       fromTimestamp(item.CreatedAt),
+			UpdatedAt:       fromTimestamp(item.UpdatedAt),
+			FirstSeenAt:     fromTimestamp(item.FirstSeenAt),
+			LastSeenAt:      fromTimestamp(item.LastSeenAt),
+			OrganizationID:  item.OrganizationId,
+			NetworkServerID: item.NetworkServerId,
+			Location:        item.Location,
+		})
+	}
+
+	return out, resp.TotalCount, nil
+}
+
+// GetStats returns the gateway stats aggregated by the given interval ("second",
+// "minute", "hour", "day", "week", "month", "quarter" or "year").
+func (s *GatewayService) GetStats(ctx context.Context, id lorawan.EUI64, interval string, start, end time.Time) ([]GatewayStats, error) {
+	resp, err := s.client.GetStats(ctx, &api.GetGatewayStatsRequest{
+		GatewayId:      id.String(),
+		Interval:       interval,
+		StartTimestamp: toTimestamp(start),
+		EndTimestamp:   toTimestamp(end),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	out := make([]GatewayStats, 0, len(resp.Result))
+	for _, st := range resp.Result {
+		out = append(out, GatewayStats{
+			Timestamp:           fromTimestamp(st.Timestamp),
+			RxPacketsReceived:   st.RxPacketsReceived,
+			RxPacketsReceivedOK: st.RxPacketsReceivedOk,
+			TxPacketsReceived:   st.TxPacketsReceived,
+			TxPacketsEmitted:    st.TxPacketsEmitted,
+		})
+	}
+
+	return out, nil
+}
+
+// GetLastPing returns the last emitted ping and gateways receiving this ping.
+func (s *GatewayService) GetLastPing(ctx context.Context, id lorawan.EUI64) (*GatewayPing, error) {
+	resp, err := s.client.GetLastPing(ctx, &api.GetLastPingRequest{
+		GatewayId: id.String(),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	ping := GatewayPing{
+		CreatedAt: fromTimestamp(resp.CreatedAt),
+		Frequency: resp.Frequency,
+		DR:        resp.Dr,
+	}
+	for _, rx := range resp.PingRx {
+		gatewayID, err := lorawan.ParseEUI64(rx.GatewayId)
+		if err != nil {
+			return nil, err
+		}
+
+		ping.PingRX = append(ping.PingRX, GatewayPingRX{
+			GatewayID: gatewayID,
+			RSSI:      rx.Rssi,
+			LoRaSNR:   rx.LoraSnr,
+			Latitude:  rx.Latitude,
+			Longitude: rx.Longitude,
+			Altitude:  rx.Altitude,
+		})
+	}
+
+	return &ping, nil
+}
+
+// StreamFrameLogs opens a stream of uplink and downlink frame-logs for the
+// given gateway ID.
+func (s *GatewayService) StreamFrameLogs(ctx context.Context, id lorawan.EUI64) (api.GatewayService_StreamFrameLogsClient, error) {
+	return s.client.StreamFrameLogs(ctx, &api.StreamGatewayFrameLogsRequest{
+		GatewayId: id.String(),
+	})
+}
+
+func (gw Gateway) toProto() *api.Gateway {
+	pb := api.Gateway{
+		Id:               gw.ID.String(),
+		Name:             gw.Name,
+		Description:      gw.Description,
+		Location:         gw.Location,
+		OrganizationId:   gw.OrganizationID,
+		DiscoveryEnabled: gw.DiscoveryEnabled,
+		NetworkServerId:  gw.NetworkServerID,
+		GatewayProfileId: gw.GatewayProfileID,
+	}
+
+	for _, b := range gw.Boards {
+		pb.Boards = append(pb.Boards, &api.GatewayBoard{
+			FpgaId:           hex.EncodeToString(b.FPGAID),
+			FineTimestampKey: keyString(b.FineTimestampKey),
+		})
+	}
+
+	return &pb
+}
+
+func gatewayFromProto(pb *api.Gateway) (Gateway, error) {
+	id, err := lorawan.ParseEUI64(pb.GetId())
+	if err != nil {
+		return Gateway{}, err
+	}
+
+	gw := Gateway{
+		ID:               id,
+		Name:             pb.GetName(),
+		Description:      pb.GetDescription(),
+		Location:         pb.GetLocation(),
+		OrganizationID:   pb.GetOrganizationId(),
+		DiscoveryEnabled: pb.GetDiscoveryEnabled(),
+		NetworkServerID:  pb.GetNetworkServerId(),
+		GatewayProfileID: pb.GetGatewayProfileId(),
+	}
+
+	for _, b := range pb.GetBoards() {
+		var board GatewayBoard
+		if board.FPGAID, err = hex.DecodeString(b.FpgaId); err != nil {
+			return Gateway{}, fmt.Errorf("client: decode FPGA ID: %w", err)
+		}
+		if board.FineTimestampKey, err = parseOptionalKey(b.FineTimestampKey); err != nil {
+			return Gateway{}, err
+		}
+		gw.Boards = append(gw.Boards, board)
+	}
+
+	return gw, nil
+}
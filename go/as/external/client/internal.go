@@ -0,0 +1,54 @@
+package client
+
+import (
+	"context"
+
+	"github.com/golang/protobuf/ptypes/empty"
+
+	"github.com/brocaar/chirpstack-api/go/as/external/api"
+)
+
+// InternalService wraps the external API internal service.
+type InternalService struct {
+	client api.InternalServiceClient
+}
+
+// API returns the underlying generated service client.
+func (s *InternalService) API() api.InternalServiceClient {
+	return s.client
+}
+
+// Login logs in the given user and returns the JWT token.
+func (s *InternalService) Login(ctx context.Context, username, password string) (string, error) {
+	resp, err := s.client.Login(ctx, &api.LoginRequest{
+		Username: username,
+		Password: password,
+	})
+	if err != nil {
+		return "", err
+	}
+	return resp.Jwt, nil
+}
+
+// Profile returns the profile of the authenticated user.
+func (s *InternalService) Profile(ctx context.Context) (*api.ProfileResponse, error) {
+	return s.client.Profile(ctx, &empty.Empty{})
+}
+
+// Branding returns the branding for the UI.
+func (s *InternalService) Branding(ctx context.Context) (*api.BrandingResponse, error) {
+	return s.client.Branding(ctx, &empty.Empty{})
+}
+
+// GlobalSearch performs a global search.
+func (s *InternalService) GlobalSearch(ctx context.Context, search string, limit, offset int64) ([]*api.GlobalSearchResult, error) {
+	resp, err := s.client.GlobalSearch(ctx, &api.GlobalSearchRequest{
+		Search: search,
+		Limit:  limit,
+		Offset: offset,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return resp.Result, nil
+}
@@ -0,0 +1,243 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sync"
+)
+
+// DefaultPageSize defines the default number of items fetched per List call.
+const DefaultPageSize = 100
+
+// ListOption configures the List iterators.
+type ListOption func(*listOptions)
+
+type listOptions struct {
+	pageSize int64
+	prefetch int
+}
+
+// WithPageSize sets the number of items fetched per List call.
+func WithPageSize(n int64) ListOption {
+	return func(o *listOptions) {
+		if n > 0 {
+			o.pageSize = n
+		}
+	}
+}
+
+// WithPrefetch sets the number of pages that are fetched concurrently ahead of
+// the page being consumed. The default (0) only fetches the next page when
+// the current page has been consumed.
+func WithPrefetch(n int) ListOption {
+	return func(o *listOptions) {
+		if n >= 0 {
+			o.prefetch = n
+		}
+	}
+}
+
+// fetchFunc fetches a single page, returning the page items and the total
+// number of items.
+type fetchFunc func(ctx context.Context, limit, offset int64) ([]interface{}, int64, error)
+
+// pageFunc fetches a single page like fetchFunc, but returns the page items
+// as a typed slice (e.g. []DeviceListItem), so that the List methods of the
+// services can be used as-is.
+type pageFunc func(ctx context.Context, limit, offset int64) (interface{}, int64, error)
+
+// listAll returns an iterator over the pages returned by the given function.
+func listAll(ctx context.Context, page pageFunc, opts []ListOption) *Iterator {
+	return newIterator(ctx, func(ctx context.Context, limit, offset int64) ([]interface{}, int64, error) {
+		items, total, err := page(ctx, limit, offset)
+		if err != nil {
+			return nil, total, err
+		}
+
+		v := reflect.ValueOf(items)
+		if v.Kind() != reflect.Slice {
+			return nil, total, fmt.Errorf("client: expected page slice, got %T", items)
+		}
+		out := make([]interface{}, v.Len())
+		for i := range out {
+			out[i] = v.Index(i).Interface()
+		}
+		return out, total, nil
+	}, opts)
+}
+
+type pageResult struct {
+	items []interface{}
+	total int64
+	err   error
+}
+
+// Iterator iterates over the items of a paginated List call. Pages are
+// fetched lazily (or ahead when using WithPrefetch) and are returned in
+// order. The iterator stops when all items have been returned, when a call
+// returns an error or when the context is cancelled.
+//
+// Close must be called when the iteration is stopped before Next returns
+// false, to release the resources of pages being prefetched.
+type Iterator struct {
+	ctx    context.Context
+	cancel context.CancelFunc
+	fetch  fetchFunc
+	opts   listOptions
+	start  sync.Once
+
+	// slots contains per page (in order) a channel to which the page result
+	// is sent once fetched.
+	slots chan chan pageResult
+
+	mu    sync.Mutex
+	total int64
+
+	items []interface{}
+	pos   int
+	err   error
+	done  bool
+}
+
+func newIterator(ctx context.Context, fetch fetchFunc, opts []ListOption) *Iterator {
+	o := listOptions{
+		pageSize: DefaultPageSize,
+	}
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+
+	return &Iterator{
+		ctx:    ctx,
+		cancel: cancel,
+		fetch:  fetch,
+		opts:   o,
+		slots:  make(chan chan pageResult, o.prefetch),
+		pos:    -1,
+	}
+}
+
+// Next advances the iterator to the next item. It returns false when there
+// are no more items or when an error occurred.
+func (it *Iterator) Next() bool {
+	if it.err != nil || it.done {
+		return false
+	}
+
+	if err := it.ctx.Err(); err != nil {
+		it.err = err
+		return false
+	}
+
+	it.start.Do(func() {
+		go it.run()
+	})
+
+	for it.pos+1 >= len(it.items) {
+		var slot chan pageResult
+		var ok bool
+
+		select {
+		case slot, ok = <-it.slots:
+			if !ok {
+				it.done = true
+				it.cancel()
+				return false
+			}
+		case <-it.ctx.Done():
+			it.err = it.ctx.Err()
+			return false
+		}
+
+		var res pageResult
+		select {
+		case res = <-slot:
+		case <-it.ctx.Done():
+			it.err = it.ctx.Err()
+			return false
+		}
+
+		if res.err != nil {
+			it.err = res.err
+			it.cancel()
+			return false
+		}
+
+		it.items = res.items
+		it.pos = -1
+	}
+
+	it.pos++
+	return true
+}
+
+// Err returns the error which stopped the iteration, if any.
+func (it *Iterator) Err() error {
+	return it.err
+}
+
+// TotalCount returns the total number of items as reported by the first
+// List call. It returns 0 until Next has been called.
+func (it *Iterator) TotalCount() int64 {
+	it.mu.Lock()
+	defer it.mu.Unlock()
+	return it.total
+}
+
+// Close stops the iteration.
+func (it *Iterator) Close() {
+	it.cancel()
+}
+
+func (it *Iterator) item() interface{} {
+	return it.items[it.pos]
+}
+
+// run fetches the pages and publishes them in order on the slots channel.
+// Fetching starts as soon as a slot has been accepted by the slots channel,
+// which limits the number of concurrent fetches to prefetch + 1.
+func (it *Iterator) run() {
+	defer close(it.slots)
+
+	size := it.opts.pageSize
+
+	// The first page is needed to know the total number of items.
+	first := make(chan pageResult, 1)
+	if !it.publish(first) {
+		return
+	}
+
+	items, total, err := it.fetch(it.ctx, size, 0)
+	it.mu.Lock()
+	it.total = total
+	it.mu.Unlock()
+	first <- pageResult{items: items, total: total, err: err}
+
+	if err != nil || len(items) == 0 {
+		return
+	}
+
+	for offset := size; offset < total; offset += size {
+		slot := make(chan pageResult, 1)
+		if !it.publish(slot) {
+			return
+		}
+
+		go func(offset int64) {
+			items, total, err := it.fetch(it.ctx, size, offset)
+			slot <- pageResult{items: items, total: total, err: err}
+		}(offset)
+	}
+}
+
+func (it *Iterator) publish(slot chan pageResult) bool {
+	select {
+	case it.slots <- slot:
+		return true
+	case <-it.ctx.Done():
+		return false
+	}
+}
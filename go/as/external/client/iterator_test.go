@@ -0,0 +1,73 @@
+package client
+
+import (
+	"context"
+	"math"
+	"testing"
+)
+
+func TestListAll(t *testing.T) {
+	all := []string{"a", "b", "c", "d", "e"}
+
+	for _, prefetch := range []int{0, 2} {
+		it := listAll(context.Background(), func(ctx context.Context, limit, offset int64) (interface{}, int64, error) {
+			end := offset + limit
+			if end > int64(len(all)) {
+				end = int64(len(all))
+			}
+			return all[offset:end], int64(len(all)), nil
+		}, []ListOption{WithPageSize(2), WithPrefetch(prefetch)})
+
+		var out []string
+		for it.Next() {
+			out = append(out, it.item().(string))
+		}
+		if err := it.Err(); err != nil {
+			t.Fatal(err)
+		}
+		if len(out) != len(all) {
+			t.Fatalf("prefetch %d: expected %v, got %v", prefetch, all, out)
+		}
+		for i := range all {
+			if out[i] != all[i] {
+				t.Errorf("prefetch %d: expected %v, got %v", prefetch, all, out)
+				break
+			}
+		}
+		if it.TotalCount() != int64(len(all)) {
+			t.Errorf("prefetch %d: expected total %d, got %d", prefetch, len(all), it.TotalCount())
+		}
+	}
+}
+
+func TestInt32Page(t *testing.T) {
+	tests := []struct {
+		limit  int64
+		offset int64
+		err    bool
+	}{
+		{100, 0, false},
+		{math.MaxInt32, math.MaxInt32, false},
+		{math.MaxInt32 + 1, 0, true},
+		{100, math.MaxInt32 + 1, true},
+		{-1, 0, true},
+		{100, -1, true},
+	}
+
+	for _, test := range tests {
+		limit, offset, err := int32Page(test.limit, test.offset)
+		if test.err {
+			if err == nil {
+				t.Errorf("limit %d, offset %d: expected error", test.limit, test.offset)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("limit %d, offset %d: unexpected error: %s", test.limit, test.offset, err)
+			continue
+		}
+		if int64(limit) != test.limit || int64(offset) != test.offset {
+			t.Errorf("expected %d/%d, got %d/%d", test.limit, test.offset, limit, offset)
+		}
+	}
+}
@@ -0,0 +1,226 @@
+package client
+
+import (
+	"context"
+)
+
+// ApplicationIterator iterates over applications.
+type ApplicationIterator struct {
+	*Iterator
+}
+
+// Item returns the current application.
+func (it *ApplicationIterator) Item() ApplicationListItem {
+	return it.item().(ApplicationListItem)
+}
+
+// ListAllApplications returns an iterator over all applications matching the given filter.
+func (c *Client) ListAllApplications(ctx context.Context, filter ApplicationFilter, opts ...ListOption) *ApplicationIterator {
+	return &ApplicationIterator{listAll(ctx, func(ctx context.Context, limit, offset int64) (interface{}, int64, error) {
+		return c.applications.List(ctx, filter, limit, offset)
+	}, opts)}
+}
+
+// DeviceIterator iterates over devices.
+type DeviceIterator struct {
+	*Iterator
+}
+
+// Item returns the current device.
+func (it *DeviceIterator) Item() DeviceListItem {
+	return it.item().(DeviceListItem)
+}
+
+// ListAllDevices returns an iterator over all devices matching the given filter.
+func (c *Client) ListAllDevices(ctx context.Context, filter DeviceFilter, opts ...ListOption) *DeviceIterator {
+	return &DeviceIterator{listAll(ctx, func(ctx context.Context, limit, offset int64) (interface{}, int64, error) {
+		return c.devices.List(ctx, filter, limit, offset)
+	}, opts)}
+}
+
+// DeviceProfileIterator iterates over device-profiles.
+type DeviceProfileIterator struct {
+	*Iterator
+}
+
+// Item returns the current device-profile.
+func (it *DeviceProfileIterator) Item() DeviceProfileListItem {
+	return it.item().(DeviceProfileListItem)
+}
+
+// ListAllDeviceProfiles returns an iterator over all device-profiles matching the given filter.
+func (c *Client) ListAllDeviceProfiles(ctx context.Context, filter DeviceProfileFilter, opts ...ListOption) *DeviceProfileIterator {
+	return &DeviceProfileIterator{listAll(ctx, func(ctx context.Context, limit, offset int64) (interface{}, int64, error) {
+		return c.deviceProfiles.List(ctx, filter, limit, offset)
+	}, opts)}
+}
+
+// FUOTADeploymentIterator iterates over FUOTA deployments.
+type FUOTADeploymentIterator struct {
+	*Iterator
+}
+
+// Item returns the current FUOTA deployment.
+func (it *FUOTADeploymentIterator) Item() FUOTADeploymentListItem {
+	return it.item().(FUOTADeploymentListItem)
+}
+
+// ListAllFUOTADeployments returns an iterator over all FUOTA deployments matching the given filter.
+func (c *Client) ListAllFUOTADeployments(ctx context.Context, filter FUOTADeploymentFilter, opts ...ListOption) *FUOTADeploymentIterator {
+	return &FUOTADeploymentIterator{listAll(ctx, func(ctx context.Context, limit, offset int64) (interface{}, int64, error) {
+		return c.fuotaDeployments.List(ctx, filter, limit, offset)
+	}, opts)}
+}
+
+// FUOTADeploymentDeviceIterator iterates over FUOTA deployment devices.
+type FUOTADeploymentDeviceIterator struct {
+	*Iterator
+}
+
+// Item returns the current FUOTA deployment device.
+func (it *FUOTADeploymentDeviceIterator) Item() FUOTADeploymentDevice {
+	return it.item().(FUOTADeploymentDevice)
+}
+
+// ListAllFUOTADeploymentDevices returns an iterator over all devices of the given FUOTA deployment.
+func (c *Client) ListAllFUOTADeploymentDevices(ctx context.Context, fuotaDeploymentID string, opts ...ListOption) *FUOTADeploymentDeviceIterator {
+	return &FUOTADeploymentDeviceIterator{listAll(ctx, func(ctx context.Context, limit, offset int64) (interface{}, int64, error) {
+		return c.fuotaDeployments.ListDeploymentDevices(ctx, fuotaDeploymentID, limit, offset)
+	}, opts)}
+}
+
+// GatewayIterator iterates over gateways.
+type GatewayIterator struct {
+	*Iterator
+}
+
+// Item returns the current gateway.
+func (it *GatewayIterator) Item() GatewayListItem {
+	return it.item().(GatewayListItem)
+}
+
+// ListAllGateways returns an iterator over all gateways matching the given filter.
+func (c *Client) ListAllGateways(ctx context.Context, filter GatewayFilter, opts ...ListOption) *GatewayIterator {
+	return &GatewayIterator{listAll(ctx, func(ctx context.Context, limit, offset int64) (interface{}, int64, error) {
+		return c.gateways.List(ctx, filter, limit, offset)
+	}, opts)}
+}
+
+// GatewayProfileIterator iterates over gateway-profiles.
+type GatewayProfileIterator struct {
+	*Iterator
+}
+
+// Item returns the current gateway-profile.
+func (it *GatewayProfileIterator) Item() GatewayProfileListItem {
+	return it.item().(GatewayProfileListItem)
+}
+
+// ListAllGatewayProfiles returns an iterator over all gateway-profiles matching the given filter.
+func (c *Client) ListAllGatewayProfiles(ctx context.Context, filter GatewayProfileFilter, opts ...ListOption) *GatewayProfileIterator {
+	return &GatewayProfileIterator{listAll(ctx, func(ctx context.Context, limit, offset int64) (interface{}, int64, error) {
+		return c.gatewayProfiles.List(ctx, filter, limit, offset)
+	}, opts)}
+}
+
+// MulticastGroupIterator iterates over multicast-groups.
+type MulticastGroupIterator struct {
+	*Iterator
+}
+
+// Item returns the current multicast-group.
+func (it *MulticastGroupIterator) Item() MulticastGroupListItem {
+	return it.item().(MulticastGroupListItem)
+}
+
+// ListAllMulticastGroups returns an iterator over all multicast-groups matching the given filter.
+func (c *Client) ListAllMulticastGroups(ctx context.Context, filter MulticastGroupFilter, opts ...ListOption) *MulticastGroupIterator {
+	return &MulticastGroupIterator{listAll(ctx, func(ctx context.Context, limit, offset int64) (interface{}, int64, error) {
+		return c.multicastGroups.List(ctx, filter, limit, offset)
+	}, opts)}
+}
+
+// NetworkServerIterator iterates over network-servers.
+type NetworkServerIterator struct {
+	*Iterator
+}
+
+// Item returns the current network-server.
+func (it *NetworkServerIterator) Item() NetworkServerListItem {
+	return it.item().(NetworkServerListItem)
+}
+
+// ListAllNetworkServers returns an iterator over all network-servers matching the given filter.
+func (c *Client) ListAllNetworkServers(ctx context.Context, filter NetworkServerFilter, opts ...ListOption) *NetworkServerIterator {
+	return &NetworkServerIterator{listAll(ctx, func(ctx context.Context, limit, offset int64) (interface{}, int64, error) {
+		return c.networkServers.List(ctx, filter, limit, offset)
+	}, opts)}
+}
+
+// OrganizationIterator iterates over organizations.
+type OrganizationIterator struct {
+	*Iterator
+}
+
+// Item returns the current organization.
+func (it *OrganizationIterator) Item() OrganizationListItem {
+	return it.item().(OrganizationListItem)
+}
+
+// ListAllOrganizations returns an iterator over all organizations matching the given filter.
+func (c *Client) ListAllOrganizations(ctx context.Context, filter OrganizationFilter, opts ...ListOption) *OrganizationIterator {
+	return &OrganizationIterator{listAll(ctx, func(ctx context.Context, limit, offset int64) (interface{}, int64, error) {
+		return c.organizations.List(ctx, filter, limit, offset)
+	}, opts)}
+}
+
+// OrganizationUserIterator iterates over organization users.
+type OrganizationUserIterator struct {
+	*Iterator
+}
+
+// Item returns the current organization user.
+func (it *OrganizationUserIterator) Item() OrganizationUserListItem {
+	return it.item().(OrganizationUserListItem)
+}
+
+// ListAllOrganizationUsers returns an iterator over all users of the given organization.
+func (c *Client) ListAllOrganizationUsers(ctx context.Context, organizationID int64, opts ...ListOption) *OrganizationUserIterator {
+	return &OrganizationUserIterator{listAll(ctx, func(ctx context.Context, limit, offset int64) (interface{}, int64, error) {
+		return c.organizations.ListUsers(ctx, organizationID, limit, offset)
+	}, opts)}
+}
+
+// ServiceProfileIterator iterates over service-profiles.
+type ServiceProfileIterator struct {
+	*Iterator
+}
+
+// Item returns the current service-profile.
+func (it *ServiceProfileIterator) Item() ServiceProfileListItem {
+	return it.item().(ServiceProfileListItem)
+}
+
+// ListAllServiceProfiles returns an iterator over all service-profiles matching the given filter.
+func (c *Client) ListAllServiceProfiles(ctx context.Context, filter ServiceProfileFilter, opts ...ListOption) *ServiceProfileIterator {
+	return &ServiceProfileIterator{listAll(ctx, func(ctx context.Context, limit, offset int64) (interface{}, int64, error) {
+		return c.serviceProfiles.List(ctx, filter, limit, offset)
+	}, opts)}
+}
+
+// UserIterator iterates over users.
+type UserIterator struct {
+	*Iterator
+}
+
+// Item returns the current user.
+func (it *UserIterator) Item() UserListItem {
+	return it.item().(UserListItem)
+}
+
+// ListAllUsers returns an iterator over all users matching the given filter.
+func (c *Client) ListAllUsers(ctx context.Context, filter UserFilter, opts ...ListOption) *UserIterator {
+	return &UserIterator{listAll(ctx, func(ctx context.Context, limit, offset int64) (interface{}, int64, error) {
+		return c.users.List(ctx, filter, limit, offset)
+	}, opts)}
+}
@@ -0,0 +1,244 @@
+package client
+
+import (
+	"context"
+	"time"
+
+	"github.com/brocaar/chirpstack-api/go/as/external/api"
+	"github.com/brocaar/chirpstack-api/go/lorawan"
+)
+
+// MulticastGroup defines a multicast-group.
+type MulticastGroup struct {
+	ID               string
+	Name             string
+	McAddr           lorawan.DevAddr
+	McNwkSKey        lorawan.AES128Key
+	McAppSKey        lorawan.AES128Key
+	FCnt             uint32
+	GroupType        api.MulticastGroupType
+	DR               uint32
+	Frequency        uint32
+	PingSlotPeriod   uint32
+	ServiceProfileID string
+}
+
+// MulticastGroupDetails contains a multicast-group together with its
+// timestamps.
+type MulticastGroupDetails struct {
+	MulticastGroup
+
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+// MulticastGroupListItem defines a multicast-group within a list result-set.
+type MulticastGroupListItem struct {
+	ID                 string
+	Name               string
+	ServiceProfileID   string
+	ServiceProfileName string
+}
+
+// MulticastGroupFilter defines the filters for listing multicast-groups.
+type MulticastGroupFilter struct {
+	OrganizationID   int64
+	DevEUI           *lorawan.EUI64
+	ServiceProfileID string
+	Search           string
+}
+
+// MulticastQueueItem defines a multicast queue-item.
+type MulticastQueueItem struct {
+	MulticastGroupID string
+	FCnt             uint32
+	FPort            uint32
+	Data             []byte
+}
+
+// MulticastGroupService wraps the external API multicast-group service.
+type MulticastGroupService struct {
+	client api.MulticastGroupServiceClient
+}
+
+// API returns the underlying generated service client.
+func (s *MulticastGroupService) API() api.MulticastGroupServiceClient {
+	return s.client
+}
+
+// Create creates the given multicast-group and returns its ID.
+func (s *MulticastGroupService) Create(ctx context.Context, mg MulticastGroup) (string, error) {
+	resp, err := s.client.Create(ctx, &api.CreateMulticastGroupRequest{
+		MulticastGroup: mg.toProto(),
+	})
+	if err != nil {
+		return "", err
+	}
+	return resp.Id, nil
+}
+
+// Get returns the multicast-group matching the given ID.
+func (s *MulticastGroupService) Get(ctx context.Context, id string) (*MulticastGroupDetails, error) {
+	resp, err := s.client.Get(ctx, &api.GetMulticastGroupRequest{
+		Id: id,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	pb := resp.GetMulticastGroup()
+	mg := MulticastGroup{
+		ID:               pb.GetId(),
+		Name:             pb.GetName(),
+		FCnt:             pb.GetFCnt(),
+		GroupType:        pb.GetGroupType(),
+		DR:               pb.GetDr(),
+		Frequency:        pb.GetFrequency(),
+		PingSlotPeriod:   pb.GetPingSlotPeriod(),
+		ServiceProfileID: pb.GetServiceProfileId(),
+	}
+	if mg.McAddr, err = lorawan.ParseDevAddr(pb.GetMcAddr()); err != nil {
+		return nil, err
+	}
+	if mg.McNwkSKey, err = lorawan.ParseAES128Key(pb.GetMcNwkSKey()); err != nil {
+		return nil, err
+	}
+	if mg.McAppSKey, err = lorawan.ParseAES128Key(pb.GetMcAppSKey()); err != nil {
+		return nil, err
+	}
+
+	return &MulticastGroupDetails{
+		MulticastGroup: mg,
+		CreatedAt:      fromTimestamp(resp.CreatedAt),
+		UpdatedAt:      fromTimestamp(resp.UpdatedAt),
+	}, nil
+}
+
+// Update updates the given multicast-group.
+func (s *MulticastGroupService) Update(ctx context.Context, mg MulticastGroup) error {
+	_, err := s.client.Update(ctx, &api.UpdateMulticastGroupRequest{
+		MulticastGroup: mg.toProto(),
+	})
+	return err
+}
+
+// Delete deletes the multicast-group matching the given ID.
+func (s *MulticastGroupService) Delete(ctx context.Context, id string) error {
+	_, err := s.client.Delete(ctx, &api.DeleteMulticastGroupRequest{
+		Id: id,
+	})
+	return err
+}
+
+// List returns a page of multicast-groups matching the given filter, together
+// with the total number of matching multicast-groups.
+func (s *MulticastGroupService) List(ctx context.Context, filter MulticastGroupFilter, limit, offset int64) ([]MulticastGroupListItem, int64, error) {
+	req := api.ListMulticastGroupRequest{
+		Limit:            limit,
+		Offset:           offset,
+		OrganizationId:   filter.OrganizationID,
+		ServiceProfileId: filter.ServiceProfileID,
+		Search:           filter.Search,
+	}
+	if filter.DevEUI != nil {
+		req.DevEui = filter.DevEUI.String()
+	}
+
+	resp, err := s.client.List(ctx, &req)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	out := make([]MulticastGroupListItem, 0, len(resp.Result))
+	for _, item := range resp.Result {
+		out = append(out, MulticastGroupListItem{
+			ID:                 item.Id,
+			Name:               item.Name,
+			ServiceProfileID:   item.ServiceProfileId,
+			ServiceProfileName: item.ServiceProfileName,
+		})
+	}
+
+	return out, resp.TotalCount, nil
+}
+
+// AddDevice adds the given device to the multicast-group.
+func (s *MulticastGroupService) AddDevice(ctx context.Context, multicastGroupID string, devEUI lorawan.EUI64) error {
+	_, err := s.client.AddDevice(ctx, &api.AddDeviceToMulticastGroupRequest{
+		MulticastGroupId: multicastGroupID,
+		DevEui:           devEUI.String(),
+	})
+	return err
+}
+
+// RemoveDevice removes the given device from the multicast-group.
+func (s *MulticastGroupService) RemoveDevice(ctx context.Context, multicastGroupID string, devEUI lorawan.EUI64) error {
+	_, err := s.client.RemoveDevice(ctx, &api.RemoveDeviceFromMulticastGroupRequest{
+		MulticastGroupId: multicastGroupID,
+		DevEui:           devEUI.String(),
+	})
+	return err
+}
+
+// Enqueue adds the given item to the multicast-group queue and returns the
+// frame-counter used for the enqueued payload.
+func (s *MulticastGroupService) Enqueue(ctx context.Context, item MulticastQueueItem) (uint32, error) {
+	resp, err := s.client.Enqueue(ctx, &api.EnqueueMulticastQueueItemRequest{
+		MulticastQueueItem: &api.MulticastQueueItem{
+			MulticastGroupId: item.MulticastGroupID,
+			FCnt:             item.FCnt,
+			FPort:            item.FPort,
+			Data:             item.Data,
+		},
+	})
+	if err != nil {
+		return 0, err
+	}
+	return resp.FCnt, nil
+}
+
+// FlushQueue flushes the multicast-group queue.
+func (s *MulticastGroupService) FlushQueue(ctx context.Context, multicastGroupID string) error {
+	_, err := s.client.FlushQueue(ctx, &api.FlushMulticastGroupQueueItemsRequest{
+		MulticastGroupId: multicastGroupID,
+	})
+	return err
+}
+
+// ListQueue lists the items in the multicast-group queue.
+func (s *MulticastGroupService) ListQueue(ctx context.Context, multicastGroupID string) ([]MulticastQueueItem, error) {
+	resp, err := s.client.ListQueue(ctx, &api.ListMulticastGroupQueueItemsRequest{
+		MulticastGroupId: multicastGroupID,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	out := make([]MulticastQueueItem, 0, len(resp.MulticastQueueItems))
+	for _, item := range resp.MulticastQueueItems {
+		out = append(out, MulticastQueueItem{
+			MulticastGroupID: item.MulticastGroupId,
+			FCnt:             item.FCnt,
+			FPort:            item.FPort,
+			Data:             item.Data,
+		})
+	}
+
+	return out, nil
+}
+
+func (mg MulticastGroup) toProto() *api.MulticastGroup {
+	return &api.MulticastGroup{
+		Id:               mg.ID,
+		Name:             mg.Name,
+		McAddr:           mg.McAddr.String(),
+		McNwkSKey:        mg.McNwkSKey.String(),
+		McAppSKey:        mg.McAppSKey.String(),
+		FCnt:             mg.FCnt,
+		GroupType:        mg.GroupType,
+		Dr:               mg.DR,
+		Frequency:        mg.Frequency,
+		PingSlotPeriod:   mg.PingSlotPeriod,
+		ServiceProfileId: mg.ServiceProfileID,
+	}
+}
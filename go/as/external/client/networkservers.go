@@ -0,0 +1,114 @@
+package client
+
+import (
+	"context"
+	"time"
+
+	"github.com/brocaar/chirpstack-api/go/as/external/api"
+)
+
+// NetworkServerDetails contains a network-server together with its
+// timestamps, version and region. The network-server message does not
+// contain any encoded fields and is therefore used as-is.
+type NetworkServerDetails struct {
+	NetworkServer *api.NetworkServer
+	CreatedAt     time.Time
+	UpdatedAt     time.Time
+	Version       string
+	Region        string
+}
+
+// NetworkServerListItem defines a network-server within a list result-set.
+type NetworkServerListItem struct {
+	ID        int64
+	Name      string
+	Server    string
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+// NetworkServerFilter defines the filters for listing network-servers.
+type NetworkServerFilter struct {
+	OrganizationID int64
+}
+
+// NetworkServerService wraps the external API network-server service.
+type NetworkServerService struct {
+	client api.NetworkServerServiceClient
+}
+
+// API returns the underlying generated service client.
+func (s *NetworkServerService) API() api.NetworkServerServiceClient {
+	return s.client
+}
+
+// Create creates the given network-server and returns its ID.
+func (s *NetworkServerService) Create(ctx context.Context, ns *api.NetworkServer) (int64, error) {
+	resp, err := s.client.Create(ctx, &api.CreateNetworkServerRequest{
+		NetworkServer: ns,
+	})
+	if err != nil {
+		return 0, err
+	}
+	return resp.Id, nil
+}
+
+// Get returns the network-server matching the given ID.
+func (s *NetworkServerService) Get(ctx context.Context, id int64) (*NetworkServerDetails, error) {
+	resp, err := s.client.Get(ctx, &api.GetNetworkServerRequest{
+		Id: id,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &NetworkServerDetails{
+		NetworkServer: resp.NetworkServer,
+		CreatedAt:     fromTimestamp(resp.CreatedAt),
+		UpdatedAt:     fromTimestamp(resp.UpdatedAt),
+		Version:       resp.Version,
+		Region:        resp.Region,
+	}, nil
+}
+
+// Update updates the given network-server.
+func (s *NetworkServerService) Update(ctx context.Context, ns *api.NetworkServer) error {
+	_, err := s.client.Update(ctx, &api.UpdateNetworkServerRequest{
+		NetworkServer: ns,
+	})
+	return err
+}
+
+// Delete deletes the network-server matching the given ID.
+func (s *NetworkServerService) Delete(ctx context.Context, id int64) error {
+	_, err := s.client.Delete(ctx, &api.DeleteNetworkServerRequest{
+		Id: id,
+	})
+	return err
+}
+
+// List returns a page of network-servers matching the given filter, together
+// with the total number of matching network-servers.
+func (s *NetworkServerService) List(ctx context.Context, filter NetworkServerFilter, limit, offset int64) ([]NetworkServerListItem, int64, error) {
+	resp, err := s.client.List(ctx, &api.ListNetworkServerRequest{
+		Limit:          limit,
+		Offset:         offset,
+		OrganizationId: filter.OrganizationID,
+	})
+	if err != nil {
+		return nil, 0, err
+	}
+
+	out := make([]NetworkServerListItem, 0, len(resp.Result))
+	for _, item := range resp.Result {
+		out = append(out, NetworkServerListItem{
+			ID:        item.Id,
+			Name:      item.Name,
+			Server:    item.Server,
+			CreatedAt: fromTimestamp(item.CreatedAt),
+			UpdatedAt: fromTimestamp(item.UpdatedAt),
+		})
+	}
+
+	return out, resp.TotalCount, nil
+}
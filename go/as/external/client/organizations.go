@@ -0,0 +1,207 @@
+package client
+
+import (
+	"context"
+	"time"
+
+	"github.com/brocaar/chirpstack-api/go/as/external/api"
+)
+
+// OrganizationDetails contains an organization together with its timestamps.
+// The organization message does not contain any encoded fields and is
+// therefore used as-is.
+type OrganizationDetails struct {
+	Organization *api.Organization
+	CreatedAt    time.Time
+	UpdatedAt    time.Time
+}
+
+// OrganizationListItem defines an organization within a list result-set.
+type OrganizationListItem struct {
+	ID              int64
+	Name            string
+	DisplayName     string
+	CanHaveGateways bool
+	CreatedAt       time.Time
+	UpdatedAt       time.Time
+}
+
+// OrganizationFilter defines the filters for listing organizations.
+type OrganizationFilter struct {
+	Search string
+}
+
+// OrganizationUserDetails contains an organization user together with its
+// timestamps.
+type OrganizationUserDetails struct {
+	OrganizationUser *api.OrganizationUser
+	CreatedAt        time.Time
+	UpdatedAt        time.Time
+}
+
+// OrganizationUserListItem defines an organization user within a list
+// result-set.
+type OrganizationUserListItem struct {
+	UserID         int64
+	Username       string
+	IsAdmin        bool
+	IsDeviceAdmin  bool
+	IsGatewayAdmin bool
+	CreatedAt      time.Time
+	UpdatedAt      time.Time
+}
+
+// OrganizationService wraps the external API organization service.
+type OrganizationService struct {
+	client api.OrganizationServiceClient
+}
+
+// API returns the underlying generated service client.
+func (s *OrganizationService) API() api.OrganizationServiceClient {
+	return s.client
+}
+
+// Create creates the given organization and returns its ID.
+func (s *OrganizationService) Create(ctx context.Context, org *api.Organization) (int64, error) {
+	resp, err := s.client.Create(ctx, &api.CreateOrganizationRequest{
+		Organization: org,
+	})
+	if err != nil {
+		return 0, err
+	}
+	return resp.Id, nil
+}
+
+// Get returns the organization matching the given ID.
+func (s *OrganizationService) Get(ctx context.Context, id int64) (*OrganizationDetails, error) {
+	resp, err := s.client.Get(ctx, &api.GetOrganizationRequest{
+		Id: id,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &OrganizationDetails{
+		Organization: resp.Organization,
+		CreatedAt:    fromTimestamp(resp.CreatedAt),
+		UpdatedAt:    fromTimestamp(resp.UpdatedAt),
+	}, nil
+}
+
+// Update updates the given organization.
+func (s *OrganizationService) Update(ctx context.Context, org *api.Organization) error {
+	_, err := s.client.Update(ctx, &api.UpdateOrganizationRequest{
+		Organization: org,
+	})
+	return err
+}
+
+// Delete deletes the organization matching the given ID.
+func (s *OrganizationService) Delete(ctx context.Context, id int64) error {
+	_, err := s.client.Delete(ctx, &api.DeleteOrganizationRequest{
+		Id: id,
+	})
+	return err
+}
+
+// List returns a page of organizations matching the given filter, together
+// with the total number of matching organizations.
+func (s *OrganizationService) List(ctx context.Context, filter OrganizationFilter, limit, offset int64) ([]OrganizationListItem, int64, error) {
+	resp, err := s.client.List(ctx, &api.ListOrganizationRequest{
+		Limit:  limit,
+		Offset: offset,
+		Search: filter.Search,
+	})
+	if err != nil {
+		return nil, 0, err
+	}
+
+	out := make([]OrganizationListItem, 0, len(resp.Result))
+	for _, item := range resp.Result {
+		out = append(out, OrganizationListItem{
+			ID:              item.Id,
+			Name:            item.Name,
+			DisplayName:     item.DisplayName,
+			CanHaveGateways: item.CanHaveGateways,
+			CreatedAt:       fromTimestamp(item.CreatedAt),
+			UpdatedAt:       fromTimestamp(item.UpdatedAt),
+		})
+	}
+
+	return out, resp.TotalCount, nil
+}
+
+// AddUser adds the given user to the organization.
+func (s *OrganizationService) AddUser(ctx context.Context, u *api.OrganizationUser) error {
+	_, err := s.client.AddUser(ctx, &api.AddOrganizationUserRequest{
+		OrganizationUser: u,
+	})
+	return err
+}
+
+// GetUser returns the user matching the given organization and user ID.
+func (s *OrganizationService) GetUser(ctx context.Context, organizationID, userID int64) (*OrganizationUserDetails, error) {
+	resp, err := s.client.GetUser(ctx, &api.GetOrganizationUserRequest{
+		OrganizationId: organizationID,
+		UserId:         userID,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &OrganizationUserDetails{
+		OrganizationUser: resp.OrganizationUser,
+		CreatedAt:        fromTimestamp(resp.CreatedAt),
+		UpdatedAt:        fromTimestamp(resp.UpdatedAt),
+	}, nil
+}
+
+// UpdateUser updates the given organization user.
+func (s *OrganizationService) UpdateUser(ctx context.Context, u *api.OrganizationUser) error {
+	_, err := s.client.UpdateUser(ctx, &api.UpdateOrganizationUserRequest{
+		OrganizationUser: u,
+	})
+	return err
+}
+
+// DeleteUser removes the given user from the organization.
+func (s *OrganizationService) DeleteUser(ctx context.Context, organizationID, userID int64) error {
+	_, err := s.client.DeleteUser(ctx, &api.DeleteOrganizationUserRequest{
+		OrganizationId: organizationID,
+		UserId:         userID,
+	})
+	return err
+}
+
+// ListUsers returns a page of users of the given organization, together with
+// the total number of organization users.
+func (s *OrganizationService) ListUsers(ctx context.Context, organizationID, limit, offset int64) ([]OrganizationUserListItem, int64, error) {
+	limit32, offset32, err := int32Page(limit, offset)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	resp, err := s.client.ListUsers(ctx, &api.ListOrganizationUsersRequest{
+		OrganizationId: organizationID,
+		Limit:          limit32,
+		Offset:         offset32,
+	})
+	if err != nil {
+		return nil, 0, err
+	}
+
+	out := make([]OrganizationUserListItem, 0, len(resp.Result))
+	for _, item := range resp.Result {
+		out = append(out, OrganizationUserListItem{
+			UserID:         item.UserId,
+			Username:       item.Username,
+			IsAdmin:        item.IsAdmin,
+			IsDeviceAdmin:  item.IsDeviceAdmin,
+			IsGatewayAdmin: item.IsGatewayAdmin,
+			CreatedAt:      fromTimestamp(item.CreatedAt),
+			UpdatedAt:      fromTimestamp(item.UpdatedAt),
+		})
+	}
+
+	return out, resp.TotalCount, nil
+}
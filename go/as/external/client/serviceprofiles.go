@@ -0,0 +1,112 @@
+package client
+
+import (
+	"context"
+	"time"
+
+	"github.com/brocaar/chirpstack-api/go/as/external/api"
+)
+
+// ServiceProfileDetails contains a service-profile together with its
+// timestamps. The service-profile message does not contain any encoded fields
+// and is therefore used as-is.
+type ServiceProfileDetails struct {
+	ServiceProfile *api.ServiceProfile
+	CreatedAt      time.Time
+	UpdatedAt      time.Time
+}
+
+// ServiceProfileListItem defines a service-profile within a list result-set.
+type ServiceProfileListItem struct {
+	ID              string
+	Name            string
+	OrganizationID  int64
+	NetworkServerID int64
+	CreatedAt       time.Time
+	UpdatedAt       time.Time
+}
+
+// ServiceProfileFilter defines the filters for listing service-profiles.
+type ServiceProfileFilter struct {
+	OrganizationID int64
+}
+
+// ServiceProfileService wraps the external API service-profile service.
+type ServiceProfileService struct {
+	client api.ServiceProfileServiceClient
+}
+
+// API returns the underlying generated service client.
+func (s *ServiceProfileService) API() api.ServiceProfileServiceClient {
+	return s.client
+}
+
+// Create creates the given service-profile and returns its ID.
+func (s *ServiceProfileService) Create(ctx context.Context, sp *api.ServiceProfile) (string, error) {
+	resp, err := s.client.Create(ctx, &api.CreateServiceProfileRequest{
+		ServiceProfile: sp,
+	})
+	if err != nil {
+		return "", err
+	}
+	return resp.Id, nil
+}
+
+// Get returns the service-profile matching the given ID.
+func (s *ServiceProfileService) Get(ctx context.Context, id string) (*ServiceProfileDetails, error) {
+	resp, err := s.client.Get(ctx, &api.GetServiceProfileRequest{
+		Id: id,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &ServiceProfileDetails{
+		ServiceProfile: resp.ServiceProfile,
+		CreatedAt:      fromTimestamp(resp.CreatedAt),
+		UpdatedAt:      fromTimestamp(resp.UpdatedAt),
+	}, nil
+}
+
+// Update updates the given service-profile.
+func (s *ServiceProfileService) Update(ctx context.Context, sp *api.ServiceProfile) error {
+	_, err := s.client.Update(ctx, &api.UpdateServiceProfileRequest{
+		ServiceProfile: sp,
+	})
+	return err
+}
+
+// Delete deletes the service-profile matching the given ID.
+func (s *ServiceProfileService) Delete(ctx context.Context, id string) error {
+	_, err := s.client.Delete(ctx, &api.DeleteServiceProfileRequest{
+		Id: id,
+	})
+	return err
+}
+
+// List returns a page of service-profiles matching the given filter, together
+// with the total number of matching service-profiles.
+func (s *ServiceProfileService) List(ctx context.Context, filter ServiceProfileFilter, limit, offset int64) ([]ServiceProfileListItem, int64, error) {
+	resp, err := s.client.List(ctx, &api.ListServiceProfileRequest{
+		Limit:          limit,
+		Offset:         offset,
+		OrganizationId: filter.OrganizationID,
+	})
+	if err != nil {
+		return nil, 0, err
+	}
+
+	out := make([]ServiceProfileListItem, 0, len(resp.Result))
+	for _, item := range resp.Result {
+		out = append(out, ServiceProfileListItem{
+			ID:              item.Id,
+			Name:            item.Name,
+			OrganizationID:  item.OrganizationId,
+			NetworkServerID: item.NetworkServerId,
+			CreatedAt:       fromTimestamp(item.CreatedAt),
+			UpdatedAt:       fromTimestamp(item.UpdatedAt),
+		})
+	}
+
+	return out, resp.TotalCount, nil
+}
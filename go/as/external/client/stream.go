@@ -0,0 +1,300 @@
+package client
+
+import (
+	"context"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/brocaar/chirpstack-api/go/as/external/api"
+	"github.com/brocaar/chirpstack-api/go/as/integration"
+	"github.com/brocaar/chirpstack-api/go/lorawan"
+)
+
+// StreamOption configures the stream subscriptions.
+type StreamOption func(*streamOptions)
+
+type streamOptions struct {
+	minBackoff time.Duration
+	maxBackoff time.Duration
+	onGap      func(StreamGap)
+}
+
+// WithBackoff sets the minimum and maximum delay between re-opening a failed
+// stream. The delay doubles after each failed attempt (default 1s up to 1m).
+func WithBackoff(min, max time.Duration) StreamOption {
+	return func(o *streamOptions) {
+		o.minBackoff = min
+		o.maxBackoff = max
+	}
+}
+
+// WithGapHandler sets the function which is called after a stream has been
+// re-opened, describing the period during which the stream was down.
+func WithGapHandler(fn func(StreamGap)) StreamOption {
+	return func(o *streamOptions) {
+		o.onGap = fn
+	}
+}
+
+// StreamGap describes a period during which a stream was disconnected. Frames
+// and events emitted during this period have been lost.
+type StreamGap struct {
+	DisconnectedAt time.Time
+	ReconnectedAt  time.Time
+
+	// Err holds the error that caused the disconnect.
+	Err error
+}
+
+// FrameLog contains either an uplink or a downlink frame-log.
+type FrameLog struct {
+	UplinkFrame   *api.UplinkFrameLog
+	DownlinkFrame *api.DownlinkFrameLog
+}
+
+// EventLog contains a device event.
+type EventLog struct {
+	Type        string
+	PayloadJSON string
+}
+
+// Decode decodes the event payload into the integration event matching the
+// event type. An error is returned for unknown event types.
+func (e EventLog) Decode() (integration.Event, error) {
+	return integration.DecodeEventJSON(e.Type, e.PayloadJSON)
+}
+
+// DecodeEventLog decodes the payload of the given event-log response into the
+// integration event matching the event type.
+func DecodeEventLog(resp *api.StreamDeviceEventLogsResponse) (integration.Event, error) {
+	return integration.DecodeEventJSON(resp.GetType(), resp.GetPayloadJson())
+}
+
+// SubscribeFrameLogs streams the frame-logs of the given device to fn. The
+// stream is re-opened when it fails. It blocks until the context is cancelled
+// or a non-recoverable error occurs (e.g. the device does not exist).
+func (s *DeviceService) SubscribeFrameLogs(ctx context.Context, devEUI lorawan.EUI64, fn func(FrameLog), opts ...StreamOption) error {
+	return subscribe(ctx, func(ctx context.Context) (recvFunc, error) {
+		stream, err := s.StreamFrameLogs(ctx, devEUI)
+		if err != nil {
+			return nil, err
+		}
+		return func() (interface{}, error) {
+			resp, err := stream.Recv()
+			if err != nil {
+				return nil, err
+			}
+			return FrameLog{
+				UplinkFrame:   resp.GetUplinkFrame(),
+				DownlinkFrame: resp.GetDownlinkFrame(),
+			}, nil
+		}, nil
+	}, func(v interface{}) {
+		fn(v.(FrameLog))
+	}, s.creds, opts)
+}
+
+// FrameLogs is the channel based version of SubscribeFrameLogs. The returned
+// frame-log channel is closed when the subscription ends, after which the
+// error channel returns the reason.
+func (s *DeviceService) FrameLogs(ctx context.Context, devEUI lorawan.EUI64, opts ...StreamOption) (<-chan FrameLog, <-chan error) {
+	out := make(chan FrameLog)
+	errc := make(chan error, 1)
+
+	go func() {
+		defer close(errc)
+		defer close(out)
+		errc <- s.SubscribeFrameLogs(ctx, devEUI, func(fl FrameLog) {
+			select {
+			case out <- fl:
+			case <-ctx.Done():
+			}
+		}, opts...)
+	}()
+
+	return out, errc
+}
+
+// SubscribeEventLogs streams the events of the given device to fn. The stream
+// is re-opened when it fails. It blocks until the context is cancelled or a
+// non-recoverable error occurs (e.g. the device does not exist).
+func (s *DeviceService) SubscribeEventLogs(ctx context.Context, devEUI lorawan.EUI64, fn func(EventLog), opts ...StreamOption) error {
+	return subscribe(ctx, func(ctx context.Context) (recvFunc, error) {
+		stream, err := s.StreamEventLogs(ctx, devEUI)
+		if err != nil {
+			return nil, err
+		}
+		return func() (interface{}, error) {
+			resp, err := stream.Recv()
+			if err != nil {
+				return nil, err
+			}
+			return EventLog{
+				Type:        resp.Type,
+				PayloadJSON: resp.PayloadJson,
+			}, nil
+		}, nil
+	}, func(v interface{}) {
+		fn(v.(EventLog))
+	}, s.creds, opts)
+}
+
+// EventLogs is the channel based version of SubscribeEventLogs. The returned
+// event-log channel is closed when the subscription ends, after which the
+// error channel returns the reason.
+func (s *DeviceService) EventLogs(ctx context.Context, devEUI lorawan.EUI64, opts ...StreamOption) (<-chan EventLog, <-chan error) {
+	out := make(chan EventLog)
+	errc := make(chan error, 1)
+
+	go func() {
+		defer close(errc)
+		defer close(out)
+		errc <- s.SubscribeEventLogs(ctx, devEUI, func(el EventLog) {
+			select {
+			case out <- el:
+			case <-ctx.Done():
+			}
+		}, opts...)
+	}()
+
+	return out, errc
+}
+
+// SubscribeFrameLogs streams the frame-logs of the given gateway to fn. The
+// stream is re-opened when it fails. It blocks until the context is cancelled
+// or a non-recoverable error occurs (e.g. the gateway does not exist).
+func (s *GatewayService) SubscribeFrameLogs(ctx context.Context, id lorawan.EUI64, fn func(FrameLog), opts ...StreamOption) error {
+	return subscribe(ctx, func(ctx context.Context) (recvFunc, error) {
+		stream, err := s.StreamFrameLogs(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+		return func() (interface{}, error) {
+			resp, err := stream.Recv()
+			if err != nil {
+				return nil, err
+			}
+			return FrameLog{
+				UplinkFrame:   resp.GetUplinkFrame(),
+				DownlinkFrame: resp.GetDownlinkFrame(),
+			}, nil
+		}, nil
+	}, func(v interface{}) {
+		fn(v.(FrameLog))
+	}, s.creds, opts)
+}
+
+// FrameLogs is the channel based version of SubscribeFrameLogs. The returned
+// frame-log channel is closed when the subscription ends, after which the
+// error channel returns the reason.
+func (s *GatewayService) FrameLogs(ctx context.Context, id lorawan.EUI64, opts ...StreamOption) (<-chan FrameLog, <-chan error) {
+	out := make(chan FrameLog)
+	errc := make(chan error, 1)
+
+	go func() {
+		defer close(errc)
+		defer close(out)
+		errc <- s.SubscribeFrameLogs(ctx, id, func(fl FrameLog) {
+			select {
+			case out <- fl:
+			case <-ctx.Done():
+			}
+		}, opts...)
+	}()
+
+	return out, errc
+}
+
+// recvFunc returns the next message of an opened stream.
+type recvFunc func() (interface{}, error)
+
+// subscribe opens the stream using open and passes each received message to
+// fn. When the stream fails, it is re-opened using an exponential backoff.
+// The creds (which may be nil) are used to decide if an authentication error
+// can be resolved by re-opening the stream.
+func subscribe(ctx context.Context, open func(context.Context) (recvFunc, error), fn func(interface{}), creds *JWTCredentials, opts []StreamOption) error {
+	o := streamOptions{
+		minBackoff: time.Second,
+		maxBackoff: time.Minute,
+	}
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	backoff := o.minBackoff
+	var gap *StreamGap
+	var connected bool
+
+	for {
+		recv, err := open(ctx)
+		if err == nil {
+			connected = true
+			openedAt := time.Now()
+			if gap != nil {
+				gap.ReconnectedAt = openedAt
+				if o.onGap != nil {
+					o.onGap(*gap)
+				}
+				gap = nil
+			}
+
+			var msg interface{}
+			for {
+				if msg, err = recv(); err != nil {
+					break
+				}
+				fn(msg)
+				backoff = o.minBackoff
+			}
+
+			// Reset the backoff for streams which have been up for a while.
+			if time.Since(openedAt) > o.maxBackoff {
+				backoff = o.minBackoff
+			}
+		}
+
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+
+		if isPermanentStreamError(err, creds) {
+			return err
+		}
+
+		// A gap is only reported for streams which have been connected
+		// before.
+		if gap == nil && connected {
+			gap = &StreamGap{
+				DisconnectedAt: time.Now(),
+				Err:            err,
+			}
+		}
+
+		select {
+		case <-time.After(backoff):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+
+		backoff *= 2
+		if backoff > o.maxBackoff {
+			backoff = o.maxBackoff
+		}
+	}
+}
+
+// isPermanentStreamError returns true for errors which will not be resolved
+// by re-opening the stream. Authentication errors are only resolved by
+// re-opening the stream when the credentials can log in again.
+func isPermanentStreamError(err error, creds *JWTCredentials) bool {
+	switch status.Code(err) {
+	case codes.InvalidArgument, codes.NotFound, codes.PermissionDenied, codes.Unimplemented:
+		return true
+	case codes.Unauthenticated:
+		return !creds.canRefresh()
+	default:
+		return false
+	}
+}
@@ -0,0 +1,158 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/brocaar/chirpstack-api/go/as/external/api"
+	"github.com/brocaar/chirpstack-api/go/lorawan"
+)
+
+// frameLogServer implements StreamFrameLogs. For every opened stream, the
+// handler is called with the (zero-based) number of the stream.
+type frameLogServer struct {
+	api.UnimplementedDeviceServiceServer
+
+	handler func(n int, stream api.DeviceService_StreamFrameLogsServer) error
+
+	mu    sync.Mutex
+	opens int
+}
+
+func (s *frameLogServer) StreamFrameLogs(req *api.StreamDeviceFrameLogsRequest, stream api.DeviceService_StreamFrameLogsServer) error {
+	s.mu.Lock()
+	n := s.opens
+	s.opens++
+	s.mu.Unlock()
+
+	return s.handler(n, stream)
+}
+
+func (s *frameLogServer) openCount() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.opens
+}
+
+func sendFrame(stream api.DeviceService_StreamFrameLogsServer, payload string) error {
+	return stream.Send(&api.StreamDeviceFrameLogsResponse{
+		Frame: &api.StreamDeviceFrameLogsResponse_UplinkFrame{
+			UplinkFrame: &api.UplinkFrameLog{PhyPayloadJson: payload},
+		},
+	})
+}
+
+func TestSubscribeReconnect(t *testing.T) {
+	srv := &frameLogServer{
+		handler: func(n int, stream api.DeviceService_StreamFrameLogsServer) error {
+			switch n {
+			case 0:
+				if err := sendFrame(stream, "frame-0"); err != nil {
+					return err
+				}
+				return status.Error(codes.Unavailable, "going away")
+			default:
+				if err := sendFrame(stream, fmt.Sprintf("frame-%d", n)); err != nil {
+					return err
+				}
+				<-stream.Context().Done()
+				return nil
+			}
+		},
+	}
+	c, stop := newTestClient(t, func(s *grpc.Server) {
+		api.RegisterDeviceServiceServer(s, srv)
+	}, WithAPIToken("token"))
+	defer stop()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var frames []string
+	var gaps []StreamGap
+	err := c.Devices().SubscribeFrameLogs(ctx, lorawan.EUI64{1, 2, 3, 4, 5, 6, 7, 8}, func(fl FrameLog) {
+		frames = append(frames, fl.UplinkFrame.GetPhyPayloadJson())
+		if len(frames) == 2 {
+			cancel()
+		}
+	}, WithBackoff(10*time.Millisecond, 50*time.Millisecond), WithGapHandler(func(gap StreamGap) {
+		gaps = append(gaps, gap)
+	}))
+	if err != context.Canceled {
+		t.Errorf("expected context.Canceled, got %v", err)
+	}
+
+	if len(frames) != 2 || frames[0] != "frame-0" || frames[1] != "frame-1" {
+		t.Errorf("expected [frame-0 frame-1], got %v", frames)
+	}
+	if n := srv.openCount(); n != 2 {
+		t.Errorf("expected 2 opened streams, got %d", n)
+	}
+
+	if len(gaps) != 1 {
+		t.Fatalf("expected 1 gap, got %d", len(gaps))
+	}
+	if status.Code(gaps[0].Err) != codes.Unavailable {
+		t.Errorf("expected gap error Unavailable, got %v", gaps[0].Err)
+	}
+	if !gaps[0].ReconnectedAt.After(gaps[0].DisconnectedAt) {
+		t.Errorf("expected reconnect after disconnect, got %+v", gaps[0])
+	}
+}
+
+func TestSubscribePermanentError(t *testing.T) {
+	tests := []struct {
+		name string
+		code codes.Code
+		opts []Option
+	}{
+		{
+			name: "not found",
+			code: codes.NotFound,
+			opts: []Option{WithAPIToken("token")},
+		},
+		{
+			name: "unauthenticated with api token",
+			code: codes.Unauthenticated,
+			opts: []Option{WithAPIToken("token")},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			srv := &frameLogServer{
+				handler: func(n int, stream api.DeviceService_StreamFrameLogsServer) error {
+					return status.Error(test.code, "error")
+				},
+			}
+			c, stop := newTestClient(t, func(s *grpc.Server) {
+				api.RegisterDeviceServiceServer(s, srv)
+			}, test.opts...)
+			defer stop()
+
+			var gaps int
+			start := time.Now()
+			err := c.Devices().SubscribeFrameLogs(context.Background(), lorawan.EUI64{1, 2, 3, 4, 5, 6, 7, 8}, func(FrameLog) {},
+				WithBackoff(time.Second, time.Second), WithGapHandler(func(StreamGap) { gaps++ }))
+			if status.Code(err) != test.code {
+				t.Errorf("expected %s, got %v", test.code, err)
+			}
+			if d := time.Since(start); d >= time.Second {
+				t.Errorf("expected an immediate return, took %s", d)
+			}
+			if n := srv.openCount(); n != 1 {
+				t.Errorf("expected 1 opened stream, got %d", n)
+			}
+			if gaps != 0 {
+				t.Errorf("expected no gaps, got %d", gaps)
+			}
+		})
+	}
+}
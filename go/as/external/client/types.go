@@ -0,0 +1,83 @@
+package client
+
+import (
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/golang/protobuf/ptypes/duration"
+	"github.com/golang/protobuf/ptypes/timestamp"
+
+	"github.com/brocaar/chirpstack-api/go/lorawan"
+)
+
+// keyString returns the HEX encoded key or an empty string for a nil key.
+func keyString(k *lorawan.AES128Key) string {
+	if k == nil {
+		return ""
+	}
+	return k.String()
+}
+
+// parseOptionalKey parses the HEX encoded key, returning nil for an empty
+// string.
+func parseOptionalKey(s string) (*lorawan.AES128Key, error) {
+	if s == "" {
+		return nil, nil
+	}
+	k, err := lorawan.ParseAES128Key(s)
+	if err != nil {
+		return nil, err
+	}
+	return &k, nil
+}
+
+// fromTimestamp returns the time.Time for the given timestamp, or the zero
+// time when the timestamp is nil.
+func fromTimestamp(ts *timestamp.Timestamp) time.Time {
+	if ts == nil {
+		return time.Time{}
+	}
+	return time.Unix(ts.Seconds, int64(ts.Nanos)).UTC()
+}
+
+// toTimestamp returns the timestamp for the given time.Time, or nil when the
+// time is the zero time.
+func toTimestamp(t time.Time) *timestamp.Timestamp {
+	if t.IsZero() {
+		return nil
+	}
+	return &timestamp.Timestamp{
+		Seconds: t.Unix(),
+		Nanos:   int32(t.Nanosecond()),
+	}
+}
+
+// fromDuration returns the time.Duration for the given duration.
+func fromDuration(d *duration.Duration) time.Duration {
+	if d == nil {
+		return 0
+	}
+	return time.Duration(d.Seconds)*time.Second + time.Duration(d.Nanos)
+}
+
+// toDuration returns the duration for the given time.Duration.
+func toDuration(d time.Duration) *duration.Duration {
+	return &duration.Duration{
+		Seconds: int64(d / time.Second),
+		Nanos:   int32(d % time.Second),
+	}
+}
+
+// int32Page returns the limit and offset as int32, for the List calls which
+// use 32 bit pagination fields. Values which do not fit are rejected instead
+// of wrapped.
+func int32Page(limit, offset int64) (int32, int32, error) {
+	if limit < 0 || limit > math.MaxInt32 {
+		return 0, 0, fmt.Errorf("client: limit %d out of range", limit)
+	}
+	if offset < 0 || offset > math.MaxInt32 {
+		return 0, 0, fmt.Errorf("client: offset %d out of range", offset)
+	}
+	return int32(limit), int32(offset), nil
+}
@@ -0,0 +1,125 @@
+package client
+
+import (
+	"context"
+	"time"
+
+	"github.com/brocaar/chirpstack-api/go/as/external/api"
+)
+
+// UserDetails contains a user together with its timestamps. The user message
+// does not contain any encoded fields and is therefore used as-is.
+type UserDetails struct {
+	User      *api.User
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+// UserListItem defines a user within a list result-set.
+type UserListItem struct {
+	ID         int64
+	Username   string
+	SessionTTL int32
+	IsAdmin    bool
+	IsActive   bool
+	CreatedAt  time.Time
+	UpdatedAt  time.Time
+}
+
+// UserFilter defines the filters for listing users.
+type UserFilter struct {
+	Search string
+}
+
+// UserService wraps the external API user service.
+type UserService struct {
+	client api.UserServiceClient
+}
+
+// API returns the underlying generated service client.
+func (s *UserService) API() api.UserServiceClient {
+	return s.client
+}
+
+// Create creates the given user with the given password and organization
+// memberships and returns its ID.
+func (s *UserService) Create(ctx context.Context, u *api.User, password string, organizations []*api.UserOrganization) (int64, error) {
+	resp, err := s.client.Create(ctx, &api.CreateUserRequest{
+		User:          u,
+		Password:      password,
+		Organizations: organizations,
+	})
+	if err != nil {
+		return 0, err
+	}
+	return resp.Id, nil
+}
+
+// Get returns the user matching the given ID.
+func (s *UserService) Get(ctx context.Context, id int64) (*UserDetails, error) {
+	resp, err := s.client.Get(ctx, &api.GetUserRequest{
+		Id: id,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &UserDetails{
+		User:      resp.User,
+		CreatedAt: fromTimestamp(resp.CreatedAt),
+		UpdatedAt: fromTimestamp(resp.UpdatedAt),
+	}, nil
+}
+
+// Update updates the given user.
+func (s *UserService) Update(ctx context.Context, u *api.User) error {
+	_, err := s.client.Update(ctx, &api.UpdateUserRequest{
+		User: u,
+	})
+	return err
+}
+
+// Delete deletes the user matching the given ID.
+func (s *UserService) Delete(ctx context.Context, id int64) error {
+	_, err := s.client.Delete(ctx, &api.DeleteUserRequest{
+		Id: id,
+	})
+	return err
+}
+
+// UpdatePassword updates the password of the given user.
+func (s *UserService) UpdatePassword(ctx context.Context, userID int64, password string) error {
+	_, err := s.client.UpdatePassword(ctx, &api.UpdateUserPasswordRequest{
+		UserId:   userID,
+		Password: password,
+	})
+	return err
+}
+
+// List returns a page of users matching the given filter, together with the
+// total number of matching users.
+func (s *UserService) List(ctx context.Context, filter UserFilter, limit, offset int64) ([]UserListItem, int64, error) {
+	resp, err := s.client.List(ctx, &api.ListUserRequest{
+		Limit:  limit,
+		Offset: offset,
+		Search: filter.Search,
+	})
+	if err != nil {
+		return nil, 0, err
+	}
+
+	out := make([]UserListItem, 0, len(resp.Result))
+	for _, item := range resp.Result {
+		out = append(out, UserListItem{
+			ID:         item.Id,
+			Username:   item.Username,
+			SessionTTL: item.SessionTtl,
+			IsAdmin:    item.IsAdmin,
+			IsActive:   item.IsActive,
+			CreatedAt:  fromTimestamp(item.CreatedAt),
+			UpdatedAt:  fromTimestamp(item.UpdatedAt),
+		})
+	}
+
+	return out, resp.TotalCount, nil
+}
@@ -0,0 +1,150 @@
+// Package convert converts between the models of the external API
+// (as/external/api) and the network-server API (ns).
+//
+// The external API encodes IDs as UUID strings and identifiers and keys as
+// HEX strings, the network-server API uses their byte representation. Fields
+// which only exist on one side are left empty by the conversion and must be
+// set by the caller (e.g. the name and organization ID of a profile, or the
+// routing-profile ID of a gateway).
+package convert
+
+import (
+	"encoding/hex"
+	"fmt"
+	"strings"
+
+	"github.com/brocaar/chirpstack-api/go/lorawan"
+)
+
+// UUIDToBytes returns the bytes of the given UUID string (e.g.
+// "6ba7b810-9dad-11d1-80b4-00c04fd430c8"). An empty string returns nil.
+func UUIDToBytes(s string) ([]byte, error) {
+	if s == "" {
+		return nil, nil
+	}
+
+	h := strings.Replace(s, "-", "", -1)
+	if len(s) != 36 || len(h) != 32 || s[8] != '-' || s[13] != '-' || s[18] != '-' || s[23] != '-' {
+		return nil, fmt.Errorf("convert: invalid UUID: %q", s)
+	}
+
+	b, err := hex.DecodeString(h)
+	if err != nil {
+		return nil, fmt.Errorf("convert: invalid UUID: %q: %w", s, err)
+	}
+	return b, nil
+}
+
+// UUIDFromBytes returns the lower-case UUID string for the given bytes. Empty
+// bytes return an empty string.
+func UUIDFromBytes(b []byte) (string, error) {
+	if len(b) == 0 {
+		return "", nil
+	}
+	if len(b) != 16 {
+		return "", fmt.Errorf("convert: invalid UUID: expected 16 bytes, got %d", len(b))
+	}
+
+	h := hex.EncodeToString(b)
+	return h[0:8] + "-" + h[8:12] + "-" + h[12:16] + "-" + h[16:20] + "-" + h[20:32], nil
+}
+
+// converter collects the first error of a sequence of field conversions, so
+// that the conversion functions read as a list of field assignments. Empty
+// strings convert to nil bytes and vice versa.
+type converter struct {
+	err error
+}
+
+func (c *converter) fail(field string, err error) {
+	if c.err == nil && err != nil {
+		c.err = fmt.Errorf("convert: %s: %w", field, err)
+	}
+}
+
+func (c *converter) uuidToBytes(field, s string) []byte {
+	b, err := UUIDToBytes(s)
+	c.fail(field, err)
+	return b
+}
+
+func (c *converter) uuidFromBytes(field string, b []byte) string {
+	s, err := UUIDFromBytes(b)
+	c.fail(field, err)
+	return s
+}
+
+func (c *converter) eui64ToBytes(field, s string) []byte {
+	if s == "" {
+		return nil
+	}
+	e, err := lorawan.ParseEUI64(s)
+	c.fail(field, err)
+	return e.Bytes()
+}
+
+func (c *converter) eui64FromBytes(field string, b []byte) string {
+	if len(b) == 0 {
+		return ""
+	}
+	e, err := lorawan.EUI64FromBytes(b)
+	c.fail(field, err)
+	return e.String()
+}
+
+func (c *converter) devAddrToBytes(field, s string) []byte {
+	if s == "" {
+		return nil
+	}
+	a, err := lorawan.ParseDevAddr(s)
+	c.fail(field, err)
+	return a.Bytes()
+}
+
+func (c *converter) devAddrFromBytes(field string, b []byte) string {
+	if len(b) == 0 {
+		return ""
+	}
+	a, err := lorawan.DevAddrFromBytes(b)
+	c.fail(field, err)
+	return a.String()
+}
+
+func (c *converter) keyToBytes(field, s string) []byte {
+	if s == "" {
+		return nil
+	}
+	k, err := lorawan.ParseAES128Key(s)
+	c.fail(field, err)
+	return k.Bytes()
+}
+
+func (c *converter) keyFromBytes(field string, b []byte) string {
+	if len(b) == 0 {
+		return ""
+	}
+	k, err := lorawan.AES128KeyFromBytes(b)
+	c.fail(field, err)
+	return k.String()
+}
+
+// copyFreqs returns a copy of the given frequencies, so that the converted
+// message does not share its slice with the input.
+func copyFreqs(freqs []uint32) []uint32 {
+	if freqs == nil {
+		return nil
+	}
+	out := make([]uint32, len(freqs))
+	copy(out, freqs)
+	return out
+}
+
+// copyBytes returns a copy of the given bytes.
+func copyBytes(b []byte) []byte {
+	if b == nil {
+		return nil
+	}
+	out := make([]byte, len(b))
+	copy(out, b)
+	return out
+}
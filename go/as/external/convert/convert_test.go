@@ -0,0 +1,256 @@
+package convert
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/golang/protobuf/proto"
+
+	"github.com/brocaar/chirpstack-api/go/as/external/api"
+	"github.com/brocaar/chirpstack-api/go/common"
+	"github.com/brocaar/chirpstack-api/go/ns"
+)
+
+func TestRoundTrip(t *testing.T) {
+	tests := []struct {
+		name string
+		in   proto.Message
+		fn   func(proto.Message) (proto.Message, error)
+	}{
+		{
+			name: "device-profile",
+			in: &api.DeviceProfile{
+				Id:                  "6ba7b810-9dad-11d1-80b4-00c04fd430c8",
+				SupportsClassB:      true,
+				ClassBTimeout:       10,
+				PingSlotPeriod:      128,
+				PingSlotDr:          3,
+				PingSlotFreq:        869525000,
+				SupportsClassC:      true,
+				ClassCTimeout:       20,
+				MacVersion:          "1.0.3",
+				RegParamsRevision:   "B",
+				RxDelay_1:           1,
+				RxDrOffset_1:        2,
+				RxDatarate_2:        3,
+				RxFreq_2:            869525000,
+				FactoryPresetFreqs:  []uint32{868100000, 868300000, 868500000},
+				MaxEirp:             14,
+				MaxDutyCycle:        10,
+				SupportsJoin:        true,
+				RfRegion:            "EU868",
+				Supports_32BitFCnt:  true,
+				GeolocBufferTtl:     60,
+				GeolocMinBufferSize: 3,
+			},
+			fn: func(m proto.Message) (proto.Message, error) {
+				out, err := DeviceProfileToNS(m.(*api.DeviceProfile))
+				if err != nil {
+					return nil, err
+				}
+				return DeviceProfileFromNS(out)
+			},
+		},
+		{
+			name: "service-profile",
+			in: &api.ServiceProfile{
+				Id:                     "6ba7b810-9dad-11d1-80b4-00c04fd430c8",
+				UlRate:                 10,
+				UlBucketSize:           20,
+				UlRatePolicy:           api.RatePolicy_MARK,
+				DlRate:                 30,
+				DlBucketSize:           40,
+				DlRatePolicy:           api.RatePolicy_DROP,
+				AddGwMetadata:          true,
+				DevStatusReqFreq:       5,
+				ReportDevStatusBattery: true,
+				ReportDevStatusMargin:  true,
+				DrMin:                  1,
+				DrMax:                  5,
+				ChannelMask:            []byte{0xff, 0x00},
+				PrAllowed:              true,
+				HrAllowed:              true,
+				RaAllowed:              true,
+				NwkGeoLoc:              true,
+				TargetPer:              10,
+				MinGwDiversity:         2,
+			},
+			fn: func(m proto.Message) (proto.Message, error) {
+				out, err := ServiceProfileToNS(m.(*api.ServiceProfile))
+				if err != nil {
+					return nil, err
+				}
+				return ServiceProfileFromNS(out)
+			},
+		},
+		{
+			name: "gateway",
+			in: &api.Gateway{
+				Id: "0102030405060708",
+				Location: &common.Location{
+					Latitude:  52.3740,
+					Longitude: 4.8897,
+					Altitude:  10,
+					Source:    common.LocationSource_CONFIG,
+				},
+				GatewayProfileId: "6ba7b810-9dad-11d1-80b4-00c04fd430c8",
+				Boards: []*api.GatewayBoard{
+					{
+						FpgaId:           "0807060504030201",
+						FineTimestampKey: "000102030405060708090a0b0c0d0e0f",
+					},
+					{},
+				},
+			},
+			fn: func(m proto.Message) (proto.Message, error) {
+				out, err := GatewayToNS(m.(*api.Gateway))
+				if err != nil {
+					return nil, err
+				}
+				return GatewayFromNS(out)
+			},
+		},
+		{
+			name: "multicast-group",
+			in: &api.MulticastGroup{
+				Id:               "6ba7b810-9dad-11d1-80b4-00c04fd430c8",
+				McAddr:           "01020304",
+				McNwkSKey:        "000102030405060708090a0b0c0d0e0f",
+				FCnt:             10,
+				GroupType:        api.MulticastGroupType_CLASS_B,
+				Dr:               3,
+				Frequency:        869525000,
+				PingSlotPeriod:   128,
+				ServiceProfileId: "7ba7b810-9dad-11d1-80b4-00c04fd430c8",
+			},
+			fn: func(m proto.Message) (proto.Message, error) {
+				out, err := MulticastGroupToNS(m.(*api.MulticastGroup))
+				if err != nil {
+					return nil, err
+				}
+				return MulticastGroupFromNS(out)
+			},
+		},
+		{
+			name: "device-activation",
+			in: &api.DeviceActivation{
+				DevEui:      "0102030405060708",
+				DevAddr:     "01020304",
+				NwkSEncKey:  "000102030405060708090a0b0c0d0e0f",
+				SNwkSIntKey: "101112131415161718191a1b1c1d1e1f",
+				FNwkSIntKey: "202122232425262728292a2b2c2d2e2f",
+				FCntUp:      10,
+				NFCntDown:   11,
+				AFCntDown:   12,
+			},
+			fn: func(m proto.Message) (proto.Message, error) {
+				out, err := DeviceActivationToNS(m.(*api.DeviceActivation))
+				if err != nil {
+					return nil, err
+				}
+				return DeviceActivationFromNS(out)
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			out, err := test.fn(test.in)
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			if !proto.Equal(test.in, out) {
+				t.Errorf("round trip mismatch:\n in: %s\nout: %s", test.in, out)
+			}
+		})
+	}
+}
+
+func TestErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		fn   func() error
+		err  string
+	}{
+		{
+			name: "invalid uuid",
+			fn: func() error {
+				_, err := DeviceProfileToNS(&api.DeviceProfile{Id: "6ba7b810-9dad-11d1-80b4"})
+				return err
+			},
+			err: `convert: id: convert: invalid UUID: "6ba7b810-9dad-11d1-80b4"`,
+		},
+		{
+			name: "invalid uuid hex",
+			fn: func() error {
+				_, err := ServiceProfileToNS(&api.ServiceProfile{Id: "6ba7b810-9dad-11d1-80b4-00c04fd430zz"})
+				return err
+			},
+			err: "convert: id: convert: invalid UUID",
+		},
+		{
+			name: "invalid uuid bytes",
+			fn: func() error {
+				_, err := MulticastGroupFromNS(&ns.MulticastGroup{Id: []byte{1, 2, 3}})
+				return err
+			},
+			err: "convert: id: convert: invalid UUID: expected 16 bytes, got 3",
+		},
+		{
+			name: "invalid hex key",
+			fn: func() error {
+				_, err := DeviceActivationToNS(&api.DeviceActivation{NwkSEncKey: "000102030405060708090a0b0c0d0e0g"})
+				return err
+			},
+			err: "convert: nwk_s_enc_key: lorawan: AES128 key",
+		},
+		{
+			name: "key too short",
+			fn: func() error {
+				_, err := MulticastGroupToNS(&api.MulticastGroup{McNwkSKey: "0001020304"})
+				return err
+			},
+			err: "convert: mc_nwk_s_key: lorawan: AES128 key: expected 32 HEX characters, got 10",
+		},
+		{
+			name: "key bytes too long",
+			fn: func() error {
+				_, err := DeviceActivationFromNS(&ns.DeviceActivation{SNwkSIntKey: make([]byte, 17)})
+				return err
+			},
+			err: "convert: s_nwk_s_int_key: lorawan: AES128 key: expected 16 bytes, got 17",
+		},
+		{
+			name: "board key",
+			fn: func() error {
+				_, err := GatewayToNS(&api.Gateway{Boards: []*api.GatewayBoard{{}, {FineTimestampKey: "00"}}})
+				return err
+			},
+			err: "convert: boards[1].fine_timestamp_key: lorawan: AES128 key",
+		},
+		{
+			name: "first error is returned",
+			fn: func() error {
+				_, err := DeviceActivationToNS(&api.DeviceActivation{DevEui: "01", DevAddr: "01"})
+				return err
+			},
+			err: "convert: dev_eui: ",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := test.fn()
+			if err == nil {
+				t.Fatal("expected error")
+			}
+			if !strings.HasPrefix(err.Error(), test.err) {
+				t.Errorf("expected error %q, got %q", test.err, err)
+			}
+			if errors.Unwrap(err) == nil {
+				t.Errorf("expected wrapped error, got %q", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,48 @@
+package convert
+
+import (
+	"github.com/brocaar/chirpstack-api/go/as/external/api"
+	"github.com/brocaar/chirpstack-api/go/ns"
+)
+
+// DeviceActivationToNS converts the external API device-activation into the
+// network-server device-activation. The AppSKey is not part of the
+// network-server device-activation, the skip frame-counter check flag must be
+// set by the caller.
+func DeviceActivationToNS(da *api.DeviceActivation) (*ns.DeviceActivation, error) {
+	var c converter
+	out := ns.DeviceActivation{
+		DevEui:      c.eui64ToBytes("dev_eui", da.DevEui),
+		DevAddr:     c.devAddrToBytes("dev_addr", da.DevAddr),
+		SNwkSIntKey: c.keyToBytes("s_nwk_s_int_key", da.SNwkSIntKey),
+		FNwkSIntKey: c.keyToBytes("f_nwk_s_int_key", da.FNwkSIntKey),
+		NwkSEncKey:  c.keyToBytes("nwk_s_enc_key", da.NwkSEncKey),
+		FCntUp:      da.FCntUp,
+		NFCntDown:   da.NFCntDown,
+		AFCntDown:   da.AFCntDown,
+	}
+	if c.err != nil {
+		return nil, c.err
+	}
+	return &out, nil
+}
+
+// DeviceActivationFromNS converts the network-server device-activation into
+// the external API device-activation. The AppSKey is left empty.
+func DeviceActivationFromNS(da *ns.DeviceActivation) (*api.DeviceActivation, error) {
+	var c converter
+	out := api.DeviceActivation{
+		DevEui:      c.eui64FromBytes("dev_eui", da.DevEui),
+		DevAddr:     c.devAddrFromBytes("dev_addr", da.DevAddr),
+		SNwkSIntKey: c.keyFromBytes("s_nwk_s_int_key", da.SNwkSIntKey),
+		FNwkSIntKey: c.keyFromBytes("f_nwk_s_int_key", da.FNwkSIntKey),
+		NwkSEncKey:  c.keyFromBytes("nwk_s_enc_key", da.NwkSEncKey),
+		FCntUp:      da.FCntUp,
+		NFCntDown:   da.NFCntDown,
+		AFCntDown:   da.AFCntDown,
+	}
+	if c.err != nil {
+		return nil, c.err
+	}
+	return &out, nil
+}
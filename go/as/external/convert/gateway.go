@@ -0,0 +1,65 @@
+package convert
+
+import (
+	"strconv"
+
+	"github.com/golang/protobuf/proto"
+
+	"github.com/brocaar/chirpstack-api/go/as/external/api"
+	"github.com/brocaar/chirpstack-api/go/common"
+	"github.com/brocaar/chirpstack-api/go/ns"
+)
+
+// GatewayToNS converts the external API gateway into the network-server
+// gateway. The routing-profile ID must be set by the caller.
+func GatewayToNS(gw *api.Gateway) (*ns.Gateway, error) {
+	var c converter
+	out := ns.Gateway{
+		Id:               c.eui64ToBytes("id", gw.Id),
+		Location:         copyLocation(gw.Location),
+		GatewayProfileId: c.uuidToBytes("gateway_profile_id", gw.GatewayProfileId),
+	}
+	for i, b := range gw.Boards {
+		out.Boards = append(out.Boards, &ns.GatewayBoard{
+			FpgaId:           c.eui64ToBytes(boardField(i, "fpga_id"), b.FpgaId),
+			FineTimestampKey: c.keyToBytes(boardField(i, "fine_timestamp_key"), b.FineTimestampKey),
+		})
+	}
+	if c.err != nil {
+		return nil, c.err
+	}
+	return &out, nil
+}
+
+// GatewayFromNS converts the network-server gateway into the external API
+// gateway. The fields which are not part of the network-server gateway are
+// left empty.
+func GatewayFromNS(gw *ns.Gateway) (*api.Gateway, error) {
+	var c converter
+	out := api.Gateway{
+		Id:               c.eui64FromBytes("id", gw.Id),
+		Location:         copyLocation(gw.Location),
+		GatewayProfileId: c.uuidFromBytes("gateway_profile_id", gw.GatewayProfileId),
+	}
+	for i, b := range gw.Boards {
+		out.Boards = append(out.Boards, &api.GatewayBoard{
+			FpgaId:           c.eui64FromBytes(boardField(i, "fpga_id"), b.FpgaId),
+			FineTimestampKey: c.keyFromBytes(boardField(i, "fine_timestamp_key"), b.FineTimestampKey),
+		})
+	}
+	if c.err != nil {
+		return nil, c.err
+	}
+	return &out, nil
+}
+
+func boardField(i int, field string) string {
+	return "boards[" + strconv.Itoa(i) + "]." + field
+}
+
+func copyLocation(loc *common.Location) *common.Location {
+	if loc == nil {
+		return nil
+	}
+	return proto.Clone(loc).(*common.Location)
+}
@@ -0,0 +1,51 @@
+package convert
+
+import (
+	"github.com/brocaar/chirpstack-api/go/as/external/api"
+	"github.com/brocaar/chirpstack-api/go/ns"
+)
+
+// MulticastGroupToNS converts the external API multicast-group into the
+// network-server multicast-group. The McAppSKey and name are not part of the
+// network-server multicast-group, the routing-profile ID must be set by the
+// caller.
+func MulticastGroupToNS(mg *api.MulticastGroup) (*ns.MulticastGroup, error) {
+	var c converter
+	out := ns.MulticastGroup{
+		Id:               c.uuidToBytes("id", mg.Id),
+		McAddr:           c.devAddrToBytes("mc_addr", mg.McAddr),
+		McNwkSKey:        c.keyToBytes("mc_nwk_s_key", mg.McNwkSKey),
+		FCnt:             mg.FCnt,
+		GroupType:        ns.MulticastGroupType(mg.GroupType),
+		Dr:               mg.Dr,
+		Frequency:        mg.Frequency,
+		PingSlotPeriod:   mg.PingSlotPeriod,
+		ServiceProfileId: c.uuidToBytes("service_profile_id", mg.ServiceProfileId),
+	}
+	if c.err != nil {
+		return nil, c.err
+	}
+	return &out, nil
+}
+
+// MulticastGroupFromNS converts the network-server multicast-group into the
+// external API multicast-group. The fields which are not part of the
+// network-server multicast-group are left empty.
+func MulticastGroupFromNS(mg *ns.MulticastGroup) (*api.MulticastGroup, error) {
+	var c converter
+	out := api.MulticastGroup{
+		Id:               c.uuidFromBytes("id", mg.Id),
+		McAddr:           c.devAddrFromBytes("mc_addr", mg.McAddr),
+		McNwkSKey:        c.keyFromBytes("mc_nwk_s_key", mg.McNwkSKey),
+		FCnt:             mg.FCnt,
+		GroupType:        api.MulticastGroupType(mg.GroupType),
+		Dr:               mg.Dr,
+		Frequency:        mg.Frequency,
+		PingSlotPeriod:   mg.PingSlotPeriod,
+		ServiceProfileId: c.uuidFromBytes("service_profile_id", mg.ServiceProfileId),
+	}
+	if c.err != nil {
+		return nil, c.err
+	}
+	return &out, nil
+}
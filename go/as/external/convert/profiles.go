@@ -0,0 +1,143 @@
+package convert
+
+import (
+	"github.com/brocaar/chirpstack-api/go/as/external/api"
+	"github.com/brocaar/chirpstack-api/go/ns"
+)
+
+// DeviceProfileToNS converts the external API device-profile into the
+// network-server device-profile. The name, organization ID, network-server ID
+// and payload codec fields are not part of the network-server device-profile.
+func DeviceProfileToNS(dp *api.DeviceProfile) (*ns.DeviceProfile, error) {
+	var c converter
+	out := ns.DeviceProfile{
+		Id:                  c.uuidToBytes("id", dp.Id),
+		SupportsClassB:      dp.SupportsClassB,
+		ClassBTimeout:       dp.ClassBTimeout,
+		PingSlotPeriod:      dp.PingSlotPeriod,
+		PingSlotDr:          dp.PingSlotDr,
+		PingSlotFreq:        dp.PingSlotFreq,
+		SupportsClassC:      dp.SupportsClassC,
+		ClassCTimeout:       dp.ClassCTimeout,
+		MacVersion:          dp.MacVersion,
+		RegParamsRevision:   dp.RegParamsRevision,
+		RxDelay_1:           dp.RxDelay_1,
+		RxDrOffset_1:        dp.RxDrOffset_1,
+		RxDatarate_2:        dp.RxDatarate_2,
+		RxFreq_2:            dp.RxFreq_2,
+		FactoryPresetFreqs:  copyFreqs(dp.FactoryPresetFreqs),
+		MaxEirp:             dp.MaxEirp,
+		MaxDutyCycle:        dp.MaxDutyCycle,
+		SupportsJoin:        dp.SupportsJoin,
+		RfRegion:            dp.RfRegion,
+		Supports_32BitFCnt:  dp.Supports_32BitFCnt,
+		GeolocBufferTtl:     dp.GeolocBufferTtl,
+		GeolocMinBufferSize: dp.GeolocMinBufferSize,
+	}
+	if c.err != nil {
+		return nil, c.err
+	}
+	return &out, nil
+}
+
+// DeviceProfileFromNS converts the network-server device-profile into the
+// external API device-profile. The fields which are not part of the
+// network-server device-profile are left empty.
+func DeviceProfileFromNS(dp *ns.DeviceProfile) (*api.DeviceProfile, error) {
+	var c converter
+	out := api.DeviceProfile{
+		Id:                  c.uuidFromBytes("id", dp.Id),
+		SupportsClassB:      dp.SupportsClassB,
+		ClassBTimeout:       dp.ClassBTimeout,
+		PingSlotPeriod:      dp.PingSlotPeriod,
+		PingSlotDr:          dp.PingSlotDr,
+		PingSlotFreq:        dp.PingSlotFreq,
+		SupportsClassC:      dp.SupportsClassC,
+		ClassCTimeout:       dp.ClassCTimeout,
+		MacVersion:          dp.MacVersion,
+		RegParamsRevision:   dp.RegParamsRevision,
+		RxDelay_1:           dp.RxDelay_1,
+		RxDrOffset_1:        dp.RxDrOffset_1,
+		RxDatarate_2:        dp.RxDatarate_2,
+		RxFreq_2:            dp.RxFreq_2,
+		FactoryPresetFreqs:  copyFreqs(dp.FactoryPresetFreqs),
+		MaxEirp:             dp.MaxEirp,
+		MaxDutyCycle:        dp.MaxDutyCycle,
+		SupportsJoin:        dp.SupportsJoin,
+		RfRegion:            dp.RfRegion,
+		Supports_32BitFCnt:  dp.Supports_32BitFCnt,
+		GeolocBufferTtl:     dp.GeolocBufferTtl,
+		GeolocMinBufferSize: dp.GeolocMinBufferSize,
+	}
+	if c.err != nil {
+		return nil, c.err
+	}
+	return &out, nil
+}
+
+// ServiceProfileToNS converts the external API service-profile into the
+// network-server service-profile. The name, organization ID and
+// network-server ID fields are not part of the network-server
+// service-profile.
+func ServiceProfileToNS(sp *api.ServiceProfile) (*ns.ServiceProfile, error) {
+	var c converter
+	out := ns.ServiceProfile{
+		Id:                     c.uuidToBytes("id", sp.Id),
+		UlRate:                 sp.UlRate,
+		UlBucketSize:           sp.UlBucketSize,
+		UlRatePolicy:           ns.RatePolicy(sp.UlRatePolicy),
+		DlRate:                 sp.DlRate,
+		DlBucketSize:           sp.DlBucketSize,
+		DlRatePolicy:           ns.RatePolicy(sp.DlRatePolicy),
+		AddGwMetadata:          sp.AddGwMetadata,
+		DevStatusReqFreq:       sp.DevStatusReqFreq,
+		ReportDevStatusBattery: sp.ReportDevStatusBattery,
+		ReportDevStatusMargin:  sp.ReportDevStatusMargin,
+		DrMin:                  sp.DrMin,
+		DrMax:                  sp.DrMax,
+		ChannelMask:            copyBytes(sp.ChannelMask),
+		PrAllowed:              sp.PrAllowed,
+		HrAllowed:              sp.HrAllowed,
+		RaAllowed:              sp.RaAllowed,
+		NwkGeoLoc:              sp.NwkGeoLoc,
+		TargetPer:              sp.TargetPer,
+		MinGwDiversity:         sp.MinGwDiversity,
+	}
+	if c.err != nil {
+		return nil, c.err
+	}
+	return &out, nil
+}
+
+// ServiceProfileFromNS converts the network-server service-profile into the
+// external API service-profile. The fields which are not part of the
+// network-server service-profile are left empty.
+func ServiceProfileFromNS(sp *ns.ServiceProfile) (*api.ServiceProfile, error) {
+	var c converter
+	out := api.ServiceProfile{
+		Id:                     c.uuidFromBytes("id", sp.Id),
+		UlRate:                 sp.UlRate,
+		UlBucketSize:           sp.UlBucketSize,
+		UlRatePolicy:           api.RatePolicy(sp.UlRatePolicy),
+		DlRate:                 sp.DlRate,
+		DlBucketSize:           sp.DlBucketSize,
+		DlRatePolicy:           api.RatePolicy(sp.DlRatePolicy),
+		AddGwMetadata:          sp.AddGwMetadata,
+		DevStatusReqFreq:       sp.DevStatusReqFreq,
+		ReportDevStatusBattery: sp.ReportDevStatusBattery,
+		ReportDevStatusMargin:  sp.ReportDevStatusMargin,
+		DrMin:                  sp.DrMin,
+		DrMax:                  sp.DrMax,
+		ChannelMask:            copyBytes(sp.ChannelMask),
+		PrAllowed:              sp.PrAllowed,
+		HrAllowed:              sp.HrAllowed,
+		RaAllowed:              sp.RaAllowed,
+		NwkGeoLoc:              sp.NwkGeoLoc,
+		TargetPer:              sp.TargetPer,
+		MinGwDiversity:         sp.MinGwDiversity,
+	}
+	if c.err != nil {
+		return nil, c.err
+	}
+	return &out, nil
+}
@@ -0,0 +1,84 @@
+package integration
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/golang/protobuf/jsonpb"
+	"github.com/golang/protobuf/proto"
+)
+
+// Event types, as used by the application-server event-logs.
+const (
+	EventTypeUplink   = "uplink"
+	EventTypeJoin     = "join"
+	EventTypeAck      = "ack"
+	EventTypeError    = "error"
+	EventTypeStatus   = "status"
+	EventTypeLocation = "location"
+)
+
+// Event is implemented by the integration event messages: *UplinkEvent,
+// *JoinEvent, *AckEvent, *ErrorEvent, *StatusEvent and *LocationEvent.
+type Event interface {
+	proto.Message
+
+	// EventType returns the event type.
+	EventType() string
+}
+
+// EventType returns the event type.
+func (*UplinkEvent) EventType() string { return EventTypeUplink }
+
+// EventType returns the event type.
+func (*JoinEvent) EventType() string { return EventTypeJoin }
+
+// EventType returns the event type.
+func (*AckEvent) EventType() string { return EventTypeAck }
+
+// EventType returns the event type.
+func (*ErrorEvent) EventType() string { return EventTypeError }
+
+// EventType returns the event type.
+func (*StatusEvent) EventType() string { return EventTypeStatus }
+
+// EventType returns the event type.
+func (*LocationEvent) EventType() string { return EventTypeLocation }
+
+// NewEvent returns an empty event message for the given event type. For
+// uplink events, "up" (the MQTT topic suffix) is accepted as alias.
+func NewEvent(typ string) (Event, error) {
+	switch typ {
+	case EventTypeUplink, "up":
+		return &UplinkEvent{}, nil
+	case EventTypeJoin:
+		return &JoinEvent{}, nil
+	case EventTypeAck:
+		return &AckEvent{}, nil
+	case EventTypeError:
+		return &ErrorEvent{}, nil
+	case EventTypeStatus:
+		return &StatusEvent{}, nil
+	case EventTypeLocation:
+		return &LocationEvent{}, nil
+	default:
+		return nil, fmt.Errorf("integration: unknown event type: %q", typ)
+	}
+}
+
+// DecodeEventJSON decodes the JSON encoded payload of the given event type.
+// Both the JSON names (e.g. devEUI, objectJSON) and the original field names
+// are accepted. Unknown fields are ignored.
+func DecodeEventJSON(typ, payloadJSON string) (Event, error) {
+	event, err := NewEvent(typ)
+	if err != nil {
+		return nil, err
+	}
+
+	u := jsonpb.Unmarshaler{AllowUnknownFields: true}
+	if err := u.Unmarshal(strings.NewReader(payloadJSON), event); err != nil {
+		return nil, fmt.Errorf("integration: decode %s event: %w", typ, err)
+	}
+
+	return event, nil
+}
@@ -0,0 +1,289 @@
+package integration
+
+import (
+	"testing"
+
+	"github.com/golang/protobuf/proto"
+
+	"github.com/brocaar/chirpstack-api/go/common"
+	"github.com/brocaar/chirpstack-api/go/gw"
+)
+
+var testDevEUI = []byte{0x01, 0x02, 0x03, 0x04, 0x05, 0x06, 0x07, 0x08}
+
+// The payloads are formatted like the application-server event-logs, which
+// use the JSON names of the fields (e.g. devEUI, objectJSON) and emit the
+// default values.
+func TestDecodeEventJSON(t *testing.T) {
+	tests := []struct {
+		name     string
+		typ      string
+		payload  string
+		expected Event
+	}{
+		{
+			name: "uplink",
+			typ:  "uplink",
+			payload: `{
+				"applicationID": "1",
+				"applicationName": "test-app",
+				"deviceName": "test-device",
+				"devEUI": "AQIDBAUGBwg=",
+				"rxInfo": [{
+					"gatewayID": "AQEBAQEBAQE=",
+					"time": null,
+					"timeSinceGPSEpoch": null,
+					"rssi": -57,
+					"loRaSNR": 10.5,
+					"channel": 2,
+					"rfChain": 1,
+					"board": 0,
+					"antenna": 0,
+					"location": null,
+					"fineTimestampType": "NONE",
+					"context": "AAAAAA==",
+					"uplinkID": null
+				}],
+				"txInfo": {
+					"frequency": 868500000,
+					"modulation": "LORA",
+					"loRaModulationInfo": {
+						"bandwidth": 125,
+						"spreadingFactor": 7,
+						"codeRate": "4/5",
+						"polarizationInversion": false
+					}
+				},
+				"adr": true,
+				"dr": 5,
+				"fCnt": 10,
+				"fPort": 2,
+				"data": "AQID",
+				"objectJSON": "{\"temperature\":21.5}",
+				"tags": {"room": "kitchen"}
+			}`,
+			expected: &UplinkEvent{
+				ApplicationId:   1,
+				ApplicationName: "test-app",
+				DeviceName:      "test-device",
+				DevEui:          testDevEUI,
+				RxInfo: []*gw.UplinkRXInfo{
+					{
+						GatewayId: []byte{0x01, 0x01, 0x01, 0x01, 0x01, 0x01, 0x01, 0x01},
+						Rssi:      -57,
+						LoraSnr:   10.5,
+						Channel:   2,
+						RfChain:   1,
+						Context:   []byte{0x00, 0x00, 0x00, 0x00},
+					},
+				},
+				TxInfo: &gw.UplinkTXInfo{
+					Frequency:  868500000,
+					Modulation: common.Modulation_LORA,
+					ModulationInfo: &gw.UplinkTXInfo_LoraModulationInfo{
+						LoraModulationInfo: &gw.LoRaModulationInfo{
+							Bandwidth:       125,
+							SpreadingFactor: 7,
+							CodeRate:        "4/5",
+						},
+					},
+				},
+				Adr:        true,
+				Dr:         5,
+				FCnt:       10,
+				FPort:      2,
+				Data:       []byte{0x01, 0x02, 0x03},
+				ObjectJson: `{"temperature":21.5}`,
+				Tags:       map[string]string{"room": "kitchen"},
+			},
+		},
+		{
+			name:    "uplink using topic alias",
+			typ:     "up",
+			payload: `{"applicationID": "1", "devEUI": "AQIDBAUGBwg=", "fCnt": 10}`,
+			expected: &UplinkEvent{
+				ApplicationId: 1,
+				DevEui:        testDevEUI,
+				FCnt:          10,
+			},
+		},
+		{
+			name: "join",
+			typ:  "join",
+			payload: `{
+				"applicationID": "1",
+				"applicationName": "test-app",
+				"deviceName": "test-device",
+				"devEUI": "AQIDBAUGBwg=",
+				"devAddr": "AAAAAQ==",
+				"rxInfo": [],
+				"txInfo": null,
+				"dr": 0,
+				"tags": {}
+			}`,
+			expected: &JoinEvent{
+				ApplicationId:   1,
+				ApplicationName: "test-app",
+				DeviceName:      "test-device",
+				DevEui:          testDevEUI,
+				DevAddr:         []byte{0x00, 0x00, 0x00, 0x01},
+			},
+		},
+		{
+			name: "ack",
+			typ:  "ack",
+			payload: `{
+				"applicationID": "1",
+				"applicationName": "test-app",
+				"deviceName": "test-device",
+				"devEUI": "AQIDBAUGBwg=",
+				"acknowledged": true,
+				"fCnt": 5,
+				"tags": {}
+			}`,
+			expected: &AckEvent{
+				ApplicationId:   1,
+				ApplicationName: "test-app",
+				DeviceName:      "test-device",
+				DevEui:          testDevEUI,
+				Acknowledged:    true,
+				FCnt:            5,
+			},
+		},
+		{
+			name: "error",
+			typ:  "error",
+			payload: `{
+				"applicationID": "1",
+				"applicationName": "test-app",
+				"deviceName": "test-device",
+				"devEUI": "AQIDBAUGBwg=",
+				"type": "UPLINK_CODEC",
+				"error": "execute js error: ReferenceError: 'foo' is not defined",
+				"fCnt": 10,
+				"tags": {}
+			}`,
+			expected: &ErrorEvent{
+				ApplicationId:   1,
+				ApplicationName: "test-app",
+				DeviceName:      "test-device",
+				DevEui:          testDevEUI,
+				Type:            ErrorType_UPLINK_CODEC,
+				Error:           "execute js error: ReferenceError: 'foo' is not defined",
+				FCnt:            10,
+			},
+		},
+		{
+			name: "status",
+			typ:  "status",
+			payload: `{
+				"applicationID": "1",
+				"applicationName": "test-app",
+				"deviceName": "test-device",
+				"devEUI": "AQIDBAUGBwg=",
+				"margin": 7,
+				"externalPowerSource": false,
+				"batteryLevelUnavailable": false,
+				"batteryLevel": 75.5,
+				"tags": {}
+			}`,
+			expected: &StatusEvent{
+				ApplicationId:   1,
+				ApplicationName: "test-app",
+				DeviceName:      "test-device",
+				DevEui:          testDevEUI,
+				Margin:          7,
+				BatteryLevel:    75.5,
+			},
+		},
+		{
+			name: "location",
+			typ:  "location",
+			payload: `{
+				"applicationID": "1",
+				"applicationName": "test-app",
+				"deviceName": "test-device",
+				"devEUI": "AQIDBAUGBwg=",
+				"location": {
+					"latitude": 52.374,
+					"longitude": 4.8897,
+					"altitude": 10,
+					"source": "GEO_RESOLVER",
+					"accuracy": 25
+				},
+				"tags": {}
+			}`,
+			expected: &LocationEvent{
+				ApplicationId:   1,
+				ApplicationName: "test-app",
+				DeviceName:      "test-device",
+				DevEui:          testDevEUI,
+				Location: &common.Location{
+					Latitude:  52.374,
+					Longitude: 4.8897,
+					Altitude:  10,
+					Source:    common.LocationSource_GEO_RESOLVER,
+					Accuracy:  25,
+				},
+			},
+		},
+		{
+			name:    "original field names",
+			typ:     "ack",
+			payload: `{"application_id": "1", "dev_eui": "AQIDBAUGBwg=", "f_cnt": 5}`,
+			expected: &AckEvent{
+				ApplicationId: 1,
+				DevEui:        testDevEUI,
+				FCnt:          5,
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			event, err := DecodeEventJSON(test.typ, test.payload)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !proto.Equal(test.expected, event) {
+				t.Errorf("expected %s, got %s", test.expected, event)
+			}
+			if event.EventType() != test.expected.EventType() {
+				t.Errorf("expected event type %q, got %q", test.expected.EventType(), event.EventType())
+			}
+		})
+	}
+}
+
+func TestDecodeEventJSONErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		typ     string
+		payload string
+		err     string
+	}{
+		{
+			name:    "unknown type",
+			typ:     "txack",
+			payload: `{}`,
+			err:     `integration: unknown event type: "txack"`,
+		},
+		{
+			name:    "invalid json",
+			typ:     "uplink",
+			payload: `{"devEUI": `,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := DecodeEventJSON(test.typ, test.payload)
+			if err == nil {
+				t.Fatal("expected error")
+			}
+			if test.err != "" && err.Error() != test.err {
+				t.Errorf("expected error %q, got %q", test.err, err)
+			}
+		})
+	}
+}
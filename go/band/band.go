@@ -0,0 +1,210 @@
+// Package band provides the regional band-plan data (data-rates, channels,
+// RX2 parameters, max payload sizes and TX power tables) of the regions
+// enumerated by common.Region, as defined by the LoRaWAN Regional Parameters.
+//
+// The max payload sizes are those for when no repeater is used. Data-rates
+// which are RFU (reserved for future use) are not defined.
+package band
+
+import (
+	"fmt"
+
+	"github.com/brocaar/chirpstack-api/go/common"
+	"github.com/brocaar/chirpstack-api/go/gw"
+)
+
+// DataRate defines a data-rate.
+type DataRate struct {
+	Modulation common.Modulation
+
+	// SpreadingFactor holds the LoRa spreading-factor.
+	SpreadingFactor int
+
+	// Bandwidth holds the LoRa bandwidth (kHz).
+	Bandwidth int
+
+	// BitRate holds the FSK bitrate (bits per second).
+	BitRate int
+
+	// Uplink and Downlink define in which direction the data-rate can be
+	// used.
+	Uplink   bool
+	Downlink bool
+}
+
+// Channel defines a channel.
+type Channel struct {
+	// Frequency (Hz).
+	Frequency uint32
+
+	// MinDR and MaxDR define the data-rate range of the channel.
+	MinDR int
+	MaxDR int
+}
+
+// MaxPayloadSize defines the max payload size of a data-rate.
+type MaxPayloadSize struct {
+	// M defines the max MACPayload size.
+	M int
+
+	// N defines the max FRMPayload size (when no FOpts are used).
+	N int
+}
+
+// Band defines the band-plan of a region.
+type Band struct {
+	Region common.Region
+
+	// MinFrequency and MaxFrequency (Hz) define the frequency range of the
+	// band.
+	MinFrequency uint32
+	MaxFrequency uint32
+
+	// DataRates holds the data-rates by data-rate index.
+	DataRates map[int]DataRate
+
+	// UplinkChannels and DownlinkChannels hold the default channels. When
+	// there are no dedicated downlink channels, the RX1 downlink is sent on
+	// the uplink channel and DownlinkChannels equals UplinkChannels.
+	UplinkChannels   []Channel
+	DownlinkChannels []Channel
+
+	// RX2Frequency (Hz) and RX2DataRate define the default RX2 parameters.
+	RX2Frequency uint32
+	RX2DataRate  int
+
+	// MaxPayloadSizes holds the max payload sizes by data-rate index.
+	MaxPayloadSizes map[int]MaxPayloadSize
+
+	// MaxEIRP holds the default max EIRP (dBm).
+	MaxEIRP float64
+
+	// TXPowerOffsets holds by TXPower index the offset (dB) to the max EIRP.
+	TXPowerOffsets []int
+}
+
+// Get returns the band-plan for the given region.
+func Get(region common.Region) (*Band, error) {
+	fn, ok := bands[region]
+	if !ok {
+		return nil, fmt.Errorf("band: unknown region: %s", region)
+	}
+	return fn(), nil
+}
+
+// ContainsFrequency returns true when the frequency is within the band.
+func (b *Band) ContainsFrequency(freq uint32) bool {
+	return freq >= b.MinFrequency && freq <= b.MaxFrequency
+}
+
+// GetDataRate returns the data-rate for the given data-rate index.
+func (b *Band) GetDataRate(dr int) (DataRate, error) {
+	d, ok := b.DataRates[dr]
+	if !ok {
+		return DataRate{}, fmt.Errorf("band: %s: invalid data-rate: %d", b.Region, dr)
+	}
+	return d, nil
+}
+
+// GetDataRateIndex returns the data-rate index for the given data-rate. The
+// uplink argument selects between the uplink and downlink data-rates, as some
+// regions use different indices for the same data-rate.
+func (b *Band) GetDataRateIndex(uplink bool, d DataRate) (int, error) {
+	for i := 0; i < 16; i++ {
+		dr, ok := b.DataRates[i]
+		if !ok || (uplink && !dr.Uplink) || (!uplink && !dr.Downlink) {
+			continue
+		}
+		if dr.Modulation != d.Modulation {
+			continue
+		}
+		switch d.Modulation {
+		case common.Modulation_LORA:
+			if dr.SpreadingFactor == d.SpreadingFactor && dr.Bandwidth == d.Bandwidth {
+				return i, nil
+			}
+		case common.Modulation_FSK:
+			if dr.BitRate == d.BitRate {
+				return i, nil
+			}
+		}
+	}
+	return 0, fmt.Errorf("band: %s: no data-rate for %s", b.Region, d)
+}
+
+// LoRaModulationInfo returns the LoRa modulation info for the given
+// data-rate index, using the LoRaWAN code rate 4/5.
+func (b *Band) LoRaModulationInfo(dr int) (*gw.LoRaModulationInfo, error) {
+	d, err := b.GetDataRate(dr)
+	if err != nil {
+		return nil, err
+	}
+	if d.Modulation != common.Modulation_LORA {
+		return nil, fmt.Errorf("band: %s: data-rate %d is not a LoRa data-rate", b.Region, dr)
+	}
+	return &gw.LoRaModulationInfo{
+		Bandwidth:       uint32(d.Bandwidth),
+		SpreadingFactor: uint32(d.SpreadingFactor),
+		CodeRate:        "4/5",
+	}, nil
+}
+
+// FSKModulationInfo returns the FSK modulation info for the given data-rate
+// index.
+func (b *Band) FSKModulationInfo(dr int) (*gw.FSKModulationInfo, error) {
+	d, err := b.GetDataRate(dr)
+	if err != nil {
+		return nil, err
+	}
+	if d.Modulation != common.Modulation_FSK {
+		return nil, fmt.Errorf("band: %s: data-rate %d is not an FSK data-rate", b.Region, dr)
+	}
+	return &gw.FSKModulationInfo{
+		Bitrate: uint32(d.BitRate),
+	}, nil
+}
+
+// DataRateFromLoRaModulationInfo returns the data-rate index for the given
+// LoRa modulation info.
+func (b *Band) DataRateFromLoRaModulationInfo(uplink bool, modInfo *gw.LoRaModulationInfo) (int, error) {
+	return b.GetDataRateIndex(uplink, DataRate{
+		Modulation:      common.Modulation_LORA,
+		SpreadingFactor: int(modInfo.GetSpreadingFactor()),
+		Bandwidth:       int(modInfo.GetBandwidth()),
+	})
+}
+
+// DataRateFromFSKModulationInfo returns the data-rate index for the given
+// FSK modulation info.
+func (b *Band) DataRateFromFSKModulationInfo(uplink bool, modInfo *gw.FSKModulationInfo) (int, error) {
+	return b.GetDataRateIndex(uplink, DataRate{
+		Modulation: common.Modulation_FSK,
+		BitRate:    int(modInfo.GetBitrate()),
+	})
+}
+
+// GetMaxPayloadSize returns the max payload size for the given data-rate
+// index.
+func (b *Band) GetMaxPayloadSize(dr int) (MaxPayloadSize, error) {
+	ps, ok := b.MaxPayloadSizes[dr]
+	if !ok {
+		return MaxPayloadSize{}, fmt.Errorf("band: %s: invalid data-rate: %d", b.Region, dr)
+	}
+	return ps, nil
+}
+
+// GetTXPower returns the EIRP (dBm) for the given TXPower index.
+func (b *Band) GetTXPower(txPower int) (float64, error) {
+	if txPower < 0 || txPower >= len(b.TXPowerOffsets) {
+		return 0, fmt.Errorf("band: %s: invalid tx-power: %d", b.Region, txPower)
+	}
+	return b.MaxEIRP + float64(b.TXPowerOffsets[txPower]), nil
+}
+
+// String returns the data-rate as a human readable string.
+func (d DataRate) String() string {
+	if d.Modulation == common.Modulation_FSK {
+		return fmt.Sprintf("FSK %d bps", d.BitRate)
+	}
+	return fmt.Sprintf("SF%d BW%d", d.SpreadingFactor, d.Bandwidth)
+}
@@ -0,0 +1,313 @@
+package band
+
+import (
+	"testing"
+
+	"github.com/brocaar/chirpstack-api/go/common"
+	"github.com/brocaar/chirpstack-api/go/gw"
+)
+
+func mustGet(t *testing.T, region common.Region) *Band {
+	t.Helper()
+	b, err := Get(region)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+// TestRegions spot-checks the band-plans against the LoRaWAN Regional
+// Parameters.
+func TestRegions(t *testing.T) {
+	tests := []struct {
+		region         common.Region
+		dataRates      map[int]DataRate
+		rfu            []int
+		uplinkChannels int
+		firstUplink    Channel
+		lastUplink     Channel
+		firstDownlink  Channel
+		rx2Frequency   uint32
+		rx2DataRate    int
+		maxPayload     map[int]MaxPayloadSize
+		txPower        map[int]float64
+	}{
+		{
+			region: common.Region_EU868,
+			dataRates: map[int]DataRate{
+				0: lora(12, 125),
+				5: lora(7, 125),
+				6: lora(7, 250),
+				7: fsk(50000),
+			},
+			rfu:            []int{8, 15},
+			uplinkChannels: 3,
+			firstUplink:    Channel{Frequency: 868100000, MinDR: 0, MaxDR: 5},
+			lastUplink:     Channel{Frequency: 868500000, MinDR: 0, MaxDR: 5},
+			firstDownlink:  Channel{Frequency: 868100000, MinDR: 0, MaxDR: 5},
+			rx2Frequency:   869525000,
+			rx2DataRate:    0,
+			maxPayload: map[int]MaxPayloadSize{
+				0: {M: 59, N: 51},
+				3: {M: 123, N: 115},
+				7: {M: 250, N: 242},
+			},
+			txPower: map[int]float64{0: 16, 1: 14, 7: 2},
+		},
+		{
+			region: common.Region_US915,
+			dataRates: map[int]DataRate{
+				0:  uplinkOnly(lora(10, 125)),
+				3:  uplinkOnly(lora(7, 125)),
+				4:  uplinkOnly(lora(8, 500)),
+				8:  downlinkOnly(lora(12, 500)),
+				13: downlinkOnly(lora(7, 500)),
+			},
+			rfu:            []int{5, 6, 7, 14, 15},
+			uplinkChannels: 72,
+			firstUplink:    Channel{Frequency: 902300000, MinDR: 0, MaxDR: 3},
+			lastUplink:     Channel{Frequency: 914200000, MinDR: 4, MaxDR: 4},
+			firstDownlink:  Channel{Frequency: 923300000, MinDR: 8, MaxDR: 13},
+			rx2Frequency:   923300000,
+			rx2DataRate:    8,
+			maxPayload: map[int]MaxPayloadSize{
+				0:  {M: 19, N: 11},
+				1:  {M: 61, N: 53},
+				2:  {M: 133, N: 125},
+				4:  {M: 250, N: 242},
+				8:  {M: 61, N: 53},
+				9:  {M: 137, N: 129},
+				13: {M: 250, N: 242},
+			},
+			txPower: map[int]float64{0: 30, 10: 10, 14: 2},
+		},
+		{
+			region: common.Region_AU915,
+			dataRates: map[int]DataRate{
+				0:  uplinkOnly(lora(12, 125)),
+				5:  uplinkOnly(lora(7, 125)),
+				6:  uplinkOnly(lora(8, 500)),
+				8:  downlinkOnly(lora(12, 500)),
+				13: downlinkOnly(lora(7, 500)),
+			},
+			rfu:            []int{7, 14, 15},
+			uplinkChannels: 72,
+			firstUplink:    Channel{Frequency: 915200000, MinDR: 0, MaxDR: 5},
+			lastUplink:     Channel{Frequency: 927100000, MinDR: 6, MaxDR: 6},
+			firstDownlink:  Channel{Frequency: 923300000, MinDR: 8, MaxDR: 13},
+			rx2Frequency:   923300000,
+			rx2DataRate:    8,
+			maxPayload: map[int]MaxPayloadSize{
+				0:  {M: 59, N: 51},
+				3:  {M: 123, N: 115},
+				6:  {M: 250, N: 242},
+				8:  {M: 61, N: 53},
+				9:  {M: 137, N: 129},
+				13: {M: 250, N: 242},
+			},
+			txPower: map[int]float64{0: 30, 14: 2},
+		},
+		{
+			region: common.Region_AS923,
+			dataRates: map[int]DataRate{
+				0: lora(12, 125),
+				2: lora(10, 125),
+				6: lora(7, 250),
+				7: fsk(50000),
+			},
+			rfu:            []int{8},
+			uplinkChannels: 2,
+			firstUplink:    Channel{Frequency: 923200000, MinDR: 0, MaxDR: 5},
+			lastUplink:     Channel{Frequency: 923400000, MinDR: 0, MaxDR: 5},
+			firstDownlink:  Channel{Frequency: 923200000, MinDR: 0, MaxDR: 5},
+			rx2Frequency:   923200000,
+			rx2DataRate:    2,
+			maxPayload: map[int]MaxPayloadSize{
+				2: {M: 59, N: 51},
+				3: {M: 123, N: 115},
+				5: {M: 250, N: 242},
+			},
+			txPower: map[int]float64{0: 16, 7: 2},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.region.String(), func(t *testing.T) {
+			b := mustGet(t, test.region)
+
+			for i, exp := range test.dataRates {
+				dr, err := b.GetDataRate(i)
+				if err != nil {
+					t.Errorf("DR%d: %s", i, err)
+					continue
+				}
+				if dr != exp {
+					t.Errorf("DR%d: expected %+v, got %+v", i, exp, dr)
+				}
+			}
+			for _, i := range test.rfu {
+				if _, err := b.GetDataRate(i); err == nil {
+					t.Errorf("DR%d: expected RFU", i)
+				}
+			}
+
+			if len(b.UplinkChannels) != test.uplinkChannels {
+				t.Fatalf("expected %d uplink channels, got %d", test.uplinkChannels, len(b.UplinkChannels))
+			}
+			if c := b.UplinkChannels[0]; c != test.firstUplink {
+				t.Errorf("expected first uplink channel %+v, got %+v", test.firstUplink, c)
+			}
+			if c := b.UplinkChannels[len(b.UplinkChannels)-1]; c != test.lastUplink {
+				t.Errorf("expected last uplink channel %+v, got %+v", test.lastUplink, c)
+			}
+			if c := b.DownlinkChannels[0]; c != test.firstDownlink {
+				t.Errorf("expected first downlink channel %+v, got %+v", test.firstDownlink, c)
+			}
+			for _, c := range append(b.UplinkChannels, b.DownlinkChannels...) {
+				if !b.ContainsFrequency(c.Frequency) {
+					t.Errorf("channel %d Hz is outside the band", c.Frequency)
+				}
+			}
+
+			if b.RX2Frequency != test.rx2Frequency || b.RX2DataRate != test.rx2DataRate {
+				t.Errorf("expected RX2 %d Hz DR%d, got %d Hz DR%d", test.rx2Frequency, test.rx2DataRate, b.RX2Frequency, b.RX2DataRate)
+			}
+			if dr, err := b.GetDataRate(b.RX2DataRate); err != nil || !dr.Downlink {
+				t.Errorf("RX2 data-rate must be a downlink data-rate (%v)", err)
+			}
+
+			for i, exp := range test.maxPayload {
+				ps, err := b.GetMaxPayloadSize(i)
+				if err != nil {
+					t.Errorf("DR%d: %s", i, err)
+					continue
+				}
+				if ps != exp {
+					t.Errorf("DR%d: expected max payload %+v, got %+v", i, exp, ps)
+				}
+			}
+			for i := range b.DataRates {
+				if _, err := b.GetMaxPayloadSize(i); err != nil {
+					t.Errorf("DR%d: expected max payload size: %s", i, err)
+				}
+			}
+
+			for i, exp := range test.txPower {
+				p, err := b.GetTXPower(i)
+				if err != nil {
+					t.Errorf("TXPower %d: %s", i, err)
+					continue
+				}
+				if p != exp {
+					t.Errorf("TXPower %d: expected %g dBm, got %g dBm", i, exp, p)
+				}
+			}
+			if _, err := b.GetTXPower(len(b.TXPowerOffsets)); err == nil {
+				t.Errorf("expected error for TXPower %d", len(b.TXPowerOffsets))
+			}
+		})
+	}
+}
+
+func TestAllRegions(t *testing.T) {
+	for _, region := range []common.Region{
+		common.Region_EU868,
+		common.Region_US915,
+		common.Region_CN779,
+		common.Region_EU433,
+		common.Region_AU915,
+		common.Region_CN470,
+		common.Region_AS923,
+		common.Region_KR920,
+		common.Region_IN865,
+		common.Region_RU864,
+	} {
+		b := mustGet(t, region)
+		if b.Region != region {
+			t.Errorf("%s: expected region %s, got %s", region, region, b.Region)
+		}
+	}
+
+	if _, err := Get(common.Region(100)); err == nil {
+		t.Error("expected error for unknown region")
+	}
+}
+
+func TestGetDataRateIndex(t *testing.T) {
+	tests := []struct {
+		region common.Region
+		uplink bool
+		dr     DataRate
+		index  int
+		err    bool
+	}{
+		{common.Region_EU868, true, lora(12, 125), 0, false},
+		{common.Region_EU868, false, lora(12, 125), 0, false},
+		{common.Region_EU868, true, fsk(50000), 7, false},
+
+		// SF8/500kHz is DR4 uplink and DR12 downlink in US915.
+		{common.Region_US915, true, lora(8, 500), 4, false},
+		{common.Region_US915, false, lora(8, 500), 12, false},
+		{common.Region_US915, true, lora(12, 500), 0, true},
+		{common.Region_US915, false, lora(10, 125), 0, true},
+		{common.Region_US915, true, lora(12, 125), 0, true},
+
+		// SF8/500kHz is DR6 uplink and DR12 downlink in AU915.
+		{common.Region_AU915, true, lora(8, 500), 6, false},
+		{common.Region_AU915, false, lora(8, 500), 12, false},
+		{common.Region_AU915, false, lora(12, 500), 8, false},
+
+		{common.Region_AS923, false, lora(10, 125), 2, false},
+		{common.Region_AS923, true, lora(7, 250), 6, false},
+	}
+
+	for _, test := range tests {
+		b := mustGet(t, test.region)
+		i, err := b.GetDataRateIndex(test.uplink, test.dr)
+		if test.err {
+			if err == nil {
+				t.Errorf("%s uplink=%t %s: expected error, got DR%d", test.region, test.uplink, test.dr, i)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s uplink=%t %s: %s", test.region, test.uplink, test.dr, err)
+			continue
+		}
+		if i != test.index {
+			t.Errorf("%s uplink=%t %s: expected DR%d, got DR%d", test.region, test.uplink, test.dr, test.index, i)
+		}
+	}
+}
+
+func TestModulationInfo(t *testing.T) {
+	b := mustGet(t, common.Region_US915)
+
+	modInfo, err := b.LoRaModulationInfo(8)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if modInfo.SpreadingFactor != 12 || modInfo.Bandwidth != 500 || modInfo.CodeRate != "4/5" {
+		t.Errorf("unexpected modulation info: %s", modInfo)
+	}
+
+	dr, err := b.DataRateFromLoRaModulationInfo(false, modInfo)
+	if err != nil || dr != 8 {
+		t.Errorf("expected DR8, got DR%d (%v)", dr, err)
+	}
+
+	eu := mustGet(t, common.Region_EU868)
+	if _, err := eu.LoRaModulationInfo(7); err == nil {
+		t.Error("expected error for the FSK data-rate")
+	}
+	fskInfo, err := eu.FSKModulationInfo(7)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if dr, err := eu.DataRateFromFSKModulationInfo(true, fskInfo); err != nil || dr != 7 {
+		t.Errorf("expected DR7, got DR%d (%v)", dr, err)
+	}
+	if _, err := eu.DataRateFromFSKModulationInfo(true, &gw.FSKModulationInfo{Bitrate: 100000}); err == nil {
+		t.Error("expected error for an unknown bitrate")
+	}
+}
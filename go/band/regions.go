@@ -0,0 +1,357 @@
+package band
+
+import (
+	"github.com/brocaar/chirpstack-api/go/common"
+)
+
+var bands = map[common.Region]func() *Band{
+	common.Region_EU868: eu868,
+	common.Region_US915: us915,
+	common.Region_CN779: cn779,
+	common.Region_EU433: eu433,
+	common.Region_AU915: au915,
+	common.Region_CN470: cn470,
+	common.Region_AS923: as923,
+	common.Region_KR920: kr920,
+	common.Region_IN865: in865,
+	common.Region_RU864: ru864,
+}
+
+// lora returns a LoRa data-rate which can be used for uplink and downlink.
+func lora(sf, bw int) DataRate {
+	return DataRate{
+		Modulation:      common.Modulation_LORA,
+		SpreadingFactor: sf,
+		Bandwidth:       bw,
+		Uplink:          true,
+		Downlink:        true,
+	}
+}
+
+// fsk returns an FSK data-rate which can be used for uplink and downlink.
+func fsk(bitRate int) DataRate {
+	return DataRate{
+		Modulation: common.Modulation_FSK,
+		BitRate:    bitRate,
+		Uplink:     true,
+		Downlink:   true,
+	}
+}
+
+func uplinkOnly(d DataRate) DataRate {
+	d.Downlink = false
+	return d
+}
+
+func downlinkOnly(d DataRate) DataRate {
+	d.Uplink = false
+	return d
+}
+
+// euLikeDataRates returns the DR0 - DR7 data-rates shared by the EU868 like
+// regions.
+func euLikeDataRates() map[int]DataRate {
+	return map[int]DataRate{
+		0: lora(12, 125),
+		1: lora(11, 125),
+		2: lora(10, 125),
+		3: lora(9, 125),
+		4: lora(8, 125),
+		5: lora(7, 125),
+		6: lora(7, 250),
+		7: fsk(50000),
+	}
+}
+
+// euLikeMaxPayloadSizes returns the max payload sizes shared by the EU868
+// like regions.
+func euLikeMaxPayloadSizes() map[int]MaxPayloadSize {
+	return map[int]MaxPayloadSize{
+		0: {M: 59, N: 51},
+		1: {M: 59, N: 51},
+		2: {M: 59, N: 51},
+		3: {M: 123, N: 115},
+		4: {M: 250, N: 242},
+		5: {M: 250, N: 242},
+		6: {M: 250, N: 242},
+		7: {M: 250, N: 242},
+	}
+}
+
+// channels returns the channels with the given frequencies and data-rate
+// range.
+func channels(minDR, maxDR int, freqs ...uint32) []Channel {
+	out := make([]Channel, len(freqs))
+	for i, f := range freqs {
+		out[i] = Channel{Frequency: f, MinDR: minDR, MaxDR: maxDR}
+	}
+	return out
+}
+
+// channelRange returns n channels, starting at the given frequency and
+// spaced by step Hz.
+func channelRange(n int, start, step uint32, minDR, maxDR int) []Channel {
+	out := make([]Channel, n)
+	for i := range out {
+		out[i] = Channel{Frequency: start + uint32(i)*step, MinDR: minDR, MaxDR: maxDR}
+	}
+	return out
+}
+
+// txPowerOffsets returns the TXPower offsets 0, -2, -4, ... for the TXPower
+// indices 0 up to and including max.
+func txPowerOffsets(max int) []int {
+	out := make([]int, max+1)
+	for i := range out {
+		out[i] = -2 * i
+	}
+	return out
+}
+
+func eu868() *Band {
+	ch := channels(0, 5, 868100000, 868300000, 868500000)
+	return &Band{
+		Region:           common.Region_EU868,
+		MinFrequency:     863000000,
+		MaxFrequency:     870000000,
+		DataRates:        euLikeDataRates(),
+		UplinkChannels:   ch,
+		DownlinkChannels: ch,
+		RX2Frequency:     869525000,
+		RX2DataRate:      0,
+		MaxPayloadSizes:  euLikeMaxPayloadSizes(),
+		MaxEIRP:          16,
+		TXPowerOffsets:   txPowerOffsets(7),
+	}
+}
+
+func us915() *Band {
+	return &Band{
+		Region:       common.Region_US915,
+		MinFrequency: 902000000,
+		MaxFrequency: 928000000,
+		DataRates: map[int]DataRate{
+			0:  uplinkOnly(lora(10, 125)),
+			1:  uplinkOnly(lora(9, 125)),
+			2:  uplinkOnly(lora(8, 125)),
+			3:  uplinkOnly(lora(7, 125)),
+			4:  uplinkOnly(lora(8, 500)),
+			8:  downlinkOnly(lora(12, 500)),
+			9:  downlinkOnly(lora(11, 500)),
+			10: downlinkOnly(lora(10, 500)),
+			11: downlinkOnly(lora(9, 500)),
+			12: downlinkOnly(lora(8, 500)),
+			13: downlinkOnly(lora(7, 500)),
+		},
+		UplinkChannels: append(
+			channelRange(64, 902300000, 200000, 0, 3),
+			channelRange(8, 903000000, 1600000, 4, 4)...,
+		),
+		DownlinkChannels: channelRange(8, 923300000, 600000, 8, 13),
+		RX2Frequency:     923300000,
+		RX2DataRate:      8,
+		MaxPayloadSizes: map[int]MaxPayloadSize{
+			0:  {M: 19, N: 11},
+			1:  {M: 61, N: 53},
+			2:  {M: 133, N: 125},
+			3:  {M: 250, N: 242},
+			4:  {M: 250, N: 242},
+			8:  {M: 61, N: 53},
+			9:  {M: 137, N: 129},
+			10: {M: 250, N: 242},
+			11: {M: 250, N: 242},
+			12: {M: 250, N: 242},
+			13: {M: 250, N: 242},
+		},
+		MaxEIRP:        30,
+		TXPowerOffsets: txPowerOffsets(14),
+	}
+}
+
+func cn779() *Band {
+	ch := channels(0, 5, 779500000, 779700000, 779900000)
+	return &Band{
+		Region:           common.Region_CN779,
+		MinFrequency:     779000000,
+		MaxFrequency:     787000000,
+		DataRates:        euLikeDataRates(),
+		UplinkChannels:   ch,
+		DownlinkChannels: ch,
+		RX2Frequency:     786000000,
+		RX2DataRate:      0,
+		MaxPayloadSizes:  euLikeMaxPayloadSizes(),
+		MaxEIRP:          12.15,
+		TXPowerOffsets:   txPowerOffsets(5),
+	}
+}
+
+func eu433() *Band {
+	ch := channels(0, 5, 433175000, 433375000, 433575000)
+	return &Band{
+		Region:           common.Region_EU433,
+		MinFrequency:     433175000,
+		MaxFrequency:     434665000,
+		DataRates:        euLikeDataRates(),
+		UplinkChannels:   ch,
+		DownlinkChannels: ch,
+		RX2Frequency:     434665000,
+		RX2DataRate:      0,
+		MaxPayloadSizes:  euLikeMaxPayloadSizes(),
+		MaxEIRP:          12.15,
+		TXPowerOffsets:   txPowerOffsets(5),
+	}
+}
+
+func au915() *Band {
+	return &Band{
+		Region:       common.Region_AU915,
+		MinFrequency: 915000000,
+		MaxFrequency: 928000000,
+		DataRates: map[int]DataRate{
+			0:  uplinkOnly(lora(12, 125)),
+			1:  uplinkOnly(lora(11, 125)),
+			2:  uplinkOnly(lora(10, 125)),
+			3:  uplinkOnly(lora(9, 125)),
+			4:  uplinkOnly(lora(8, 125)),
+			5:  uplinkOnly(lora(7, 125)),
+			6:  uplinkOnly(lora(8, 500)),
+			8:  downlinkOnly(lora(12, 500)),
+			9:  downlinkOnly(lora(11, 500)),
+			10: downlinkOnly(lora(10, 500)),
+			11: downlinkOnly(lora(9, 500)),
+			12: downlinkOnly(lora(8, 500)),
+			13: downlinkOnly(lora(7, 500)),
+		},
+		UplinkChannels: append(
+			channelRange(64, 915200000, 200000, 0, 5),
+			channelRange(8, 915900000, 1600000, 6, 6)...,
+		),
+		DownlinkChannels: channelRange(8, 923300000, 600000, 8, 13),
+		RX2Frequency:     923300000,
+		RX2DataRate:      8,
+		MaxPayloadSizes: map[int]MaxPayloadSize{
+			0:  {M: 59, N: 51},
+			1:  {M: 59, N: 51},
+			2:  {M: 59, N: 51},
+			3:  {M: 123, N: 115},
+			4:  {M: 250, N: 242},
+			5:  {M: 250, N: 242},
+			6:  {M: 250, N: 242},
+			8:  {M: 61, N: 53},
+			9:  {M: 137, N: 129},
+			10: {M: 250, N: 242},
+			11: {M: 250, N: 242},
+			12: {M: 250, N: 242},
+			13: {M: 250, N: 242},
+		},
+		MaxEIRP:        30,
+		TXPowerOffsets: txPowerOffsets(14),
+	}
+}
+
+func cn470() *Band {
+	dataRates := euLikeDataRates()
+	delete(dataRates, 6)
+	delete(dataRates, 7)
+
+	maxPayloadSizes := euLikeMaxPayloadSizes()
+	delete(maxPayloadSizes, 6)
+	delete(maxPayloadSizes, 7)
+
+	return &Band{
+		Region:           common.Region_CN470,
+		MinFrequency:     470000000,
+		MaxFrequency:     510000000,
+		DataRates:        dataRates,
+		UplinkChannels:   channelRange(96, 470300000, 200000, 0, 5),
+		DownlinkChannels: channelRange(48, 500300000, 200000, 0, 5),
+		RX2Frequency:     505300000,
+		RX2DataRate:      0,
+		MaxPayloadSizes:  maxPayloadSizes,
+		MaxEIRP:          19.15,
+		TXPowerOffsets:   txPowerOffsets(7),
+	}
+}
+
+func as923() *Band {
+	ch := channels(0, 5, 923200000, 923400000)
+	return &Band{
+		Region:           common.Region_AS923,
+		MinFrequency:     915000000,
+		MaxFrequency:     928000000,
+		DataRates:        euLikeDataRates(),
+		UplinkChannels:   ch,
+		DownlinkChannels: ch,
+		RX2Frequency:     923200000,
+		RX2DataRate:      2,
+		MaxPayloadSizes:  euLikeMaxPayloadSizes(),
+		MaxEIRP:          16,
+		TXPowerOffsets:   txPowerOffsets(7),
+	}
+}
+
+func kr920() *Band {
+	dataRates := euLikeDataRates()
+	delete(dataRates, 6)
+	delete(dataRates, 7)
+
+	maxPayloadSizes := euLikeMaxPayloadSizes()
+	delete(maxPayloadSizes, 6)
+	delete(maxPayloadSizes, 7)
+
+	ch := channels(0, 5, 922100000, 922300000, 922500000)
+	return &Band{
+		Region:           common.Region_KR920,
+		MinFrequency:     920900000,
+		MaxFrequency:     923300000,
+		DataRates:        dataRates,
+		UplinkChannels:   ch,
+		DownlinkChannels: ch,
+		RX2Frequency:     921900000,
+		RX2DataRate:      0,
+		MaxPayloadSizes:  maxPayloadSizes,
+		MaxEIRP:          14,
+		TXPowerOffsets:   txPowerOffsets(7),
+	}
+}
+
+func in865() *Band {
+	// DR6 is RFU in IN865.
+	dataRates := euLikeDataRates()
+	delete(dataRates, 6)
+
+	maxPayloadSizes := euLikeMaxPayloadSizes()
+	delete(maxPayloadSizes, 6)
+
+	ch := channels(0, 5, 865062500, 865402500, 865985000)
+	return &Band{
+		Region:           common.Region_IN865,
+		MinFrequency:     865000000,
+		MaxFrequency:     867000000,
+		DataRates:        dataRates,
+		UplinkChannels:   ch,
+		DownlinkChannels: ch,
+		RX2Frequency:     866550000,
+		RX2DataRate:      2,
+		MaxPayloadSizes:  maxPayloadSizes,
+		MaxEIRP:          30,
+		TXPowerOffsets:   txPowerOffsets(10),
+	}
+}
+
+func ru864() *Band {
+	ch := channels(0, 5, 868900000, 869100000)
+	return &Band{
+		Region:           common.Region_RU864,
+		MinFrequency:     864000000,
+		MaxFrequency:     870000000,
+		DataRates:        euLikeDataRates(),
+		UplinkChannels:   ch,
+		DownlinkChannels: ch,
+		RX2Frequency:     869100000,
+		RX2DataRate:      0,
+		MaxPayloadSizes:  euLikeMaxPayloadSizes(),
+		MaxEIRP:          16,
+		TXPowerOffsets:   txPowerOffsets(7),
+	}
+}
@@ -0,0 +1,97 @@
+// Package keyenvelope wraps and unwraps the session keys of the
+// common.KeyEnvelope message (e.g. the app_s_key of the
+// as.DeviceActivationContext), as defined by the LoRaWAN Backend Interfaces
+// 'Key Transport Security' section. The kek_label is resolved to the
+// key-encryption key (KEK) by a KEKProvider:
+//
+//	kp := keyenvelope.NewMemoryProvider(map[string][]byte{
+//		"kek-1": kek,
+//	})
+//	appSKey, err := keyenvelope.AppSKey(kp, req)
+//
+// An envelope without kek_label contains the plaintext key.
+package keyenvelope
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/brocaar/chirpstack-api/go/as"
+	"github.com/brocaar/chirpstack-api/go/common"
+	"github.com/brocaar/chirpstack-api/go/lorawan"
+)
+
+// ErrUnknownKEK is returned by a KEKProvider when the label is unknown.
+var ErrUnknownKEK = errors.New("keyenvelope: unknown kek label")
+
+// KEKProvider resolves a kek_label to the key-encryption key.
+type KEKProvider interface {
+	// KEK returns the KEK (16, 24 or 32 bytes) for the given label.
+	KEK(label string) ([]byte, error)
+}
+
+// Seal returns the envelope of the key. When the label is empty, the key is
+// stored as plaintext and the provider may be nil.
+func Seal(kp KEKProvider, label string, key lorawan.AES128Key) (*common.KeyEnvelope, error) {
+	if label == "" {
+		return &common.KeyEnvelope{
+			AesKey: key.Bytes(),
+		}, nil
+	}
+
+	kek, err := resolve(kp, label)
+	if err != nil {
+		return nil, err
+	}
+	b, err := Wrap(kek, key[:])
+	if err != nil {
+		return nil, err
+	}
+	return &common.KeyEnvelope{
+		KekLabel: label,
+		AesKey:   b,
+	}, nil
+}
+
+// Open returns the key of the envelope. When the envelope has no label, the
+// key is plaintext and the provider may be nil.
+func Open(kp KEKProvider, env *common.KeyEnvelope) (lorawan.AES128Key, error) {
+	if env == nil {
+		return lorawan.AES128Key{}, errors.New("keyenvelope: key envelope must not be nil")
+	}
+	if env.GetKekLabel() == "" {
+		return lorawan.AES128KeyFromBytes(env.GetAesKey())
+	}
+
+	kek, err := resolve(kp, env.GetKekLabel())
+	if err != nil {
+		return lorawan.AES128Key{}, err
+	}
+	b, err := Unwrap(kek, env.GetAesKey())
+	if err != nil {
+		return lorawan.AES128Key{}, err
+	}
+	return lorawan.AES128KeyFromBytes(b)
+}
+
+// AppSKey returns the AppSKey of the device-activation context of the
+// uplink request.
+func AppSKey(kp KEKProvider, req *as.HandleUplinkDataRequest) (lorawan.AES128Key, error) {
+	dac := req.GetDeviceActivationContext()
+	if dac == nil {
+		return lorawan.AES128Key{}, errors.New("keyenvelope: request has no device activation context")
+	}
+	return Open(kp, dac.GetAppSKey())
+}
+
+// resolve returns the KEK for the given label.
+func resolve(kp KEKProvider, label string) ([]byte, error) {
+	if kp == nil {
+		return nil, fmt.Errorf("%w: %s (no kek provider)", ErrUnknownKEK, label)
+	}
+	kek, err := kp.KEK(label)
+	if err != nil {
+		return nil, err
+	}
+	return kek, nil
+}
@@ -0,0 +1,80 @@
+package keyenvelope
+
+import (
+	"crypto/aes"
+	"crypto/subtle"
+	"encoding/binary"
+	"errors"
+	"fmt"
+)
+
+// defaultIV defines the RFC 3394 default initial value.
+var defaultIV = []byte{0xa6, 0xa6, 0xa6, 0xa6, 0xa6, 0xa6, 0xa6, 0xa6}
+
+// ErrIntegrity is returned when the unwrapped key fails the integrity check,
+// e.g. when the wrong KEK is used.
+var ErrIntegrity = errors.New("keyenvelope: key integrity check failed")
+
+// Wrap wraps the key using the KEK (16, 24 or 32 bytes), as defined by
+// RFC 3394. The key must be a multiple of 8 bytes and at least 16 bytes.
+func Wrap(kek, key []byte) ([]byte, error) {
+	if len(key) < 16 || len(key)%8 != 0 {
+		return nil, fmt.Errorf("keyenvelope: invalid key length %d", len(key))
+	}
+	block, err := aes.NewCipher(kek)
+	if err != nil {
+		return nil, fmt.Errorf("keyenvelope: new cipher error: %w", err)
+	}
+
+	n := len(key) / 8
+	out := make([]byte, len(key)+8)
+	copy(out, defaultIV)
+	copy(out[8:], key)
+
+	b := make([]byte, 16)
+	for j := 0; j < 6; j++ {
+		for i := 1; i <= n; i++ {
+			copy(b, out[:8])
+			copy(b[8:], out[i*8:])
+			block.Encrypt(b, b)
+
+			t := uint64(n*j + i)
+			binary.BigEndian.PutUint64(out[:8], binary.BigEndian.Uint64(b[:8])^t)
+			copy(out[i*8:], b[8:])
+		}
+	}
+	return out, nil
+}
+
+// Unwrap unwraps the wrapped key using the KEK, as defined by RFC 3394.
+func Unwrap(kek, wrapped []byte) ([]byte, error) {
+	if len(wrapped) < 24 || len(wrapped)%8 != 0 {
+		return nil, fmt.Errorf("keyenvelope: invalid wrapped key length %d", len(wrapped))
+	}
+	block, err := aes.NewCipher(kek)
+	if err != nil {
+		return nil, fmt.Errorf("keyenvelope: new cipher error: %w", err)
+	}
+
+	n := len(wrapped)/8 - 1
+	out := make([]byte, len(wrapped))
+	copy(out, wrapped)
+
+	b := make([]byte, 16)
+	for j := 5; j >= 0; j-- {
+		for i := n; i >= 1; i-- {
+			t := uint64(n*j + i)
+			binary.BigEndian.PutUint64(b[:8], binary.BigEndian.Uint64(out[:8])^t)
+			copy(b[8:], out[i*8:])
+			block.Decrypt(b, b)
+
+			copy(out[:8], b[:8])
+			copy(out[i*8:], b[8:])
+		}
+	}
+
+	if subtle.ConstantTimeCompare(out[:8], defaultIV) != 1 {
+		return nil, ErrIntegrity
+	}
+	return out[8:], nil
+}
@@ -0,0 +1,204 @@
+package keyenvelope
+
+import (
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/brocaar/chirpstack-api/go/common"
+	"github.com/brocaar/chirpstack-api/go/lorawan"
+)
+
+func mustHEX(s string) []byte {
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		panic(err)
+	}
+	return b
+}
+
+// TestKeyWrap tests the RFC 3394 section 4 test vectors.
+func TestKeyWrap(t *testing.T) {
+	tests := []struct {
+		name    string
+		kek     string
+		key     string
+		wrapped string
+	}{
+		{
+			name:    "4.1 128 bits of key data with a 128-bit kek",
+			kek:     "000102030405060708090A0B0C0D0E0F",
+			key:     "00112233445566778899AABBCCDDEEFF",
+			wrapped: "1FA68B0A8112B447AEF34BD8FB5A7B829D3E862371D2CFE5",
+		},
+		{
+			name:    "4.2 128 bits of key data with a 192-bit kek",
+			kek:     "000102030405060708090A0B0C0D0E0F1011121314151617",
+			key:     "00112233445566778899AABBCCDDEEFF",
+			wrapped: "96778B25AE6CA435F92B5B97C050AED2468AB8A17AD84E5D",
+		},
+		{
+			name:    "4.3 128 bits of key data with a 256-bit kek",
+			kek:     "000102030405060708090A0B0C0D0E0F101112131415161718191A1B1C1D1E1F",
+			key:     "00112233445566778899AABBCCDDEEFF",
+			wrapped: "64E8C3F9CE0F5BA263E9777905818A2A93C8191E7D6E8AE7",
+		},
+		{
+			name:    "4.4 192 bits of key data with a 192-bit kek",
+			kek:     "000102030405060708090A0B0C0D0E0F1011121314151617",
+			key:     "00112233445566778899AABBCCDDEEFF0001020304050607",
+			wrapped: "031D33264E15D33268F24EC260743EDCE1C6C7DDEE725A936BA814915C6762D2",
+		},
+		{
+			name:    "4.5 192 bits of key data with a 256-bit kek",
+			kek:     "000102030405060708090A0B0C0D0E0F101112131415161718191A1B1C1D1E1F",
+			key:     "00112233445566778899AABBCCDDEEFF0001020304050607",
+			wrapped: "A8F9BC1612C68B3FF6E6F4FBE30E71E4769C8B80A32CB8958CD5D17D6B254DA1",
+		},
+		{
+			name:    "4.6 256 bits of key data with a 256-bit kek",
+			kek:     "000102030405060708090A0B0C0D0E0F101112131415161718191A1B1C1D1E1F",
+			key:     "00112233445566778899AABBCCDDEEFF000102030405060708090A0B0C0D0E0F",
+			wrapped: "28C9F404C4B810F4CBCCB35CFB87F8263F5786E2D80ED326CBC7F0E71A99F43BFB988B9B7A02DD21",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			kek, key, wrapped := mustHEX(test.kek), mustHEX(test.key), mustHEX(test.wrapped)
+
+			b, err := Wrap(kek, key)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(wrapped, b) {
+				t.Errorf("wrap: expected %X, got %X", wrapped, b)
+			}
+
+			b, err = Unwrap(kek, wrapped)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(key, b) {
+				t.Errorf("unwrap: expected %X, got %X", key, b)
+			}
+		})
+	}
+}
+
+func TestUnwrapIntegrity(t *testing.T) {
+	kek := mustHEX("000102030405060708090A0B0C0D0E0F")
+	wrapped := mustHEX("1FA68B0A8112B447AEF34BD8FB5A7B829D3E862371D2CFE5")
+
+	// wrong kek
+	otherKEK := mustHEX("0F0E0D0C0B0A09080706050403020100")
+	if _, err := Unwrap(otherKEK, wrapped); err != ErrIntegrity {
+		t.Errorf("wrong kek: expected ErrIntegrity, got %v", err)
+	}
+
+	// tampered wrapped key
+	wrapped[len(wrapped)-1] ^= 0x01
+	if _, err := Unwrap(kek, wrapped); err != ErrIntegrity {
+		t.Errorf("tampered key: expected ErrIntegrity, got %v", err)
+	}
+
+	// invalid length
+	if _, err := Unwrap(kek, wrapped[:16]); err == nil || err == ErrIntegrity {
+		t.Errorf("invalid length: expected length error, got %v", err)
+	}
+}
+
+func TestSealOpen(t *testing.T) {
+	key := lorawan.AES128Key{0x00, 0x11, 0x22, 0x33, 0x44, 0x55, 0x66, 0x77, 0x88, 0x99, 0xaa, 0xbb, 0xcc, 0xdd, 0xee, 0xff}
+
+	t.Run("plaintext", func(t *testing.T) {
+		env, err := Seal(nil, "", key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if env.KekLabel != "" || !bytes.Equal(key[:], env.AesKey) {
+			t.Errorf("expected plaintext envelope, got %s", env)
+		}
+
+		k, err := Open(nil, env)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if k != key {
+			t.Errorf("expected %s, got %s", key, k)
+		}
+	})
+
+	t.Run("wrapped", func(t *testing.T) {
+		kp := NewMemoryProvider(map[string][]byte{
+			"kek": mustHEX("000102030405060708090A0B0C0D0E0F"),
+		})
+
+		env, err := Seal(kp, "kek", key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if expected := mustHEX("1FA68B0A8112B447AEF34BD8FB5A7B829D3E862371D2CFE5"); env.KekLabel != "kek" || !bytes.Equal(expected, env.AesKey) {
+			t.Errorf("unexpected envelope: %s", env)
+		}
+
+		k, err := Open(kp, env)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if k != key {
+			t.Errorf("expected %s, got %s", key, k)
+		}
+
+		kp.Delete("kek")
+		if _, err := Open(kp, env); !errors.Is(err, ErrUnknownKEK) {
+			t.Errorf("expected ErrUnknownKEK, got %v", err)
+		}
+	})
+
+	t.Run("no provider", func(t *testing.T) {
+		if _, err := Seal(nil, "kek", key); !errors.Is(err, ErrUnknownKEK) {
+			t.Errorf("seal: expected ErrUnknownKEK, got %v", err)
+		}
+		if _, err := Open(nil, &common.KeyEnvelope{KekLabel: "kek"}); !errors.Is(err, ErrUnknownKEK) {
+			t.Errorf("open: expected ErrUnknownKEK, got %v", err)
+		}
+	})
+}
+
+func TestFileProvider(t *testing.T) {
+	dir, err := ioutil.TempDir("", "keyenvelope")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	if err := ioutil.WriteFile(filepath.Join(dir, "kek"), []byte("000102030405060708090a0b0c0d0e0f\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	kp := NewFileProvider(dir)
+
+	kek, err := kp.KEK("kek")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := mustHEX("000102030405060708090A0B0C0D0E0F"); !bytes.Equal(expected, kek) {
+		t.Errorf("expected %X, got %X", expected, kek)
+	}
+
+	if _, err := kp.KEK("unknown"); !errors.Is(err, ErrUnknownKEK) {
+		t.Errorf("expected ErrUnknownKEK, got %v", err)
+	}
+
+	for _, label := range []string{"", ".", "..", "../kek", "a/b", `a\b`} {
+		_, err := kp.KEK(label)
+		if err == nil || !strings.HasPrefix(err.Error(), "keyenvelope: invalid kek label") {
+			t.Errorf("label %q: expected invalid label error, got %v", label, err)
+		}
+	}
+}
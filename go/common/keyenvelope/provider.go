@@ -0,0 +1,95 @@
+package keyenvelope
+
+import (
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+var (
+	_ KEKProvider = (*MemoryProvider)(nil)
+	_ KEKProvider = (*FileProvider)(nil)
+)
+
+// MemoryProvider holds the KEKs in memory. It is safe for concurrent use.
+type MemoryProvider struct {
+	mu   sync.RWMutex
+	keks map[string][]byte
+}
+
+// NewMemoryProvider returns a new MemoryProvider holding the given KEKs.
+func NewMemoryProvider(keks map[string][]byte) *MemoryProvider {
+	p := MemoryProvider{
+		keks: make(map[string][]byte, len(keks)),
+	}
+	for label, kek := range keks {
+		p.Set(label, kek)
+	}
+	return &p
+}
+
+// Set sets the KEK for the given label.
+func (p *MemoryProvider) Set(label string, kek []byte) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.keks[label] = append([]byte(nil), kek...)
+}
+
+// Delete removes the KEK of the given label.
+func (p *MemoryProvider) Delete(label string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	delete(p.keks, label)
+}
+
+// KEK implements KEKProvider.
+func (p *MemoryProvider) KEK(label string) ([]byte, error) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	kek, ok := p.keks[label]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownKEK, label)
+	}
+	return append([]byte(nil), kek...), nil
+}
+
+// FileProvider reads the KEKs from a directory, in which every file holds
+// the HEX encoded KEK of the label matching the filename (e.g. a mounted
+// secret). The files are read on every lookup, so KEKs can be added or
+// rotated without restart.
+type FileProvider struct {
+	dir string
+}
+
+// NewFileProvider returns a new FileProvider for the given directory.
+func NewFileProvider(dir string) *FileProvider {
+	return &FileProvider{
+		dir: dir,
+	}
+}
+
+// KEK implements KEKProvider.
+func (p *FileProvider) KEK(label string) ([]byte, error) {
+	// The label must not escape the directory.
+	if label == "" || label == "." || label == ".." || strings.ContainsAny(label, `/\`) {
+		return nil, fmt.Errorf("keyenvelope: invalid kek label: %q", label)
+	}
+
+	b, err := ioutil.ReadFile(filepath.Join(p.dir, label))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, fmt.Errorf("%w: %s", ErrUnknownKEK, label)
+		}
+		return nil, fmt.Errorf("keyenvelope: read kek error: %w", err)
+	}
+
+	kek, err := hex.DecodeString(strings.TrimSpace(string(b)))
+	if err != nil {
+		return nil, fmt.Errorf("keyenvelope: kek %s: %w", label, err)
+	}
+	return kek, nil
+}
@@ -0,0 +1,96 @@
+// Package geodesy implements the coordinate conversions used by the
+// geolocation resolvers.
+package geodesy
+
+import "math"
+
+// WGS84 ellipsoid parameters.
+const (
+	semiMajorAxis = 6378137.0
+	flattening    = 1 / 298.257223563
+	eccentricity2 = flattening * (2 - flattening)
+)
+
+// SpeedOfLight (m/s).
+const SpeedOfLight = 299792458.0
+
+// Vec3 holds an Earth-centered, Earth-fixed (ECEF) position in meters.
+type Vec3 [3]float64
+
+// Distance returns the distance (m) between a and b.
+func (a Vec3) Distance(b Vec3) float64 {
+	dx, dy, dz := a[0]-b[0], a[1]-b[1], a[2]-b[2]
+	return math.Sqrt(dx*dx + dy*dy + dz*dz)
+}
+
+// ECEF returns the ECEF position for the given latitude and longitude
+// (degrees) and altitude (m).
+func ECEF(lat, lon, alt float64) Vec3 {
+	phi, lambda := radians(lat), radians(lon)
+	sinPhi := math.Sin(phi)
+	n := semiMajorAxis / math.Sqrt(1-eccentricity2*sinPhi*sinPhi)
+
+	return Vec3{
+		(n + alt) * math.Cos(phi) * math.Cos(lambda),
+		(n + alt) * math.Cos(phi) * math.Sin(lambda),
+		(n*(1-eccentricity2) + alt) * sinPhi,
+	}
+}
+
+// Plane is a local tangent plane around an origin, in which positions are
+// expressed as east and north offsets (m) from the origin. It is accurate
+// for the distances covered by a LoRa network (tens of kilometers).
+type Plane struct {
+	lat, lon float64
+
+	// meters per degree latitude and longitude at the origin.
+	mLat, mLon float64
+}
+
+// NewPlane returns the local tangent plane around the given latitude and
+// longitude (degrees).
+func NewPlane(lat, lon float64) Plane {
+	phi := radians(lat)
+	sinPhi := math.Sin(phi)
+	w := 1 - eccentricity2*sinPhi*sinPhi
+
+	// Meridian and prime vertical radius of curvature.
+	m := semiMajorAxis * (1 - eccentricity2) / math.Pow(w, 1.5)
+	n := semiMajorAxis / math.Sqrt(w)
+
+	return Plane{
+		lat:  lat,
+		lon:  lon,
+		mLat: radians(m),
+		mLon: radians(n * math.Cos(phi)),
+	}
+}
+
+// Offset returns the east and north offset (m) of the given latitude and
+// longitude.
+func (p Plane) Offset(lat, lon float64) (east, north float64) {
+	dLon := lon - p.lon
+	if dLon > 180 {
+		dLon -= 360
+	} else if dLon < -180 {
+		dLon += 360
+	}
+	return dLon * p.mLon, (lat - p.lat) * p.mLat
+}
+
+// LatLon returns the latitude and longitude of the given east and north
+// offset (m).
+func (p Plane) LatLon(east, north float64) (lat, lon float64) {
+	lat = p.lat + north/p.mLat
+	lon = p.lon + east/p.mLon
+	if lon > 180 {
+		lon -= 360
+	} else if lon < -180 {
+		lon += 360
+	}
+	return lat, lon
+}
+
+func radians(deg float64) float64 {
+	return deg * math.Pi / 180
+}
@@ -0,0 +1,209 @@
+// Package rssi implements a geo.GeolocationServerServiceServer which
+// estimates the device location from the RSSI and SNR of the uplink at the
+// gateways. It can be used as a fallback for gateways without
+// fine-timestamps.
+//
+// The distance to every gateway is estimated using a log-distance path-loss
+// model, the location is the centroid of the gateway locations weighted by
+// the inverse square of the estimated distances. The accuracy is much lower
+// than that of TDOA, the reported accuracy is never lower than the
+// configured minimum accuracy:
+//
+//	srv := grpc.NewServer()
+//	geo.RegisterGeolocationServerServiceServer(srv, rssi.NewServer())
+package rssi
+
+import (
+	"context"
+	"math"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/brocaar/chirpstack-api/go/common"
+	"github.com/brocaar/chirpstack-api/go/geo"
+	"github.com/brocaar/chirpstack-api/go/geo/internal/geodesy"
+	"github.com/brocaar/chirpstack-api/go/gw"
+	"github.com/brocaar/chirpstack-api/go/lorawan"
+)
+
+var _ geo.GeolocationServerServiceServer = (*Server)(nil)
+
+// DefaultMinAccuracy defines the default minimum accuracy (m).
+const DefaultMinAccuracy = 250
+
+// PathLossModel defines the log-distance path-loss model:
+//
+//	rssi = ReferenceRSSI - 10 * Exponent * log10(d / ReferenceDistance)
+type PathLossModel struct {
+	// ReferenceRSSI holds the RSSI (dBm) at the reference distance.
+	ReferenceRSSI float64
+
+	// ReferenceDistance holds the reference distance (m).
+	ReferenceDistance float64
+
+	// Exponent holds the path-loss exponent, 2 for free space and
+	// typically 2.7 - 3.5 for urban areas.
+	Exponent float64
+}
+
+// DefaultPathLossModel defines the default path-loss model, for a 14 dBm
+// transmitter at 868 MHz in an urban environment.
+var DefaultPathLossModel = PathLossModel{
+	ReferenceRSSI:     -17,
+	ReferenceDistance: 1,
+	Exponent:          2.7,
+}
+
+// Distance returns the estimated distance (m) for the given RSSI.
+func (m PathLossModel) Distance(rssi float64) float64 {
+	return m.ReferenceDistance * math.Pow(10, (m.ReferenceRSSI-rssi)/(10*m.Exponent))
+}
+
+// Option configures the Server.
+type Option func(*Server)
+
+// WithPathLossModel sets the path-loss model.
+func WithPathLossModel(m PathLossModel) Option {
+	return func(s *Server) {
+		s.model = m
+	}
+}
+
+// WithMinAccuracy sets the minimum reported accuracy (m).
+func WithMinAccuracy(accuracy uint32) Option {
+	return func(s *Server) {
+		s.minAccuracy = accuracy
+	}
+}
+
+// Server implements a RSSI based geo.GeolocationServerServiceServer.
+type Server struct {
+	model       PathLossModel
+	minAccuracy uint32
+}
+
+// NewServer returns a new Server.
+func NewServer(opts ...Option) *Server {
+	s := &Server{
+		model:       DefaultPathLossModel,
+		minAccuracy: DefaultMinAccuracy,
+	}
+	for _, o := range opts {
+		o(s)
+	}
+	return s
+}
+
+// ResolveTDOA resolves the location of a single frame.
+func (s *Server) ResolveTDOA(ctx context.Context, req *geo.ResolveTDOARequest) (*geo.ResolveTDOAResponse, error) {
+	loc, err := s.resolve([]*geo.FrameRXInfo{req.GetFrameRxInfo()}, req.GetDeviceReferenceAltitude())
+	if err != nil {
+		return nil, err
+	}
+	return &geo.ResolveTDOAResponse{
+		Result: &geo.ResolveResult{
+			Location: loc,
+		},
+	}, nil
+}
+
+// ResolveMultiFrameTDOA resolves the location using multiple frames of the
+// same (stationary) device. The signal strength at every gateway is averaged
+// over the frames.
+func (s *Server) ResolveMultiFrameTDOA(ctx context.Context, req *geo.ResolveMultiFrameTDOARequest) (*geo.ResolveMultiFrameTDOAResponse, error) {
+	loc, err := s.resolve(req.GetFrameRxInfoSet(), req.GetDeviceReferenceAltitude())
+	if err != nil {
+		return nil, err
+	}
+	return &geo.ResolveMultiFrameTDOAResponse{
+		Result: &geo.ResolveResult{
+			Location: loc,
+		},
+	}, nil
+}
+
+// gateway holds the (averaged) signal strength at a gateway.
+type gateway struct {
+	lat, lon float64
+	sum      float64
+	count    int
+}
+
+func (s *Server) resolve(frames []*geo.FrameRXInfo, altitude float64) (*common.Location, error) {
+	var ids []lorawan.EUI64
+	gateways := make(map[lorawan.EUI64]*gateway)
+
+	for _, f := range frames {
+		seen := make(map[lorawan.EUI64]struct{})
+		for _, rx := range f.GetRxInfo() {
+			loc := rx.GetLocation()
+			if loc == nil {
+				continue
+			}
+			gatewayID, err := lorawan.EUI64FromBytes(rx.GetGatewayId())
+			if err != nil {
+				continue
+			}
+			if _, ok := seen[gatewayID]; ok {
+				continue
+			}
+			seen[gatewayID] = struct{}{}
+
+			g, ok := gateways[gatewayID]
+			if !ok {
+				g = &gateway{lat: loc.Latitude, lon: loc.Longitude}
+				gateways[gatewayID] = g
+				ids = append(ids, gatewayID)
+			}
+			g.sum += signalStrength(rx)
+			g.count++
+		}
+	}
+	if len(ids) == 0 {
+		return nil, status.Error(codes.InvalidArgument, "at least one gateway with location is required")
+	}
+
+	// Weighted centroid within the plane around the first gateway.
+	plane := geodesy.NewPlane(gateways[ids[0]].lat, gateways[ids[0]].lon)
+	dists := make([]float64, len(ids))
+	var east, north, wSum float64
+	for i, id := range ids {
+		g := gateways[id]
+		dists[i] = math.Max(s.model.Distance(g.sum/float64(g.count)), 1)
+		w := 1 / (dists[i] * dists[i])
+
+		e, n := plane.Offset(g.lat, g.lon)
+		east += w * e
+		north += w * n
+		wSum += w
+	}
+	east, north = east/wSum, north/wSum
+
+	// The accuracy is the weighted mean of the estimated distances.
+	var accuracy float64
+	for _, d := range dists {
+		accuracy += d / (d * d) / wSum
+	}
+	accuracy = math.Max(math.Round(accuracy), float64(s.minAccuracy))
+
+	lat, lon := plane.LatLon(east, north)
+	return &common.Location{
+		Latitude:  lat,
+		Longitude: lon,
+		Altitude:  altitude,
+		Source:    common.LocationSource_GEO_RESOLVER,
+		Accuracy:  uint32(math.Min(accuracy, math.MaxUint32)),
+	}, nil
+}
+
+// signalStrength returns the signal strength (dBm) of the rx-info. Below the
+// noise floor (negative SNR), the RSSI mostly contains noise and the signal
+// strength is the RSSI corrected by the SNR.
+func signalStrength(rx *gw.UplinkRXInfo) float64 {
+	rssi := float64(rx.GetRssi())
+	if snr := rx.GetLoraSnr(); snr < 0 {
+		return rssi + snr
+	}
+	return rssi
+}
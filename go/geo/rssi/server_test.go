@@ -0,0 +1,226 @@
+package rssi
+
+import (
+	"context"
+	"math"
+	"testing"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/brocaar/chirpstack-api/go/common"
+	"github.com/brocaar/chirpstack-api/go/geo"
+	"github.com/brocaar/chirpstack-api/go/geo/internal/geodesy"
+	"github.com/brocaar/chirpstack-api/go/gw"
+)
+
+// Gateway locations, roughly 10 km apart.
+var (
+	gatewayA = &common.Location{Latitude: 52.0, Longitude: 5.0}
+	gatewayB = &common.Location{Latitude: 52.0, Longitude: 5.146}
+)
+
+// With the default path-loss model, these RSSI values (dBm) correspond with
+// a distance of 1 km and 10 km.
+const (
+	rssi1km  = -98
+	rssi10km = -125
+)
+
+func rxInfo(id byte, loc *common.Location, rssi int32, snr float64) *gw.UplinkRXInfo {
+	return &gw.UplinkRXInfo{
+		GatewayId: []byte{1, 2, 3, 4, 5, 6, 7, id},
+		Location:  loc,
+		Rssi:      rssi,
+		LoraSnr:   snr,
+	}
+}
+
+// offset returns the offset (m) of the location from gateway A, in the
+// direction of gateway B.
+func offset(loc *common.Location) float64 {
+	plane := geodesy.NewPlane(gatewayA.Latitude, gatewayA.Longitude)
+	bEast, bNorth := plane.Offset(gatewayB.Latitude, gatewayB.Longitude)
+	east, north := plane.Offset(loc.Latitude, loc.Longitude)
+	return (east*bEast + north*bNorth) / math.Hypot(bEast, bNorth)
+}
+
+// distanceAB returns the distance (m) between gateway A and B.
+func distanceAB() float64 {
+	plane := geodesy.NewPlane(gatewayA.Latitude, gatewayA.Longitude)
+	return math.Hypot(plane.Offset(gatewayB.Latitude, gatewayB.Longitude))
+}
+
+func TestPathLossModel(t *testing.T) {
+	for rssi, d := range map[float64]float64{-17: 1, rssi1km: 1000, rssi10km: 10000} {
+		if got := DefaultPathLossModel.Distance(rssi); math.Abs(got-d) > 1e-6*d {
+			t.Errorf("rssi %.0f dBm: expected %f m, got %f m", rssi, d, got)
+		}
+	}
+}
+
+func TestResolveTDOA(t *testing.T) {
+	ab := distanceAB()
+
+	tests := []struct {
+		name     string
+		rxInfo   []*gw.UplinkRXInfo
+		offset   float64
+		accuracy uint32
+	}{
+		{
+			name:     "equal rssi",
+			rxInfo:   []*gw.UplinkRXInfo{rxInfo(1, gatewayA, rssi10km, 5), rxInfo(2, gatewayB, rssi10km, 5)},
+			offset:   ab / 2,
+			accuracy: 10000,
+		},
+		{
+			// The weight of A is 100 times the weight of B.
+			name:     "stronger gateway",
+			rxInfo:   []*gw.UplinkRXInfo{rxInfo(1, gatewayA, rssi1km, 5), rxInfo(2, gatewayB, rssi10km, 5)},
+			offset:   ab / 101,
+			accuracy: 1089,
+		},
+		{
+			// The RSSI of A is corrected by the negative SNR, the
+			// positive SNR of B is ignored.
+			name:     "snr correction",
+			rxInfo:   []*gw.UplinkRXInfo{rxInfo(1, gatewayA, rssi10km+5, -5), rxInfo(2, gatewayB, rssi10km, 10)},
+			offset:   ab / 2,
+			accuracy: 10000,
+		},
+		{
+			// Only the first rx-info of a gateway is used and rx-info
+			// without location are ignored.
+			name: "duplicate gateway and no location",
+			rxInfo: []*gw.UplinkRXInfo{
+				rxInfo(1, gatewayA, rssi10km, 5),
+				rxInfo(1, gatewayA, rssi1km, 5),
+				rxInfo(3, nil, rssi1km, 5),
+				rxInfo(2, gatewayB, rssi10km, 5),
+			},
+			offset:   ab / 2,
+			accuracy: 10000,
+		},
+		{
+			name:     "single gateway",
+			rxInfo:   []*gw.UplinkRXInfo{rxInfo(2, gatewayB, rssi1km, 5)},
+			offset:   ab,
+			accuracy: 1000,
+		},
+		{
+			// The estimated distance of 1 m is below the minimum accuracy.
+			name:     "minimum accuracy",
+			rxInfo:   []*gw.UplinkRXInfo{rxInfo(1, gatewayA, -17, 5)},
+			offset:   0,
+			accuracy: DefaultMinAccuracy,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			resp, err := NewServer().ResolveTDOA(context.Background(), &geo.ResolveTDOARequest{
+				FrameRxInfo:             &geo.FrameRXInfo{RxInfo: test.rxInfo},
+				DeviceReferenceAltitude: 15,
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			loc := resp.GetResult().GetLocation()
+			if loc.Source != common.LocationSource_GEO_RESOLVER {
+				t.Errorf("expected source GEO_RESOLVER, got %s", loc.Source)
+			}
+			if loc.Altitude != 15 {
+				t.Errorf("expected altitude 15, got %f", loc.Altitude)
+			}
+			if got := offset(loc); math.Abs(got-test.offset) > 1 {
+				t.Errorf("expected offset %.0f m, got %.0f m", test.offset, got)
+			}
+			if loc.Accuracy != test.accuracy {
+				t.Errorf("expected accuracy %d m, got %d m", test.accuracy, loc.Accuracy)
+			}
+		})
+	}
+}
+
+func TestResolveMultiFrameTDOA(t *testing.T) {
+	ab := distanceAB()
+
+	// The RSSI (dBm) of A is averaged to rssi10km, the same as B. Gateway B
+	// did not receive the second frame.
+	resp, err := NewServer().ResolveMultiFrameTDOA(context.Background(), &geo.ResolveMultiFrameTDOARequest{
+		FrameRxInfoSet: []*geo.FrameRXInfo{
+			{RxInfo: []*gw.UplinkRXInfo{rxInfo(1, gatewayA, rssi1km, 5), rxInfo(2, gatewayB, rssi10km, 5)}},
+			{RxInfo: []*gw.UplinkRXInfo{rxInfo(1, gatewayA, 2*rssi10km-rssi1km, 5)}},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	loc := resp.GetResult().GetLocation()
+	if got := offset(loc); math.Abs(got-ab/2) > 1 {
+		t.Errorf("expected offset %.0f m, got %.0f m", ab/2, got)
+	}
+	if loc.Accuracy != 10000 {
+		t.Errorf("expected accuracy 10000 m, got %d m", loc.Accuracy)
+	}
+}
+
+func TestOptions(t *testing.T) {
+	s := NewServer(
+		WithMinAccuracy(10),
+		WithPathLossModel(PathLossModel{ReferenceRSSI: -40, ReferenceDistance: 10, Exponent: 2}),
+	)
+
+	// -80 dBm is 40 dB below the reference: 10 * 10^(40/20) = 1000 m.
+	resp, err := s.ResolveTDOA(context.Background(), &geo.ResolveTDOARequest{
+		FrameRxInfo: &geo.FrameRXInfo{RxInfo: []*gw.UplinkRXInfo{rxInfo(1, gatewayA, -80, 5)}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if acc := resp.GetResult().GetLocation().Accuracy; acc != 1000 {
+		t.Errorf("expected accuracy 1000 m, got %d m", acc)
+	}
+
+	// 3.2 m is below the minimum accuracy of 10 m.
+	resp, err = s.ResolveTDOA(context.Background(), &geo.ResolveTDOARequest{
+		FrameRxInfo: &geo.FrameRXInfo{RxInfo: []*gw.UplinkRXInfo{rxInfo(1, gatewayA, -30, 5)}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if acc := resp.GetResult().GetLocation().Accuracy; acc != 10 {
+		t.Errorf("expected accuracy 10 m, got %d m", acc)
+	}
+}
+
+func TestResolveInvalidArgument(t *testing.T) {
+	tests := []struct {
+		name   string
+		frames []*geo.FrameRXInfo
+	}{
+		{"no frames", nil},
+		{"no rx-info", []*geo.FrameRXInfo{{}}},
+		{"no location", []*geo.FrameRXInfo{{RxInfo: []*gw.UplinkRXInfo{rxInfo(1, nil, rssi1km, 5), rxInfo(2, nil, rssi1km, 5)}}}},
+		{"invalid gateway id", []*geo.FrameRXInfo{{RxInfo: []*gw.UplinkRXInfo{{GatewayId: []byte{1}, Location: gatewayA}}}}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := NewServer().ResolveMultiFrameTDOA(context.Background(), &geo.ResolveMultiFrameTDOARequest{
+				FrameRxInfoSet: test.frames,
+			})
+			if status.Code(err) != codes.InvalidArgument {
+				t.Errorf("expected InvalidArgument, got %v", err)
+			}
+		})
+	}
+
+	_, err := NewServer().ResolveTDOA(context.Background(), &geo.ResolveTDOARequest{})
+	if status.Code(err) != codes.InvalidArgument {
+		t.Errorf("expected InvalidArgument, got %v", err)
+	}
+}
@@ -0,0 +1,277 @@
+// Package tdoa implements a reference geo.GeolocationServerServiceServer
+// which resolves the device location using the time difference of arrival
+// (TDOA) of the uplink at the gateways.
+//
+// The location is solved by multilateration, minimizing the least squares
+// of the difference between the measured and the expected time differences
+// (expressed in meters). Only gateways with a location and a (plain, or
+// decryptable) fine-timestamp are used. The device is assumed to be at the
+// device_reference_altitude of the request.
+//
+// The accuracy is the horizontal root-mean-square error of the location,
+// derived from its covariance given the accuracy of the fine-timestamps.
+// When the gateway geometry does not determine the location, it is set to
+// the maximum value.
+//
+//	srv := grpc.NewServer()
+//	geo.RegisterGeolocationServerServiceServer(srv, tdoa.NewServer())
+package tdoa
+
+import (
+	"context"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/golang/protobuf/ptypes"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/brocaar/chirpstack-api/go/common"
+	"github.com/brocaar/chirpstack-api/go/geo"
+	"github.com/brocaar/chirpstack-api/go/geo/internal/geodesy"
+	"github.com/brocaar/chirpstack-api/go/gw"
+	"github.com/brocaar/chirpstack-api/go/gw/finetimestamp"
+	"github.com/brocaar/chirpstack-api/go/lorawan"
+)
+
+var _ geo.GeolocationServerServiceServer = (*Server)(nil)
+
+// MinGateways defines the minimum number of gateways (per frame) with a
+// location and fine-timestamp.
+const MinGateways = 3
+
+// DefaultTimestampAccuracy defines the default (1-sigma) accuracy of the
+// fine-timestamps.
+const DefaultTimestampAccuracy = 50 * time.Nanosecond
+
+// maxRange defines the maximum distance (m) of the location from the center
+// of the gateways. Solutions further away are ignored, as the measured time
+// differences can also be matched far outside the gateways (e.g. with
+// three gateways).
+const maxRange = 100e3
+
+// BoardsFunc returns the boards of the given gateway, for decrypting the
+// encrypted fine-timestamps.
+type BoardsFunc func(gatewayID lorawan.EUI64) ([]finetimestamp.Board, error)
+
+// Option configures the Server.
+type Option func(*Server)
+
+// WithTimestampAccuracy sets the (1-sigma) accuracy of the fine-timestamps,
+// used for the accuracy estimate of the resolved location.
+func WithTimestampAccuracy(d time.Duration) Option {
+	return func(s *Server) {
+		s.timestampAccuracy = d
+	}
+}
+
+// WithBoards sets the function returning the gateway boards. When set,
+// encrypted fine-timestamps are decrypted, else they are ignored.
+func WithBoards(fn BoardsFunc) Option {
+	return func(s *Server) {
+		s.boards = fn
+	}
+}
+
+// Server implements a TDOA geo.GeolocationServerServiceServer.
+type Server struct {
+	timestampAccuracy time.Duration
+	boards            BoardsFunc
+}
+
+// NewServer returns a new Server.
+func NewServer(opts ...Option) *Server {
+	s := &Server{
+		timestampAccuracy: DefaultTimestampAccuracy,
+	}
+	for _, o := range opts {
+		o(s)
+	}
+	return s
+}
+
+// ResolveTDOA resolves the location of a single frame.
+func (s *Server) ResolveTDOA(ctx context.Context, req *geo.ResolveTDOARequest) (*geo.ResolveTDOAResponse, error) {
+	loc, err := s.resolve([]*geo.FrameRXInfo{req.GetFrameRxInfo()}, req.GetDeviceReferenceAltitude())
+	if err != nil {
+		return nil, err
+	}
+	return &geo.ResolveTDOAResponse{
+		Result: &geo.ResolveResult{
+			Location: loc,
+		},
+	}, nil
+}
+
+// ResolveMultiFrameTDOA resolves the location using multiple frames of the
+// same (stationary) device. Frames with less than MinGateways usable
+// gateways are ignored.
+func (s *Server) ResolveMultiFrameTDOA(ctx context.Context, req *geo.ResolveMultiFrameTDOARequest) (*geo.ResolveMultiFrameTDOAResponse, error) {
+	loc, err := s.resolve(req.GetFrameRxInfoSet(), req.GetDeviceReferenceAltitude())
+	if err != nil {
+		return nil, err
+	}
+	return &geo.ResolveMultiFrameTDOAResponse{
+		Result: &geo.ResolveResult{
+			Location: loc,
+		},
+	}, nil
+}
+
+// observation holds the reception of a frame by a gateway.
+type observation struct {
+	lat, lon float64
+	pos      geodesy.Vec3
+	time     time.Time
+}
+
+func (s *Server) resolve(frames []*geo.FrameRXInfo, altitude float64) (*common.Location, error) {
+	var sets [][]observation
+	for _, f := range frames {
+		obs := s.observations(f.GetRxInfo())
+		if len(obs) >= MinGateways {
+			sets = append(sets, obs)
+		}
+	}
+	if len(sets) == 0 {
+		return nil, status.Errorf(codes.InvalidArgument, "at least %d gateways with location and fine-timestamp are required", MinGateways)
+	}
+
+	// The plane origin is the center of the gateways.
+	var lat, lon float64
+	var n int
+	for _, obs := range sets {
+		for _, o := range obs {
+			lat += o.lat
+			lon += o.lon
+			n++
+		}
+	}
+	plane := geodesy.NewPlane(lat/float64(n), lon/float64(n))
+
+	residuals := func(east, north float64, out []float64) []float64 {
+		lat, lon := plane.LatLon(east, north)
+		p := geodesy.ECEF(lat, lon, altitude)
+
+		out = out[:0]
+		for _, obs := range sets {
+			ref := obs[0]
+			refDist := p.Distance(ref.pos)
+			for _, o := range obs[1:] {
+				measured := o.time.Sub(ref.time).Seconds() * geodesy.SpeedOfLight
+				out = append(out, p.Distance(o.pos)-refDist-measured)
+			}
+		}
+		return out
+	}
+
+	// Start from the center and from every gateway, as the cost function
+	// can have local minima.
+	starts := [][2]float64{{0, 0}}
+	for _, obs := range sets {
+		for _, o := range obs {
+			e, n := plane.Offset(o.lat, o.lon)
+			starts = append(starts, [2]float64{e, n})
+		}
+	}
+
+	best, bestCost := [2]float64{}, math.Inf(1)
+	for _, start := range starts {
+		x, cost := leastSquares(residuals, start)
+		if math.Hypot(x[0], x[1]) > maxRange {
+			continue
+		}
+		if cost < bestCost {
+			best, bestCost = x, cost
+		}
+	}
+	if math.IsInf(bestCost, 1) || math.IsNaN(bestCost) {
+		return nil, status.Error(codes.Internal, "tdoa solver did not converge")
+	}
+
+	// The accuracy is derived from the covariance of the solution, given
+	// the (1-sigma) error of the measured distance of a timestamp, so that
+	// it reflects the gateway geometry (GDOP).
+	var groups []int
+	for _, obs := range sets {
+		groups = append(groups, len(obs)-1)
+	}
+	sigma := s.timestampAccuracy.Seconds() * geodesy.SpeedOfLight
+	accuracy := float64(math.MaxUint32)
+	if cov, ok := covariance(residuals, best, groups, sigma); ok {
+		accuracy = math.Ceil(math.Sqrt(cov[0][0] + cov[1][1]))
+	}
+
+	resLat, resLon := plane.LatLon(best[0], best[1])
+	return &common.Location{
+		Latitude:  resLat,
+		Longitude: resLon,
+		Altitude:  altitude,
+		Source:    common.LocationSource_GEO_RESOLVER,
+		Accuracy:  uint32(math.Min(accuracy, math.MaxUint32)),
+	}, nil
+}
+
+// observations returns the observations of the usable gateways, sorted by
+// time. Only the first rx-info of a gateway is used.
+func (s *Server) observations(rxInfo []*gw.UplinkRXInfo) []observation {
+	var out []observation
+	seen := make(map[lorawan.EUI64]struct{})
+
+	for _, rx := range rxInfo {
+		loc := rx.GetLocation()
+		if loc == nil {
+			continue
+		}
+		gatewayID, err := lorawan.EUI64FromBytes(rx.GetGatewayId())
+		if err != nil {
+			continue
+		}
+		if _, ok := seen[gatewayID]; ok {
+			continue
+		}
+
+		t, ok := s.fineTimestamp(gatewayID, rx)
+		if !ok {
+			continue
+		}
+
+		seen[gatewayID] = struct{}{}
+		out = append(out, observation{
+			lat:  loc.Latitude,
+			lon:  loc.Longitude,
+			pos:  geodesy.ECEF(loc.Latitude, loc.Longitude, loc.Altitude),
+			time: t,
+		})
+	}
+
+	sort.SliceStable(out, func(i, j int) bool {
+		return out[i].time.Before(out[j].time)
+	})
+
+	return out
+}
+
+// fineTimestamp returns the fine-timestamp of the rx-info.
+func (s *Server) fineTimestamp(gatewayID lorawan.EUI64, rx *gw.UplinkRXInfo) (time.Time, bool) {
+	pt := rx.GetPlainFineTimestamp()
+	if pt == nil && rx.GetEncryptedFineTimestamp() != nil && s.boards != nil {
+		boards, err := s.boards(gatewayID)
+		if err != nil {
+			return time.Time{}, false
+		}
+		if pt, err = finetimestamp.Decrypt(rx, boards); err != nil {
+			return time.Time{}, false
+		}
+	}
+	if pt == nil {
+		return time.Time{}, false
+	}
+
+	t, err := ptypes.Timestamp(pt.GetTime())
+	if err != nil {
+		return time.Time{}, false
+	}
+	return t, true
+}
@@ -0,0 +1,248 @@
+package tdoa
+
+import (
+	"context"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/golang/protobuf/ptypes"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/brocaar/chirpstack-api/go/common"
+	"github.com/brocaar/chirpstack-api/go/geo"
+	"github.com/brocaar/chirpstack-api/go/geo/internal/geodesy"
+	"github.com/brocaar/chirpstack-api/go/gw"
+)
+
+// gateways contains the gateway locations (roughly a 10 km square), the
+// device is located within the square.
+var gateways = []*common.Location{
+	{Latitude: 52.00, Longitude: 5.00, Altitude: 30},
+	{Latitude: 52.09, Longitude: 5.00, Altitude: 20},
+	{Latitude: 52.09, Longitude: 5.15, Altitude: 40},
+	{Latitude: 52.00, Longitude: 5.15, Altitude: 10},
+}
+
+var device = common.Location{Latitude: 52.031, Longitude: 5.062, Altitude: 250}
+
+// frame returns the rx-info of the gateways receiving an uplink of a device
+// at the given location. The fine-timestamps are exact (within 1 ns).
+func frame(t *testing.T, dev common.Location, gws []*common.Location) *geo.FrameRXInfo {
+	t.Helper()
+
+	txTime := time.Date(2020, time.January, 1, 0, 0, 0, 0, time.UTC)
+	devPos := geodesy.ECEF(dev.Latitude, dev.Longitude, dev.Altitude)
+
+	var out geo.FrameRXInfo
+	for i, loc := range gws {
+		d := devPos.Distance(geodesy.ECEF(loc.Latitude, loc.Longitude, loc.Altitude))
+		ts, err := ptypes.TimestampProto(txTime.Add(time.Duration(math.Round(d / geodesy.SpeedOfLight * 1e9))))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		out.RxInfo = append(out.RxInfo, &gw.UplinkRXInfo{
+			GatewayId:         []byte{1, 2, 3, 4, 5, 6, 7, byte(i)},
+			Location:          loc,
+			FineTimestampType: gw.FineTimestampType_PLAIN,
+			FineTimestamp: &gw.UplinkRXInfo_PlainFineTimestamp{PlainFineTimestamp: &gw.PlainFineTimestamp{
+				Time: ts,
+			}},
+		})
+	}
+	return &out
+}
+
+// distance returns the distance (m) between the given locations.
+func distance(a, b *common.Location) float64 {
+	return geodesy.ECEF(a.Latitude, a.Longitude, a.Altitude).Distance(geodesy.ECEF(b.Latitude, b.Longitude, b.Altitude))
+}
+
+func TestResolveTDOA(t *testing.T) {
+	tests := []struct {
+		name        string
+		device      common.Location
+		gateways    []*common.Location
+		altitude    float64
+		maxError    float64
+		minAccuracy uint32
+		maxAccuracy uint32
+	}{
+		{
+			name:        "four gateways",
+			device:      device,
+			gateways:    gateways,
+			altitude:    device.Altitude,
+			maxError:    2,
+			minAccuracy: 10,
+			maxAccuracy: 50,
+		},
+		{
+			name:        "three gateways",
+			device:      device,
+			gateways:    gateways[:3],
+			altitude:    device.Altitude,
+			maxError:    2,
+			minAccuracy: 10,
+			maxAccuracy: 100,
+		},
+		{
+			// The geometry outside the gateways is much worse (GDOP).
+			name:        "outside the gateways",
+			device:      common.Location{Latitude: 51.90, Longitude: 5.30, Altitude: 250},
+			gateways:    gateways,
+			altitude:    250,
+			maxError:    5,
+			minAccuracy: 100,
+			maxAccuracy: 2000,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			resp, err := NewServer().ResolveTDOA(context.Background(), &geo.ResolveTDOARequest{
+				FrameRxInfo:             frame(t, test.device, test.gateways),
+				DeviceReferenceAltitude: test.altitude,
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			loc := resp.GetResult().GetLocation()
+			if loc.Source != common.LocationSource_GEO_RESOLVER {
+				t.Errorf("expected source GEO_RESOLVER, got %s", loc.Source)
+			}
+			if loc.Altitude != test.altitude {
+				t.Errorf("expected altitude %f, got %f", test.altitude, loc.Altitude)
+			}
+			if d := distance(loc, &test.device); d > test.maxError {
+				t.Errorf("expected an error of at most %.0f m, got %.2f m", test.maxError, d)
+			}
+			if loc.Accuracy < test.minAccuracy || loc.Accuracy > test.maxAccuracy {
+				t.Errorf("expected an accuracy between %d and %d m, got %d m", test.minAccuracy, test.maxAccuracy, loc.Accuracy)
+			}
+		})
+	}
+}
+
+// The accuracy scales with the timestamp accuracy.
+func TestResolveTDOAAccuracy(t *testing.T) {
+	req := &geo.ResolveTDOARequest{
+		FrameRxInfo:             frame(t, device, gateways),
+		DeviceReferenceAltitude: device.Altitude,
+	}
+
+	a, err := NewServer().ResolveTDOA(context.Background(), req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := NewServer(WithTimestampAccuracy(2*DefaultTimestampAccuracy)).ResolveTDOA(context.Background(), req)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	accA, accB := float64(a.GetResult().GetLocation().Accuracy), float64(b.GetResult().GetLocation().Accuracy)
+	if math.Abs(accB-2*accA) > 2 {
+		t.Errorf("expected the accuracy to double, got %.0f and %.0f m", accA, accB)
+	}
+}
+
+// The device is assumed to be at the device_reference_altitude, a wrong
+// altitude results in a horizontal error.
+func TestResolveTDOAReferenceAltitude(t *testing.T) {
+	dev := common.Location{Latitude: 51.98, Longitude: 4.97, Altitude: 1500}
+	f := frame(t, dev, gateways)
+
+	resp, err := NewServer().ResolveTDOA(context.Background(), &geo.ResolveTDOARequest{
+		FrameRxInfo:             f,
+		DeviceReferenceAltitude: 1500,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d := distance(resp.GetResult().GetLocation(), &dev); d > 2 {
+		t.Errorf("expected an error of at most 2 m, got %.2f m", d)
+	}
+
+	resp, err = NewServer().ResolveTDOA(context.Background(), &geo.ResolveTDOARequest{
+		FrameRxInfo: f,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	loc := resp.GetResult().GetLocation()
+	loc.Altitude = dev.Altitude
+	if d := distance(loc, &dev); d < 20 {
+		t.Errorf("expected a horizontal error of at least 20 m, got %.2f m", d)
+	}
+}
+
+func TestResolveMultiFrameTDOA(t *testing.T) {
+	// The frames are each received by 3 gateways, the frame with 2 gateways
+	// is ignored.
+	resp, err := NewServer().ResolveMultiFrameTDOA(context.Background(), &geo.ResolveMultiFrameTDOARequest{
+		FrameRxInfoSet: []*geo.FrameRXInfo{
+			frame(t, device, gateways[:3]),
+			frame(t, device, gateways[1:]),
+			frame(t, device, gateways[:2]),
+		},
+		DeviceReferenceAltitude: device.Altitude,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d := distance(resp.GetResult().GetLocation(), &device); d > 2 {
+		t.Errorf("expected an error of at most 2 m, got %.2f m", d)
+	}
+}
+
+func TestResolveTDOAInvalidArgument(t *testing.T) {
+	noLocation := frame(t, device, gateways[:3])
+	noLocation.RxInfo[0].Location = nil
+
+	noTimestamp := frame(t, device, gateways[:3])
+	noTimestamp.RxInfo[1].FineTimestampType = gw.FineTimestampType_NONE
+	noTimestamp.RxInfo[1].FineTimestamp = nil
+
+	encrypted := frame(t, device, gateways[:3])
+	encrypted.RxInfo[2].FineTimestampType = gw.FineTimestampType_ENCRYPTED
+	encrypted.RxInfo[2].FineTimestamp = &gw.UplinkRXInfo_EncryptedFineTimestamp{EncryptedFineTimestamp: &gw.EncryptedFineTimestamp{
+		EncryptedNs: make([]byte, 16),
+	}}
+
+	// The same gateway is only used once.
+	duplicate := frame(t, device, gateways[:3])
+	duplicate.RxInfo[2].GatewayId = duplicate.RxInfo[1].GatewayId
+
+	tests := []struct {
+		name  string
+		frame *geo.FrameRXInfo
+	}{
+		{"two gateways", frame(t, device, gateways[:2])},
+		{"no location", noLocation},
+		{"no fine-timestamp", noTimestamp},
+		{"encrypted fine-timestamp without boards", encrypted},
+		{"duplicate gateway", duplicate},
+		{"no rx-info", &geo.FrameRXInfo{}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := NewServer().ResolveTDOA(context.Background(), &geo.ResolveTDOARequest{
+				FrameRxInfo: test.frame,
+			})
+			if status.Code(err) != codes.InvalidArgument {
+				t.Errorf("expected InvalidArgument, got %v", err)
+			}
+		})
+	}
+
+	_, err := NewServer().ResolveMultiFrameTDOA(context.Background(), &geo.ResolveMultiFrameTDOARequest{
+		FrameRxInfoSet: []*geo.FrameRXInfo{frame(t, device, gateways[:2]), noLocation},
+	})
+	if status.Code(err) != codes.InvalidArgument {
+		t.Errorf("expected InvalidArgument, got %v", err)
+	}
+}
@@ -0,0 +1,136 @@
+package tdoa
+
+import "math"
+
+// Solver parameters.
+const (
+	maxIterations = 100
+	jacobianStep  = 1.0  // m
+	convergence   = 1e-3 // m
+)
+
+// residualsFunc returns the residuals (appended to out[:0]) for the given
+// position.
+type residualsFunc func(east, north float64, out []float64) []float64
+
+// leastSquares minimizes the sum of the squared residuals using the
+// Levenberg-Marquardt algorithm, starting at x. It returns the solution and
+// its cost (sum of squared residuals).
+func leastSquares(f residualsFunc, x [2]float64) ([2]float64, float64) {
+	r := f(x[0], x[1], nil)
+	cost := sumSquares(r)
+	lambda := 1e-3
+
+	var re, rn, rt []float64
+	for i := 0; i < maxIterations; i++ {
+		// Numerical Jacobian.
+		re = f(x[0]+jacobianStep, x[1], re)
+		rn = f(x[0], x[1]+jacobianStep, rn)
+
+		// Normal equations: (JtJ + lambda * diag(JtJ)) * d = -Jt * r.
+		var a11, a12, a22, b1, b2 float64
+		for k := range r {
+			je := (re[k] - r[k]) / jacobianStep
+			jn := (rn[k] - r[k]) / jacobianStep
+			a11 += je * je
+			a12 += je * jn
+			a22 += jn * jn
+			b1 -= je * r[k]
+			b2 -= jn * r[k]
+		}
+
+		improved := false
+		for !improved && lambda < 1e10 {
+			m11, m22 := a11*(1+lambda), a22*(1+lambda)
+			det := m11*m22 - a12*a12
+			if det == 0 || math.IsNaN(det) {
+				lambda *= 10
+				continue
+			}
+			d := [2]float64{
+				(b1*m22 - b2*a12) / det,
+				(b2*m11 - b1*a12) / det,
+			}
+
+			rt = f(x[0]+d[0], x[1]+d[1], rt)
+			if c := sumSquares(rt); c < cost {
+				x[0], x[1] = x[0]+d[0], x[1]+d[1]
+				r, rt = rt, r
+				cost = c
+				lambda /= 10
+				improved = true
+
+				if math.Hypot(d[0], d[1]) < convergence {
+					return x, cost
+				}
+			} else {
+				lambda *= 10
+			}
+		}
+		if !improved {
+			break
+		}
+	}
+
+	return x, cost
+}
+
+// covariance returns the covariance matrix (m²) of the solution x, or false
+// when it is undetermined. The residuals are split into groups of which the
+// residuals share the same reference measurement (the time differences of a
+// frame), each measurement having an error of sigma (m). The covariance of
+// a group of m residuals is then sigma² * (I + 11ᵀ), of which the inverse
+// is (I - 11ᵀ / (m+1)) / sigma². When the residuals are larger than
+// expected from sigma, the covariance is scaled by the a posteriori
+// variance factor.
+func covariance(f residualsFunc, x [2]float64, groups []int, sigma float64) ([2][2]float64, bool) {
+	r := f(x[0], x[1], nil)
+	re := f(x[0]+jacobianStep, x[1], nil)
+	rn := f(x[0], x[1]+jacobianStep, nil)
+
+	// Normal matrix JᵀC⁻¹J and weighted cost rᵀC⁻¹r (times sigma²).
+	var a11, a12, a22, cost float64
+	var k int
+	for _, m := range groups {
+		var se, sn, sr float64
+		for end := k + m; k < end; k++ {
+			je := (re[k] - r[k]) / jacobianStep
+			jn := (rn[k] - r[k]) / jacobianStep
+			a11 += je * je
+			a12 += je * jn
+			a22 += jn * jn
+			cost += r[k] * r[k]
+			se += je
+			sn += jn
+			sr += r[k]
+		}
+		w := float64(m + 1)
+		a11 -= se * se / w
+		a12 -= se * sn / w
+		a22 -= sn * sn / w
+		cost -= sr * sr / w
+	}
+
+	det := a11*a22 - a12*a12
+	if !(det > 0) {
+		return [2][2]float64{}, false
+	}
+
+	variance := sigma * sigma
+	if dof := k - 2; dof > 0 {
+		variance = math.Max(variance, cost/float64(dof))
+	}
+
+	return [2][2]float64{
+		{variance * a22 / det, -variance * a12 / det},
+		{-variance * a12 / det, variance * a11 / det},
+	}, true
+}
+
+func sumSquares(r []float64) float64 {
+	var out float64
+	for _, v := range r {
+		out += v * v
+	}
+	return out
+}
@@ -0,0 +1,178 @@
+// Package airtime calculates the time-on-air of LoRa and FSK frames, based on
+// the modulation info of the gw.UplinkTXInfo and gw.DownlinkTXInfo messages,
+// and implements per-gateway duty-cycle accounting.
+package airtime
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/brocaar/chirpstack-api/go/common"
+	"github.com/brocaar/chirpstack-api/go/gw"
+)
+
+// Frame defaults as defined by the LoRaWAN Regional Parameters.
+const (
+	// LoRaPreambleSymbols defines the number of LoRa preamble symbols.
+	LoRaPreambleSymbols = 8
+
+	// FSKPreambleBytes defines the number of FSK preamble bytes.
+	FSKPreambleBytes = 5
+
+	// FSKSyncWordBytes defines the number of FSK sync-word bytes.
+	FSKSyncWordBytes = 3
+)
+
+// TXInfo is implemented by *gw.UplinkTXInfo and *gw.DownlinkTXInfo.
+type TXInfo interface {
+	GetModulation() common.Modulation
+	GetLoraModulationInfo() *gw.LoRaModulationInfo
+	GetFskModulationInfo() *gw.FSKModulationInfo
+}
+
+// Airtime returns the time-on-air of a frame with the given PHYPayload length
+// sent using the given TX info. Uplink frames are assumed to contain a
+// payload CRC, downlink frames are not (as defined by LoRaWAN).
+func Airtime(txInfo TXInfo, payloadLen int) (time.Duration, error) {
+	_, downlink := txInfo.(*gw.DownlinkTXInfo)
+
+	switch txInfo.GetModulation() {
+	case common.Modulation_LORA:
+		modInfo := txInfo.GetLoraModulationInfo()
+		if modInfo == nil {
+			return 0, errors.New("airtime: lora_modulation_info must not be nil")
+		}
+		p, err := LoRaParamsFromModulationInfo(modInfo)
+		if err != nil {
+			return 0, err
+		}
+		p.CRC = !downlink
+		return p.Airtime(payloadLen), nil
+	case common.Modulation_FSK:
+		modInfo := txInfo.GetFskModulationInfo()
+		if modInfo == nil {
+			return 0, errors.New("airtime: fsk_modulation_info must not be nil")
+		}
+		if modInfo.Bitrate == 0 {
+			return 0, errors.New("airtime: bitrate must not be 0")
+		}
+		return FSKAirtime(payloadLen, int(modInfo.Bitrate)), nil
+	default:
+		return 0, fmt.Errorf("airtime: unsupported modulation: %s", txInfo.GetModulation())
+	}
+}
+
+// LoRaParams contains the LoRa parameters which determine the time-on-air.
+type LoRaParams struct {
+	// SpreadingFactor (7 - 12).
+	SpreadingFactor int
+
+	// Bandwidth in Hz.
+	Bandwidth int
+
+	// CodeRate defines the coding rate denominator minus 4 (1 = 4/5 up to
+	// 4 = 4/8).
+	CodeRate int
+
+	// PreambleSymbols defines the number of (programmed) preamble symbols.
+	PreambleSymbols int
+
+	// ImplicitHeader disables the explicit header. LoRaWAN frames always
+	// use the explicit header.
+	ImplicitHeader bool
+
+	// CRC enables the payload CRC. LoRaWAN uplink frames contain a CRC,
+	// downlink frames do not.
+	CRC bool
+
+	// LowDataRateOptimize enables the low data-rate optimization. It is
+	// always enabled when the symbol duration is 16ms or more.
+	LowDataRateOptimize bool
+}
+
+// LoRaParamsFromModulationInfo returns the LoRa parameters for the given
+// modulation info, using the LoRaWAN preamble length and with the payload
+// CRC enabled.
+func LoRaParamsFromModulationInfo(modInfo *gw.LoRaModulationInfo) (LoRaParams, error) {
+	if modInfo.SpreadingFactor < 7 || modInfo.SpreadingFactor > 12 {
+		return LoRaParams{}, fmt.Errorf("airtime: invalid spreading-factor: %d", modInfo.SpreadingFactor)
+	}
+	if modInfo.Bandwidth == 0 {
+		return LoRaParams{}, errors.New("airtime: bandwidth must not be 0")
+	}
+
+	cr, err := ParseCodeRate(modInfo.CodeRate)
+	if err != nil {
+		return LoRaParams{}, err
+	}
+
+	return LoRaParams{
+		SpreadingFactor: int(modInfo.SpreadingFactor),
+		Bandwidth:       int(modInfo.Bandwidth) * 1000,
+		CodeRate:        cr,
+		PreambleSymbols: LoRaPreambleSymbols,
+		CRC:             true,
+	}, nil
+}
+
+// ParseCodeRate parses the code rate (e.g. "4/5") and returns the coding rate
+// denominator minus 4. An empty string returns the LoRaWAN code rate 4/5.
+func ParseCodeRate(s string) (int, error) {
+	switch strings.TrimSpace(s) {
+	case "", "4/5":
+		return 1, nil
+	case "4/6":
+		return 2, nil
+	case "4/7":
+		return 3, nil
+	case "4/8":
+		return 4, nil
+	default:
+		return 0, fmt.Errorf("airtime: invalid code rate: %q", s)
+	}
+}
+
+// SymbolDuration returns the duration of a single symbol.
+func (p LoRaParams) SymbolDuration() time.Duration {
+	return seconds(float64(uint(1)<<uint(p.SpreadingFactor)) / float64(p.Bandwidth))
+}
+
+// Airtime returns the time-on-air of a frame with the given payload length.
+func (p LoRaParams) Airtime(payloadLen int) time.Duration {
+	tSym := float64(uint(1)<<uint(p.SpreadingFactor)) / float64(p.Bandwidth)
+	tPreamble := (float64(p.PreambleSymbols) + 4.25) * tSym
+
+	var crc, ih, de float64
+	if p.CRC {
+		crc = 1
+	}
+	if p.ImplicitHeader {
+		ih = 1
+	}
+	if p.LowDataRateOptimize || tSym >= 0.016 {
+		de = 1
+	}
+
+	sf := float64(p.SpreadingFactor)
+	payloadSymbols := 8 + math.Max(
+		math.Ceil((8*float64(payloadLen)-4*sf+28+16*crc-20*ih)/(4*(sf-2*de)))*float64(p.CodeRate+4),
+		0,
+	)
+
+	return seconds(tPreamble + payloadSymbols*tSym)
+}
+
+// FSKAirtime returns the time-on-air of an FSK frame with the given payload
+// length and bitrate (bits per second). The frame consists of the preamble,
+// sync-word, length byte, payload and 2 byte CRC, as defined by LoRaWAN.
+func FSKAirtime(payloadLen, bitrate int) time.Duration {
+	bytes := FSKPreambleBytes + FSKSyncWordBytes + 1 + payloadLen + 2
+	return seconds(float64(bytes*8) / float64(bitrate))
+}
+
+func seconds(s float64) time.Duration {
+	return time.Duration(math.Round(s * float64(time.Second)))
+}
@@ -0,0 +1,133 @@
+package airtime
+
+import (
+	"testing"
+	"time"
+
+	"github.com/brocaar/chirpstack-api/go/common"
+	"github.com/brocaar/chirpstack-api/go/gw"
+)
+
+func loraUplink(sf, bw uint32, cr string) *gw.UplinkTXInfo {
+	return &gw.UplinkTXInfo{
+		Modulation: common.Modulation_LORA,
+		ModulationInfo: &gw.UplinkTXInfo_LoraModulationInfo{
+			LoraModulationInfo: &gw.LoRaModulationInfo{
+				SpreadingFactor: sf,
+				Bandwidth:       bw,
+				CodeRate:        cr,
+			},
+		},
+	}
+}
+
+func loraDownlink(freq, sf uint32) *gw.DownlinkTXInfo {
+	return &gw.DownlinkTXInfo{
+		GatewayId:  []byte{0x01, 0x02, 0x03, 0x04, 0x05, 0x06, 0x07, 0x08},
+		Frequency:  freq,
+		Modulation: common.Modulation_LORA,
+		ModulationInfo: &gw.DownlinkTXInfo_LoraModulationInfo{
+			LoraModulationInfo: &gw.LoRaModulationInfo{
+				SpreadingFactor: sf,
+				Bandwidth:       125,
+				CodeRate:        "4/5",
+			},
+		},
+	}
+}
+
+// The expected LoRa values match the Semtech LoRa calculator, using 8
+// preamble symbols and an explicit header.
+func TestAirtime(t *testing.T) {
+	tests := []struct {
+		name       string
+		txInfo     TXInfo
+		payloadLen int
+		expected   time.Duration
+	}{
+		{
+			name:       "SF7 125kHz uplink",
+			txInfo:     loraUplink(7, 125, "4/5"),
+			payloadLen: 13,
+			expected:   46336 * time.Microsecond,
+		},
+		{
+			name:       "SF12 125kHz uplink (low data-rate optimization)",
+			txInfo:     loraUplink(12, 125, "4/5"),
+			payloadLen: 13,
+			expected:   1155072 * time.Microsecond,
+		},
+		{
+			name:       "SF7 125kHz downlink (no CRC)",
+			txInfo:     loraDownlink(868100000, 7),
+			payloadLen: 13,
+			expected:   41216 * time.Microsecond,
+		},
+		{
+			name:       "SF9 125kHz 4/8 uplink",
+			txInfo:     loraUplink(9, 125, "4/8"),
+			payloadLen: 51,
+			expected:   476160 * time.Microsecond,
+		},
+		{
+			name:       "SF8 500kHz uplink",
+			txInfo:     loraUplink(8, 500, "4/5"),
+			payloadLen: 13,
+			expected:   20608 * time.Microsecond,
+		},
+		{
+			name: "FSK 50kbps",
+			txInfo: &gw.UplinkTXInfo{
+				Modulation: common.Modulation_FSK,
+				ModulationInfo: &gw.UplinkTXInfo_FskModulationInfo{
+					FskModulationInfo: &gw.FSKModulationInfo{
+						Bandwidth: 125,
+						Bitrate:   50000,
+					},
+				},
+			},
+			payloadLen: 13,
+			expected:   3840 * time.Microsecond,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			d, err := Airtime(test.txInfo, test.payloadLen)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if d != test.expected {
+				t.Errorf("expected %s, got %s", test.expected, d)
+			}
+		})
+	}
+}
+
+func TestAirtimeErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		txInfo TXInfo
+	}{
+		{"SF6", loraUplink(6, 125, "4/5")},
+		{"SF13", loraUplink(13, 125, "4/5")},
+		{"zero bandwidth", loraUplink(7, 0, "4/5")},
+		{"invalid code rate", loraUplink(7, 125, "4/9")},
+		{"missing lora modulation info", &gw.UplinkTXInfo{Modulation: common.Modulation_LORA}},
+		{"missing fsk modulation info", &gw.UplinkTXInfo{Modulation: common.Modulation_FSK}},
+		{"zero bitrate", &gw.UplinkTXInfo{
+			Modulation: common.Modulation_FSK,
+			ModulationInfo: &gw.UplinkTXInfo_FskModulationInfo{
+				FskModulationInfo: &gw.FSKModulationInfo{},
+			},
+		}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if _, err := Airtime(test.txInfo, 13); err == nil {
+				t.Error("expected error")
+			}
+		})
+	}
+}
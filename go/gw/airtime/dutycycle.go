@@ -0,0 +1,242 @@
+package airtime
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/brocaar/chirpstack-api/go/gw"
+)
+
+// DefaultDutyCycleWindow defines the default period over which the duty-cycle
+// is calculated.
+const DefaultDutyCycleWindow = time.Hour
+
+// ErrNoSubBand is returned when a frequency is not within any of the
+// configured sub-bands.
+var ErrNoSubBand = errors.New("airtime: frequency is not within a sub-band")
+
+// SubBand defines a frequency range and the duty-cycle which applies to it.
+type SubBand struct {
+	// MinFrequency and MaxFrequency (Hz) define the frequency range
+	// (inclusive).
+	MinFrequency uint32
+	MaxFrequency uint32
+
+	// DutyCycle defines the maximum fraction of time a gateway may transmit
+	// within the sub-band (e.g. 0.01 for 1%).
+	DutyCycle float64
+}
+
+// Contains returns true when the frequency is within the sub-band.
+func (b SubBand) Contains(freq uint32) bool {
+	return freq >= b.MinFrequency && freq <= b.MaxFrequency
+}
+
+// String returns the sub-band as a human readable string.
+func (b SubBand) String() string {
+	return fmt.Sprintf("%.2f - %.2f MHz (%g%%)", float64(b.MinFrequency)/1e6, float64(b.MaxFrequency)/1e6, b.DutyCycle*100)
+}
+
+// EU868SubBands defines the EU868 sub-bands (ETSI EN 300.220).
+var EU868SubBands = []SubBand{
+	{MinFrequency: 863000000, MaxFrequency: 864999999, DutyCycle: 0.001},
+	{MinFrequency: 865000000, MaxFrequency: 867999999, DutyCycle: 0.01},
+	{MinFrequency: 868000000, MaxFrequency: 868600000, DutyCycle: 0.01},
+	{MinFrequency: 868700000, MaxFrequency: 869200000, DutyCycle: 0.001},
+	{MinFrequency: 869400000, MaxFrequency: 869650000, DutyCycle: 0.1},
+	{MinFrequency: 869700000, MaxFrequency: 870000000, DutyCycle: 0.01},
+}
+
+// LimitError is returned when a transmission would exceed the duty-cycle of
+// a sub-band.
+type LimitError struct {
+	GatewayID []byte
+	SubBand   SubBand
+
+	// Used holds the airtime used within the window, including the
+	// transmission.
+	Used time.Duration
+
+	// Limit holds the maximum airtime within the window.
+	Limit time.Duration
+}
+
+// Error implements the error interface.
+func (e *LimitError) Error() string {
+	return fmt.Sprintf("airtime: gateway %x exceeds duty-cycle of sub-band %s: %s used, %s allowed", e.GatewayID, e.SubBand, e.Used, e.Limit)
+}
+
+// DutyCycleOption configures the DutyCycle.
+type DutyCycleOption func(*DutyCycle)
+
+// WithWindow sets the period over which the duty-cycle is calculated
+// (default DefaultDutyCycleWindow).
+func WithWindow(d time.Duration) DutyCycleOption {
+	return func(dc *DutyCycle) {
+		if d > 0 {
+			dc.window = d
+		}
+	}
+}
+
+// transmission is a recorded transmission.
+type transmission struct {
+	subBand int
+	at      time.Time
+	airtime time.Duration
+}
+
+// DutyCycle implements per-gateway duty-cycle accounting, using a sliding
+// window. Transmissions which are older than the window are discarded, the
+// given times are therefore expected to be increasing. It is safe for
+// concurrent use.
+type DutyCycle struct {
+	subBands []SubBand
+	window   time.Duration
+
+	mu            sync.Mutex
+	transmissions map[string][]transmission
+}
+
+// NewDutyCycle returns a new DutyCycle for the given sub-bands. When no
+// sub-bands are given, no duty-cycle limits apply.
+func NewDutyCycle(subBands []SubBand, opts ...DutyCycleOption) *DutyCycle {
+	dc := &DutyCycle{
+		subBands:      subBands,
+		window:        DefaultDutyCycleWindow,
+		transmissions: make(map[string][]transmission),
+	}
+	for _, opt := range opts {
+		opt(dc)
+	}
+	return dc
+}
+
+// Check returns a *LimitError when transmitting the given frame at the given
+// time would exceed the duty-cycle of the sub-band. It does not record the
+// transmission.
+func (dc *DutyCycle) Check(frame *gw.DownlinkFrame, at time.Time) error {
+	if len(dc.subBands) == 0 {
+		return nil
+	}
+
+	t, err := dc.transmission(frame, at)
+	if err != nil {
+		return err
+	}
+
+	dc.mu.Lock()
+	defer dc.mu.Unlock()
+
+	return dc.check(frame.GetTxInfo().GetGatewayId(), t)
+}
+
+// Record records the transmission of the given frame at the given time. It
+// returns a *LimitError when the transmission exceeded the duty-cycle of the
+// sub-band, the transmission is recorded regardless.
+func (dc *DutyCycle) Record(frame *gw.DownlinkFrame, at time.Time) error {
+	if len(dc.subBands) == 0 {
+		return nil
+	}
+
+	t, err := dc.transmission(frame, at)
+	if err != nil {
+		return err
+	}
+
+	dc.mu.Lock()
+	defer dc.mu.Unlock()
+
+	gatewayID := frame.GetTxInfo().GetGatewayId()
+	err = dc.check(gatewayID, t)
+	dc.transmissions[string(gatewayID)] = append(dc.transmissions[string(gatewayID)], t)
+	return err
+}
+
+// Usage returns the airtime used by the gateway within the sub-band which
+// contains the given frequency, during the window ending at the given time.
+func (dc *DutyCycle) Usage(gatewayID []byte, freq uint32, at time.Time) (time.Duration, error) {
+	i, err := dc.subBand(freq)
+	if err != nil {
+		return 0, err
+	}
+
+	dc.mu.Lock()
+	defer dc.mu.Unlock()
+
+	return dc.used(gatewayID, i, at), nil
+}
+
+// transmission returns the transmission for the given frame.
+func (dc *DutyCycle) transmission(frame *gw.DownlinkFrame, at time.Time) (transmission, error) {
+	txInfo := frame.GetTxInfo()
+	if txInfo == nil {
+		return transmission{}, errors.New("airtime: tx_info must not be nil")
+	}
+
+	i, err := dc.subBand(txInfo.Frequency)
+	if err != nil {
+		return transmission{}, err
+	}
+
+	d, err := Airtime(txInfo, len(frame.PhyPayload))
+	if err != nil {
+		return transmission{}, err
+	}
+
+	return transmission{
+		subBand: i,
+		at:      at,
+		airtime: d,
+	}, nil
+}
+
+// check returns a *LimitError when the transmission exceeds the duty-cycle.
+// The caller must hold the lock.
+func (dc *DutyCycle) check(gatewayID []byte, t transmission) error {
+	b := dc.subBands[t.subBand]
+	limit := time.Duration(float64(dc.window) * b.DutyCycle)
+	used := dc.used(gatewayID, t.subBand, t.at) + t.airtime
+	if used > limit {
+		return &LimitError{
+			GatewayID: gatewayID,
+			SubBand:   b,
+			Used:      used,
+			Limit:     limit,
+		}
+	}
+	return nil
+}
+
+// used returns the airtime used within the window ending at the given time,
+// removing the transmissions which are no longer within any window. The
+// caller must hold the lock.
+func (dc *DutyCycle) used(gatewayID []byte, subBand int, at time.Time) time.Duration {
+	start := at.Add(-dc.window)
+
+	ts := dc.transmissions[string(gatewayID)]
+	for len(ts) != 0 && !ts[0].at.After(start) && ts[0].at.Before(at) {
+		ts = ts[1:]
+	}
+	dc.transmissions[string(gatewayID)] = ts
+
+	var used time.Duration
+	for _, t := range ts {
+		if t.subBand == subBand && t.at.After(start) && !t.at.After(at) {
+			used += t.airtime
+		}
+	}
+	return used
+}
+
+// subBand returns the index of the sub-band containing the frequency.
+func (dc *DutyCycle) subBand(freq uint32) (int, error) {
+	for i, b := range dc.subBands {
+		if b.Contains(freq) {
+			return i, nil
+		}
+	}
+	return 0, ErrNoSubBand
+}
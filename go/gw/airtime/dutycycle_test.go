@@ -0,0 +1,133 @@
+package airtime
+
+import (
+	"testing"
+	"time"
+
+	"github.com/brocaar/chirpstack-api/go/gw"
+)
+
+func TestEU868SubBands(t *testing.T) {
+	tests := []struct {
+		freq      uint32
+		dutyCycle float64
+	}{
+		{863100000, 0.001},
+		{864900000, 0.001},
+		{865100000, 0.01},
+		{867900000, 0.01},
+		{868100000, 0.01},
+		{868900000, 0.001},
+		{869525000, 0.1},
+		{869850000, 0.01},
+	}
+
+	for _, test := range tests {
+		var found bool
+		for _, b := range EU868SubBands {
+			if b.Contains(test.freq) {
+				found = true
+				if b.DutyCycle != test.dutyCycle {
+					t.Errorf("%d Hz: expected duty-cycle %g, got %g", test.freq, test.dutyCycle, b.DutyCycle)
+				}
+			}
+		}
+		if !found {
+			t.Errorf("%d Hz: expected sub-band", test.freq)
+		}
+	}
+}
+
+func TestDutyCycle(t *testing.T) {
+	// With a window of one minute, the 1% sub-band allows 600ms, the 0.1%
+	// sub-band 60ms. Each SF7 frame of 13 bytes takes 41.216ms.
+	dc := NewDutyCycle(EU868SubBands, WithWindow(time.Minute))
+	frame := &gw.DownlinkFrame{
+		PhyPayload: make([]byte, 13),
+		TxInfo:     loraDownlink(868100000, 7),
+	}
+	gatewayID := frame.TxInfo.GatewayId
+	start := time.Date(2019, 11, 5, 12, 0, 0, 0, time.UTC)
+
+	for i := 0; i < 14; i++ {
+		if err := dc.Record(frame, start.Add(time.Duration(i)*time.Second)); err != nil {
+			t.Fatalf("frame %d: unexpected error: %s", i, err)
+		}
+	}
+
+	at := start.Add(20 * time.Second)
+	used, err := dc.Usage(gatewayID, 868100000, at)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if exp := 14 * 41216 * time.Microsecond; used != exp {
+		t.Errorf("expected %s used, got %s", exp, used)
+	}
+
+	err = dc.Check(frame, at)
+	if lerr, ok := err.(*LimitError); !ok {
+		t.Fatalf("expected *LimitError, got %v", err)
+	} else if lerr.Limit != 600*time.Millisecond || lerr.Used != 15*41216*time.Microsecond {
+		t.Errorf("unexpected limit error: %s", lerr)
+	}
+
+	// Other gateways and sub-bands are not affected.
+	other := &gw.DownlinkFrame{
+		PhyPayload: make([]byte, 13),
+		TxInfo:     loraDownlink(869525000, 7),
+	}
+	if err := dc.Check(other, at); err != nil {
+		t.Errorf("other sub-band: unexpected error: %s", err)
+	}
+	otherGW := &gw.DownlinkFrame{
+		PhyPayload: make([]byte, 13),
+		TxInfo:     loraDownlink(868100000, 7),
+	}
+	otherGW.TxInfo.GatewayId = []byte{0x08, 0x07, 0x06, 0x05, 0x04, 0x03, 0x02, 0x01}
+	if err := dc.Check(otherGW, at); err != nil {
+		t.Errorf("other gateway: unexpected error: %s", err)
+	}
+
+	// Once the first frame falls outside the window, the next frame fits.
+	if err := dc.Check(frame, start.Add(time.Minute)); err != nil {
+		t.Errorf("after window expiry: unexpected error: %s", err)
+	}
+	used, err = dc.Usage(gatewayID, 868100000, start.Add(time.Minute+12500*time.Millisecond))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if exp := 41216 * time.Microsecond; used != exp {
+		t.Errorf("expected %s used after window expiry, got %s", exp, used)
+	}
+}
+
+func TestDutyCycleLowSubBand(t *testing.T) {
+	dc := NewDutyCycle(EU868SubBands, WithWindow(time.Minute))
+	frame := &gw.DownlinkFrame{
+		PhyPayload: make([]byte, 13),
+		TxInfo:     loraDownlink(864100000, 7),
+	}
+	start := time.Date(2019, 11, 5, 12, 0, 0, 0, time.UTC)
+
+	if err := dc.Record(frame, start); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := dc.Record(frame, start.Add(time.Second)).(*LimitError); !ok {
+		t.Error("expected *LimitError for the 0.1% sub-band")
+	}
+}
+
+func TestDutyCycleNoSubBand(t *testing.T) {
+	dc := NewDutyCycle(EU868SubBands)
+	frame := &gw.DownlinkFrame{
+		PhyPayload: make([]byte, 13),
+		TxInfo:     loraDownlink(868650000, 7),
+	}
+	if err := dc.Check(frame, time.Now()); err != ErrNoSubBand {
+		t.Errorf("expected ErrNoSubBand, got %v", err)
+	}
+
+	if err := NewDutyCycle(nil).Check(frame, time.Now()); err != nil {
+		t.Errorf("expected no error without sub-bands, got %s", err)
+	}
+}
@@ -0,0 +1,104 @@
+// Package basicstation implements the LoRa Basics Station LNS protocol
+// messages and converts them to and from the gw messages. The data-rates of
+// the uplink and downlink messages are resolved using the DR table of the
+// router_config.
+//
+// The uplink context (as used by DownlinkTXInfo.Context) contains the xtime
+// and rctx values, each as 8 byte big-endian value.
+package basicstation
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/brocaar/chirpstack-api/go/lorawan"
+)
+
+// MessageType defines the message type.
+type MessageType string
+
+// Message types.
+const (
+	UplinkDataFrameMessage      MessageType = "updf"
+	JoinRequestMessage          MessageType = "jreq"
+	ProprietaryDataFrameMessage MessageType = "propdf"
+	DownlinkMessageMessage      MessageType = "dnmsg"
+	DownlinkTransmittedMessage  MessageType = "dntxed"
+	RouterConfigMessage         MessageType = "router_config"
+	TimeSyncMessage             MessageType = "timesync"
+)
+
+// GetMessageType returns the message type of the given JSON message.
+func GetMessageType(data []byte) (MessageType, error) {
+	var msg struct {
+		MessageType MessageType `json:"msgtype"`
+	}
+	if err := json.Unmarshal(data, &msg); err != nil {
+		return "", fmt.Errorf("basicstation: unmarshal json error: %w", err)
+	}
+	if msg.MessageType == "" {
+		return "", fmt.Errorf("basicstation: msgtype is missing")
+	}
+	return msg.MessageType, nil
+}
+
+// EUI64 implements the Station EUI format (e.g. "01-02-03-04-05-06-07-08").
+// Unmarshaling also accepts the ":" separated and plain HEX format.
+type EUI64 lorawan.EUI64
+
+// MarshalText implements encoding.TextMarshaler.
+func (e EUI64) MarshalText() ([]byte, error) {
+	parts := make([]string, len(e))
+	for i, b := range e {
+		parts[i] = hex.EncodeToString([]byte{b})
+	}
+	return []byte(strings.Join(parts, "-")), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler.
+func (e *EUI64) UnmarshalText(text []byte) error {
+	s := strings.NewReplacer("-", "", ":", "").Replace(string(text))
+	eui, err := lorawan.ParseEUI64(s)
+	if err != nil {
+		return fmt.Errorf("basicstation: %w", err)
+	}
+	*e = EUI64(eui)
+	return nil
+}
+
+// HEXBytes implements a HEX encoded byte slice.
+type HEXBytes []byte
+
+// MarshalText implements encoding.TextMarshaler.
+func (h HEXBytes) MarshalText() ([]byte, error) {
+	return []byte(hex.EncodeToString(h)), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler.
+func (h *HEXBytes) UnmarshalText(text []byte) error {
+	b, err := hex.DecodeString(string(text))
+	if err != nil {
+		return fmt.Errorf("basicstation: decode hex error: %w", err)
+	}
+	*h = b
+	return nil
+}
+
+// contextFromXTime returns the uplink context for the given xtime and rctx.
+func contextFromXTime(xtime, rctx int64) []byte {
+	b := make([]byte, 16)
+	binary.BigEndian.PutUint64(b[0:8], uint64(xtime))
+	binary.BigEndian.PutUint64(b[8:16], uint64(rctx))
+	return b
+}
+
+// xtimeFromContext returns the xtime and rctx of the given uplink context.
+func xtimeFromContext(b []byte) (xtime, rctx int64, err error) {
+	if len(b) != 16 {
+		return 0, 0, fmt.Errorf("basicstation: context must be exactly 16 bytes, got %d", len(b))
+	}
+	return int64(binary.BigEndian.Uint64(b[0:8])), int64(binary.BigEndian.Uint64(b[8:16])), nil
+}
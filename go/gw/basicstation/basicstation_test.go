@@ -0,0 +1,552 @@
+package basicstation
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang/protobuf/jsonpb"
+	"github.com/golang/protobuf/proto"
+
+	"github.com/brocaar/chirpstack-api/go/band"
+	"github.com/brocaar/chirpstack-api/go/common"
+	"github.com/brocaar/chirpstack-api/go/gw"
+	"github.com/brocaar/chirpstack-api/go/lorawan"
+)
+
+var gatewayID = lorawan.EUI64{0x01, 0x02, 0x03, 0x04, 0x05, 0x06, 0x07, 0x08}
+
+func dataRates(t *testing.T, region common.Region) DataRates {
+	t.Helper()
+	b, err := band.Get(region)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return NewDataRates(b)
+}
+
+// assertJSON compares the JSON documents semantically, thus ignoring the
+// object key order and number formatting.
+func assertJSON(t *testing.T, expected string, got []byte) {
+	t.Helper()
+
+	exp, err := decodeJSON([]byte(expected))
+	if err != nil {
+		t.Fatalf("expected json: %s", err)
+	}
+	v, err := decodeJSON(got)
+	if err != nil {
+		t.Fatalf("got json: %s", err)
+	}
+	if !reflect.DeepEqual(exp, v) {
+		t.Errorf("json mismatch:\nexpected: %s\n     got: %s", expected, got)
+	}
+}
+
+// decodeJSON decodes the JSON document, decoding numbers as int64 when
+// possible so that large values (e.g. xtime) are compared exactly.
+func decodeJSON(b []byte) (interface{}, error) {
+	d := json.NewDecoder(bytes.NewReader(b))
+	d.UseNumber()
+	var v interface{}
+	if err := d.Decode(&v); err != nil {
+		return nil, err
+	}
+	return normalizeJSON(v), nil
+}
+
+func normalizeJSON(v interface{}) interface{} {
+	switch v := v.(type) {
+	case json.Number:
+		if i, err := strconv.ParseInt(v.String(), 10, 64); err == nil {
+			return i
+		}
+		f, _ := strconv.ParseFloat(v.String(), 64)
+		if f == float64(int64(f)) {
+			return int64(f)
+		}
+		return f
+	case map[string]interface{}:
+		for k := range v {
+			v[k] = normalizeJSON(v[k])
+		}
+	case []interface{}:
+		for i := range v {
+			v[i] = normalizeJSON(v[i])
+		}
+	}
+	return v
+}
+
+func unmarshalProto(t *testing.T, s string, m proto.Message) proto.Message {
+	t.Helper()
+	if err := jsonpb.UnmarshalString(s, m); err != nil {
+		t.Fatal(err)
+	}
+	return m
+}
+
+func assertProto(t *testing.T, expected, got proto.Message) {
+	t.Helper()
+	if !proto.Equal(expected, got) {
+		t.Errorf("message mismatch:\nexpected: %s\n     got: %s", expected, got)
+	}
+}
+
+func TestGetMessageType(t *testing.T) {
+	for _, mt := range []MessageType{UplinkDataFrameMessage, JoinRequestMessage, ProprietaryDataFrameMessage, DownlinkMessageMessage, DownlinkTransmittedMessage, RouterConfigMessage, TimeSyncMessage} {
+		got, err := GetMessageType([]byte(`{"msgtype":"` + string(mt) + `","DR":0}`))
+		if err != nil || got != mt {
+			t.Errorf("expected %s, got %s (%v)", mt, got, err)
+		}
+	}
+
+	for _, s := range []string{`{"DR":0}`, `{"msgtype":""}`, `[`} {
+		if _, err := GetMessageType([]byte(s)); err == nil {
+			t.Errorf("%s: expected error", s)
+		}
+	}
+}
+
+func TestEUI64(t *testing.T) {
+	exp := EUI64{0x00, 0x04, 0xa3, 0x0b, 0x00, 0x1c, 0x05, 0x30}
+	for _, s := range []string{"00-04-a3-0b-00-1c-05-30", "00:04:A3:0B:00:1C:05:30", "0004a30b001c0530"} {
+		var e EUI64
+		if err := e.UnmarshalText([]byte(s)); err != nil || e != exp {
+			t.Errorf("%s: expected %v, got %v (%v)", s, exp, e, err)
+		}
+	}
+
+	b, err := exp.MarshalText()
+	if err != nil || string(b) != "00-04-a3-0b-00-1c-05-30" {
+		t.Errorf("expected 00-04-a3-0b-00-1c-05-30, got %s (%v)", b, err)
+	}
+
+	var e EUI64
+	if err := e.UnmarshalText([]byte("00-04-a3-0b-00-1c-05")); err == nil {
+		t.Error("expected error for 7 bytes")
+	}
+}
+
+// The Station messages follow the examples of the Station LNS protocol
+// documentation. As gw.UplinkFrame does not contain all the Station fields
+// (e.g. RefTime), the re-encoded messages are compared with separate
+// expected messages.
+func TestUplinkMessages(t *testing.T) {
+	tests := []struct {
+		name     string
+		station  string
+		expected string
+		encoded  string
+	}{
+		{
+			name: "updf",
+			station: `{
+				"msgtype": "updf",
+				"MHdr": 64, "DevAddr": 637604404, "FCtrl": 130, "FCnt": 5, "FOpts": "0307",
+				"FPort": 1, "FRMPayload": "A1B2C3", "MIC": -1964048448, "RefTime": 0.000000,
+				"DR": 5, "Freq": 868100000,
+				"upinfo": {
+					"rctx": 0, "xtime": 40250921680313459, "gpstime": 1256989643123456, "fts": 123456789,
+					"rssi": -43.0, "snr": 9.25, "rxtime": 1572954425.123456
+				}
+			}`,
+			expected: `{
+				"phyPayload": "QDQSASaCBQADBwGhssPA/+6K",
+				"txInfo": {
+					"frequency": 868100000,
+					"loRaModulationInfo": {"bandwidth": 125, "spreadingFactor": 7, "codeRate": "4/5"}
+				},
+				"rxInfo": {
+					"gatewayID": "AQIDBAUGBwg=",
+					"time": "2019-11-05T11:47:05.123456Z",
+					"timeSinceGPSEpoch": "1256989643.123456s",
+					"rssi": -43,
+					"loRaSNR": 9.25,
+					"fineTimestampType": "PLAIN",
+					"plainFineTimestamp": {"time": "2019-11-05T11:47:05.123456789Z"},
+					"context": "AI8AAACjHHMAAAAAAAAAAA=="
+				}
+			}`,
+			encoded: `{
+				"msgtype": "updf",
+				"MHdr": 64, "DevAddr": 637604404, "FCtrl": 130, "FCnt": 5, "FOpts": "0307",
+				"FPort": 1, "FRMPayload": "a1b2c3", "MIC": -1964048448,
+				"DR": 5, "Freq": 868100000,
+				"upinfo": {
+					"rctx": 0, "xtime": 40250921680313459, "gpstime": 1256989643123456, "fts": 123456789,
+					"rssi": -43, "snr": 9.25, "rxtime": 1572954425.123456
+				}
+			}`,
+		},
+		{
+			// Without GPS, gpstime is 0 and fts is -1.
+			name: "jreq",
+			station: `{
+				"msgtype": "jreq",
+				"MHdr": 0, "JoinEui": "70-b3-d5-7e-d0-00-00-01", "DevEui": "00-04-a3-0b-00-1c-05-30",
+				"DevNonce": 12345, "MIC": 1295788826, "RefTime": 0.000000,
+				"DR": 0, "Freq": 868300000,
+				"upinfo": {
+					"rctx": 0, "xtime": 40250921680313459, "gpstime": 0, "fts": -1,
+					"rssi": -110.5, "snr": -12.75, "rxtime": 1572954425.5
+				}
+			}`,
+			expected: `{
+				"phyPayload": "AAEAANB+1bNwMAUcAAujBAA5MBorPE0=",
+				"txInfo": {
+					"frequency": 868300000,
+					"loRaModulationInfo": {"bandwidth": 125, "spreadingFactor": 12, "codeRate": "4/5"}
+				},
+				"rxInfo": {
+					"gatewayID": "AQIDBAUGBwg=",
+					"time": "2019-11-05T11:47:05.500Z",
+					"rssi": -111,
+					"loRaSNR": -12.75,
+					"context": "AI8AAACjHHMAAAAAAAAAAA=="
+				}
+			}`,
+			encoded: `{
+				"msgtype": "jreq",
+				"MHdr": 0, "JoinEui": "70-b3-d5-7e-d0-00-00-01", "DevEui": "00-04-a3-0b-00-1c-05-30",
+				"DevNonce": 12345, "MIC": 1295788826,
+				"DR": 0, "Freq": 868300000,
+				"upinfo": {
+					"rctx": 0, "xtime": 40250921680313459, "gpstime": 0, "fts": -1,
+					"rssi": -111, "snr": -12.75, "rxtime": 1572954425.5
+				}
+			}`,
+		},
+		{
+			name: "propdf",
+			station: `{
+				"msgtype": "propdf",
+				"FRMPayload": "E0010203", "RefTime": 0.000000,
+				"DR": 7, "Freq": 868800000,
+				"upinfo": {"rctx": 1, "xtime": 40250921680313459, "gpstime": 0, "fts": -1, "rssi": -80, "snr": 0, "rxtime": 0}
+			}`,
+			expected: `{
+				"phyPayload": "4AECAw==",
+				"txInfo": {
+					"frequency": 868800000,
+					"modulation": "FSK",
+					"fskModulationInfo": {"bitrate": 50000}
+				},
+				"rxInfo": {
+					"gatewayID": "AQIDBAUGBwg=",
+					"rssi": -80,
+					"context": "AI8AAACjHHMAAAAAAAAAAQ=="
+				}
+			}`,
+			encoded: `{
+				"msgtype": "propdf",
+				"FRMPayload": "e0010203",
+				"DR": 7, "Freq": 868800000,
+				"upinfo": {"rctx": 1, "xtime": 40250921680313459, "gpstime": 0, "fts": -1, "rssi": -80, "snr": 0, "rxtime": 0}
+			}`,
+		},
+	}
+
+	drs := dataRates(t, common.Region_EU868)
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			mt, err := GetMessageType([]byte(test.station))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var frame *gw.UplinkFrame
+			switch mt {
+			case UplinkDataFrameMessage:
+				var m UplinkDataFrame
+				if err := json.Unmarshal([]byte(test.station), &m); err != nil {
+					t.Fatal(err)
+				}
+				frame, err = m.UplinkFrame(gatewayID, drs)
+			case JoinRequestMessage:
+				var m JoinRequest
+				if err := json.Unmarshal([]byte(test.station), &m); err != nil {
+					t.Fatal(err)
+				}
+				frame, err = m.UplinkFrame(gatewayID, drs)
+			case ProprietaryDataFrameMessage:
+				var m ProprietaryDataFrame
+				if err := json.Unmarshal([]byte(test.station), &m); err != nil {
+					t.Fatal(err)
+				}
+				frame, err = m.UplinkFrame(gatewayID, drs)
+			default:
+				t.Fatalf("unexpected message type %s", mt)
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			assertProto(t, unmarshalProto(t, test.expected, &gw.UplinkFrame{}), frame)
+
+			m, err := NewUplinkMessage(frame, drs)
+			if err != nil {
+				t.Fatal(err)
+			}
+			b, err := json.Marshal(m)
+			if err != nil {
+				t.Fatal(err)
+			}
+			assertJSON(t, test.encoded, b)
+		})
+	}
+}
+
+func TestNewUplinkMessageErrors(t *testing.T) {
+	drs := dataRates(t, common.Region_EU868)
+	frame := func(phyPayload []byte) *gw.UplinkFrame {
+		return &gw.UplinkFrame{
+			PhyPayload: phyPayload,
+			TxInfo: &gw.UplinkTXInfo{
+				Frequency: 868100000,
+				ModulationInfo: &gw.UplinkTXInfo_LoraModulationInfo{
+					LoraModulationInfo: &gw.LoRaModulationInfo{Bandwidth: 125, SpreadingFactor: 7},
+				},
+			},
+			RxInfo: &gw.UplinkRXInfo{},
+		}
+	}
+
+	tests := []struct {
+		name  string
+		frame *gw.UplinkFrame
+	}{
+		{"empty", frame(nil)},
+		{"short join-request", frame(make([]byte, 22))},
+		{"short data frame", frame([]byte{0x40, 0x01, 0x02, 0x03, 0x04, 0x00, 0x00, 0x00, 0x01, 0x02, 0x03})},
+		{"missing FOpts", frame([]byte{0x40, 0x01, 0x02, 0x03, 0x04, 0x0f, 0x00, 0x00, 0x01, 0x02, 0x03, 0x04})},
+		{"join-accept", frame(append([]byte{0x20}, make([]byte, 16)...))},
+		{"unknown data-rate", &gw.UplinkFrame{
+			PhyPayload: []byte{0xe0},
+			TxInfo: &gw.UplinkTXInfo{
+				ModulationInfo: &gw.UplinkTXInfo_LoraModulationInfo{
+					LoraModulationInfo: &gw.LoRaModulationInfo{Bandwidth: 500, SpreadingFactor: 7},
+				},
+			},
+			RxInfo: &gw.UplinkRXInfo{},
+		}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if _, err := NewUplinkMessage(test.frame, drs); err == nil {
+				t.Error("expected error")
+			}
+		})
+	}
+}
+
+// A Class-A dnmsg contains the RX1 and RX2 parameters, of which only RX1 is
+// used. As gw.DownlinkFrame does not contain the DevEUI, the re-encoded
+// messages have a zero DevEui.
+func TestDownlinkMessage(t *testing.T) {
+	tests := []struct {
+		name     string
+		station  string
+		expected string
+		encoded  string
+	}{
+		{
+			name: "class-a",
+			station: `{
+				"msgtype": "dnmsg", "DevEui": "00-04-a3-0b-00-1c-05-30", "dC": 0, "diid": 35,
+				"pdu": "6034120126000100C0FFEE8A", "RxDelay": 1, "RX1DR": 5, "RX1Freq": 868100000,
+				"RX2DR": 0, "RX2Freq": 869525000, "priority": 0,
+				"xtime": 40250921680313459, "rctx": 0, "MuxTime": 1572954425.234567
+			}`,
+			expected: `{
+				"phyPayload": "YDQSASYAAQDA/+6K",
+				"txInfo": {
+					"gatewayID": "AQIDBAUGBwg=",
+					"frequency": 868100000,
+					"loRaModulationInfo": {"bandwidth": 125, "spreadingFactor": 7, "codeRate": "4/5", "polarizationInversion": true},
+					"timing": "DELAY",
+					"delayTimingInfo": {"delay": "1s"},
+					"context": "AI8AAACjHHMAAAAAAAAAAA=="
+				},
+				"token": 35
+			}`,
+			encoded: `{
+				"msgtype": "dnmsg", "DevEui": "00-00-00-00-00-00-00-00", "dC": 0, "diid": 35,
+				"pdu": "6034120126000100c0ffee8a", "RxDelay": 1, "RX1DR": 5, "RX1Freq": 868100000,
+				"priority": 0, "xtime": 40250921680313459, "rctx": 0
+			}`,
+		},
+		{
+			name: "class-b",
+			station: `{
+				"msgtype": "dnmsg", "DevEui": "00-04-a3-0b-00-1c-05-30", "dC": 1, "diid": 36,
+				"pdu": "6034120126000100C0FFEE8A", "gpstime": 1256989700000000, "DR": 3, "Freq": 869525000,
+				"priority": 0, "rctx": 0, "MuxTime": 1572954425.234567
+			}`,
+			expected: `{
+				"phyPayload": "YDQSASYAAQDA/+6K",
+				"txInfo": {
+					"gatewayID": "AQIDBAUGBwg=",
+					"frequency": 869525000,
+					"loRaModulationInfo": {"bandwidth": 125, "spreadingFactor": 9, "codeRate": "4/5", "polarizationInversion": true},
+					"timing": "GPS_EPOCH",
+					"gpsEpochTimingInfo": {"timeSinceGPSEpoch": "1256989700s"}
+				},
+				"token": 36
+			}`,
+			encoded: `{
+				"msgtype": "dnmsg", "DevEui": "00-00-00-00-00-00-00-00", "dC": 1, "diid": 36,
+				"pdu": "6034120126000100c0ffee8a", "gpstime": 1256989700000000, "DR": 3, "Freq": 869525000,
+				"priority": 0
+			}`,
+		},
+		{
+			name: "class-c",
+			station: `{
+				"msgtype": "dnmsg", "DevEui": "00-04-a3-0b-00-1c-05-30", "dC": 2, "diid": 37,
+				"pdu": "6034120126000100C0FFEE8A", "RX2DR": 0, "RX2Freq": 869525000,
+				"priority": 0, "rctx": 0, "MuxTime": 1572954425.234567
+			}`,
+			expected: `{
+				"phyPayload": "YDQSASYAAQDA/+6K",
+				"txInfo": {
+					"gatewayID": "AQIDBAUGBwg=",
+					"frequency": 869525000,
+					"loRaModulationInfo": {"bandwidth": 125, "spreadingFactor": 12, "codeRate": "4/5", "polarizationInversion": true},
+					"timing": "IMMEDIATELY",
+					"immediatelyTimingInfo": {},
+					"context": "AAAAAAAAAAAAAAAAAAAAAA=="
+				},
+				"token": 37
+			}`,
+			encoded: `{
+				"msgtype": "dnmsg", "DevEui": "00-00-00-00-00-00-00-00", "dC": 2, "diid": 37,
+				"pdu": "6034120126000100c0ffee8a", "RX2DR": 0, "RX2Freq": 869525000,
+				"priority": 0, "rctx": 0
+			}`,
+		},
+	}
+
+	drs := dataRates(t, common.Region_EU868)
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var m DownlinkMessage
+			if err := json.Unmarshal([]byte(test.station), &m); err != nil {
+				t.Fatal(err)
+			}
+			frame, err := m.DownlinkFrame(gatewayID, drs)
+			if err != nil {
+				t.Fatal(err)
+			}
+			assertProto(t, unmarshalProto(t, test.expected, &gw.DownlinkFrame{}), frame)
+
+			out, err := NewDownlinkMessage(frame, drs)
+			if err != nil {
+				t.Fatal(err)
+			}
+			b, err := json.Marshal(out)
+			if err != nil {
+				t.Fatal(err)
+			}
+			assertJSON(t, test.encoded, b)
+		})
+	}
+}
+
+func TestDownlinkMessageErrors(t *testing.T) {
+	drs := dataRates(t, common.Region_EU868)
+
+	for _, s := range []string{
+		`{"msgtype":"dnmsg","dC":0,"diid":1,"pdu":"","RX1DR":5,"RX1Freq":868100000}`,
+		`{"msgtype":"dnmsg","dC":0,"diid":1,"pdu":"","xtime":1,"RxDelay":1}`,
+		`{"msgtype":"dnmsg","dC":1,"diid":1,"pdu":"","DR":3,"Freq":869525000}`,
+		`{"msgtype":"dnmsg","dC":2,"diid":1,"pdu":"","RX2DR":8,"RX2Freq":869525000}`,
+		`{"msgtype":"dnmsg","dC":3,"diid":1,"pdu":""}`,
+	} {
+		var m DownlinkMessage
+		if err := json.Unmarshal([]byte(s), &m); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := m.DownlinkFrame(gatewayID, drs); err == nil {
+			t.Errorf("%s: expected error", s)
+		}
+	}
+
+	frame := unmarshalProto(t, `{
+		"txInfo": {
+			"frequency": 868100000,
+			"loRaModulationInfo": {"bandwidth": 125, "spreadingFactor": 7, "codeRate": "4/5"},
+			"timing": "DELAY",
+			"delayTimingInfo": {"delay": "1.5s"},
+			"context": "AI8AAACjHHMAAAAAAAAAAA=="
+		}
+	}`, &gw.DownlinkFrame{}).(*gw.DownlinkFrame)
+	if _, err := NewDownlinkMessage(frame, drs); err == nil || !strings.Contains(err.Error(), "whole number of seconds") {
+		t.Errorf("expected delay error, got %v", err)
+	}
+}
+
+func TestDownlinkTransmitted(t *testing.T) {
+	var m DownlinkTransmitted
+	if err := json.Unmarshal([]byte(`{
+		"msgtype": "dntxed", "diid": 35, "DevEui": "00-04-a3-0b-00-1c-05-30", "rctx": 0,
+		"xtime": 40250921681313459, "txtime": 1572954426.123456, "gpstime": 1256989644123456
+	}`), &m); err != nil {
+		t.Fatal(err)
+	}
+
+	ack := m.DownlinkTXAck(gatewayID)
+	assertProto(t, &gw.DownlinkTXAck{GatewayId: gatewayID.Bytes(), Token: 35}, ack)
+
+	out, err := NewDownlinkTransmitted(ack)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := json.Marshal(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertJSON(t, `{"msgtype": "dntxed", "diid": 35, "DevEui": "00-00-00-00-00-00-00-00", "rctx": 0, "xtime": 0, "txtime": 0, "gpstime": 0}`, b)
+
+	if _, err := NewDownlinkTransmitted(&gw.DownlinkTXAck{Token: 35, Error: "TOO_LATE"}); err == nil {
+		t.Error("expected error for a failed transmission")
+	}
+}
+
+func TestTimeSync(t *testing.T) {
+	var req TimeSyncRequest
+	if err := json.Unmarshal([]byte(`{"msgtype": "timesync", "txtime": 1023024197.5}`), &req); err != nil {
+		t.Fatal(err)
+	}
+
+	now := time.Date(2019, 11, 5, 11, 47, 5, 0, time.UTC)
+	resp := NewTimeSyncResponse(req, now)
+	b, err := json.Marshal(resp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertJSON(t, `{"msgtype": "timesync", "txtime": 1023024197.5, "gpstime": 1256989643000000}`, b)
+	if !resp.Time().Equal(now) {
+		t.Errorf("expected %s, got %s", now, resp.Time())
+	}
+
+	// GPS time transfer, initiated by the server.
+	transfer := `{"msgtype": "timesync", "xtime": 40250921680313459, "gpstime": 1256989643500000}`
+	var tr TimeSyncResponse
+	if err := json.Unmarshal([]byte(transfer), &tr); err != nil {
+		t.Fatal(err)
+	}
+	if exp := now.Add(500 * time.Millisecond); !tr.Time().Equal(exp) {
+		t.Errorf("expected %s, got %s", exp, tr.Time())
+	}
+	if b, err = json.Marshal(tr); err != nil {
+		t.Fatal(err)
+	}
+	assertJSON(t, transfer, b)
+}
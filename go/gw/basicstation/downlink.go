@@ -0,0 +1,228 @@
+package basicstation
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/golang/protobuf/ptypes"
+
+	"github.com/brocaar/chirpstack-api/go/gw"
+	"github.com/brocaar/chirpstack-api/go/lorawan"
+)
+
+// Device classes as used by the dnmsg dC field.
+const (
+	DeviceClassA = 0
+	DeviceClassB = 1
+	DeviceClassC = 2
+)
+
+// DownlinkMessage implements the dnmsg message.
+//
+// Class-A downlinks use RxDelay, RX1DR and RX1Freq relative to xtime,
+// Class-B downlinks use DR and Freq at gpstime and Class-C downlinks use
+// RX2DR and RX2Freq and are sent immediately.
+type DownlinkMessage struct {
+	MessageType MessageType `json:"msgtype"`
+	DevEUI      EUI64       `json:"DevEui"`
+	DeviceClass uint8       `json:"dC"`
+	DIID        int64       `json:"diid"`
+	PDU         HEXBytes    `json:"pdu"`
+	Priority    int         `json:"priority"`
+
+	RCtx    *int64  `json:"rctx,omitempty"`
+	XTime   *int64  `json:"xtime,omitempty"`
+	RxDelay *int    `json:"RxDelay,omitempty"`
+	RX1DR   *int    `json:"RX1DR,omitempty"`
+	RX1Freq *uint32 `json:"RX1Freq,omitempty"`
+	RX2DR   *int    `json:"RX2DR,omitempty"`
+	RX2Freq *uint32 `json:"RX2Freq,omitempty"`
+
+	// GPSTime holds the time since GPS epoch (microseconds) of the Class-B
+	// transmission.
+	GPSTime   *int64  `json:"gpstime,omitempty"`
+	DR        *int    `json:"DR,omitempty"`
+	Frequency *uint32 `json:"Freq,omitempty"`
+}
+
+// NewDownlinkMessage returns the dnmsg for the given downlink frame. The
+// frame token is used as diid. DelayTimingInfo is mapped to a Class-A
+// downlink (using the uplink context), GPSEpochTimingInfo to a Class-B
+// downlink and ImmediatelyTimingInfo to a Class-C downlink.
+//
+// The delay of a Class-A downlink must be a whole number of seconds.
+func NewDownlinkMessage(frame *gw.DownlinkFrame, drs DataRates) (DownlinkMessage, error) {
+	txInfo := frame.GetTxInfo()
+	if txInfo == nil {
+		return DownlinkMessage{}, errors.New("basicstation: tx_info must not be nil")
+	}
+
+	dr, err := drs.index(false, txInfo)
+	if err != nil {
+		return DownlinkMessage{}, err
+	}
+	freq := txInfo.Frequency
+
+	m := DownlinkMessage{
+		MessageType: DownlinkMessageMessage,
+		DIID:        int64(frame.Token),
+		PDU:         HEXBytes(frame.PhyPayload),
+	}
+
+	switch ti := txInfo.TimingInfo.(type) {
+	case *gw.DownlinkTXInfo_DelayTimingInfo:
+		xtime, rctx, err := xtimeFromContext(txInfo.Context)
+		if err != nil {
+			return DownlinkMessage{}, err
+		}
+		delay, err := ptypes.Duration(ti.DelayTimingInfo.GetDelay())
+		if err != nil {
+			return DownlinkMessage{}, fmt.Errorf("basicstation: delay error: %w", err)
+		}
+		if delay%time.Second != 0 {
+			return DownlinkMessage{}, fmt.Errorf("basicstation: delay must be a whole number of seconds, got %s", delay)
+		}
+		rxDelay := int(delay / time.Second)
+
+		m.DeviceClass = DeviceClassA
+		m.XTime = &xtime
+		m.RCtx = &rctx
+		m.RxDelay = &rxDelay
+		m.RX1DR = &dr
+		m.RX1Freq = &freq
+	case *gw.DownlinkTXInfo_GpsEpochTimingInfo:
+		d, err := ptypes.Duration(ti.GpsEpochTimingInfo.GetTimeSinceGpsEpoch())
+		if err != nil {
+			return DownlinkMessage{}, fmt.Errorf("basicstation: time_since_gps_epoch error: %w", err)
+		}
+		gpsTime := int64(d / time.Microsecond)
+
+		m.DeviceClass = DeviceClassB
+		m.GPSTime = &gpsTime
+		m.DR = &dr
+		m.Frequency = &freq
+	case *gw.DownlinkTXInfo_ImmediatelyTimingInfo:
+		m.DeviceClass = DeviceClassC
+		m.RX2DR = &dr
+		m.RX2Freq = &freq
+		if len(txInfo.Context) != 0 {
+			_, rctx, err := xtimeFromContext(txInfo.Context)
+			if err != nil {
+				return DownlinkMessage{}, err
+			}
+			m.RCtx = &rctx
+		}
+	default:
+		return DownlinkMessage{}, errors.New("basicstation: timing_info must be set")
+	}
+
+	return m, nil
+}
+
+// DownlinkFrame returns the downlink frame of the dnmsg for the given
+// gateway. The diid is used as frame token.
+func (m DownlinkMessage) DownlinkFrame(gatewayID lorawan.EUI64, drs DataRates) (*gw.DownlinkFrame, error) {
+	txInfo := gw.DownlinkTXInfo{
+		GatewayId: gatewayID.Bytes(),
+	}
+
+	var dr *int
+	var freq *uint32
+
+	switch m.DeviceClass {
+	case DeviceClassA:
+		if m.XTime == nil || m.RxDelay == nil {
+			return nil, errors.New("basicstation: class-a dnmsg requires xtime and RxDelay")
+		}
+		var rctx int64
+		if m.RCtx != nil {
+			rctx = *m.RCtx
+		}
+
+		dr, freq = m.RX1DR, m.RX1Freq
+		txInfo.Context = contextFromXTime(*m.XTime, rctx)
+		txInfo.Timing = gw.DownlinkTiming_DELAY
+		txInfo.TimingInfo = &gw.DownlinkTXInfo_DelayTimingInfo{
+			DelayTimingInfo: &gw.DelayTimingInfo{
+				Delay: ptypes.DurationProto(time.Duration(*m.RxDelay) * time.Second),
+			},
+		}
+	case DeviceClassB:
+		if m.GPSTime == nil {
+			return nil, errors.New("basicstation: class-b dnmsg requires gpstime")
+		}
+
+		dr, freq = m.DR, m.Frequency
+		txInfo.Timing = gw.DownlinkTiming_GPS_EPOCH
+		txInfo.TimingInfo = &gw.DownlinkTXInfo_GpsEpochTimingInfo{
+			GpsEpochTimingInfo: &gw.GPSEpochTimingInfo{
+				TimeSinceGpsEpoch: ptypes.DurationProto(time.Duration(*m.GPSTime) * time.Microsecond),
+			},
+		}
+	case DeviceClassC:
+		dr, freq = m.RX2DR, m.RX2Freq
+		txInfo.Timing = gw.DownlinkTiming_IMMEDIATELY
+		txInfo.TimingInfo = &gw.DownlinkTXInfo_ImmediatelyTimingInfo{
+			ImmediatelyTimingInfo: &gw.ImmediatelyTimingInfo{},
+		}
+		if m.RCtx != nil {
+			txInfo.Context = contextFromXTime(0, *m.RCtx)
+		}
+	default:
+		return nil, fmt.Errorf("basicstation: invalid device class: %d", m.DeviceClass)
+	}
+
+	if dr == nil || freq == nil {
+		return nil, fmt.Errorf("basicstation: dnmsg data-rate and frequency are missing for device class %d", m.DeviceClass)
+	}
+	txInfo.Frequency = *freq
+	if err := drs.setDownlinkModulation(&txInfo, *dr); err != nil {
+		return nil, err
+	}
+
+	return &gw.DownlinkFrame{
+		PhyPayload: m.PDU,
+		TxInfo:     &txInfo,
+		Token:      uint32(m.DIID),
+	}, nil
+}
+
+// DownlinkTransmitted implements the dntxed message.
+type DownlinkTransmitted struct {
+	MessageType MessageType `json:"msgtype"`
+	DIID        int64       `json:"diid"`
+	DevEUI      EUI64       `json:"DevEui"`
+	RCtx        int64       `json:"rctx"`
+	XTime       int64       `json:"xtime"`
+
+	// TXTime holds the UTC time of the transmission (seconds since Unix
+	// epoch).
+	TXTime float64 `json:"txtime"`
+
+	// GPSTime holds the time since GPS epoch (microseconds) of the
+	// transmission, 0 when unknown.
+	GPSTime int64 `json:"gpstime"`
+}
+
+// DownlinkTXAck returns the downlink TX acknowledgement of the dntxed message
+// for the given gateway. Station only reports successful transmissions.
+func (m DownlinkTransmitted) DownlinkTXAck(gatewayID lorawan.EUI64) *gw.DownlinkTXAck {
+	return &gw.DownlinkTXAck{
+		GatewayId: gatewayID.Bytes(),
+		Token:     uint32(m.DIID),
+	}
+}
+
+// NewDownlinkTransmitted returns the dntxed message for the given downlink
+// TX acknowledgement. As Station does not report failed transmissions, an
+// acknowledgement with an error returns an error.
+func NewDownlinkTransmitted(ack *gw.DownlinkTXAck) (DownlinkTransmitted, error) {
+	if ack.GetError() != "" {
+		return DownlinkTransmitted{}, fmt.Errorf("basicstation: dntxed can not contain an error: %s", ack.GetError())
+	}
+	return DownlinkTransmitted{
+		MessageType: DownlinkTransmittedMessage,
+		DIID:        int64(ack.GetToken()),
+	}, nil
+}
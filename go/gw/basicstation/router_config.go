@@ -0,0 +1,544 @@
+package basicstation
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/brocaar/chirpstack-api/go/band"
+	"github.com/brocaar/chirpstack-api/go/common"
+	"github.com/brocaar/chirpstack-api/go/gw"
+	"github.com/brocaar/chirpstack-api/go/lorawan"
+)
+
+// RadioBandwidth defines the receive bandwidth (Hz) of a single SX1301 radio
+// for channels up to 125 kHz. For 250 and 500 kHz channels, the receive
+// bandwidth is 1 and 1.1 MHz.
+const RadioBandwidth = 925000
+
+// FSKBitRate defines the bitrate of the FSK data-rate.
+const FSKBitRate = 50000
+
+// stationRegions maps the regions to the Station region names.
+var stationRegions = map[common.Region]string{
+	common.Region_EU868: "EU863",
+	common.Region_US915: "US902",
+	common.Region_CN779: "CN779",
+	common.Region_EU433: "EU433",
+	common.Region_AU915: "AU915",
+	common.Region_CN470: "CN470",
+	common.Region_AS923: "AS923",
+	common.Region_KR920: "KR920",
+	common.Region_IN865: "IN865",
+	common.Region_RU864: "RU864",
+}
+
+// RouterConfig implements the router_config message. NetID and JoinEUI hold
+// the optional uplink filters, when nil these are sent as null and Station
+// forwards all uplinks. Note that an empty filter would drop all uplinks.
+type RouterConfig struct {
+	MessageType MessageType  `json:"msgtype"`
+	NetID       []uint32     `json:"NetID"`
+	JoinEUI     [][2]uint64  `json:"JoinEui"`
+	Region      string       `json:"region"`
+	HWSpec      string       `json:"hwspec"`
+	FreqRange   [2]uint32    `json:"freq_range"`
+	DRs         DataRates    `json:"DRs"`
+	SX1301Conf  []SX1301Conf `json:"sx1301_conf"`
+}
+
+// DataRate defines a Station data-rate, encoded as [SF, BW, DNONLY]. A
+// spreading-factor of 0 defines FSK, -1 defines an unused data-rate.
+type DataRate struct {
+	SpreadingFactor int
+
+	// Bandwidth (kHz).
+	Bandwidth int
+
+	DownlinkOnly bool
+}
+
+// MarshalJSON implements json.Marshaler.
+func (d DataRate) MarshalJSON() ([]byte, error) {
+	var dnOnly int
+	if d.DownlinkOnly {
+		dnOnly = 1
+	}
+	return json.Marshal([3]int{d.SpreadingFactor, d.Bandwidth, dnOnly})
+}
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (d *DataRate) UnmarshalJSON(data []byte) error {
+	var v [3]int
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+	*d = DataRate{
+		SpreadingFactor: v[0],
+		Bandwidth:       v[1],
+		DownlinkOnly:    v[2] == 1,
+	}
+	return nil
+}
+
+// DataRates contains the data-rates by data-rate index.
+type DataRates []DataRate
+
+// NewDataRates returns the DR table for the given band.
+func NewDataRates(b *band.Band) DataRates {
+	drs := make(DataRates, 16)
+	for i := range drs {
+		d, ok := b.DataRates[i]
+		switch {
+		case !ok:
+			drs[i] = DataRate{SpreadingFactor: -1}
+		case d.Modulation == common.Modulation_FSK:
+			drs[i] = DataRate{}
+		default:
+			drs[i] = DataRate{
+				SpreadingFactor: d.SpreadingFactor,
+				Bandwidth:       d.Bandwidth,
+				DownlinkOnly:    !d.Uplink,
+			}
+		}
+	}
+	return drs
+}
+
+// uplinkTXInfo returns the uplink TX info for the given frequency and
+// data-rate index.
+func (drs DataRates) uplinkTXInfo(freq uint32, dr int) (*gw.UplinkTXInfo, error) {
+	d, err := drs.get(dr)
+	if err != nil {
+		return nil, err
+	}
+
+	txInfo := gw.UplinkTXInfo{
+		Frequency: freq,
+	}
+	if d.SpreadingFactor == 0 {
+		txInfo.Modulation = common.Modulation_FSK
+		txInfo.ModulationInfo = &gw.UplinkTXInfo_FskModulationInfo{
+			FskModulationInfo: &gw.FSKModulationInfo{
+				Bitrate: FSKBitRate,
+			},
+		}
+	} else {
+		txInfo.Modulation = common.Modulation_LORA
+		txInfo.ModulationInfo = &gw.UplinkTXInfo_LoraModulationInfo{
+			LoraModulationInfo: &gw.LoRaModulationInfo{
+				Bandwidth:       uint32(d.Bandwidth),
+				SpreadingFactor: uint32(d.SpreadingFactor),
+				CodeRate:        "4/5",
+			},
+		}
+	}
+	return &txInfo, nil
+}
+
+// setDownlinkModulation sets the modulation of the TX info for the given
+// data-rate index.
+func (drs DataRates) setDownlinkModulation(txInfo *gw.DownlinkTXInfo, dr int) error {
+	d, err := drs.get(dr)
+	if err != nil {
+		return err
+	}
+
+	if d.SpreadingFactor == 0 {
+		txInfo.Modulation = common.Modulation_FSK
+		txInfo.ModulationInfo = &gw.DownlinkTXInfo_FskModulationInfo{
+			FskModulationInfo: &gw.FSKModulationInfo{
+				Bitrate: FSKBitRate,
+			},
+		}
+	} else {
+		txInfo.Modulation = common.Modulation_LORA
+		txInfo.ModulationInfo = &gw.DownlinkTXInfo_LoraModulationInfo{
+			LoraModulationInfo: &gw.LoRaModulationInfo{
+				Bandwidth:             uint32(d.Bandwidth),
+				SpreadingFactor:       uint32(d.SpreadingFactor),
+				CodeRate:              "4/5",
+				PolarizationInversion: true,
+			},
+		}
+	}
+	return nil
+}
+
+// index returns the data-rate index for the given TX info. For downlink,
+// downlink-only data-rates take precedence.
+func (drs DataRates) index(uplink bool, txInfo interface {
+	GetModulation() common.Modulation
+	GetLoraModulationInfo() *gw.LoRaModulationInfo
+}) (int, error) {
+	var sf, bw int
+	if txInfo.GetModulation() == common.Modulation_LORA {
+		modInfo := txInfo.GetLoraModulationInfo()
+		if modInfo == nil {
+			return 0, errors.New("basicstation: lora_modulation_info must not be nil")
+		}
+		sf, bw = int(modInfo.SpreadingFactor), int(modInfo.Bandwidth)
+	}
+
+	match := -1
+	for i, d := range drs {
+		if d.SpreadingFactor != sf || (sf != 0 && d.Bandwidth != bw) {
+			continue
+		}
+		if uplink && d.DownlinkOnly {
+			continue
+		}
+		if !uplink && d.DownlinkOnly {
+			return i, nil
+		}
+		if match == -1 {
+			match = i
+		}
+	}
+	if match == -1 {
+		return 0, fmt.Errorf("basicstation: no data-rate for %s SF%d BW%d", txInfo.GetModulation(), sf, bw)
+	}
+	return match, nil
+}
+
+func (drs DataRates) get(dr int) (DataRate, error) {
+	if dr < 0 || dr >= len(drs) || drs[dr].SpreadingFactor == -1 {
+		return DataRate{}, fmt.Errorf("basicstation: invalid data-rate: %d", dr)
+	}
+	return drs[dr], nil
+}
+
+// SX1301Conf implements the sx1301_conf configuration of a single board.
+type SX1301Conf struct {
+	Radio0      SX1301ConfRadio
+	Radio1      SX1301ConfRadio
+	ChanFSK     SX1301ConfChanFSK
+	ChanLoRaStd SX1301ConfChanLoRaStd
+	ChanMultiSF [8]SX1301ConfChanMultiSF
+}
+
+// SX1301ConfRadio implements the radio configuration.
+type SX1301ConfRadio struct {
+	Enable bool   `json:"enable"`
+	Freq   uint32 `json:"freq"`
+}
+
+// SX1301ConfChanMultiSF implements the multi-SF (LoRa 125 kHz) channel
+// configuration.
+type SX1301ConfChanMultiSF struct {
+	Enable bool  `json:"enable"`
+	Radio  int   `json:"radio"`
+	IF     int32 `json:"if"`
+}
+
+// SX1301ConfChanLoRaStd implements the single-SF LoRa channel configuration.
+type SX1301ConfChanLoRaStd struct {
+	Enable bool  `json:"enable"`
+	Radio  int   `json:"radio"`
+	IF     int32 `json:"if"`
+
+	// Bandwidth (Hz).
+	Bandwidth    uint32 `json:"bandwidth,omitempty"`
+	SpreadFactor uint32 `json:"spread_factor,omitempty"`
+}
+
+// SX1301ConfChanFSK implements the FSK channel configuration.
+type SX1301ConfChanFSK struct {
+	Enable bool  `json:"enable"`
+	Radio  int   `json:"radio"`
+	IF     int32 `json:"if"`
+
+	// Bandwidth (Hz).
+	Bandwidth uint32 `json:"bandwidth,omitempty"`
+	DataRate  uint32 `json:"datarate,omitempty"`
+}
+
+// MarshalJSON implements json.Marshaler.
+func (c SX1301Conf) MarshalJSON() ([]byte, error) {
+	m := map[string]interface{}{
+		"radio_0":       c.Radio0,
+		"radio_1":       c.Radio1,
+		"chan_FSK":      c.ChanFSK,
+		"chan_Lora_std": c.ChanLoRaStd,
+	}
+	for i, ch := range c.ChanMultiSF {
+		m[fmt.Sprintf("chan_multiSF_%d", i)] = ch
+	}
+	return json.Marshal(m)
+}
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (c *SX1301Conf) UnmarshalJSON(data []byte) error {
+	var m map[string]json.RawMessage
+	if err := json.Unmarshal(data, &m); err != nil {
+		return err
+	}
+
+	*c = SX1301Conf{}
+	fields := map[string]interface{}{
+		"radio_0":       &c.Radio0,
+		"radio_1":       &c.Radio1,
+		"chan_FSK":      &c.ChanFSK,
+		"chan_Lora_std": &c.ChanLoRaStd,
+	}
+	for i := range c.ChanMultiSF {
+		fields[fmt.Sprintf("chan_multiSF_%d", i)] = &c.ChanMultiSF[i]
+	}
+
+	for k, v := range fields {
+		raw, ok := m[k]
+		if !ok {
+			continue
+		}
+		if err := json.Unmarshal(raw, v); err != nil {
+			return fmt.Errorf("%s: %w", k, err)
+		}
+	}
+	return nil
+}
+
+// NewRouterConfig returns the router_config for the given region and
+// gateway configuration. The channels of every board are mapped to the
+// SX1301 multi-SF (LoRa 125 kHz), LoRa standard and FSK channels, and the
+// radio center frequencies are derived from the channel frequencies.
+func NewRouterConfig(region common.Region, conf *gw.GatewayConfiguration) (RouterConfig, error) {
+	b, err := band.Get(region)
+	if err != nil {
+		return RouterConfig{}, fmt.Errorf("basicstation: %w", err)
+	}
+
+	rc := RouterConfig{
+		MessageType: RouterConfigMessage,
+		Region:      stationRegions[region],
+		FreqRange:   [2]uint32{b.MinFrequency, b.MaxFrequency},
+		DRs:         NewDataRates(b),
+	}
+
+	boards := make(map[uint32][]*gw.ChannelConfiguration)
+	var maxBoard uint32
+	for _, ch := range conf.GetChannels() {
+		boards[ch.Board] = append(boards[ch.Board], ch)
+		if ch.Board > maxBoard {
+			maxBoard = ch.Board
+		}
+	}
+
+	for i := uint32(0); i <= maxBoard; i++ {
+		c, err := newSX1301Conf(boards[i])
+		if err != nil {
+			return RouterConfig{}, fmt.Errorf("basicstation: board %d: %w", i, err)
+		}
+		rc.SX1301Conf = append(rc.SX1301Conf, c)
+	}
+	rc.HWSpec = fmt.Sprintf("sx1301/%d", len(rc.SX1301Conf))
+
+	return rc, nil
+}
+
+// GatewayConfiguration returns the gateway configuration of the
+// router_config for the given gateway ID. The multi-SF channels are returned
+// with all the 125 kHz uplink spreading-factors of the DR table.
+func (rc RouterConfig) GatewayConfiguration(gatewayID lorawan.EUI64) *gw.GatewayConfiguration {
+	var multiSFs []uint32
+	for _, d := range rc.DRs {
+		if d.SpreadingFactor > 0 && d.Bandwidth == 125 && !d.DownlinkOnly {
+			multiSFs = append(multiSFs, uint32(d.SpreadingFactor))
+		}
+	}
+	sort.Slice(multiSFs, func(i, j int) bool { return multiSFs[i] < multiSFs[j] })
+
+	out := gw.GatewayConfiguration{
+		GatewayId: gatewayID.Bytes(),
+	}
+
+	for board, c := range rc.SX1301Conf {
+		radios := [2]uint32{c.Radio0.Freq, c.Radio1.Freq}
+		freq := func(radio int, ifreq int32) uint32 {
+			if radio < 0 || radio > 1 {
+				return 0
+			}
+			return uint32(int64(radios[radio]) + int64(ifreq))
+		}
+
+		for i, ch := range c.ChanMultiSF {
+			if !ch.Enable {
+				continue
+			}
+			out.Channels = append(out.Channels, &gw.ChannelConfiguration{
+				Frequency:  freq(ch.Radio, ch.IF),
+				Modulation: common.Modulation_LORA,
+				ModulationConfig: &gw.ChannelConfiguration_LoraModulationConfig{
+					LoraModulationConfig: &gw.LoRaModulationConfig{
+						Bandwidth:        125,
+						SpreadingFactors: append([]uint32(nil), multiSFs...),
+					},
+				},
+				Board:       uint32(board),
+				Demodulator: uint32(i),
+			})
+		}
+
+		if ch := c.ChanLoRaStd; ch.Enable {
+			out.Channels = append(out.Channels, &gw.ChannelConfiguration{
+				Frequency:  freq(ch.Radio, ch.IF),
+				Modulation: common.Modulation_LORA,
+				ModulationConfig: &gw.ChannelConfiguration_LoraModulationConfig{
+					LoraModulationConfig: &gw.LoRaModulationConfig{
+						Bandwidth:        ch.Bandwidth / 1000,
+						SpreadingFactors: []uint32{ch.SpreadFactor},
+					},
+				},
+				Board:       uint32(board),
+				Demodulator: 8,
+			})
+		}
+
+		if ch := c.ChanFSK; ch.Enable {
+			out.Channels = append(out.Channels, &gw.ChannelConfiguration{
+				Frequency:  freq(ch.Radio, ch.IF),
+				Modulation: common.Modulation_FSK,
+				ModulationConfig: &gw.ChannelConfiguration_FskModulationConfig{
+					FskModulationConfig: &gw.FSKModulationConfig{
+						Bandwidth: ch.Bandwidth / 1000,
+						Bitrate:   ch.DataRate,
+					},
+				},
+				Board:       uint32(board),
+				Demodulator: 9,
+			})
+		}
+	}
+
+	return &out
+}
+
+// radio contains the range (Hz) of possible center frequencies of a radio,
+// given the channels assigned to it.
+type radio struct {
+	enabled bool
+	min     uint32
+	max     uint32
+}
+
+// radioBandwidth returns the radio receive bandwidth (Hz) for a channel of
+// the given bandwidth (Hz).
+func radioBandwidth(bw uint32) uint32 {
+	switch {
+	case bw > 250000:
+		return 1100000
+	case bw > 125000:
+		return 1000000
+	default:
+		return RadioBandwidth
+	}
+}
+
+func newSX1301Conf(channels []*gw.ChannelConfiguration) (SX1301Conf, error) {
+	var c SX1301Conf
+
+	// Every channel defines the range of radio center frequencies which can
+	// receive it. Sorted by the end of these ranges, a radio is centered
+	// within the range of the first unassigned channel and all the
+	// following channels overlapping with it, which results in the minimum
+	// number of radios.
+	ranges := make(map[*gw.ChannelConfiguration]radio)
+	sorted := append([]*gw.ChannelConfiguration(nil), channels...)
+	for _, ch := range sorted {
+		bw := channelBandwidth(ch)
+		rbw := radioBandwidth(bw)
+		if bw > rbw {
+			return c, fmt.Errorf("channel %d Hz: unsupported bandwidth %d Hz", ch.Frequency, bw)
+		}
+		maxIF := (rbw - bw) / 2
+		ranges[ch] = radio{enabled: true, min: ch.Frequency - maxIF, max: ch.Frequency + maxIF}
+	}
+	sort.SliceStable(sorted, func(i, j int) bool { return ranges[sorted[i]].max < ranges[sorted[j]].max })
+
+	var radios [2]radio
+	var n int
+	assigned := make(map[*gw.ChannelConfiguration]int)
+	for _, ch := range sorted {
+		cr := ranges[ch]
+		if n > 0 && cr.min <= radios[n-1].max {
+			if r := &radios[n-1]; cr.min > r.min {
+				r.min = cr.min
+			}
+			assigned[ch] = n - 1
+			continue
+		}
+		if n == len(radios) {
+			return c, fmt.Errorf("channel %d Hz does not fit in the radio bandwidth", ch.Frequency)
+		}
+		radios[n] = cr
+		assigned[ch] = n
+		n++
+	}
+
+	center := func(r radio) uint32 {
+		return r.min + (r.max-r.min)/2
+	}
+	c.Radio0 = SX1301ConfRadio{Enable: radios[0].enabled, Freq: center(radios[0])}
+	c.Radio1 = SX1301ConfRadio{Enable: radios[1].enabled, Freq: center(radios[1])}
+	centers := [2]uint32{c.Radio0.Freq, c.Radio1.Freq}
+
+	var multiSF int
+	for _, ch := range channels {
+		r := assigned[ch]
+		ifreq := int32(int64(ch.Frequency) - int64(centers[r]))
+
+		switch mc := ch.ModulationConfig.(type) {
+		case *gw.ChannelConfiguration_LoraModulationConfig:
+			lmc := mc.LoraModulationConfig
+			if lmc.Bandwidth == 125 {
+				if multiSF == len(c.ChanMultiSF) {
+					return c, fmt.Errorf("more than %d multi-SF channels", len(c.ChanMultiSF))
+				}
+				c.ChanMultiSF[multiSF] = SX1301ConfChanMultiSF{Enable: true, Radio: r, IF: ifreq}
+				multiSF++
+				continue
+			}
+
+			if c.ChanLoRaStd.Enable {
+				return c, errors.New("more than one LoRa standard channel")
+			}
+			if len(lmc.SpreadingFactors) != 1 {
+				return c, fmt.Errorf("LoRa standard channel %d Hz must have exactly one spreading-factor", ch.Frequency)
+			}
+			c.ChanLoRaStd = SX1301ConfChanLoRaStd{
+				Enable:       true,
+				Radio:        r,
+				IF:           ifreq,
+				Bandwidth:    lmc.Bandwidth * 1000,
+				SpreadFactor: lmc.SpreadingFactors[0],
+			}
+		case *gw.ChannelConfiguration_FskModulationConfig:
+			if c.ChanFSK.Enable {
+				return c, errors.New("more than one FSK channel")
+			}
+			c.ChanFSK = SX1301ConfChanFSK{
+				Enable:    true,
+				Radio:     r,
+				IF:        ifreq,
+				Bandwidth: mc.FskModulationConfig.Bandwidth * 1000,
+				DataRate:  mc.FskModulationConfig.Bitrate,
+			}
+		default:
+			return c, fmt.Errorf("channel %d Hz: modulation_config must be set", ch.Frequency)
+		}
+	}
+
+	return c, nil
+}
+
+// channelBandwidth returns the bandwidth (Hz) of the channel.
+func channelBandwidth(ch *gw.ChannelConfiguration) uint32 {
+	switch mc := ch.ModulationConfig.(type) {
+	case *gw.ChannelConfiguration_LoraModulationConfig:
+		return mc.LoraModulationConfig.GetBandwidth() * 1000
+	case *gw.ChannelConfiguration_FskModulationConfig:
+		return mc.FskModulationConfig.GetBandwidth() * 1000
+	default:
+		return 0
+	}
+}
@@ -0,0 +1,329 @@
+package basicstation
+
+import (
+	"encoding/json"
+	"sort"
+	"testing"
+
+	"github.com/brocaar/chirpstack-api/go/common"
+	"github.com/brocaar/chirpstack-api/go/gw"
+)
+
+func loraChannel(freq, bw uint32, sfs ...uint32) *gw.ChannelConfiguration {
+	return &gw.ChannelConfiguration{
+		Frequency:  freq,
+		Modulation: common.Modulation_LORA,
+		ModulationConfig: &gw.ChannelConfiguration_LoraModulationConfig{
+			LoraModulationConfig: &gw.LoRaModulationConfig{
+				Bandwidth:        bw,
+				SpreadingFactors: sfs,
+			},
+		},
+	}
+}
+
+func fskChannel(freq, bw, bitrate uint32) *gw.ChannelConfiguration {
+	return &gw.ChannelConfiguration{
+		Frequency:  freq,
+		Modulation: common.Modulation_FSK,
+		ModulationConfig: &gw.ChannelConfiguration_FskModulationConfig{
+			FskModulationConfig: &gw.FSKModulationConfig{
+				Bandwidth: bw,
+				Bitrate:   bitrate,
+			},
+		},
+	}
+}
+
+func TestDataRates(t *testing.T) {
+	tests := []struct {
+		region   common.Region
+		expected string
+	}{
+		{
+			region: common.Region_EU868,
+			expected: `[
+				[12,125,0],[11,125,0],[10,125,0],[9,125,0],[8,125,0],[7,125,0],[7,250,0],[0,0,0],
+				[-1,0,0],[-1,0,0],[-1,0,0],[-1,0,0],[-1,0,0],[-1,0,0],[-1,0,0],[-1,0,0]
+			]`,
+		},
+		{
+			region: common.Region_US915,
+			expected: `[
+				[10,125,0],[9,125,0],[8,125,0],[7,125,0],[8,500,0],[-1,0,0],[-1,0,0],[-1,0,0],
+				[12,500,1],[11,500,1],[10,500,1],[9,500,1],[8,500,1],[7,500,1],[-1,0,0],[-1,0,0]
+			]`,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.region.String(), func(t *testing.T) {
+			b, err := json.Marshal(dataRates(t, test.region))
+			if err != nil {
+				t.Fatal(err)
+			}
+			assertJSON(t, test.expected, b)
+
+			var drs DataRates
+			if err := json.Unmarshal([]byte(test.expected), &drs); err != nil {
+				t.Fatal(err)
+			}
+			if b2, _ := json.Marshal(drs); string(b2) != string(b) {
+				t.Errorf("expected %s after unmarshal, got %s", b, b2)
+			}
+		})
+	}
+
+	// SF8/500kHz is DR4 uplink and DR12 downlink in US915.
+	drs := dataRates(t, common.Region_US915)
+	txInfo := &gw.UplinkTXInfo{
+		ModulationInfo: &gw.UplinkTXInfo_LoraModulationInfo{
+			LoraModulationInfo: &gw.LoRaModulationInfo{SpreadingFactor: 8, Bandwidth: 500},
+		},
+	}
+	if dr, err := drs.index(true, txInfo); err != nil || dr != 4 {
+		t.Errorf("expected uplink DR4, got DR%d (%v)", dr, err)
+	}
+	if dr, err := drs.index(false, txInfo); err != nil || dr != 12 {
+		t.Errorf("expected downlink DR12, got DR%d (%v)", dr, err)
+	}
+	if _, err := drs.get(5); err == nil {
+		t.Error("expected error for RFU DR5")
+	}
+}
+
+// The radio center frequencies are derived from the channels, such that
+// every channel is within the radio receive bandwidth (|IF| <= 400 kHz for
+// 125 kHz channels, 375 kHz for 250 kHz channels and 300 kHz for 500 kHz
+// channels).
+func TestNewRouterConfig(t *testing.T) {
+	tests := []struct {
+		name     string
+		region   common.Region
+		channels []*gw.ChannelConfiguration
+		expected string
+	}{
+		{
+			name:   "EU868",
+			region: common.Region_EU868,
+			channels: []*gw.ChannelConfiguration{
+				loraChannel(868100000, 125, 7, 8, 9, 10, 11, 12),
+				loraChannel(868300000, 125, 7, 8, 9, 10, 11, 12),
+				loraChannel(868500000, 125, 7, 8, 9, 10, 11, 12),
+				loraChannel(867100000, 125, 7, 8, 9, 10, 11, 12),
+				loraChannel(867300000, 125, 7, 8, 9, 10, 11, 12),
+				loraChannel(867500000, 125, 7, 8, 9, 10, 11, 12),
+				loraChannel(867700000, 125, 7, 8, 9, 10, 11, 12),
+				loraChannel(867900000, 125, 7, 8, 9, 10, 11, 12),
+				loraChannel(868300000, 250, 7),
+				fskChannel(868800000, 125, 50000),
+			},
+			expected: `{
+				"msgtype": "router_config",
+				"NetID": null,
+				"JoinEui": null,
+				"region": "EU863",
+				"hwspec": "sx1301/1",
+				"freq_range": [863000000, 870000000],
+				"DRs": [
+					[12,125,0],[11,125,0],[10,125,0],[9,125,0],[8,125,0],[7,125,0],[7,250,0],[0,0,0],
+					[-1,0,0],[-1,0,0],[-1,0,0],[-1,0,0],[-1,0,0],[-1,0,0],[-1,0,0],[-1,0,0]
+				],
+				"sx1301_conf": [{
+					"radio_0": {"enable": true, "freq": 867500000},
+					"radio_1": {"enable": true, "freq": 868450000},
+					"chan_FSK": {"enable": true, "radio": 1, "if": 350000, "bandwidth": 125000, "datarate": 50000},
+					"chan_Lora_std": {"enable": true, "radio": 1, "if": -150000, "bandwidth": 250000, "spread_factor": 7},
+					"chan_multiSF_0": {"enable": true, "radio": 1, "if": -350000},
+					"chan_multiSF_1": {"enable": true, "radio": 1, "if": -150000},
+					"chan_multiSF_2": {"enable": true, "radio": 1, "if": 50000},
+					"chan_multiSF_3": {"enable": true, "radio": 0, "if": -400000},
+					"chan_multiSF_4": {"enable": true, "radio": 0, "if": -200000},
+					"chan_multiSF_5": {"enable": true, "radio": 0, "if": 0},
+					"chan_multiSF_6": {"enable": true, "radio": 0, "if": 200000},
+					"chan_multiSF_7": {"enable": true, "radio": 0, "if": 400000}
+				}]
+			}`,
+		},
+		{
+			// Sub-band 2 (channels 8 - 15 and 65).
+			name:   "US915",
+			region: common.Region_US915,
+			channels: []*gw.ChannelConfiguration{
+				loraChannel(903900000, 125, 7, 8, 9, 10),
+				loraChannel(904100000, 125, 7, 8, 9, 10),
+				loraChannel(904300000, 125, 7, 8, 9, 10),
+				loraChannel(904500000, 125, 7, 8, 9, 10),
+				loraChannel(904700000, 125, 7, 8, 9, 10),
+				loraChannel(904900000, 125, 7, 8, 9, 10),
+				loraChannel(905100000, 125, 7, 8, 9, 10),
+				loraChannel(905300000, 125, 7, 8, 9, 10),
+				loraChannel(904600000, 500, 8),
+			},
+			expected: `{
+				"msgtype": "router_config",
+				"NetID": null,
+				"JoinEui": null,
+				"region": "US902",
+				"hwspec": "sx1301/1",
+				"freq_range": [902000000, 928000000],
+				"DRs": [
+					[10,125,0],[9,125,0],[8,125,0],[7,125,0],[8,500,0],[-1,0,0],[-1,0,0],[-1,0,0],
+					[12,500,1],[11,500,1],[10,500,1],[9,500,1],[8,500,1],[7,500,1],[-1,0,0],[-1,0,0]
+				],
+				"sx1301_conf": [{
+					"radio_0": {"enable": true, "freq": 904300000},
+					"radio_1": {"enable": true, "freq": 905100000},
+					"chan_FSK": {"enable": false, "radio": 0, "if": 0},
+					"chan_Lora_std": {"enable": true, "radio": 0, "if": 300000, "bandwidth": 500000, "spread_factor": 8},
+					"chan_multiSF_0": {"enable": true, "radio": 0, "if": -400000},
+					"chan_multiSF_1": {"enable": true, "radio": 0, "if": -200000},
+					"chan_multiSF_2": {"enable": true, "radio": 0, "if": 0},
+					"chan_multiSF_3": {"enable": true, "radio": 0, "if": 200000},
+					"chan_multiSF_4": {"enable": true, "radio": 0, "if": 400000},
+					"chan_multiSF_5": {"enable": true, "radio": 1, "if": -200000},
+					"chan_multiSF_6": {"enable": true, "radio": 1, "if": 0},
+					"chan_multiSF_7": {"enable": true, "radio": 1, "if": 200000}
+				}]
+			}`,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			rc, err := NewRouterConfig(test.region, &gw.GatewayConfiguration{Channels: test.channels})
+			if err != nil {
+				t.Fatal(err)
+			}
+			b, err := json.Marshal(rc)
+			if err != nil {
+				t.Fatal(err)
+			}
+			assertJSON(t, test.expected, b)
+
+			// The router_config must result in the same channels.
+			var rc2 RouterConfig
+			if err := json.Unmarshal([]byte(test.expected), &rc2); err != nil {
+				t.Fatal(err)
+			}
+			conf := rc2.GatewayConfiguration(gatewayID)
+			if exp, got := channelFrequencies(test.channels), channelFrequencies(conf.Channels); !equalUint32s(exp, got) {
+				t.Errorf("expected channels %v, got %v", exp, got)
+			}
+		})
+	}
+}
+
+func TestNewRouterConfigErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		channels []*gw.ChannelConfiguration
+	}{
+		{
+			// Three radios would be needed.
+			name: "too wide",
+			channels: []*gw.ChannelConfiguration{
+				loraChannel(863100000, 125, 7),
+				loraChannel(865100000, 125, 7),
+				loraChannel(867100000, 125, 7),
+			},
+		},
+		{
+			// 866.2 MHz needs a radio of its own, 867.1 and 868.0 MHz are
+			// too far apart for the second radio.
+			name: "too wide for the second radio",
+			channels: []*gw.ChannelConfiguration{
+				loraChannel(867100000, 125, 7),
+				loraChannel(867900000, 125, 7),
+				loraChannel(866200000, 125, 7),
+				loraChannel(868000000, 125, 7),
+			},
+		},
+		{
+			name: "two LoRa standard channels",
+			channels: []*gw.ChannelConfiguration{
+				loraChannel(868300000, 250, 7),
+				loraChannel(868500000, 250, 7),
+			},
+		},
+		{
+			name: "multiple spreading-factors on a LoRa standard channel",
+			channels: []*gw.ChannelConfiguration{
+				loraChannel(868300000, 250, 7, 8),
+			},
+		},
+		{
+			name: "nine multi-SF channels",
+			channels: []*gw.ChannelConfiguration{
+				loraChannel(867100000, 125, 7), loraChannel(867200000, 125, 7), loraChannel(867300000, 125, 7),
+				loraChannel(867400000, 125, 7), loraChannel(867500000, 125, 7), loraChannel(867600000, 125, 7),
+				loraChannel(867700000, 125, 7), loraChannel(867800000, 125, 7), loraChannel(867900000, 125, 7),
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if _, err := NewRouterConfig(common.Region_EU868, &gw.GatewayConfiguration{Channels: test.channels}); err == nil {
+				t.Error("expected error")
+			}
+		})
+	}
+}
+
+func TestRouterConfigGatewayConfiguration(t *testing.T) {
+	rc, err := NewRouterConfig(common.Region_EU868, &gw.GatewayConfiguration{
+		Channels: []*gw.ChannelConfiguration{
+			loraChannel(868100000, 125, 7, 8, 9, 10, 11, 12),
+			loraChannel(868300000, 250, 7),
+			fskChannel(868800000, 125, 50000),
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	conf := rc.GatewayConfiguration(gatewayID)
+	if len(conf.Channels) != 3 {
+		t.Fatalf("expected 3 channels, got %d", len(conf.Channels))
+	}
+
+	multiSF := conf.Channels[0]
+	if multiSF.Frequency != 868100000 || multiSF.Demodulator != 0 {
+		t.Errorf("unexpected multi-SF channel: %s", multiSF)
+	}
+	if sfs := multiSF.GetLoraModulationConfig().GetSpreadingFactors(); !equalUint32s(sfs, []uint32{7, 8, 9, 10, 11, 12}) {
+		t.Errorf("expected SF7 - SF12, got %v", sfs)
+	}
+
+	loRaStd := conf.Channels[1]
+	if loRaStd.Frequency != 868300000 || loRaStd.Demodulator != 8 || loRaStd.GetLoraModulationConfig().GetBandwidth() != 250 {
+		t.Errorf("unexpected LoRa standard channel: %s", loRaStd)
+	}
+
+	fsk := conf.Channels[2]
+	if fsk.Frequency != 868800000 || fsk.Demodulator != 9 || fsk.GetFskModulationConfig().GetBitrate() != 50000 {
+		t.Errorf("unexpected FSK channel: %s", fsk)
+	}
+}
+
+func channelFrequencies(channels []*gw.ChannelConfiguration) []uint32 {
+	var out []uint32
+	for _, ch := range channels {
+		out = append(out, ch.Frequency)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i] < out[j] })
+	return out
+}
+
+func equalUint32s(a, b []uint32) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
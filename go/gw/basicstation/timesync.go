@@ -0,0 +1,42 @@
+package basicstation
+
+import (
+	"time"
+
+	"github.com/brocaar/chirpstack-api/go/gw/gpstime"
+)
+
+// TimeSyncRequest implements the timesync message sent by Station.
+type TimeSyncRequest struct {
+	MessageType MessageType `json:"msgtype"`
+
+	// TXTime holds the Station local time (microseconds).
+	TXTime float64 `json:"txtime"`
+}
+
+// TimeSyncResponse implements the timesync response. It either answers a
+// TimeSyncRequest (TXTime set) or transfers the GPS time of a given xtime
+// (XTime set).
+type TimeSyncResponse struct {
+	MessageType MessageType `json:"msgtype"`
+	TXTime      float64     `json:"txtime,omitempty"`
+	XTime       int64       `json:"xtime,omitempty"`
+
+	// GPSTime holds the time since GPS epoch (microseconds).
+	GPSTime int64 `json:"gpstime"`
+}
+
+// NewTimeSyncResponse returns the response to the given timesync request,
+// containing the given server time.
+func NewTimeSyncResponse(req TimeSyncRequest, now time.Time) TimeSyncResponse {
+	return TimeSyncResponse{
+		MessageType: TimeSyncMessage,
+		TXTime:      req.TXTime,
+		GPSTime:     int64(gpstime.FromTime(now) / time.Microsecond),
+	}
+}
+
+// Time returns the UTC time of the GPS time.
+func (r TimeSyncResponse) Time() time.Time {
+	return gpstime.ToTime(time.Duration(r.GPSTime) * time.Microsecond)
+}
@@ -0,0 +1,309 @@
+package basicstation
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/golang/protobuf/ptypes"
+
+	"github.com/brocaar/chirpstack-api/go/gw"
+	"github.com/brocaar/chirpstack-api/go/gw/gpstime"
+	"github.com/brocaar/chirpstack-api/go/lorawan"
+)
+
+// LoRaWAN message types (MHDR MType).
+const (
+	mTypeJoinRequest       = 0x00
+	mTypeUnconfirmedDataUp = 0x02
+	mTypeConfirmedDataUp   = 0x04
+	mTypeProprietary       = 0x07
+)
+
+// PHYPayload lengths.
+const (
+	joinRequestPayloadLen   = 23
+	minUplinkDataPayloadLen = 12
+)
+
+// RadioMetaData contains the radio meta-data of an uplink message.
+type RadioMetaData struct {
+	DR        int                 `json:"DR"`
+	Frequency uint32              `json:"Freq"`
+	UpInfo    RadioMetaDataUpInfo `json:"upinfo"`
+}
+
+// RadioMetaDataUpInfo contains the upinfo object.
+type RadioMetaDataUpInfo struct {
+	RCtx  int64 `json:"rctx"`
+	XTime int64 `json:"xtime"`
+
+	// GPSTime holds the time since GPS epoch (microseconds), 0 when unknown.
+	GPSTime int64 `json:"gpstime"`
+
+	// FTS holds the fine timestamp (nanoseconds within the GPS second), -1
+	// when unavailable.
+	FTS int64 `json:"fts"`
+
+	RSSI float64 `json:"rssi"`
+	SNR  float64 `json:"snr"`
+
+	// RXTime holds the UTC time of the reception (seconds since Unix epoch).
+	RXTime float64 `json:"rxtime"`
+}
+
+// UplinkDataFrame implements the updf message.
+type UplinkDataFrame struct {
+	RadioMetaData
+
+	MessageType MessageType `json:"msgtype"`
+	MHDR        uint8       `json:"MHdr"`
+	DevAddr     int32       `json:"DevAddr"`
+	FCtrl       uint8       `json:"FCtrl"`
+	FCnt        uint16      `json:"FCnt"`
+	FOpts       HEXBytes    `json:"FOpts"`
+
+	// FPort holds the FPort, -1 when the frame does not contain a FPort.
+	FPort      int      `json:"FPort"`
+	FRMPayload HEXBytes `json:"FRMPayload"`
+	MIC        int32    `json:"MIC"`
+}
+
+// JoinRequest implements the jreq message.
+type JoinRequest struct {
+	RadioMetaData
+
+	MessageType MessageType `json:"msgtype"`
+	MHDR        uint8       `json:"MHdr"`
+	JoinEUI     EUI64       `json:"JoinEui"`
+	DevEUI      EUI64       `json:"DevEui"`
+	DevNonce    uint16      `json:"DevNonce"`
+	MIC         int32       `json:"MIC"`
+}
+
+// ProprietaryDataFrame implements the propdf message. The FRMPayload holds
+// the complete PHYPayload.
+type ProprietaryDataFrame struct {
+	RadioMetaData
+
+	MessageType MessageType `json:"msgtype"`
+	FRMPayload  HEXBytes    `json:"FRMPayload"`
+}
+
+// UplinkFrame returns the uplink frame of the updf message.
+func (m UplinkDataFrame) UplinkFrame(gatewayID lorawan.EUI64, drs DataRates) (*gw.UplinkFrame, error) {
+	b := make([]byte, 0, minUplinkDataPayloadLen+len(m.FOpts)+1+len(m.FRMPayload))
+	b = append(b, m.MHDR)
+	b = appendUint32(b, uint32(m.DevAddr))
+	b = append(b, m.FCtrl)
+	b = append(b, byte(m.FCnt), byte(m.FCnt>>8))
+	b = append(b, m.FOpts...)
+	if m.FPort >= 0 {
+		b = append(b, byte(m.FPort))
+	}
+	b = append(b, m.FRMPayload...)
+	b = appendUint32(b, uint32(m.MIC))
+
+	return m.RadioMetaData.uplinkFrame(gatewayID, drs, b)
+}
+
+// UplinkFrame returns the uplink frame of the jreq message.
+func (m JoinRequest) UplinkFrame(gatewayID lorawan.EUI64, drs DataRates) (*gw.UplinkFrame, error) {
+	b := make([]byte, 0, joinRequestPayloadLen)
+	b = append(b, m.MHDR)
+	b = append(b, reverse(m.JoinEUI[:])...)
+	b = append(b, reverse(m.DevEUI[:])...)
+	b = append(b, byte(m.DevNonce), byte(m.DevNonce>>8))
+	b = appendUint32(b, uint32(m.MIC))
+
+	return m.RadioMetaData.uplinkFrame(gatewayID, drs, b)
+}
+
+// UplinkFrame returns the uplink frame of the propdf message.
+func (m ProprietaryDataFrame) UplinkFrame(gatewayID lorawan.EUI64, drs DataRates) (*gw.UplinkFrame, error) {
+	return m.RadioMetaData.uplinkFrame(gatewayID, drs, m.FRMPayload)
+}
+
+// NewUplinkMessage returns the Station uplink message for the given uplink
+// frame, based on the MType of the PHYPayload. It returns an
+// *UplinkDataFrame, *JoinRequest or *ProprietaryDataFrame.
+func NewUplinkMessage(frame *gw.UplinkFrame, drs DataRates) (interface{}, error) {
+	rmd, err := newRadioMetaData(frame, drs)
+	if err != nil {
+		return nil, err
+	}
+
+	b := frame.PhyPayload
+	if len(b) == 0 {
+		return nil, errors.New("basicstation: phy_payload must not be empty")
+	}
+
+	switch mType := b[0] >> 5; mType {
+	case mTypeJoinRequest:
+		if len(b) != joinRequestPayloadLen {
+			return nil, fmt.Errorf("basicstation: join-request must be exactly %d bytes, got %d", joinRequestPayloadLen, len(b))
+		}
+		m := JoinRequest{
+			RadioMetaData: rmd,
+			MessageType:   JoinRequestMessage,
+			MHDR:          b[0],
+			DevNonce:      binary.LittleEndian.Uint16(b[17:19]),
+			MIC:           int32(binary.LittleEndian.Uint32(b[19:23])),
+		}
+		copy(m.JoinEUI[:], reverse(b[1:9]))
+		copy(m.DevEUI[:], reverse(b[9:17]))
+		return &m, nil
+	case mTypeUnconfirmedDataUp, mTypeConfirmedDataUp:
+		if len(b) < minUplinkDataPayloadLen {
+			return nil, fmt.Errorf("basicstation: uplink data frame must be at least %d bytes, got %d", minUplinkDataPayloadLen, len(b))
+		}
+		fOptsLen := int(b[5] & 0x0f)
+		if len(b) < minUplinkDataPayloadLen+fOptsLen {
+			return nil, errors.New("basicstation: uplink data frame does not contain all FOpts")
+		}
+
+		m := UplinkDataFrame{
+			RadioMetaData: rmd,
+			MessageType:   UplinkDataFrameMessage,
+			MHDR:          b[0],
+			DevAddr:       int32(binary.LittleEndian.Uint32(b[1:5])),
+			FCtrl:         b[5],
+			FCnt:          binary.LittleEndian.Uint16(b[6:8]),
+			FOpts:         HEXBytes(append([]byte{}, b[8:8+fOptsLen]...)),
+			FPort:         -1,
+			FRMPayload:    HEXBytes{},
+			MIC:           int32(binary.LittleEndian.Uint32(b[len(b)-4:])),
+		}
+		if rest := b[8+fOptsLen : len(b)-4]; len(rest) > 0 {
+			m.FPort = int(rest[0])
+			m.FRMPayload = append(m.FRMPayload, rest[1:]...)
+		}
+		return &m, nil
+	case mTypeProprietary:
+		return &ProprietaryDataFrame{
+			RadioMetaData: rmd,
+			MessageType:   ProprietaryDataFrameMessage,
+			FRMPayload:    HEXBytes(b),
+		}, nil
+	default:
+		return nil, fmt.Errorf("basicstation: unsupported mtype: %d", mType)
+	}
+}
+
+func (rmd RadioMetaData) uplinkFrame(gatewayID lorawan.EUI64, drs DataRates, phyPayload []byte) (*gw.UplinkFrame, error) {
+	txInfo, err := drs.uplinkTXInfo(rmd.Frequency, rmd.DR)
+	if err != nil {
+		return nil, err
+	}
+
+	up := rmd.UpInfo
+	rxInfo := gw.UplinkRXInfo{
+		GatewayId: gatewayID.Bytes(),
+		Rssi:      int32(math.Round(up.RSSI)),
+		LoraSnr:   up.SNR,
+		Context:   contextFromXTime(up.XTime, up.RCtx),
+	}
+
+	if up.RXTime != 0 {
+		sec, frac := math.Modf(up.RXTime)
+		if rxInfo.Time, err = ptypes.TimestampProto(time.Unix(int64(sec), int64(math.Round(frac*1e6))*int64(time.Microsecond)).UTC()); err != nil {
+			return nil, fmt.Errorf("basicstation: rxtime error: %w", err)
+		}
+	}
+
+	if up.GPSTime != 0 {
+		gpsTime := time.Duration(up.GPSTime) * time.Microsecond
+		rxInfo.TimeSinceGpsEpoch = ptypes.DurationProto(gpsTime)
+
+		if up.FTS >= 0 {
+			ts, err := ptypes.TimestampProto(gpstime.ToTime(gpsTime.Truncate(time.Second) + time.Duration(up.FTS)))
+			if err != nil {
+				return nil, fmt.Errorf("basicstation: fine timestamp error: %w", err)
+			}
+			rxInfo.FineTimestampType = gw.FineTimestampType_PLAIN
+			rxInfo.FineTimestamp = &gw.UplinkRXInfo_PlainFineTimestamp{
+				PlainFineTimestamp: &gw.PlainFineTimestamp{
+					Time: ts,
+				},
+			}
+		}
+	}
+
+	return &gw.UplinkFrame{
+		PhyPayload: phyPayload,
+		TxInfo:     txInfo,
+		RxInfo:     &rxInfo,
+	}, nil
+}
+
+func newRadioMetaData(frame *gw.UplinkFrame, drs DataRates) (RadioMetaData, error) {
+	txInfo := frame.GetTxInfo()
+	rxInfo := frame.GetRxInfo()
+	if txInfo == nil || rxInfo == nil {
+		return RadioMetaData{}, errors.New("basicstation: tx_info and rx_info must not be nil")
+	}
+
+	dr, err := drs.index(true, txInfo)
+	if err != nil {
+		return RadioMetaData{}, err
+	}
+
+	rmd := RadioMetaData{
+		DR:        dr,
+		Frequency: txInfo.Frequency,
+		UpInfo: RadioMetaDataUpInfo{
+			FTS:  -1,
+			RSSI: float64(rxInfo.Rssi),
+			SNR:  rxInfo.LoraSnr,
+		},
+	}
+
+	if len(rxInfo.Context) != 0 {
+		if rmd.UpInfo.XTime, rmd.UpInfo.RCtx, err = xtimeFromContext(rxInfo.Context); err != nil {
+			return RadioMetaData{}, err
+		}
+	}
+	if rxInfo.Time != nil {
+		t, err := ptypes.Timestamp(rxInfo.Time)
+		if err != nil {
+			return RadioMetaData{}, fmt.Errorf("basicstation: time error: %w", err)
+		}
+		rmd.UpInfo.RXTime = float64(t.UnixNano()) / float64(time.Second)
+	}
+	if rxInfo.TimeSinceGpsEpoch != nil {
+		d, err := ptypes.Duration(rxInfo.TimeSinceGpsEpoch)
+		if err != nil {
+			return RadioMetaData{}, fmt.Errorf("basicstation: time_since_gps_epoch error: %w", err)
+		}
+		rmd.UpInfo.GPSTime = int64(d / time.Microsecond)
+	}
+	if ft := rxInfo.GetPlainFineTimestamp(); ft != nil {
+		t, err := ptypes.Timestamp(ft.GetTime())
+		if err != nil {
+			return RadioMetaData{}, fmt.Errorf("basicstation: plain fine timestamp error: %w", err)
+		}
+		d := gpstime.FromTime(t)
+		rmd.UpInfo.FTS = int64(d % time.Second)
+		if rmd.UpInfo.GPSTime == 0 {
+			rmd.UpInfo.GPSTime = int64(d / time.Microsecond)
+		}
+	}
+
+	return rmd, nil
+}
+
+func appendUint32(b []byte, v uint32) []byte {
+	return append(b, byte(v), byte(v>>8), byte(v>>16), byte(v>>24))
+}
+
+// reverse returns a reversed copy of b (EUIs are little-endian encoded
+// within the PHYPayload).
+func reverse(b []byte) []byte {
+	out := make([]byte, len(b))
+	for i := range b {
+		out[len(b)-1-i] = b[i]
+	}
+	return out
+}
@@ -0,0 +1,179 @@
+// Package command executes gateway commands (GatewayCommandExecRequest) and
+// correlates the GatewayCommandExecResponse through the exec ID. The
+// requests are sent and the responses are received through a Transport, e.g.
+// the MQTTTransport or, for tests, the MemoryTransport:
+//
+//	executor := command.NewExecutor(transport, command.WithTimeout(30*time.Second))
+//	res, err := executor.Exec(ctx, gatewayID, "reboot", nil, nil)
+package command
+
+import (
+	"context"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/brocaar/chirpstack-api/go/gw"
+	"github.com/brocaar/chirpstack-api/go/lorawan"
+)
+
+// DefaultTimeout defines the default timeout for receiving the response.
+const DefaultTimeout = 10 * time.Second
+
+// Errors.
+var (
+	ErrTimeout       = errors.New("command: timeout waiting for response")
+	ErrUnknownExecID = errors.New("command: unknown exec id")
+)
+
+// Transport sends the exec requests to the gateways and passes the received
+// exec responses to the response handler.
+type Transport interface {
+	// Send sends the exec request.
+	Send(ctx context.Context, req *gw.GatewayCommandExecRequest) error
+
+	// SetResponseHandler sets the handler which must be called for every
+	// received exec response.
+	SetResponseHandler(fn func(resp *gw.GatewayCommandExecResponse))
+}
+
+// Result contains the result of an executed command.
+type Result struct {
+	GatewayID lorawan.EUI64
+	ExecID    []byte
+	Stdout    []byte
+	Stderr    []byte
+
+	// Error holds the error returned by the gateway, e.g. when the command
+	// exited with a non-zero exit code.
+	Error string
+}
+
+// Err returns the error returned by the gateway as error, or nil.
+func (r Result) Err() error {
+	if r.Error == "" {
+		return nil
+	}
+	return fmt.Errorf("command: gateway %s: %s", r.GatewayID, r.Error)
+}
+
+// Option configures the Executor.
+type Option func(*Executor)
+
+// WithTimeout sets the timeout for receiving the response.
+func WithTimeout(d time.Duration) Option {
+	return func(e *Executor) {
+		e.timeout = d
+	}
+}
+
+// Executor executes gateway commands.
+type Executor struct {
+	transport Transport
+	timeout   time.Duration
+
+	mu      sync.Mutex
+	pending map[string]chan *gw.GatewayCommandExecResponse
+}
+
+// NewExecutor returns a new Executor using the given transport.
+func NewExecutor(transport Transport, opts ...Option) *Executor {
+	e := &Executor{
+		transport: transport,
+		timeout:   DefaultTimeout,
+		pending:   make(map[string]chan *gw.GatewayCommandExecResponse),
+	}
+	for _, o := range opts {
+		o(e)
+	}
+
+	transport.SetResponseHandler(func(resp *gw.GatewayCommandExecResponse) {
+		_ = e.HandleResponse(resp)
+	})
+
+	return e
+}
+
+// Exec executes the command on the given gateway and waits for the
+// response. The command must be configured at the gateway side. The returned
+// error is only set on local errors (e.g. ErrTimeout), the error returned by
+// the gateway is set in the Result.
+func (e *Executor) Exec(ctx context.Context, gatewayID lorawan.EUI64, command string, stdin []byte, env map[string]string) (Result, error) {
+	execID, err := newExecID()
+	if err != nil {
+		return Result{}, err
+	}
+
+	ch := make(chan *gw.GatewayCommandExecResponse, 1)
+	key := string(execID)
+
+	e.mu.Lock()
+	e.pending[key] = ch
+	e.mu.Unlock()
+
+	defer func() {
+		e.mu.Lock()
+		delete(e.pending, key)
+		e.mu.Unlock()
+	}()
+
+	ctx, cancel := context.WithTimeout(ctx, e.timeout)
+	defer cancel()
+
+	if err := e.transport.Send(ctx, &gw.GatewayCommandExecRequest{
+		GatewayId:   gatewayID.Bytes(),
+		Command:     command,
+		ExecId:      execID,
+		Stdin:       stdin,
+		Environment: env,
+	}); err != nil {
+		return Result{}, fmt.Errorf("command: send request error: %w", err)
+	}
+
+	select {
+	case resp := <-ch:
+		return Result{
+			GatewayID: gatewayID,
+			ExecID:    execID,
+			Stdout:    resp.Stdout,
+			Stderr:    resp.Stderr,
+			Error:     resp.Error,
+		}, nil
+	case <-ctx.Done():
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			return Result{}, ErrTimeout
+		}
+		return Result{}, ctx.Err()
+	}
+}
+
+// HandleResponse passes the response to the pending Exec call with the same
+// exec ID. It returns ErrUnknownExecID when there is no such call (e.g. it
+// timed out).
+func (e *Executor) HandleResponse(resp *gw.GatewayCommandExecResponse) error {
+	e.mu.Lock()
+	ch, ok := e.pending[string(resp.GetExecId())]
+	if ok {
+		delete(e.pending, string(resp.GetExecId()))
+	}
+	e.mu.Unlock()
+
+	if !ok {
+		return ErrUnknownExecID
+	}
+	ch <- resp
+	return nil
+}
+
+// newExecID returns a random (version 4) UUID.
+func newExecID() ([]byte, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return nil, fmt.Errorf("command: generate exec id error: %w", err)
+	}
+	b[6] = (b[6] & 0x0f) | 0x40
+	b[8] = (b[8] & 0x3f) | 0x80
+	return b, nil
+}
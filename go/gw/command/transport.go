@@ -0,0 +1,153 @@
+package command
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/golang/protobuf/proto"
+
+	"github.com/brocaar/chirpstack-api/go/gw"
+	"github.com/brocaar/chirpstack-api/go/gw/mqtt"
+	"github.com/brocaar/chirpstack-api/go/lorawan"
+)
+
+// MemoryHandler handles an exec request within the MemoryTransport. A nil
+// response simulates a gateway which does not respond.
+type MemoryHandler func(ctx context.Context, req *gw.GatewayCommandExecRequest) *gw.GatewayCommandExecResponse
+
+// MemoryTransport implements an in-memory Transport, for testing.
+type MemoryTransport struct {
+	mu       sync.Mutex
+	handlers map[lorawan.EUI64]MemoryHandler
+	requests []*gw.GatewayCommandExecRequest
+	respond  func(*gw.GatewayCommandExecResponse)
+}
+
+// NewMemoryTransport returns a new MemoryTransport.
+func NewMemoryTransport() *MemoryTransport {
+	return &MemoryTransport{
+		handlers: make(map[lorawan.EUI64]MemoryHandler),
+	}
+}
+
+// SetHandler sets the handler for the given gateway ID.
+func (t *MemoryTransport) SetHandler(gatewayID lorawan.EUI64, h MemoryHandler) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.handlers[gatewayID] = h
+}
+
+// Requests returns the sent requests.
+func (t *MemoryTransport) Requests() []*gw.GatewayCommandExecRequest {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	out := make([]*gw.GatewayCommandExecRequest, len(t.requests))
+	for i, req := range t.requests {
+		out[i] = proto.Clone(req).(*gw.GatewayCommandExecRequest)
+	}
+	return out
+}
+
+// Send implements Transport. The handler of the gateway is called
+// asynchronously. Its response is returned with the exec ID and gateway ID of
+// the request.
+func (t *MemoryTransport) Send(ctx context.Context, req *gw.GatewayCommandExecRequest) error {
+	gatewayID, err := lorawan.EUI64FromBytes(req.GatewayId)
+	if err != nil {
+		return err
+	}
+
+	t.mu.Lock()
+	h, ok := t.handlers[gatewayID]
+	respond := t.respond
+	t.requests = append(t.requests, proto.Clone(req).(*gw.GatewayCommandExecRequest))
+	t.mu.Unlock()
+
+	if !ok {
+		return fmt.Errorf("no handler for gateway %s", gatewayID)
+	}
+
+	req = proto.Clone(req).(*gw.GatewayCommandExecRequest)
+	go func() {
+		resp := h(ctx, req)
+		if resp == nil || respond == nil {
+			return
+		}
+		resp.GatewayId = req.GatewayId
+		resp.ExecId = req.ExecId
+		respond(resp)
+	}()
+
+	return nil
+}
+
+// SetResponseHandler implements Transport.
+func (t *MemoryTransport) SetResponseHandler(fn func(*gw.GatewayCommandExecResponse)) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.respond = fn
+}
+
+// PublishFunc publishes the payload on the given MQTT topic.
+type PublishFunc func(ctx context.Context, topic string, payload []byte) error
+
+// MQTTTransport implements a Transport using the gateway MQTT topics. The
+// requests are published using the PublishFunc, the exec events received by
+// the MQTT client must be passed to HandleEvent.
+type MQTTTransport struct {
+	codec   *mqtt.Codec
+	publish PublishFunc
+
+	mu      sync.Mutex
+	respond func(*gw.GatewayCommandExecResponse)
+}
+
+// NewMQTTTransport returns a new MQTTTransport.
+func NewMQTTTransport(codec *mqtt.Codec, publish PublishFunc) *MQTTTransport {
+	return &MQTTTransport{
+		codec:   codec,
+		publish: publish,
+	}
+}
+
+// Send implements Transport.
+func (t *MQTTTransport) Send(ctx context.Context, req *gw.GatewayCommandExecRequest) error {
+	gatewayID, err := lorawan.EUI64FromBytes(req.GatewayId)
+	if err != nil {
+		return err
+	}
+	topic, payload, err := t.codec.EncodeCommand(gatewayID, req)
+	if err != nil {
+		return err
+	}
+	return t.publish(ctx, topic, payload)
+}
+
+// SetResponseHandler implements Transport.
+func (t *MQTTTransport) SetResponseHandler(fn func(*gw.GatewayCommandExecResponse)) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.respond = fn
+}
+
+// HandleEvent handles a gateway event received by the MQTT client. Events
+// other than exec events are ignored.
+func (t *MQTTTransport) HandleEvent(topic string, payload []byte) error {
+	t.mu.Lock()
+	respond := t.respond
+	t.mu.Unlock()
+
+	if respond == nil {
+		return errors.New("command: no response handler")
+	}
+
+	return t.codec.HandleEvent(topic, payload, mqtt.EventHandlers{
+		Exec: func(gatewayID lorawan.EUI64, resp *gw.GatewayCommandExecResponse) error {
+			respond(resp)
+			return nil
+		},
+	})
+}
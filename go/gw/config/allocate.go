@@ -0,0 +1,328 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/brocaar/chirpstack-api/go/band"
+	"github.com/brocaar/chirpstack-api/go/common"
+	"github.com/brocaar/chirpstack-api/go/gw"
+)
+
+// channelKind defines the demodulator kind used by a channel.
+type channelKind int
+
+const (
+	multiSF channelKind = iota
+	loRaStd
+	fsk
+)
+
+// channel contains a channel to allocate.
+type channel struct {
+	kind channelKind
+	freq uint32
+
+	// bandwidth (Hz).
+	bandwidth uint32
+
+	conf *gw.ChannelConfiguration
+}
+
+// board contains the allocation state of a board.
+type board struct {
+	radios  [2]radio
+	multiSF int
+	loRaStd bool
+	fsk     bool
+}
+
+// radio contains the end (Hz) of the range of possible center frequencies
+// of a radio, given the channels assigned to it.
+type radio struct {
+	used bool
+	max  int64
+}
+
+// allocation contains the state of allocate.
+type allocation struct {
+	boards []board
+	chans  []channel
+
+	// ranges holds the range (Hz) of radio center frequencies which can
+	// receive the channel, order holds the channel indices sorted by the end
+	// of this range.
+	ranges [][2]int64
+	order  []int
+
+	assigned []int
+	failed   map[string]bool
+	maxDepth int
+}
+
+// allocate assigns the board and demodulator of the channels, which must be
+// sorted by frequency (the multi-SF demodulators are numbered in this
+// order).
+//
+// The channels are allocated in the order of the end of their range of
+// possible radio center frequencies. All the channels assigned to a radio
+// then share a center frequency when the range of every channel starts
+// before the end of the range of the first channel of the radio. When a
+// channel can be allocated to multiple boards, every board is tried
+// (backtracking), so that channels which fit the hardware are never
+// rejected.
+func allocate(hw Hardware, chans []channel) error {
+	a := allocation{
+		boards:   make([]board, hw.Boards),
+		chans:    chans,
+		assigned: make([]int, len(chans)),
+		failed:   make(map[string]bool),
+	}
+
+	for i, c := range chans {
+		rbw, ok := hw.RadioBandwidths[c.bandwidth/1000]
+		if !ok || rbw < c.bandwidth {
+			return fmt.Errorf("%w: unsupported bandwidth %d kHz", ErrDoesNotFit, c.bandwidth/1000)
+		}
+
+		maxIF := int64(rbw-c.bandwidth) / 2
+		a.ranges = append(a.ranges, [2]int64{int64(c.freq) - maxIF, int64(c.freq) + maxIF})
+		a.order = append(a.order, i)
+	}
+	sort.SliceStable(a.order, func(i, j int) bool {
+		return a.ranges[a.order[i]][1] < a.ranges[a.order[j]][1]
+	})
+
+	if !a.assign(0) {
+		return fmt.Errorf("%w: channel %d Hz", ErrDoesNotFit, chans[a.order[a.maxDepth]].freq)
+	}
+
+	demods := make([]board, hw.Boards)
+	for i, c := range chans {
+		b := &demods[a.assigned[i]]
+
+		var demod uint32
+		switch c.kind {
+		case multiSF:
+			demod = uint32(b.multiSF)
+			b.multiSF++
+		case loRaStd:
+			demod = LoRaStdDemodulator
+		case fsk:
+			demod = FSKDemodulator
+		}
+		c.conf.Board = uint32(a.assigned[i])
+		c.conf.Demodulator = demod
+	}
+
+	return nil
+}
+
+// assign allocates the n-th channel (of order) and the channels after it,
+// returning false when these do not fit any of the boards.
+func (a *allocation) assign(n int) bool {
+	if n == len(a.order) {
+		return true
+	}
+	if n > a.maxDepth {
+		a.maxDepth = n
+	}
+
+	key := a.key(n)
+	if a.failed[key] {
+		return false
+	}
+
+	i := a.order[n]
+	c := a.chans[i]
+	for bi := range a.boards {
+		b := &a.boards[bi]
+		if a.tried(bi) || !b.hasDemodulator(c.kind) {
+			continue
+		}
+
+		prev := *b
+		if !b.assignRadio(a.ranges[i][0], a.ranges[i][1]) {
+			continue
+		}
+		b.useDemodulator(c.kind)
+		a.assigned[i] = bi
+
+		if a.assign(n + 1) {
+			return true
+		}
+		*b = prev
+	}
+
+	a.failed[key] = true
+	return false
+}
+
+// tried returns true when a previous board has the same state as the given
+// board, as the result would be the same.
+func (a *allocation) tried(bi int) bool {
+	for i := 0; i < bi; i++ {
+		if a.boards[i] == a.boards[bi] {
+			return true
+		}
+	}
+	return false
+}
+
+// key returns the key of the allocation state for allocating the n-th
+// channel. As the boards are identical, the board states are sorted.
+func (a *allocation) key(n int) string {
+	states := make([]string, len(a.boards))
+	for i, b := range a.boards {
+		states[i] = fmt.Sprint(b)
+	}
+	sort.Strings(states)
+	return fmt.Sprint(n, states)
+}
+
+func (b *board) hasDemodulator(kind channelKind) bool {
+	switch kind {
+	case multiSF:
+		return b.multiSF < multiSFDemodulators
+	case loRaStd:
+		return !b.loRaStd
+	case fsk:
+		return !b.fsk
+	}
+	return false
+}
+
+func (b *board) useDemodulator(kind channelKind) {
+	switch kind {
+	case multiSF:
+		b.multiSF++
+	case loRaStd:
+		b.loRaStd = true
+	case fsk:
+		b.fsk = true
+	}
+}
+
+// assignRadio assigns the channel with the given center frequency range to
+// a radio, returning false when there is none. As the channels are assigned
+// in the order of the end of their range, a channel fits a radio when its
+// range starts before the end of the radio range. An unused radio is only
+// used when the channel does not fit the radios in use, as the range of a
+// radio used by a later channel ends later.
+func (b *board) assignRadio(lo, hi int64) bool {
+	for i := range b.radios {
+		if r := b.radios[i]; r.used && lo <= r.max {
+			return true
+		}
+	}
+	for i := range b.radios {
+		if r := &b.radios[i]; !r.used {
+			*r = radio{used: true, max: hi}
+			return true
+		}
+	}
+	return false
+}
+
+// bandChannel returns the band-plan uplink channel with the given index.
+func bandChannel(b *band.Band, i uint32) (channel, error) {
+	if int(i) >= len(b.UplinkChannels) {
+		return channel{}, fmt.Errorf("config: invalid channel index %d for %s", i, b.Region)
+	}
+	bc := b.UplinkChannels[i]
+
+	var bw uint32
+	var sfs []uint32
+	for dr := bc.MinDR; dr <= bc.MaxDR; dr++ {
+		d, err := b.GetDataRate(dr)
+		if err != nil {
+			return channel{}, fmt.Errorf("config: channel %d: %w", i, err)
+		}
+		if d.Modulation != common.Modulation_LORA {
+			continue
+		}
+		if bw != 0 && uint32(d.Bandwidth) != bw {
+			return channel{}, fmt.Errorf("config: channel %d: mixed bandwidths are not supported", i)
+		}
+		bw = uint32(d.Bandwidth)
+		sfs = append(sfs, uint32(d.SpreadingFactor))
+	}
+	if bw == 0 {
+		return channel{}, fmt.Errorf("config: channel %d: no LoRa data-rates", i)
+	}
+
+	c, err := extraChannel(ExtraChannel{
+		Modulation:       common.Modulation_LORA,
+		Frequency:        bc.Frequency,
+		Bandwidth:        bw,
+		SpreadingFactors: sfs,
+	})
+	if err != nil {
+		return channel{}, fmt.Errorf("config: channel %d: %w", i, err)
+	}
+	return c, nil
+}
+
+// extraChannel returns the channel for the given extra channel.
+func extraChannel(ec ExtraChannel) (channel, error) {
+	if ec.Frequency == 0 {
+		return channel{}, errors.New("frequency must be set")
+	}
+	if ec.Bandwidth == 0 {
+		return channel{}, errors.New("bandwidth must be set")
+	}
+
+	c := channel{
+		freq:      ec.Frequency,
+		bandwidth: ec.Bandwidth * 1000,
+		conf: &gw.ChannelConfiguration{
+			Frequency:  ec.Frequency,
+			Modulation: ec.Modulation,
+		},
+	}
+
+	switch ec.Modulation {
+	case common.Modulation_LORA:
+		if len(ec.SpreadingFactors) == 0 {
+			return channel{}, errors.New("spreading_factors must be set")
+		}
+		sfs := append([]uint32(nil), ec.SpreadingFactors...)
+		sort.Slice(sfs, func(i, j int) bool { return sfs[i] < sfs[j] })
+		for _, sf := range sfs {
+			if sf < 5 || sf > 12 {
+				return channel{}, fmt.Errorf("invalid spreading-factor: %d", sf)
+			}
+		}
+
+		if ec.Bandwidth == 125 {
+			c.kind = multiSF
+		} else {
+			if len(sfs) != 1 {
+				return channel{}, fmt.Errorf("LoRa %d kHz channel must have exactly one spreading-factor", ec.Bandwidth)
+			}
+			c.kind = loRaStd
+		}
+		c.conf.ModulationConfig = &gw.ChannelConfiguration_LoraModulationConfig{
+			LoraModulationConfig: &gw.LoRaModulationConfig{
+				Bandwidth:        ec.Bandwidth,
+				SpreadingFactors: sfs,
+			},
+		}
+	case common.Modulation_FSK:
+		if ec.Bitrate == 0 {
+			return channel{}, errors.New("bitrate must be set")
+		}
+		c.kind = fsk
+		c.conf.ModulationConfig = &gw.ChannelConfiguration_FskModulationConfig{
+			FskModulationConfig: &gw.FSKModulationConfig{
+				Bandwidth: ec.Bandwidth,
+				Bitrate:   ec.Bitrate,
+			},
+		}
+	default:
+		return channel{}, fmt.Errorf("unsupported modulation: %s", ec.Modulation)
+	}
+
+	return c, nil
+}
@@ -0,0 +1,242 @@
+// Package config builds the gateway configuration (gw.GatewayConfiguration)
+// from a gateway-profile and the band-plan of a region.
+//
+// The channels are allocated to the boards and (per board) to the two radios
+// and the concentrator demodulators. Every board has 8 multi-SF demodulators
+// for the LoRa 125 kHz channels (demodulator 0 - 7), one LoRa standard
+// demodulator (demodulator 8) and one FSK demodulator (demodulator 9). The
+// intermediate frequency (IF) of every channel must be within the receive
+// bandwidth of its radio.
+package config
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/golang/protobuf/proto"
+
+	"github.com/brocaar/chirpstack-api/go/as/external/api"
+	"github.com/brocaar/chirpstack-api/go/band"
+	"github.com/brocaar/chirpstack-api/go/common"
+	"github.com/brocaar/chirpstack-api/go/gw"
+	"github.com/brocaar/chirpstack-api/go/lorawan"
+	"github.com/brocaar/chirpstack-api/go/ns"
+)
+
+// Demodulator indices of the LoRa standard and FSK channel.
+const (
+	LoRaStdDemodulator = 8
+	FSKDemodulator     = 9
+)
+
+// multiSFDemodulators defines the number of multi-SF demodulators per board.
+const multiSFDemodulators = 8
+
+// ErrDoesNotFit is returned when the channels do not fit the hardware.
+var ErrDoesNotFit = errors.New("config: channels do not fit the hardware")
+
+// Hardware defines the concentrator hardware constraints.
+type Hardware struct {
+	// Boards defines the number of concentrator boards.
+	Boards int
+
+	// RadioBandwidths defines the radio receive bandwidth (Hz) per channel
+	// bandwidth (kHz). A channel fits a radio when the channel (including
+	// its bandwidth) is within the radio receive bandwidth around the radio
+	// center frequency, thus |IF| <= (radio bandwidth - channel bandwidth) / 2.
+	RadioBandwidths map[uint32]uint32
+}
+
+// Hardware presets (single board).
+var (
+	// SX1301 defines the SX1301 (SX1257 radios) constraints.
+	SX1301 = Hardware{
+		Boards: 1,
+		RadioBandwidths: map[uint32]uint32{
+			125: 925000,
+			250: 1000000,
+			500: 1100000,
+		},
+	}
+
+	// SX1302 defines the SX1302 (SX1250 radios) constraints.
+	SX1302 = Hardware{
+		Boards: 1,
+		RadioBandwidths: map[uint32]uint32{
+			125: 1600000,
+			250: 1600000,
+			500: 1600000,
+		},
+	}
+)
+
+// WithBoards returns a copy of the hardware with the given number of boards.
+func (h Hardware) WithBoards(n int) Hardware {
+	h.Boards = n
+	return h
+}
+
+// ExtraChannel defines an additional (non band-plan) channel.
+type ExtraChannel struct {
+	Modulation common.Modulation
+	Frequency  uint32
+
+	// Bandwidth (kHz).
+	Bandwidth uint32
+
+	// Bitrate holds the FSK bitrate.
+	Bitrate uint32
+
+	// SpreadingFactors holds the LoRa spreading-factors. LoRa 125 kHz
+	// channels are multi-SF channels, other LoRa channels must have exactly
+	// one spreading-factor.
+	SpreadingFactors []uint32
+}
+
+type options struct {
+	hardware  Hardware
+	gatewayID []byte
+}
+
+// Option configures the build.
+type Option func(*options)
+
+// WithHardware sets the hardware constraints. The default is SX1301.
+func WithHardware(h Hardware) Option {
+	return func(o *options) {
+		o.hardware = h
+	}
+}
+
+// WithGatewayID sets the gateway ID of the configuration.
+func WithGatewayID(gatewayID lorawan.EUI64) Option {
+	return func(o *options) {
+		o.gatewayID = gatewayID.Bytes()
+	}
+}
+
+// FromAPIGatewayProfile builds the gateway configuration for the given
+// external API gateway-profile.
+func FromAPIGatewayProfile(gp *api.GatewayProfile, region common.Region, opts ...Option) (*gw.GatewayConfiguration, error) {
+	var extra []ExtraChannel
+	for _, c := range gp.GetExtraChannels() {
+		extra = append(extra, ExtraChannel{
+			Modulation:       c.Modulation,
+			Frequency:        c.Frequency,
+			Bandwidth:        c.Bandwidth,
+			Bitrate:          c.Bitrate,
+			SpreadingFactors: c.SpreadingFactors,
+		})
+	}
+	return Build(region, gp.GetChannels(), extra, opts...)
+}
+
+// FromNSGatewayProfile builds the gateway configuration for the given
+// network-server gateway-profile.
+func FromNSGatewayProfile(gp *ns.GatewayProfile, region common.Region, opts ...Option) (*gw.GatewayConfiguration, error) {
+	var extra []ExtraChannel
+	for _, c := range gp.GetExtraChannels() {
+		extra = append(extra, ExtraChannel{
+			Modulation:       c.Modulation,
+			Frequency:        c.Frequency,
+			Bandwidth:        c.Bandwidth,
+			Bitrate:          c.Bitrate,
+			SpreadingFactors: c.SpreadingFactors,
+		})
+	}
+	return Build(region, gp.GetChannels(), extra, opts...)
+}
+
+// Build builds the gateway configuration for the given band-plan channel
+// indices (of the uplink channels of the region) and extra channels. The
+// version is a hash of the channel configuration, it only depends on the
+// resulting channels and not on the order of the input.
+func Build(region common.Region, channels []uint32, extra []ExtraChannel, opts ...Option) (*gw.GatewayConfiguration, error) {
+	o := options{hardware: SX1301}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	if o.hardware.Boards < 1 {
+		return nil, errors.New("config: hardware must have at least one board")
+	}
+
+	b, err := band.Get(region)
+	if err != nil {
+		return nil, fmt.Errorf("config: %w", err)
+	}
+
+	var chans []channel
+	for _, i := range channels {
+		c, err := bandChannel(b, i)
+		if err != nil {
+			return nil, err
+		}
+		chans = append(chans, c)
+	}
+	for i, ec := range extra {
+		c, err := extraChannel(ec)
+		if err != nil {
+			return nil, fmt.Errorf("config: extra channel %d: %w", i, err)
+		}
+		chans = append(chans, c)
+	}
+
+	for _, c := range chans {
+		if !b.ContainsFrequency(c.freq) {
+			return nil, fmt.Errorf("config: frequency %d is not within the %s band", c.freq, region)
+		}
+	}
+
+	sort.SliceStable(chans, func(i, j int) bool {
+		if chans[i].freq != chans[j].freq {
+			return chans[i].freq < chans[j].freq
+		}
+		return chans[i].kind < chans[j].kind
+	})
+	for i := 1; i < len(chans); i++ {
+		if chans[i].freq == chans[i-1].freq && chans[i].kind == chans[i-1].kind {
+			return nil, fmt.Errorf("config: duplicate channel %d Hz", chans[i].freq)
+		}
+	}
+
+	if err := allocate(o.hardware, chans); err != nil {
+		return nil, err
+	}
+
+	conf := gw.GatewayConfiguration{
+		GatewayId: o.gatewayID,
+	}
+	for _, c := range chans {
+		conf.Channels = append(conf.Channels, c.conf)
+	}
+	sort.SliceStable(conf.Channels, func(i, j int) bool {
+		if conf.Channels[i].Board != conf.Channels[j].Board {
+			return conf.Channels[i].Board < conf.Channels[j].Board
+		}
+		return conf.Channels[i].Demodulator < conf.Channels[j].Demodulator
+	})
+
+	if conf.Version, err = Version(conf.Channels); err != nil {
+		return nil, err
+	}
+
+	return &conf, nil
+}
+
+// Version returns the version hash of the given channel configuration.
+func Version(channels []*gw.ChannelConfiguration) (string, error) {
+	h := sha256.New()
+	for _, c := range channels {
+		b, err := proto.Marshal(c)
+		if err != nil {
+			return "", fmt.Errorf("config: marshal channel error: %w", err)
+		}
+		// Length-prefix every channel, so that the hash is unambiguous.
+		h.Write([]byte{byte(len(b) >> 8), byte(len(b))})
+		h.Write(b)
+	}
+	return hex.EncodeToString(h.Sum(nil)[:8]), nil
+}
@@ -0,0 +1,288 @@
+package config
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/golang/protobuf/proto"
+
+	"github.com/brocaar/chirpstack-api/go/as/external/api"
+	"github.com/brocaar/chirpstack-api/go/common"
+	"github.com/brocaar/chirpstack-api/go/gw"
+)
+
+// expectedChannel contains the expected frequency, board and demodulator of a
+// channel.
+type expectedChannel struct {
+	freq        uint32
+	board       uint32
+	demodulator uint32
+}
+
+func lora125(freqs ...uint32) []ExtraChannel {
+	var out []ExtraChannel
+	for _, f := range freqs {
+		out = append(out, ExtraChannel{
+			Modulation:       common.Modulation_LORA,
+			Frequency:        f,
+			Bandwidth:        125,
+			SpreadingFactors: []uint32{7, 8, 9, 10, 11, 12},
+		})
+	}
+	return out
+}
+
+func loRaStdChannel(freq, bw, sf uint32) ExtraChannel {
+	return ExtraChannel{
+		Modulation:       common.Modulation_LORA,
+		Frequency:        freq,
+		Bandwidth:        bw,
+		SpreadingFactors: []uint32{sf},
+	}
+}
+
+func seq(from, to uint32) []uint32 {
+	var out []uint32
+	for i := from; i <= to; i++ {
+		out = append(out, i)
+	}
+	return out
+}
+
+func TestBuild(t *testing.T) {
+	tests := []struct {
+		name     string
+		region   common.Region
+		channels []uint32
+		extra    []ExtraChannel
+		hardware Hardware
+		expected []expectedChannel
+	}{
+		{
+			name:     "EU868",
+			region:   common.Region_EU868,
+			channels: []uint32{0, 1, 2},
+			extra: append(lora125(867100000, 867300000, 867500000, 867700000, 867900000),
+				loRaStdChannel(868300000, 250, 7),
+				ExtraChannel{Modulation: common.Modulation_FSK, Frequency: 868800000, Bandwidth: 125, Bitrate: 50000},
+			),
+			hardware: SX1301,
+			expected: []expectedChannel{
+				{867100000, 0, 0}, {867300000, 0, 1}, {867500000, 0, 2}, {867700000, 0, 3},
+				{867900000, 0, 4}, {868100000, 0, 5}, {868300000, 0, 6}, {868500000, 0, 7},
+				{868300000, 0, LoRaStdDemodulator}, {868800000, 0, FSKDemodulator},
+			},
+		},
+		{
+			name:     "US915 sub-band 1",
+			region:   common.Region_US915,
+			channels: append(seq(0, 7), 64),
+			hardware: SX1301,
+			expected: []expectedChannel{
+				{902300000, 0, 0}, {902500000, 0, 1}, {902700000, 0, 2}, {902900000, 0, 3},
+				{903100000, 0, 4}, {903300000, 0, 5}, {903500000, 0, 6}, {903700000, 0, 7},
+				{903000000, 0, LoRaStdDemodulator},
+			},
+		},
+		{
+			name:     "US915 sub-band 2",
+			region:   common.Region_US915,
+			channels: append(seq(8, 15), 65),
+			hardware: SX1301,
+			expected: []expectedChannel{
+				{903900000, 0, 0}, {904100000, 0, 1}, {904300000, 0, 2}, {904500000, 0, 3},
+				{904700000, 0, 4}, {904900000, 0, 5}, {905100000, 0, 6}, {905300000, 0, 7},
+				{904600000, 0, LoRaStdDemodulator},
+			},
+		},
+		{
+			name:     "US915 sub-band 1 and 2",
+			region:   common.Region_US915,
+			channels: append(seq(0, 15), 64, 65),
+			hardware: SX1301.WithBoards(2),
+			expected: []expectedChannel{
+				{902300000, 0, 0}, {902500000, 0, 1}, {902700000, 0, 2}, {902900000, 0, 3},
+				{903100000, 0, 4}, {903300000, 0, 5}, {903500000, 0, 6}, {903700000, 0, 7},
+				{903000000, 0, LoRaStdDemodulator},
+				{903900000, 1, 0}, {904100000, 1, 1}, {904300000, 1, 2}, {904500000, 1, 3},
+				{904700000, 1, 4}, {904900000, 1, 5}, {905100000, 1, 6}, {905300000, 1, 7},
+				{904600000, 1, LoRaStdDemodulator},
+			},
+		},
+		{
+			// Every board can only receive one of the 250 kHz channels. A
+			// first-fit allocation puts 866.2 and 867.3 MHz on the radios of
+			// the first board, after which 869.1 MHz does not fit.
+			name:   "backtracking",
+			region: common.Region_EU868,
+			extra: append(lora125(866200000, 867300000, 869400000),
+				loRaStdChannel(868400000, 250, 7),
+				loRaStdChannel(869100000, 250, 7),
+			),
+			hardware: SX1301.WithBoards(2),
+			expected: []expectedChannel{
+				{866200000, 0, 0}, {868400000, 0, LoRaStdDemodulator},
+				{867300000, 1, 0}, {869400000, 1, 1}, {869100000, 1, LoRaStdDemodulator},
+			},
+		},
+		{
+			// The SX1250 radios have a receive bandwidth of 1.6 MHz.
+			name:     "SX1302",
+			region:   common.Region_EU868,
+			extra:    lora125(866100000, 867500000, 868100000, 869500000),
+			hardware: SX1302,
+			expected: []expectedChannel{
+				{866100000, 0, 0}, {867500000, 0, 1}, {868100000, 0, 2}, {869500000, 0, 3},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			conf, err := Build(test.region, test.channels, test.extra, WithHardware(test.hardware))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if len(conf.Channels) != len(test.expected) {
+				t.Fatalf("expected %d channels, got %d", len(test.expected), len(conf.Channels))
+			}
+			for i, exp := range test.expected {
+				c := conf.Channels[i]
+				if got := (expectedChannel{c.Frequency, c.Board, c.Demodulator}); got != exp {
+					t.Errorf("channel %d: expected %+v, got %+v", i, exp, got)
+				}
+			}
+		})
+	}
+}
+
+func TestBuildDoesNotFit(t *testing.T) {
+	tests := []struct {
+		name     string
+		region   common.Region
+		channels []uint32
+		extra    []ExtraChannel
+		hardware Hardware
+	}{
+		{
+			// 863.1, 865.1 and 868.1 - 868.5 MHz would need three radios.
+			name:     "too wide",
+			region:   common.Region_EU868,
+			channels: []uint32{0, 1, 2},
+			extra:    lora125(863100000, 865100000),
+			hardware: SX1301,
+		},
+		{
+			name:     "US915 sub-band 1 and 2 on a single board",
+			region:   common.Region_US915,
+			channels: append(seq(0, 15), 64, 65),
+			hardware: SX1301,
+		},
+		{
+			name:     "two LoRa standard channels",
+			region:   common.Region_EU868,
+			extra:    []ExtraChannel{loRaStdChannel(868300000, 250, 7), loRaStdChannel(868500000, 250, 7)},
+			hardware: SX1301,
+		},
+		{
+			name:     "unsupported bandwidth",
+			region:   common.Region_EU868,
+			extra:    []ExtraChannel{loRaStdChannel(868300000, 62, 7)},
+			hardware: SX1301,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := Build(test.region, test.channels, test.extra, WithHardware(test.hardware))
+			if !errors.Is(err, ErrDoesNotFit) {
+				t.Errorf("expected ErrDoesNotFit, got %v", err)
+			}
+		})
+	}
+}
+
+func TestBuildErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		channels []uint32
+		extra    []ExtraChannel
+	}{
+		{"invalid channel index", []uint32{16}, nil},
+		{"duplicate channel", []uint32{0}, lora125(868100000)},
+		{"outside the band", nil, lora125(902300000)},
+		{"missing spreading-factors", nil, []ExtraChannel{{Modulation: common.Modulation_LORA, Frequency: 867100000, Bandwidth: 125}}},
+		{"missing bitrate", nil, []ExtraChannel{{Modulation: common.Modulation_FSK, Frequency: 868800000, Bandwidth: 125}}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if _, err := Build(common.Region_EU868, test.channels, test.extra); err == nil {
+				t.Error("expected error")
+			}
+		})
+	}
+}
+
+// The version and channels must not depend on the order of the channels of
+// the gateway-profile.
+func TestBuildChannelOrder(t *testing.T) {
+	extra := append(lora125(867100000, 867300000, 867500000, 867700000, 867900000), loRaStdChannel(868300000, 250, 7))
+	reversed := make([]ExtraChannel, len(extra))
+	for i := range extra {
+		reversed[len(extra)-1-i] = extra[i]
+	}
+
+	a, err := Build(common.Region_EU868, []uint32{0, 1, 2}, extra)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := Build(common.Region_EU868, []uint32{2, 0, 1}, reversed)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if a.Version == "" || a.Version != b.Version {
+		t.Errorf("expected the same version, got %q and %q", a.Version, b.Version)
+	}
+	if !proto.Equal(a, b) {
+		t.Errorf("expected the same configuration:\n%s\n%s", a, b)
+	}
+
+	c, err := Build(common.Region_EU868, []uint32{0, 1}, extra)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.Version == a.Version {
+		t.Error("expected a different version for different channels")
+	}
+}
+
+func TestFromAPIGatewayProfile(t *testing.T) {
+	gp := api.GatewayProfile{
+		Channels: []uint32{0, 1, 2},
+		ExtraChannels: []*api.GatewayProfileExtraChannel{
+			{Modulation: common.Modulation_LORA, Frequency: 867100000, Bandwidth: 125, SpreadingFactors: []uint32{7, 8, 9, 10, 11, 12}},
+		},
+	}
+
+	conf, err := FromAPIGatewayProfile(&gp, common.Region_EU868)
+	if err != nil {
+		t.Fatal(err)
+	}
+	exp, err := Build(common.Region_EU868, []uint32{0, 1, 2}, lora125(867100000))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !proto.Equal(conf, exp) {
+		t.Errorf("expected %s, got %s", exp, conf)
+	}
+
+	if lmc := conf.Channels[0].GetLoraModulationConfig(); lmc.GetBandwidth() != 125 || len(lmc.GetSpreadingFactors()) != 6 {
+		t.Errorf("unexpected modulation config: %s", lmc)
+	}
+	if _, ok := conf.Channels[0].ModulationConfig.(*gw.ChannelConfiguration_LoraModulationConfig); !ok {
+		t.Errorf("expected LoRa modulation config, got %T", conf.Channels[0].ModulationConfig)
+	}
+}
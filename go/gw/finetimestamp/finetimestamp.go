@@ -0,0 +1,168 @@
+// Package finetimestamp decrypts the encrypted fine-timestamp of an uplink,
+// using the fine-timestamp key of the gateway board which received it.
+//
+// The decrypted fine-timestamp holds the nanoseconds within the second of
+// reception. The second itself is taken from the time_since_gps_epoch of the
+// UplinkRXInfo, or when not set, from its time field.
+package finetimestamp
+
+import (
+	"bytes"
+	"crypto/aes"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/golang/protobuf/ptypes"
+
+	"github.com/brocaar/chirpstack-api/go/as/external/api"
+	"github.com/brocaar/chirpstack-api/go/gw"
+	"github.com/brocaar/chirpstack-api/go/gw/gpstime"
+	"github.com/brocaar/chirpstack-api/go/lorawan"
+	"github.com/brocaar/chirpstack-api/go/ns"
+)
+
+// Errors.
+var (
+	ErrNoFineTimestamp = errors.New("finetimestamp: rx-info does not contain a fine-timestamp")
+	ErrUnknownBoard    = errors.New("finetimestamp: unknown board")
+	ErrNoKey           = errors.New("finetimestamp: board does not have a fine-timestamp key")
+	ErrFPGAIDMismatch  = errors.New("finetimestamp: fpga id does not match the board fpga id")
+	ErrNoTime          = errors.New("finetimestamp: rx-info does not contain a (gps) time")
+	ErrInvalidKey      = errors.New("finetimestamp: decrypted nanoseconds exceed one second, is the key correct?")
+)
+
+// Board contains the fine-timestamp configuration of a gateway board.
+type Board struct {
+	// FPGAID holds the (optional) FPGA ID. When set, it must match the FPGA
+	// ID of the encrypted fine-timestamp (when set).
+	FPGAID []byte
+
+	// Key holds the fine-timestamp decryption key.
+	Key lorawan.AES128Key
+}
+
+// BoardsFromAPI returns the boards for the given external API gateway boards.
+func BoardsFromAPI(boards []*api.GatewayBoard) ([]Board, error) {
+	out := make([]Board, len(boards))
+	for i, b := range boards {
+		if b.GetFpgaId() != "" {
+			id, err := hex.DecodeString(b.GetFpgaId())
+			if err != nil {
+				return nil, fmt.Errorf("finetimestamp: board %d: invalid fpga_id: %w", i, err)
+			}
+			out[i].FPGAID = id
+		}
+		if b.GetFineTimestampKey() != "" {
+			key, err := lorawan.ParseAES128Key(b.GetFineTimestampKey())
+			if err != nil {
+				return nil, fmt.Errorf("finetimestamp: board %d: %w", i, err)
+			}
+			out[i].Key = key
+		}
+	}
+	return out, nil
+}
+
+// BoardsFromNS returns the boards for the given network-server gateway
+// boards.
+func BoardsFromNS(boards []*ns.GatewayBoard) ([]Board, error) {
+	out := make([]Board, len(boards))
+	for i, b := range boards {
+		out[i].FPGAID = b.GetFpgaId()
+		if len(b.GetFineTimestampKey()) != 0 {
+			key, err := lorawan.AES128KeyFromBytes(b.GetFineTimestampKey())
+			if err != nil {
+				return nil, fmt.Errorf("finetimestamp: board %d: %w", i, err)
+			}
+			out[i].Key = key
+		}
+	}
+	return out, nil
+}
+
+// Decrypt returns the plain fine-timestamp of the given rx-info. The key is
+// selected by the aes_key_index of the encrypted fine-timestamp, which is
+// the index of the board holding the key (this is not necessarily the board
+// which received the uplink). When the rx-info already contains a plain
+// fine-timestamp, a copy of it is returned.
+func Decrypt(rxInfo *gw.UplinkRXInfo, boards []Board) (*gw.PlainFineTimestamp, error) {
+	if pt := rxInfo.GetPlainFineTimestamp(); pt != nil {
+		return proto.Clone(pt).(*gw.PlainFineTimestamp), nil
+	}
+
+	et := rxInfo.GetEncryptedFineTimestamp()
+	if et == nil {
+		return nil, ErrNoFineTimestamp
+	}
+
+	if int(et.AesKeyIndex) >= len(boards) {
+		return nil, fmt.Errorf("%w: aes_key_index %d", ErrUnknownBoard, et.AesKeyIndex)
+	}
+	board := boards[et.AesKeyIndex]
+	if board.Key.IsZero() {
+		return nil, ErrNoKey
+	}
+	if len(board.FPGAID) != 0 && len(et.FpgaId) != 0 && !bytes.Equal(board.FPGAID, et.FpgaId) {
+		return nil, ErrFPGAIDMismatch
+	}
+
+	nanos, err := DecryptNanoseconds(board.Key, et.EncryptedNs)
+	if err != nil {
+		return nil, err
+	}
+
+	var t time.Time
+	switch {
+	case rxInfo.GetTimeSinceGpsEpoch() != nil:
+		d, err := ptypes.Duration(rxInfo.GetTimeSinceGpsEpoch())
+		if err != nil {
+			return nil, fmt.Errorf("finetimestamp: time_since_gps_epoch error: %w", err)
+		}
+		t = gpstime.ToTime(d.Truncate(time.Second) + nanos)
+	case rxInfo.GetTime() != nil:
+		ts, err := ptypes.Timestamp(rxInfo.GetTime())
+		if err != nil {
+			return nil, fmt.Errorf("finetimestamp: time error: %w", err)
+		}
+		t = ts.Truncate(time.Second).Add(nanos)
+	default:
+		return nil, ErrNoTime
+	}
+
+	ts, err := ptypes.TimestampProto(t)
+	if err != nil {
+		return nil, fmt.Errorf("finetimestamp: time error: %w", err)
+	}
+
+	return &gw.PlainFineTimestamp{
+		Time: ts,
+	}, nil
+}
+
+// DecryptNanoseconds decrypts the encrypted nanoseconds (a single AES block)
+// using the given key. The last 8 bytes of the plaintext contain the
+// (big-endian) number of nanoseconds within the second of reception.
+func DecryptNanoseconds(key lorawan.AES128Key, encryptedNS []byte) (time.Duration, error) {
+	block, err := aes.NewCipher(key[:])
+	if err != nil {
+		return 0, fmt.Errorf("finetimestamp: new cipher error: %w", err)
+	}
+	if len(encryptedNS) != block.BlockSize() {
+		return 0, fmt.Errorf("finetimestamp: encrypted_ns must be exactly %d bytes", block.BlockSize())
+	}
+
+	b := make([]byte, block.BlockSize())
+	block.Decrypt(b, encryptedNS)
+
+	// Compare as uint64, as values exceeding the int64 range would wrap
+	// into a negative duration.
+	nanos := binary.BigEndian.Uint64(b[8:])
+	if nanos >= uint64(time.Second) {
+		return 0, ErrInvalidKey
+	}
+	return time.Duration(nanos), nil
+}
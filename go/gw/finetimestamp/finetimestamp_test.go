@@ -0,0 +1,224 @@
+package finetimestamp
+
+import (
+	"crypto/aes"
+	"encoding/binary"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/golang/protobuf/ptypes"
+	"github.com/golang/protobuf/ptypes/duration"
+	"github.com/golang/protobuf/ptypes/timestamp"
+
+	"github.com/brocaar/chirpstack-api/go/gw"
+	"github.com/brocaar/chirpstack-api/go/lorawan"
+)
+
+// encrypt returns the encrypted_ns for the given nanoseconds.
+func encrypt(t *testing.T, key lorawan.AES128Key, nanos uint64) []byte {
+	t.Helper()
+
+	block, err := aes.NewCipher(key[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	b := make([]byte, block.BlockSize())
+	binary.BigEndian.PutUint64(b[8:], nanos)
+	block.Encrypt(b, b)
+	return b
+}
+
+func TestDecrypt(t *testing.T) {
+	key0 := lorawan.AES128Key{1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15, 16}
+	key1 := lorawan.AES128Key{16, 15, 14, 13, 12, 11, 10, 9, 8, 7, 6, 5, 4, 3, 2, 1}
+	boards := []Board{
+		{FPGAID: []byte{0x01}, Key: key0},
+		{Key: key1},
+		{},
+	}
+
+	// 2020-01-01T00:00:00.5Z, GPS time is 18 leap seconds ahead of UTC.
+	gpsTime := &duration.Duration{Seconds: 1261872018, Nanos: 500000000}
+	utcTime := &timestamp.Timestamp{Seconds: 1577836800, Nanos: 500000000}
+	plain := &gw.PlainFineTimestamp{Time: &timestamp.Timestamp{Seconds: 1577836800, Nanos: 123}}
+
+	tests := []struct {
+		name     string
+		rxInfo   *gw.UplinkRXInfo
+		expected time.Time
+		err      error
+	}{
+		{
+			name: "time since gps epoch",
+			rxInfo: &gw.UplinkRXInfo{
+				TimeSinceGpsEpoch: gpsTime,
+				Time:              &timestamp.Timestamp{Seconds: 1},
+				FineTimestampType: gw.FineTimestampType_ENCRYPTED,
+				FineTimestamp: &gw.UplinkRXInfo_EncryptedFineTimestamp{EncryptedFineTimestamp: &gw.EncryptedFineTimestamp{
+					EncryptedNs: encrypt(t, key0, 123456789),
+					FpgaId:      []byte{0x01},
+				}},
+			},
+			expected: time.Date(2020, time.January, 1, 0, 0, 0, 123456789, time.UTC),
+		},
+		{
+			name: "time",
+			rxInfo: &gw.UplinkRXInfo{
+				Time:              utcTime,
+				FineTimestampType: gw.FineTimestampType_ENCRYPTED,
+				FineTimestamp: &gw.UplinkRXInfo_EncryptedFineTimestamp{EncryptedFineTimestamp: &gw.EncryptedFineTimestamp{
+					EncryptedNs: encrypt(t, key0, 999999999),
+				}},
+			},
+			expected: time.Date(2020, time.January, 1, 0, 0, 0, 999999999, time.UTC),
+		},
+		{
+			// The key is selected by the aes_key_index, not by the board
+			// which received the uplink.
+			name: "aes key index",
+			rxInfo: &gw.UplinkRXInfo{
+				Time:              utcTime,
+				Board:             0,
+				FineTimestampType: gw.FineTimestampType_ENCRYPTED,
+				FineTimestamp: &gw.UplinkRXInfo_EncryptedFineTimestamp{EncryptedFineTimestamp: &gw.EncryptedFineTimestamp{
+					AesKeyIndex: 1,
+					EncryptedNs: encrypt(t, key1, 42),
+					FpgaId:      []byte{0x02},
+				}},
+			},
+			expected: time.Date(2020, time.January, 1, 0, 0, 0, 42, time.UTC),
+		},
+		{
+			name: "plain",
+			rxInfo: &gw.UplinkRXInfo{
+				FineTimestampType: gw.FineTimestampType_PLAIN,
+				FineTimestamp:     &gw.UplinkRXInfo_PlainFineTimestamp{PlainFineTimestamp: plain},
+			},
+			expected: time.Date(2020, time.January, 1, 0, 0, 0, 123, time.UTC),
+		},
+		{
+			// Decrypting with the wrong key results in (almost certainly)
+			// more than one second of nanoseconds.
+			name: "wrong key",
+			rxInfo: &gw.UplinkRXInfo{
+				Time:              utcTime,
+				FineTimestampType: gw.FineTimestampType_ENCRYPTED,
+				FineTimestamp: &gw.UplinkRXInfo_EncryptedFineTimestamp{EncryptedFineTimestamp: &gw.EncryptedFineTimestamp{
+					AesKeyIndex: 1,
+					EncryptedNs: encrypt(t, key0, 123456789),
+				}},
+			},
+			err: ErrInvalidKey,
+		},
+		{
+			name: "nanoseconds exceed one second",
+			rxInfo: &gw.UplinkRXInfo{
+				Time:              utcTime,
+				FineTimestampType: gw.FineTimestampType_ENCRYPTED,
+				FineTimestamp: &gw.UplinkRXInfo_EncryptedFineTimestamp{EncryptedFineTimestamp: &gw.EncryptedFineTimestamp{
+					EncryptedNs: encrypt(t, key0, uint64(time.Second)),
+				}},
+			},
+			err: ErrInvalidKey,
+		},
+		{
+			name:   "no fine-timestamp",
+			rxInfo: &gw.UplinkRXInfo{Time: utcTime},
+			err:    ErrNoFineTimestamp,
+		},
+		{
+			name: "unknown aes key index",
+			rxInfo: &gw.UplinkRXInfo{
+				Time: utcTime,
+				FineTimestamp: &gw.UplinkRXInfo_EncryptedFineTimestamp{EncryptedFineTimestamp: &gw.EncryptedFineTimestamp{
+					AesKeyIndex: 3,
+					EncryptedNs: encrypt(t, key0, 1),
+				}},
+			},
+			err: ErrUnknownBoard,
+		},
+		{
+			name: "no key",
+			rxInfo: &gw.UplinkRXInfo{
+				Time: utcTime,
+				FineTimestamp: &gw.UplinkRXInfo_EncryptedFineTimestamp{EncryptedFineTimestamp: &gw.EncryptedFineTimestamp{
+					AesKeyIndex: 2,
+					EncryptedNs: encrypt(t, key0, 1),
+				}},
+			},
+			err: ErrNoKey,
+		},
+		{
+			name: "fpga id mismatch",
+			rxInfo: &gw.UplinkRXInfo{
+				Time: utcTime,
+				FineTimestamp: &gw.UplinkRXInfo_EncryptedFineTimestamp{EncryptedFineTimestamp: &gw.EncryptedFineTimestamp{
+					EncryptedNs: encrypt(t, key0, 1),
+					FpgaId:      []byte{0x02},
+				}},
+			},
+			err: ErrFPGAIDMismatch,
+		},
+		{
+			name: "no time",
+			rxInfo: &gw.UplinkRXInfo{
+				FineTimestamp: &gw.UplinkRXInfo_EncryptedFineTimestamp{EncryptedFineTimestamp: &gw.EncryptedFineTimestamp{
+					EncryptedNs: encrypt(t, key0, 1),
+				}},
+			},
+			err: ErrNoTime,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			pt, err := Decrypt(test.rxInfo, boards)
+			if !errors.Is(err, test.err) {
+				t.Fatalf("expected error %v, got %v", test.err, err)
+			}
+			if err != nil {
+				return
+			}
+
+			ts, err := ptypes.Timestamp(pt.GetTime())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !ts.Equal(test.expected) {
+				t.Errorf("expected %s, got %s", test.expected.Format(time.RFC3339Nano), ts.Format(time.RFC3339Nano))
+			}
+		})
+	}
+
+	// The plain fine-timestamp of the rx-info must not be shared.
+	pt, err := Decrypt(tests[3].rxInfo, boards)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pt == plain || !proto.Equal(pt, plain) {
+		t.Error("expected a copy of the plain fine-timestamp")
+	}
+}
+
+func TestDecryptNanoseconds(t *testing.T) {
+	key := lorawan.AES128Key{1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15, 16}
+
+	nanos, err := DecryptNanoseconds(key, encrypt(t, key, 500))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if nanos != 500 {
+		t.Errorf("expected 500ns, got %s", nanos)
+	}
+
+	// A value exceeding the int64 range must not wrap.
+	if _, err := DecryptNanoseconds(key, encrypt(t, key, 1<<63)); err != ErrInvalidKey {
+		t.Errorf("expected ErrInvalidKey, got %v", err)
+	}
+
+	if _, err := DecryptNanoseconds(key, []byte{1, 2, 3}); err == nil {
+		t.Error("expected error for a short encrypted_ns")
+	}
+}
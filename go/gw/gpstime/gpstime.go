@@ -0,0 +1,56 @@
+// Package gpstime converts between time.Time (UTC) and the time since GPS
+// epoch, as used by the time_since_gps_epoch fields of the gw messages. GPS
+// time does not contain leap seconds, the conversion takes the leap seconds
+// inserted since the GPS epoch into account.
+package gpstime
+
+import "time"
+
+// Epoch defines the GPS epoch.
+var Epoch = time.Date(1980, time.January, 6, 0, 0, 0, 0, time.UTC)
+
+// leapSeconds contains the (UTC) times at which the leap seconds since the
+// GPS epoch became effective.
+var leapSeconds = []time.Time{
+	time.Date(1981, time.July, 1, 0, 0, 0, 0, time.UTC),
+	time.Date(1982, time.July, 1, 0, 0, 0, 0, time.UTC),
+	time.Date(1983, time.July, 1, 0, 0, 0, 0, time.UTC),
+	time.Date(1985, time.July, 1, 0, 0, 0, 0, time.UTC),
+	time.Date(1988, time.January, 1, 0, 0, 0, 0, time.UTC),
+	time.Date(1990, time.January, 1, 0, 0, 0, 0, time.UTC),
+	time.Date(1991, time.January, 1, 0, 0, 0, 0, time.UTC),
+	time.Date(1992, time.July, 1, 0, 0, 0, 0, time.UTC),
+	time.Date(1993, time.July, 1, 0, 0, 0, 0, time.UTC),
+	time.Date(1994, time.July, 1, 0, 0, 0, 0, time.UTC),
+	time.Date(1996, time.January, 1, 0, 0, 0, 0, time.UTC),
+	time.Date(1997, time.July, 1, 0, 0, 0, 0, time.UTC),
+	time.Date(1999, time.January, 1, 0, 0, 0, 0, time.UTC),
+	time.Date(2006, time.January, 1, 0, 0, 0, 0, time.UTC),
+	time.Date(2009, time.January, 1, 0, 0, 0, 0, time.UTC),
+	time.Date(2012, time.July, 1, 0, 0, 0, 0, time.UTC),
+	time.Date(2015, time.July, 1, 0, 0, 0, 0, time.UTC),
+	time.Date(2017, time.January, 1, 0, 0, 0, 0, time.UTC),
+}
+
+// ToTime returns the UTC time for the given time since GPS epoch.
+func ToTime(d time.Duration) time.Time {
+	t := Epoch.Add(d)
+	offset := 0
+	for i, ls := range leapSeconds {
+		if !t.Before(ls.Add(time.Duration(i+1) * time.Second)) {
+			offset = i + 1
+		}
+	}
+	return t.Add(-time.Duration(offset) * time.Second)
+}
+
+// FromTime returns the time since GPS epoch for the given time.
+func FromTime(t time.Time) time.Duration {
+	offset := 0
+	for _, ls := range leapSeconds {
+		if !t.Before(ls) {
+			offset++
+		}
+	}
+	return t.Sub(Epoch) + time.Duration(offset)*time.Second
+}
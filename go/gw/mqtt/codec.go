@@ -0,0 +1,198 @@
+// Package mqtt implements the MQTT topic and payload handling of the gateway
+// event and command messages. It does not depend on a MQTT client, the
+// Codec returns the topics and payloads to publish and dispatches the
+// received messages to typed handlers:
+//
+//	codec, err := mqtt.NewCodec(mqtt.JSON, "", "")
+//	...
+//	topic, payload, err := codec.EncodeEvent(gatewayID, uplinkFrame)
+//	...
+//	err = codec.HandleEvent(topic, payload, mqtt.EventHandlers{
+//		Uplink: func(gatewayID lorawan.EUI64, f *gw.UplinkFrame) error { ... },
+//	})
+package mqtt
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/golang/protobuf/proto"
+
+	"github.com/brocaar/chirpstack-api/go/gw"
+	"github.com/brocaar/chirpstack-api/go/lorawan"
+)
+
+// ErrUnknownType is returned for an unknown event or command type.
+var ErrUnknownType = errors.New("mqtt: unknown event or command type")
+
+// EventHandlers contains the gateway event handlers. Events without handler
+// are ignored.
+type EventHandlers struct {
+	Uplink func(gatewayID lorawan.EUI64, frame *gw.UplinkFrame) error
+	Stats  func(gatewayID lorawan.EUI64, stats *gw.GatewayStats) error
+	Ack    func(gatewayID lorawan.EUI64, ack *gw.DownlinkTXAck) error
+	Exec   func(gatewayID lorawan.EUI64, resp *gw.GatewayCommandExecResponse) error
+	Raw    func(gatewayID lorawan.EUI64, event *gw.RawPacketForwarderEvent) error
+}
+
+// CommandHandlers contains the gateway command handlers. Commands without
+// handler are ignored.
+type CommandHandlers struct {
+	Down   func(gatewayID lorawan.EUI64, frame *gw.DownlinkFrame) error
+	Config func(gatewayID lorawan.EUI64, conf *gw.GatewayConfiguration) error
+	Exec   func(gatewayID lorawan.EUI64, req *gw.GatewayCommandExecRequest) error
+	Raw    func(gatewayID lorawan.EUI64, cmd *gw.RawPacketForwarderCommand) error
+}
+
+// Codec encodes and decodes the gateway events and commands.
+type Codec struct {
+	encoding Encoding
+	topics   *Topics
+}
+
+// NewCodec returns a new Codec for the given encoding and topic templates.
+// An empty template selects the default template.
+func NewCodec(encoding Encoding, eventTopicTemplate, commandTopicTemplate string) (*Codec, error) {
+	topics, err := NewTopics(eventTopicTemplate, commandTopicTemplate)
+	if err != nil {
+		return nil, err
+	}
+	return &Codec{
+		encoding: encoding,
+		topics:   topics,
+	}, nil
+}
+
+// Encoding returns the payload encoding.
+func (c *Codec) Encoding() Encoding {
+	return c.encoding
+}
+
+// Topics returns the topics.
+func (c *Codec) Topics() *Topics {
+	return c.topics
+}
+
+// EncodeEvent returns the topic and payload for the given event message. The
+// event type is derived from the message type.
+func (c *Codec) EncodeEvent(gatewayID lorawan.EUI64, msg proto.Message) (string, []byte, error) {
+	var eventType EventType
+	switch msg.(type) {
+	case *gw.UplinkFrame:
+		eventType = EventUplink
+	case *gw.GatewayStats:
+		eventType = EventStats
+	case *gw.DownlinkTXAck:
+		eventType = EventAck
+	case *gw.GatewayCommandExecResponse:
+		eventType = EventExec
+	case *gw.RawPacketForwarderEvent:
+		eventType = EventRaw
+	default:
+		return "", nil, fmt.Errorf("mqtt: %T is not an event message", msg)
+	}
+
+	topic, err := c.topics.EventTopic(gatewayID, eventType)
+	if err != nil {
+		return "", nil, err
+	}
+	payload, err := c.encoding.Marshal(msg)
+	if err != nil {
+		return "", nil, err
+	}
+	return topic, payload, nil
+}
+
+// EncodeCommand returns the topic and payload for the given command message.
+// The command type is derived from the message type.
+func (c *Codec) EncodeCommand(gatewayID lorawan.EUI64, msg proto.Message) (string, []byte, error) {
+	var commandType CommandType
+	switch msg.(type) {
+	case *gw.DownlinkFrame:
+		commandType = CommandDown
+	case *gw.GatewayConfiguration:
+		commandType = CommandConfig
+	case *gw.GatewayCommandExecRequest:
+		commandType = CommandExec
+	case *gw.RawPacketForwarderCommand:
+		commandType = CommandRaw
+	default:
+		return "", nil, fmt.Errorf("mqtt: %T is not a command message", msg)
+	}
+
+	topic, err := c.topics.CommandTopic(gatewayID, commandType)
+	if err != nil {
+		return "", nil, err
+	}
+	payload, err := c.encoding.Marshal(msg)
+	if err != nil {
+		return "", nil, err
+	}
+	return topic, payload, nil
+}
+
+// HandleEvent decodes the event received on the given topic and calls the
+// matching handler.
+func (c *Codec) HandleEvent(topic string, payload []byte, h EventHandlers) error {
+	gatewayID, eventType, err := c.topics.ParseEventTopic(topic)
+	if err != nil {
+		return err
+	}
+
+	switch eventType {
+	case EventUplink:
+		var msg gw.UplinkFrame
+		return handle(c.encoding, payload, &msg, h.Uplink != nil, func() error { return h.Uplink(gatewayID, &msg) })
+	case EventStats:
+		var msg gw.GatewayStats
+		return handle(c.encoding, payload, &msg, h.Stats != nil, func() error { return h.Stats(gatewayID, &msg) })
+	case EventAck:
+		var msg gw.DownlinkTXAck
+		return handle(c.encoding, payload, &msg, h.Ack != nil, func() error { return h.Ack(gatewayID, &msg) })
+	case EventExec:
+		var msg gw.GatewayCommandExecResponse
+		return handle(c.encoding, payload, &msg, h.Exec != nil, func() error { return h.Exec(gatewayID, &msg) })
+	case EventRaw:
+		var msg gw.RawPacketForwarderEvent
+		return handle(c.encoding, payload, &msg, h.Raw != nil, func() error { return h.Raw(gatewayID, &msg) })
+	default:
+		return fmt.Errorf("%w: %s", ErrUnknownType, eventType)
+	}
+}
+
+// HandleCommand decodes the command received on the given topic and calls
+// the matching handler.
+func (c *Codec) HandleCommand(topic string, payload []byte, h CommandHandlers) error {
+	gatewayID, commandType, err := c.topics.ParseCommandTopic(topic)
+	if err != nil {
+		return err
+	}
+
+	switch commandType {
+	case CommandDown:
+		var msg gw.DownlinkFrame
+		return handle(c.encoding, payload, &msg, h.Down != nil, func() error { return h.Down(gatewayID, &msg) })
+	case CommandConfig:
+		var msg gw.GatewayConfiguration
+		return handle(c.encoding, payload, &msg, h.Config != nil, func() error { return h.Config(gatewayID, &msg) })
+	case CommandExec:
+		var msg gw.GatewayCommandExecRequest
+		return handle(c.encoding, payload, &msg, h.Exec != nil, func() error { return h.Exec(gatewayID, &msg) })
+	case CommandRaw:
+		var msg gw.RawPacketForwarderCommand
+		return handle(c.encoding, payload, &msg, h.Raw != nil, func() error { return h.Raw(gatewayID, &msg) })
+	default:
+		return fmt.Errorf("%w: %s", ErrUnknownType, commandType)
+	}
+}
+
+// handle unmarshals the payload and calls fn, when there is a handler.
+func handle(e Encoding, payload []byte, msg proto.Message, ok bool, fn func() error) error {
+	if !ok {
+		return nil
+	}
+	if err := e.Unmarshal(payload, msg); err != nil {
+		return err
+	}
+	return fn()
+}
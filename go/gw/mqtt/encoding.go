@@ -0,0 +1,167 @@
+package mqtt
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+
+	"github.com/golang/protobuf/jsonpb"
+	"github.com/golang/protobuf/proto"
+)
+
+// Encoding defines the payload encoding.
+type Encoding int
+
+// Encodings.
+const (
+	// Protobuf encodes the payload as Protobuf.
+	Protobuf Encoding = iota
+
+	// JSON encodes the payload as Protobuf JSON mapping.
+	JSON
+
+	// V2JSON encodes the payload as Protobuf JSON mapping, with the ID fields
+	// (e.g. gatewayID) HEX encoded instead of base64 encoded, as used by the
+	// v2 gateway bridge JSON format.
+	V2JSON
+)
+
+// v2HEXFields contains the (JSON) fields which are HEX encoded by V2JSON.
+var v2HEXFields = map[string]bool{
+	"gatewayID":  true,
+	"uplinkID":   true,
+	"downlinkID": true,
+	"statsID":    true,
+	"execID":     true,
+	"rawID":      true,
+}
+
+// ParseEncoding parses the encoding name ("protobuf", "json" or "v2_json").
+func ParseEncoding(s string) (Encoding, error) {
+	switch s {
+	case "protobuf":
+		return Protobuf, nil
+	case "json":
+		return JSON, nil
+	case "v2_json":
+		return V2JSON, nil
+	default:
+		return 0, fmt.Errorf("mqtt: unknown encoding: %q", s)
+	}
+}
+
+// String returns the encoding name.
+func (e Encoding) String() string {
+	switch e {
+	case Protobuf:
+		return "protobuf"
+	case JSON:
+		return "json"
+	case V2JSON:
+		return "v2_json"
+	default:
+		return fmt.Sprintf("Encoding(%d)", int(e))
+	}
+}
+
+// Marshal marshals the message using the encoding.
+func (e Encoding) Marshal(msg proto.Message) ([]byte, error) {
+	switch e {
+	case Protobuf:
+		return proto.Marshal(msg)
+	case JSON, V2JSON:
+		var buf bytes.Buffer
+		m := jsonpb.Marshaler{EmitDefaults: true}
+		if err := m.Marshal(&buf, msg); err != nil {
+			return nil, fmt.Errorf("mqtt: marshal json error: %w", err)
+		}
+		if e == JSON {
+			return buf.Bytes(), nil
+		}
+		return convertV2JSON(buf.Bytes(), base64ToHEX)
+	default:
+		return nil, fmt.Errorf("mqtt: unknown encoding: %s", e)
+	}
+}
+
+// Unmarshal unmarshals the payload into the message using the encoding.
+// Unknown JSON fields are ignored.
+func (e Encoding) Unmarshal(b []byte, msg proto.Message) error {
+	switch e {
+	case Protobuf:
+		return proto.Unmarshal(b, msg)
+	case JSON, V2JSON:
+		if e == V2JSON {
+			var err error
+			if b, err = convertV2JSON(b, hexToBase64); err != nil {
+				return err
+			}
+		}
+		u := jsonpb.Unmarshaler{AllowUnknownFields: true}
+		if err := u.Unmarshal(bytes.NewReader(b), msg); err != nil {
+			return fmt.Errorf("mqtt: unmarshal json error: %w", err)
+		}
+		return nil
+	default:
+		return fmt.Errorf("mqtt: unknown encoding: %s", e)
+	}
+}
+
+// convertV2JSON applies fn to the v2HEXFields of the JSON object.
+func convertV2JSON(b []byte, fn func(string) (string, error)) ([]byte, error) {
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.UseNumber()
+
+	var v interface{}
+	if err := dec.Decode(&v); err != nil {
+		return nil, fmt.Errorf("mqtt: unmarshal json error: %w", err)
+	}
+	if err := convertFields(v, fn); err != nil {
+		return nil, err
+	}
+	return json.Marshal(v)
+}
+
+func convertFields(v interface{}, fn func(string) (string, error)) error {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		for k, fv := range v {
+			if s, ok := fv.(string); ok && v2HEXFields[k] {
+				out, err := fn(s)
+				if err != nil {
+					return fmt.Errorf("mqtt: %s: %w", k, err)
+				}
+				v[k] = out
+				continue
+			}
+			if err := convertFields(fv, fn); err != nil {
+				return err
+			}
+		}
+	case []interface{}:
+		for _, fv := range v {
+			if err := convertFields(fv, fn); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func base64ToHEX(s string) (string, error) {
+	b, err := base64.StdEncoding.DecodeString(s)
+	if err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+func hexToBase64(s string) (string, error) {
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(b), nil
+}
@@ -0,0 +1,172 @@
+package mqtt
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"strings"
+	"text/template"
+
+	"github.com/brocaar/chirpstack-api/go/lorawan"
+)
+
+// Default topic templates.
+const (
+	DefaultEventTopicTemplate   = "gateway/{{ .GatewayID }}/event/{{ .EventType }}"
+	DefaultCommandTopicTemplate = "gateway/{{ .GatewayID }}/command/{{ .CommandType }}"
+)
+
+// EventType defines the gateway event type.
+type EventType string
+
+// Event types.
+const (
+	EventUplink EventType = "up"
+	EventStats  EventType = "stats"
+	EventAck    EventType = "ack"
+	EventExec   EventType = "exec"
+	EventRaw    EventType = "raw"
+)
+
+// CommandType defines the gateway command type.
+type CommandType string
+
+// Command types.
+const (
+	CommandDown   CommandType = "down"
+	CommandConfig CommandType = "config"
+	CommandExec   CommandType = "exec"
+	CommandRaw    CommandType = "raw"
+)
+
+// Placeholders used for deriving the topic patterns from the templates.
+const (
+	gatewayIDPlaceholder = "\x00gateway_id\x00"
+	typePlaceholder      = "\x00type\x00"
+)
+
+// Topics builds and parses the event and command topics. The templates are
+// Go templates, using the .GatewayID field and the .EventType or
+// .CommandType field.
+type Topics struct {
+	event   *template.Template
+	command *template.Template
+
+	eventPattern   *regexp.Regexp
+	commandPattern *regexp.Regexp
+}
+
+// NewTopics returns a new Topics for the given event and command topic
+// templates. An empty template selects the default template.
+func NewTopics(eventTemplate, commandTemplate string) (*Topics, error) {
+	if eventTemplate == "" {
+		eventTemplate = DefaultEventTopicTemplate
+	}
+	if commandTemplate == "" {
+		commandTemplate = DefaultCommandTopicTemplate
+	}
+
+	var t Topics
+	var err error
+
+	if t.event, err = template.New("event").Parse(eventTemplate); err != nil {
+		return nil, fmt.Errorf("mqtt: parse event topic template error: %w", err)
+	}
+	if t.command, err = template.New("command").Parse(commandTemplate); err != nil {
+		return nil, fmt.Errorf("mqtt: parse command topic template error: %w", err)
+	}
+	if t.eventPattern, err = topicPattern(t.event, "EventType"); err != nil {
+		return nil, err
+	}
+	if t.commandPattern, err = topicPattern(t.command, "CommandType"); err != nil {
+		return nil, err
+	}
+
+	return &t, nil
+}
+
+// EventTopic returns the topic for the given gateway ID and event type.
+func (t *Topics) EventTopic(gatewayID lorawan.EUI64, eventType EventType) (string, error) {
+	return execute(t.event, gatewayID.String(), "EventType", string(eventType))
+}
+
+// CommandTopic returns the topic for the given gateway ID and command type.
+func (t *Topics) CommandTopic(gatewayID lorawan.EUI64, commandType CommandType) (string, error) {
+	return execute(t.command, gatewayID.String(), "CommandType", string(commandType))
+}
+
+// EventSubscription returns the topic filter matching the events of all
+// gateways.
+func (t *Topics) EventSubscription() (string, error) {
+	return execute(t.event, "+", "EventType", "+")
+}
+
+// CommandSubscription returns the topic filter matching the commands of the
+// given gateway.
+func (t *Topics) CommandSubscription(gatewayID lorawan.EUI64) (string, error) {
+	return execute(t.command, gatewayID.String(), "CommandType", "+")
+}
+
+// ParseEventTopic returns the gateway ID and event type of the given topic.
+func (t *Topics) ParseEventTopic(topic string) (lorawan.EUI64, EventType, error) {
+	gatewayID, typ, err := parse(t.eventPattern, topic)
+	return gatewayID, EventType(typ), err
+}
+
+// ParseCommandTopic returns the gateway ID and command type of the given
+// topic.
+func (t *Topics) ParseCommandTopic(topic string) (lorawan.EUI64, CommandType, error) {
+	gatewayID, typ, err := parse(t.commandPattern, topic)
+	return gatewayID, CommandType(typ), err
+}
+
+func execute(tmpl *template.Template, gatewayID, typeField, typ string) (string, error) {
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, map[string]string{
+		"GatewayID": gatewayID,
+		typeField:   typ,
+	}); err != nil {
+		return "", fmt.Errorf("mqtt: execute topic template error: %w", err)
+	}
+	return buf.String(), nil
+}
+
+// topicPattern returns the regular expression matching the topics of the
+// template. The gateway ID and type must each be used exactly once.
+func topicPattern(tmpl *template.Template, typeField string) (*regexp.Regexp, error) {
+	s, err := execute(tmpl, gatewayIDPlaceholder, typeField, typePlaceholder)
+	if err != nil {
+		return nil, err
+	}
+	if strings.Count(s, gatewayIDPlaceholder) != 1 || strings.Count(s, typePlaceholder) != 1 {
+		return nil, fmt.Errorf("mqtt: topic template %q must contain .GatewayID and .%s exactly once", tmpl.Name(), typeField)
+	}
+
+	s = regexp.QuoteMeta(s)
+	s = strings.Replace(s, gatewayIDPlaceholder, `(?P<gateway_id>[0-9a-fA-F]{16})`, 1)
+	s = strings.Replace(s, typePlaceholder, `(?P<type>[^/]+)`, 1)
+
+	return regexp.Compile("^" + s + "$")
+}
+
+func parse(re *regexp.Regexp, topic string) (lorawan.EUI64, string, error) {
+	m := re.FindStringSubmatch(topic)
+	if m == nil {
+		return lorawan.EUI64{}, "", fmt.Errorf("mqtt: topic %q does not match the topic template", topic)
+	}
+
+	var gatewayID lorawan.EUI64
+	var typ string
+	for i, name := range re.SubexpNames() {
+		switch name {
+		case "gateway_id":
+			var err error
+			if gatewayID, err = lorawan.ParseEUI64(m[i]); err != nil {
+				return lorawan.EUI64{}, "", fmt.Errorf("mqtt: %w", err)
+			}
+		case "type":
+			typ = m[i]
+		}
+	}
+	return gatewayID, typ, nil
+}
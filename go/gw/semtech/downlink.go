@@ -0,0 +1,206 @@
+package semtech
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/golang/protobuf/ptypes"
+
+	"github.com/brocaar/chirpstack-api/go/common"
+	"github.com/brocaar/chirpstack-api/go/gw"
+	"github.com/brocaar/chirpstack-api/go/lorawan"
+)
+
+// TX_ACK error values.
+const (
+	TXACKErrorNone = "NONE"
+)
+
+// NewPullRespPacket returns the PULL_RESP packet for the given downlink
+// frame. The frame token is used as random token.
+//
+// DelayTimingInfo requires the uplink context (tmst), the delay is added to
+// tmst. GPSEpochTimingInfo is sent as tmms.
+func NewPullRespPacket(protocolVersion uint8, frame *gw.DownlinkFrame) (PullRespPacket, error) {
+	p := PullRespPacket{
+		ProtocolVersion: protocolVersion,
+		RandomToken:     uint16(frame.GetToken()),
+	}
+
+	txInfo := frame.GetTxInfo()
+	if txInfo == nil {
+		return p, errors.New("semtech: tx_info must not be nil")
+	}
+	if txInfo.Power < 0 || txInfo.Power > 255 {
+		return p, fmt.Errorf("semtech: invalid power: %d", txInfo.Power)
+	}
+
+	txpk := TXPK{
+		Freq: hzToMHz(txInfo.Frequency),
+		Powe: uint8(txInfo.Power),
+		Ant:  uint8(txInfo.Antenna),
+		Brd:  txInfo.Board,
+		Size: uint16(len(frame.PhyPayload)),
+		Data: frame.PhyPayload,
+	}
+
+	switch txInfo.Modulation {
+	case common.Modulation_LORA:
+		modInfo := txInfo.GetLoraModulationInfo()
+		if modInfo == nil {
+			return p, errors.New("semtech: lora_modulation_info must not be nil")
+		}
+		txpk.Modu = ModulationLoRa
+		txpk.DatR = NewLoRaDatR(modInfo.SpreadingFactor, modInfo.Bandwidth)
+		txpk.CodR = modInfo.CodeRate
+		txpk.IPol = modInfo.PolarizationInversion
+	case common.Modulation_FSK:
+		modInfo := txInfo.GetFskModulationInfo()
+		if modInfo == nil {
+			return p, errors.New("semtech: fsk_modulation_info must not be nil")
+		}
+		txpk.Modu = ModulationFSK
+		txpk.DatR = DatR{FSK: modInfo.Bitrate}
+		txpk.FDev = uint16(modInfo.Bitrate / 2)
+	default:
+		return p, fmt.Errorf("semtech: unsupported modulation: %s", txInfo.Modulation)
+	}
+
+	switch ti := txInfo.TimingInfo.(type) {
+	case *gw.DownlinkTXInfo_ImmediatelyTimingInfo:
+		txpk.Imme = true
+	case *gw.DownlinkTXInfo_DelayTimingInfo:
+		if len(txInfo.Context) != 4 {
+			return p, fmt.Errorf("semtech: context must be exactly 4 bytes, got %d", len(txInfo.Context))
+		}
+		delay, err := ptypes.Duration(ti.DelayTimingInfo.GetDelay())
+		if err != nil {
+			return p, fmt.Errorf("semtech: delay error: %w", err)
+		}
+		tmst := binary.BigEndian.Uint32(txInfo.Context) + uint32(delay/time.Microsecond)
+		txpk.Tmst = &tmst
+	case *gw.DownlinkTXInfo_GpsEpochTimingInfo:
+		d, err := ptypes.Duration(ti.GpsEpochTimingInfo.GetTimeSinceGpsEpoch())
+		if err != nil {
+			return p, fmt.Errorf("semtech: time_since_gps_epoch error: %w", err)
+		}
+		tmms := int64(d / time.Millisecond)
+		txpk.Tmms = &tmms
+	default:
+		return p, errors.New("semtech: timing_info must be set")
+	}
+
+	p.Payload.TXPK = txpk
+	return p, nil
+}
+
+// DownlinkFrame returns the downlink frame of the PULL_RESP packet for the
+// given gateway. The random token is used as frame token.
+//
+// A tmst is returned as DelayTimingInfo with a zero delay and the tmst as
+// context.
+func (p PullRespPacket) DownlinkFrame(gatewayID lorawan.EUI64) (*gw.DownlinkFrame, error) {
+	txpk := &p.Payload.TXPK
+
+	txInfo := gw.DownlinkTXInfo{
+		GatewayId: gatewayID.Bytes(),
+		Frequency: mhzToHz(txpk.Freq),
+		Power:     int32(txpk.Powe),
+		Board:     txpk.Brd,
+		Antenna:   uint32(txpk.Ant),
+	}
+
+	switch txpk.Modu {
+	case ModulationLoRa:
+		sf, bw, err := txpk.DatR.ParseLoRa()
+		if err != nil {
+			return nil, err
+		}
+		txInfo.Modulation = common.Modulation_LORA
+		txInfo.ModulationInfo = &gw.DownlinkTXInfo_LoraModulationInfo{
+			LoraModulationInfo: &gw.LoRaModulationInfo{
+				Bandwidth:             bw,
+				SpreadingFactor:       sf,
+				CodeRate:              txpk.CodR,
+				PolarizationInversion: txpk.IPol,
+			},
+		}
+	case ModulationFSK:
+		txInfo.Modulation = common.Modulation_FSK
+		txInfo.ModulationInfo = &gw.DownlinkTXInfo_FskModulationInfo{
+			FskModulationInfo: &gw.FSKModulationInfo{
+				Bitrate: txpk.DatR.FSK,
+			},
+		}
+	default:
+		return nil, fmt.Errorf("semtech: invalid modulation: %q", txpk.Modu)
+	}
+
+	switch {
+	case txpk.Imme:
+		txInfo.Timing = gw.DownlinkTiming_IMMEDIATELY
+		txInfo.TimingInfo = &gw.DownlinkTXInfo_ImmediatelyTimingInfo{
+			ImmediatelyTimingInfo: &gw.ImmediatelyTimingInfo{},
+		}
+	case txpk.Tmst != nil:
+		txInfo.Timing = gw.DownlinkTiming_DELAY
+		txInfo.TimingInfo = &gw.DownlinkTXInfo_DelayTimingInfo{
+			DelayTimingInfo: &gw.DelayTimingInfo{
+				Delay: ptypes.DurationProto(0),
+			},
+		}
+		txInfo.Context = make([]byte, 4)
+		binary.BigEndian.PutUint32(txInfo.Context, *txpk.Tmst)
+	case txpk.Tmms != nil:
+		txInfo.Timing = gw.DownlinkTiming_GPS_EPOCH
+		txInfo.TimingInfo = &gw.DownlinkTXInfo_GpsEpochTimingInfo{
+			GpsEpochTimingInfo: &gw.GPSEpochTimingInfo{
+				TimeSinceGpsEpoch: ptypes.DurationProto(time.Duration(*txpk.Tmms) * time.Millisecond),
+			},
+		}
+	default:
+		return nil, errors.New("semtech: imme, tmst or tmms must be set")
+	}
+
+	return &gw.DownlinkFrame{
+		PhyPayload: txpk.Data,
+		TxInfo:     &txInfo,
+		Token:      uint32(p.RandomToken),
+	}, nil
+}
+
+// DownlinkTXAck returns the downlink TX acknowledgement of the TX_ACK
+// packet. A "NONE" error is returned as an empty error.
+func (p TXACKPacket) DownlinkTXAck() *gw.DownlinkTXAck {
+	ack := gw.DownlinkTXAck{
+		GatewayId: p.GatewayMAC.Bytes(),
+		Token:     uint32(p.RandomToken),
+	}
+	if p.Payload != nil && p.Payload.TXPKACK.Error != TXACKErrorNone {
+		ack.Error = p.Payload.TXPKACK.Error
+	}
+	return &ack
+}
+
+// NewTXACKPacket returns the TX_ACK packet for the given downlink TX
+// acknowledgement.
+func NewTXACKPacket(protocolVersion uint8, ack *gw.DownlinkTXAck) (TXACKPacket, error) {
+	mac, err := lorawan.EUI64FromBytes(ack.GetGatewayId())
+	if err != nil {
+		return TXACKPacket{}, fmt.Errorf("semtech: gateway_id: %w", err)
+	}
+
+	txpkACK := TXPKACK{Error: ack.GetError()}
+	if txpkACK.Error == "" {
+		txpkACK.Error = TXACKErrorNone
+	}
+
+	return TXACKPacket{
+		ProtocolVersion: protocolVersion,
+		RandomToken:     uint16(ack.GetToken()),
+		GatewayMAC:      mac,
+		Payload:         &TXACKPayload{TXPKACK: txpkACK},
+	}, nil
+}
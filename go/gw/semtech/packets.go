@@ -0,0 +1,323 @@
+// Package semtech implements the Semtech UDP packet-forwarder protocol
+// (PROTOCOL.TXT, protocol version 1 and 2) and converts the PUSH_DATA,
+// PULL_RESP and TX_ACK packets to and from the gw messages.
+//
+// The uplink context (as used by DownlinkTXInfo.Context) contains the
+// internal concentrator counter (tmst) as 4 byte big-endian value.
+package semtech
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/brocaar/chirpstack-api/go/lorawan"
+)
+
+// Protocol versions.
+const (
+	ProtocolVersion1 uint8 = 0x01
+	ProtocolVersion2 uint8 = 0x02
+)
+
+// PacketType defines the packet type.
+type PacketType byte
+
+// Packet types.
+const (
+	PushData PacketType = 0x00
+	PushACK  PacketType = 0x01
+	PullData PacketType = 0x02
+	PullResp PacketType = 0x03
+	PullACK  PacketType = 0x04
+	TXACK    PacketType = 0x05
+)
+
+// String returns the packet type name as used by PROTOCOL.TXT.
+func (t PacketType) String() string {
+	switch t {
+	case PushData:
+		return "PUSH_DATA"
+	case PushACK:
+		return "PUSH_ACK"
+	case PullData:
+		return "PULL_DATA"
+	case PullResp:
+		return "PULL_RESP"
+	case PullACK:
+		return "PULL_ACK"
+	case TXACK:
+		return "TX_ACK"
+	default:
+		return fmt.Sprintf("PacketType(%d)", byte(t))
+	}
+}
+
+// Errors.
+var (
+	ErrInvalidProtocolVersion = errors.New("semtech: invalid protocol version")
+	ErrInvalidPacketType      = errors.New("semtech: invalid packet type")
+)
+
+// Packet is implemented by the (pointers to the) packet types.
+type Packet interface {
+	MarshalBinary() ([]byte, error)
+	UnmarshalBinary(data []byte) error
+}
+
+// GetPacketType returns the packet type of the given packet.
+func GetPacketType(data []byte) (PacketType, error) {
+	if len(data) < 4 {
+		return 0, errors.New("semtech: at least 4 bytes of data are expected")
+	}
+	if data[0] != ProtocolVersion1 && data[0] != ProtocolVersion2 {
+		return 0, ErrInvalidProtocolVersion
+	}
+	if data[3] > byte(TXACK) {
+		return 0, ErrInvalidPacketType
+	}
+	return PacketType(data[3]), nil
+}
+
+// UnmarshalPacket unmarshals the given data into the packet of the matching
+// type (e.g. *PushDataPacket).
+func UnmarshalPacket(data []byte) (Packet, error) {
+	t, err := GetPacketType(data)
+	if err != nil {
+		return nil, err
+	}
+
+	var p Packet
+	switch t {
+	case PushData:
+		p = &PushDataPacket{}
+	case PushACK:
+		p = &PushACKPacket{}
+	case PullData:
+		p = &PullDataPacket{}
+	case PullResp:
+		p = &PullRespPacket{}
+	case PullACK:
+		p = &PullACKPacket{}
+	case TXACK:
+		p = &TXACKPacket{}
+	}
+
+	if err := p.UnmarshalBinary(data); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// PushDataPacket is used by the gateway to send the received packets and
+// gateway status.
+type PushDataPacket struct {
+	ProtocolVersion uint8
+	RandomToken     uint16
+	GatewayMAC      lorawan.EUI64
+	Payload         PushDataPayload
+}
+
+// PushDataPayload contains the PUSH_DATA JSON payload.
+type PushDataPayload struct {
+	RXPK []RXPK `json:"rxpk,omitempty"`
+	Stat *Stat  `json:"stat,omitempty"`
+}
+
+// MarshalBinary implements encoding.BinaryMarshaler.
+func (p PushDataPacket) MarshalBinary() ([]byte, error) {
+	pl, err := json.Marshal(p.Payload)
+	if err != nil {
+		return nil, fmt.Errorf("semtech: marshal json error: %w", err)
+	}
+	out := marshalHeader(p.ProtocolVersion, p.RandomToken, PushData)
+	out = append(out, p.GatewayMAC[:]...)
+	return append(out, pl...), nil
+}
+
+// UnmarshalBinary implements encoding.BinaryUnmarshaler.
+func (p *PushDataPacket) UnmarshalBinary(data []byte) error {
+	var err error
+	if p.ProtocolVersion, p.RandomToken, err = unmarshalHeader(data, PushData, 13); err != nil {
+		return err
+	}
+	copy(p.GatewayMAC[:], data[4:12])
+	p.Payload = PushDataPayload{}
+	if err := json.Unmarshal(data[12:], &p.Payload); err != nil {
+		return fmt.Errorf("semtech: unmarshal json error: %w", err)
+	}
+	return nil
+}
+
+// PushACKPacket is used by the server to acknowledge a PUSH_DATA packet.
+type PushACKPacket struct {
+	ProtocolVersion uint8
+	RandomToken     uint16
+}
+
+// MarshalBinary implements encoding.BinaryMarshaler.
+func (p PushACKPacket) MarshalBinary() ([]byte, error) {
+	return marshalHeader(p.ProtocolVersion, p.RandomToken, PushACK), nil
+}
+
+// UnmarshalBinary implements encoding.BinaryUnmarshaler.
+func (p *PushACKPacket) UnmarshalBinary(data []byte) error {
+	var err error
+	p.ProtocolVersion, p.RandomToken, err = unmarshalHeader(data, PushACK, 4)
+	return err
+}
+
+// PullDataPacket is used by the gateway to poll data from the server.
+type PullDataPacket struct {
+	ProtocolVersion uint8
+	RandomToken     uint16
+	GatewayMAC      lorawan.EUI64
+}
+
+// MarshalBinary implements encoding.BinaryMarshaler.
+func (p PullDataPacket) MarshalBinary() ([]byte, error) {
+	out := marshalHeader(p.ProtocolVersion, p.RandomToken, PullData)
+	return append(out, p.GatewayMAC[:]...), nil
+}
+
+// UnmarshalBinary implements encoding.BinaryUnmarshaler.
+func (p *PullDataPacket) UnmarshalBinary(data []byte) error {
+	var err error
+	if p.ProtocolVersion, p.RandomToken, err = unmarshalHeader(data, PullData, 12); err != nil {
+		return err
+	}
+	copy(p.GatewayMAC[:], data[4:12])
+	return nil
+}
+
+// PullACKPacket is used by the server to acknowledge a PULL_DATA packet.
+type PullACKPacket struct {
+	ProtocolVersion uint8
+	RandomToken     uint16
+}
+
+// MarshalBinary implements encoding.BinaryMarshaler.
+func (p PullACKPacket) MarshalBinary() ([]byte, error) {
+	return marshalHeader(p.ProtocolVersion, p.RandomToken, PullACK), nil
+}
+
+// UnmarshalBinary implements encoding.BinaryUnmarshaler.
+func (p *PullACKPacket) UnmarshalBinary(data []byte) error {
+	var err error
+	p.ProtocolVersion, p.RandomToken, err = unmarshalHeader(data, PullACK, 4)
+	return err
+}
+
+// PullRespPacket is used by the server to send a packet to the gateway for
+// transmission. With protocol version 1, the random token is unused.
+type PullRespPacket struct {
+	ProtocolVersion uint8
+	RandomToken     uint16
+	Payload         PullRespPayload
+}
+
+// PullRespPayload contains the PULL_RESP JSON payload.
+type PullRespPayload struct {
+	TXPK TXPK `json:"txpk"`
+}
+
+// MarshalBinary implements encoding.BinaryMarshaler.
+func (p PullRespPacket) MarshalBinary() ([]byte, error) {
+	pl, err := json.Marshal(p.Payload)
+	if err != nil {
+		return nil, fmt.Errorf("semtech: marshal json error: %w", err)
+	}
+	out := marshalHeader(p.ProtocolVersion, p.RandomToken, PullResp)
+	return append(out, pl...), nil
+}
+
+// UnmarshalBinary implements encoding.BinaryUnmarshaler.
+func (p *PullRespPacket) UnmarshalBinary(data []byte) error {
+	var err error
+	if p.ProtocolVersion, p.RandomToken, err = unmarshalHeader(data, PullResp, 5); err != nil {
+		return err
+	}
+	p.Payload = PullRespPayload{}
+	if err := json.Unmarshal(data[4:], &p.Payload); err != nil {
+		return fmt.Errorf("semtech: unmarshal json error: %w", err)
+	}
+	return nil
+}
+
+// TXACKPacket is used by the gateway to report the result of a PULL_RESP.
+// The random token equals the random token of the PULL_RESP. The payload
+// is optional.
+type TXACKPacket struct {
+	ProtocolVersion uint8
+	RandomToken     uint16
+	GatewayMAC      lorawan.EUI64
+	Payload         *TXACKPayload
+}
+
+// TXACKPayload contains the TX_ACK JSON payload.
+type TXACKPayload struct {
+	TXPKACK TXPKACK `json:"txpk_ack"`
+}
+
+// TXPKACK contains the result of the transmission.
+type TXPKACK struct {
+	// Error holds the error (e.g. TOO_LATE), "NONE" or an empty string means
+	// no error.
+	Error string `json:"error,omitempty"`
+}
+
+// MarshalBinary implements encoding.BinaryMarshaler.
+func (p TXACKPacket) MarshalBinary() ([]byte, error) {
+	out := marshalHeader(p.ProtocolVersion, p.RandomToken, TXACK)
+	out = append(out, p.GatewayMAC[:]...)
+	if p.Payload == nil {
+		return out, nil
+	}
+
+	pl, err := json.Marshal(p.Payload)
+	if err != nil {
+		return nil, fmt.Errorf("semtech: marshal json error: %w", err)
+	}
+	return append(out, pl...), nil
+}
+
+// UnmarshalBinary implements encoding.BinaryUnmarshaler.
+func (p *TXACKPacket) UnmarshalBinary(data []byte) error {
+	var err error
+	if p.ProtocolVersion, p.RandomToken, err = unmarshalHeader(data, TXACK, 12); err != nil {
+		return err
+	}
+	copy(p.GatewayMAC[:], data[4:12])
+
+	p.Payload = nil
+	// Some packet-forwarders terminate the (empty) payload with a null byte.
+	if pl := data[12:]; len(pl) > 0 && pl[0] != 0x00 {
+		p.Payload = &TXACKPayload{}
+		if err := json.Unmarshal(pl, p.Payload); err != nil {
+			return fmt.Errorf("semtech: unmarshal json error: %w", err)
+		}
+	}
+	return nil
+}
+
+func marshalHeader(version uint8, token uint16, t PacketType) []byte {
+	out := make([]byte, 4, 12)
+	out[0] = version
+	binary.LittleEndian.PutUint16(out[1:3], token)
+	out[3] = byte(t)
+	return out
+}
+
+func unmarshalHeader(data []byte, t PacketType, minLen int) (uint8, uint16, error) {
+	if len(data) < minLen {
+		return 0, 0, fmt.Errorf("semtech: %s: at least %d bytes of data are expected", t, minLen)
+	}
+	if data[0] != ProtocolVersion1 && data[0] != ProtocolVersion2 {
+		return 0, 0, ErrInvalidProtocolVersion
+	}
+	if PacketType(data[3]) != t {
+		return 0, 0, fmt.Errorf("semtech: %s expected, got %s", t, PacketType(data[3]))
+	}
+	return data[0], binary.LittleEndian.Uint16(data[1:3]), nil
+}
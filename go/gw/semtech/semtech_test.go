@@ -0,0 +1,464 @@
+package semtech
+
+import (
+	"bytes"
+	"encoding"
+	"encoding/json"
+	"flag"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/golang/protobuf/jsonpb"
+	"github.com/golang/protobuf/proto"
+
+	"github.com/brocaar/chirpstack-api/go/gw"
+	"github.com/brocaar/chirpstack-api/go/lorawan"
+)
+
+var update = flag.Bool("update", false, "update the golden .json files")
+
+// golden contains the gw messages of a datagram, in the jsonpb format.
+type golden struct {
+	UplinkFrames  []json.RawMessage `json:"uplinkFrames,omitempty"`
+	GatewayStats  json.RawMessage   `json:"gatewayStats,omitempty"`
+	DownlinkFrame json.RawMessage   `json:"downlinkFrame,omitempty"`
+	DownlinkTXAck json.RawMessage   `json:"downlinkTXAck,omitempty"`
+}
+
+// pullRespGatewayID is used for the PULL_RESP datagrams, as these do not
+// contain the gateway MAC.
+var pullRespGatewayID = lorawan.EUI64{0x01, 0x02, 0x03, 0x04, 0x05, 0x06, 0x07, 0x08}
+
+// TestGolden decodes the datagrams of testdata/*.bin into gw messages,
+// compares these with testdata/*.json and re-encodes the messages, which
+// must result in the same datagram. The datagrams use the field order and
+// number formatting of the encoder (e.g. "freq":868.1 instead of
+// "freq":868.100000), so that they can be compared byte-for-byte. As these
+// only test the codec against itself, the packet-forwarder formatted
+// datagrams are tested by TestForwarderDatagrams. Run with -update to
+// regenerate the .json files.
+func TestGolden(t *testing.T) {
+	tests := []string{
+		"push_data_v1",
+		"push_data_v2",
+		"pull_resp_tmst",
+		"pull_resp_tmms",
+		"pull_resp_imme",
+		"tx_ack",
+		"tx_ack_none",
+	}
+
+	for _, name := range tests {
+		t.Run(name, func(t *testing.T) {
+			data, err := ioutil.ReadFile(filepath.Join("testdata", name+".bin"))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			msgs, reencoded := decodeDatagram(t, data)
+
+			goldenFile := filepath.Join("testdata", name+".json")
+			if *update {
+				writeGolden(t, goldenFile, msgs)
+			}
+			assertMessages(t, readGolden(t, goldenFile), msgs)
+
+			b, err := reencoded.MarshalBinary()
+			if err != nil {
+				t.Fatalf("marshal error: %s", err)
+			}
+			if !bytes.Equal(data, b) {
+				t.Errorf("re-encoded datagram mismatch:\nexpected: %q\n     got: %q", data, b)
+			}
+		})
+	}
+}
+
+// TestForwarderDatagrams decodes datagrams using the field order and number
+// formatting of the Semtech packet-forwarder (lora_pkt_fwd) and of the v2
+// forwarders sending rsig objects, and compares the gw messages with the
+// expected messages. Unknown fields (e.g. jver, mid, rssis) must be ignored.
+// As the encoder uses a different formatting, the re-encoded datagram is
+// compared by decoding it again.
+func TestForwarderDatagrams(t *testing.T) {
+	tests := []struct {
+		name     string
+		expected string
+	}{
+		{
+			// The second rxpk has stat -1 and is skipped, the SF9 rxpk has an
+			// lsnr of 0.0.
+			name: "forwarder_rxpk",
+			expected: `{
+				"uplinkFrames": [
+					{
+						"phyPayload": "QPF9vkkAAgABlUN4disR/w0=",
+						"txInfo": {
+							"frequency": 868500000,
+							"loRaModulationInfo": {"bandwidth": 125, "spreadingFactor": 7, "codeRate": "4/5"}
+						},
+						"rxInfo": {
+							"gatewayID": "qlVaAAAAAQE=",
+							"time": "2019-11-05T11:47:05.123456Z",
+							"timeSinceGPSEpoch": "1256989643.123s",
+							"rssi": -43,
+							"loRaSNR": 9.8,
+							"channel": 2,
+							"context": "0Vovww=="
+						}
+					},
+					{
+						"phyPayload": "QPF9vkkAAgABlUN4disR/w0=",
+						"txInfo": {
+							"frequency": 868800000,
+							"modulation": "FSK",
+							"fskModulationInfo": {"bitrate": 50000}
+						},
+						"rxInfo": {
+							"gatewayID": "qlVaAAAAAQE=",
+							"rssi": -75,
+							"channel": 8,
+							"rfChain": 1,
+							"context": "0Vo/Yw=="
+						}
+					},
+					{
+						"phyPayload": "QPF9vkkAAgABlUN4disR/w0=",
+						"txInfo": {
+							"frequency": 867700000,
+							"loRaModulationInfo": {"bandwidth": 125, "spreadingFactor": 9, "codeRate": "4/5"}
+						},
+						"rxInfo": {
+							"gatewayID": "qlVaAAAAAQE=",
+							"rssi": -110,
+							"channel": 6,
+							"rfChain": 1,
+							"context": "0VpHMw=="
+						}
+					}
+				]
+			}`,
+		},
+		{
+			name: "forwarder_stat",
+			expected: `{
+				"gatewayStats": {
+					"gatewayID": "qlVaAAAAAQE=",
+					"time": "2019-11-05T11:47:05Z",
+					"location": {"latitude": 52.37403, "longitude": 4.88969, "altitude": 12, "source": "GPS"},
+					"rxPacketsReceived": 4,
+					"rxPacketsReceivedOK": 3,
+					"txPacketsReceived": 1,
+					"txPacketsEmitted": 1
+				}
+			}`,
+		},
+		{
+			name: "forwarder_stat_nogps",
+			expected: `{
+				"gatewayStats": {
+					"gatewayID": "qlVaAAAAAQE=",
+					"time": "2019-11-05T11:47:35Z"
+				}
+			}`,
+		},
+		{
+			// Every rsig object results in an uplink frame. The ftime is the
+			// nanosecond within the GPS second of tmms, the etime is
+			// encrypted with the key of aesk.
+			name: "forwarder_v2_rsig",
+			expected: `{
+				"uplinkFrames": [
+					{
+						"phyPayload": "QPF9vkkAAgABlUN4disR/w0=",
+						"txInfo": {
+							"frequency": 868500000,
+							"loRaModulationInfo": {"bandwidth": 125, "spreadingFactor": 7, "codeRate": "4/5"}
+						},
+						"rxInfo": {
+							"gatewayID": "qlVaAAAAAQE=",
+							"time": "2019-11-05T11:47:05.123456Z",
+							"timeSinceGPSEpoch": "1256989643.123s",
+							"rssi": -43,
+							"loRaSNR": 9.8,
+							"channel": 2,
+							"fineTimestampType": "PLAIN",
+							"plainFineTimestamp": {"time": "2019-11-05T11:47:05.123456789Z"},
+							"context": "0Vovww=="
+						}
+					},
+					{
+						"phyPayload": "QPF9vkkAAgABlUN4disR/w0=",
+						"txInfo": {
+							"frequency": 868500000,
+							"loRaModulationInfo": {"bandwidth": 125, "spreadingFactor": 7, "codeRate": "4/5"}
+						},
+						"rxInfo": {
+							"gatewayID": "qlVaAAAAAQE=",
+							"time": "2019-11-05T11:47:05.123456Z",
+							"timeSinceGPSEpoch": "1256989643.123s",
+							"rssi": -50,
+							"loRaSNR": 7.2,
+							"channel": 10,
+							"antenna": 1,
+							"fineTimestampType": "PLAIN",
+							"plainFineTimestamp": {"time": "2019-11-05T11:47:05.1234568Z"},
+							"context": "0Vovww=="
+						}
+					},
+					{
+						"phyPayload": "QPF9vkkAAgABlUN4disR/w0=",
+						"txInfo": {
+							"frequency": 868100000,
+							"loRaModulationInfo": {"bandwidth": 125, "spreadingFactor": 10, "codeRate": "4/5"}
+						},
+						"rxInfo": {
+							"gatewayID": "qlVaAAAAAQE=",
+							"time": "2019-11-05T11:47:05.125456Z",
+							"timeSinceGPSEpoch": "1256989643.125s",
+							"rssi": -99,
+							"loRaSNR": -3.5,
+							"channel": 3,
+							"rfChain": 1,
+							"board": 1,
+							"fineTimestampType": "ENCRYPTED",
+							"encryptedFineTimestamp": {"aesKeyIndex": 2, "encryptedNS": "4OpKL0veG0wOGmp+wZ1/Tg=="},
+							"context": "0Vo3kw=="
+						}
+					},
+					{
+						"phyPayload": "QPF9vkkAAgABlUN4disR/w0=",
+						"txInfo": {
+							"frequency": 868100000,
+							"loRaModulationInfo": {"bandwidth": 125, "spreadingFactor": 10, "codeRate": "4/5"}
+						},
+						"rxInfo": {
+							"gatewayID": "qlVaAAAAAQE=",
+							"time": "2019-11-05T11:47:05.125456Z",
+							"timeSinceGPSEpoch": "1256989643.125s",
+							"rssi": -104,
+							"loRaSNR": -6,
+							"channel": 11,
+							"rfChain": 1,
+							"board": 1,
+							"antenna": 1,
+							"fineTimestampType": "ENCRYPTED",
+							"encryptedFineTimestamp": {"aesKeyIndex": 2, "encryptedNS": "W8lQ8TiWyV3Lz9EOQ2oGHA=="},
+							"context": "0Vo3kw=="
+						}
+					}
+				]
+			}`,
+		},
+		{
+			// The random token is little-endian, a warning is not an error.
+			name: "forwarder_txack_warn",
+			expected: `{
+				"downlinkTXAck": {"gatewayID": "qlVaAAAAAQE=", "token": 19772}
+			}`,
+		},
+		{
+			name: "forwarder_txack_error",
+			expected: `{
+				"downlinkTXAck": {"gatewayID": "qlVaAAAAAQE=", "token": 20028, "error": "COLLISION_BEACON"}
+			}`,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			data, err := ioutil.ReadFile(filepath.Join("testdata", test.name+".bin"))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			msgs, reencoded := decodeDatagram(t, data)
+			expected := parseGolden(t, test.name, []byte(test.expected))
+			assertMessages(t, expected, msgs)
+
+			b, err := reencoded.MarshalBinary()
+			if err != nil {
+				t.Fatalf("marshal error: %s", err)
+			}
+			msgs, _ = decodeDatagram(t, b)
+			assertMessages(t, expected, msgs)
+		})
+	}
+}
+
+// TestUplinkFramesCRC tests that packets without a valid CRC are skipped,
+// unless skipCRCCheck is set.
+func TestUplinkFramesCRC(t *testing.T) {
+	p := PushDataPacket{
+		Payload: PushDataPayload{
+			RXPK: []RXPK{
+				{Stat: 1, Modu: ModulationLoRa, DatR: NewLoRaDatR(7, 125)},
+				{Stat: -1, Modu: ModulationLoRa, DatR: NewLoRaDatR(7, 125)},
+				{Stat: 0, Modu: ModulationLoRa, DatR: NewLoRaDatR(7, 125)},
+			},
+		},
+	}
+
+	for _, test := range []struct {
+		skipCRCCheck bool
+		count        int
+	}{
+		{false, 1},
+		{true, 3},
+	} {
+		frames, err := p.UplinkFrames(test.skipCRCCheck)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(frames) != test.count {
+			t.Errorf("skipCRCCheck %t: expected %d frames, got %d", test.skipCRCCheck, test.count, len(frames))
+		}
+	}
+}
+
+// decodeDatagram returns the gw messages of the datagram and the packet
+// re-encoded from these messages.
+func decodeDatagram(t *testing.T, data []byte) ([]proto.Message, encoding.BinaryMarshaler) {
+	t.Helper()
+
+	pkt, err := UnmarshalPacket(data)
+	if err != nil {
+		t.Fatalf("unmarshal packet error: %s", err)
+	}
+
+	var msgs []proto.Message
+
+	switch p := pkt.(type) {
+	case *PushDataPacket:
+		frames, err := p.UplinkFrames(false)
+		if err != nil {
+			t.Fatalf("uplink frames error: %s", err)
+		}
+		stats, err := p.GatewayStats()
+		if err != nil {
+			t.Fatalf("gateway stats error: %s", err)
+		}
+		for _, f := range frames {
+			msgs = append(msgs, f)
+		}
+		if stats != nil {
+			msgs = append(msgs, stats)
+		}
+
+		out, err := NewPushDataPacket(p.ProtocolVersion, p.RandomToken, frames, stats)
+		if err != nil {
+			t.Fatalf("new push data packet error: %s", err)
+		}
+		return msgs, out
+	case *PullRespPacket:
+		frame, err := p.DownlinkFrame(pullRespGatewayID)
+		if err != nil {
+			t.Fatalf("downlink frame error: %s", err)
+		}
+		msgs = append(msgs, frame)
+
+		out, err := NewPullRespPacket(p.ProtocolVersion, frame)
+		if err != nil {
+			t.Fatalf("new pull resp packet error: %s", err)
+		}
+		return msgs, out
+	case *TXACKPacket:
+		ack := p.DownlinkTXAck()
+		msgs = append(msgs, ack)
+
+		out, err := NewTXACKPacket(p.ProtocolVersion, ack)
+		if err != nil {
+			t.Fatalf("new tx ack packet error: %s", err)
+		}
+		return msgs, out
+	default:
+		t.Fatalf("unexpected packet type %T", pkt)
+		return nil, nil
+	}
+}
+
+func assertMessages(t *testing.T, expected, msgs []proto.Message) {
+	t.Helper()
+
+	if len(expected) != len(msgs) {
+		t.Fatalf("expected %d messages, got %d", len(expected), len(msgs))
+	}
+	for i := range msgs {
+		if !proto.Equal(expected[i], msgs[i]) {
+			t.Errorf("message %d mismatch:\nexpected: %s\n     got: %s", i, expected[i], msgs[i])
+		}
+	}
+}
+
+func readGolden(t *testing.T, file string) []proto.Message {
+	t.Helper()
+
+	b, err := ioutil.ReadFile(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return parseGolden(t, file, b)
+}
+
+func parseGolden(t *testing.T, name string, b []byte) []proto.Message {
+	t.Helper()
+
+	var g golden
+	if err := json.Unmarshal(b, &g); err != nil {
+		t.Fatal(err)
+	}
+
+	var out []proto.Message
+	unmarshal := func(raw json.RawMessage, m proto.Message) {
+		if err := jsonpb.Unmarshal(bytes.NewReader(raw), m); err != nil {
+			t.Fatalf("%s: %s", name, err)
+		}
+		out = append(out, m)
+	}
+	for _, raw := range g.UplinkFrames {
+		unmarshal(raw, &gw.UplinkFrame{})
+	}
+	if g.GatewayStats != nil {
+		unmarshal(g.GatewayStats, &gw.GatewayStats{})
+	}
+	if g.DownlinkFrame != nil {
+		unmarshal(g.DownlinkFrame, &gw.DownlinkFrame{})
+	}
+	if g.DownlinkTXAck != nil {
+		unmarshal(g.DownlinkTXAck, &gw.DownlinkTXAck{})
+	}
+	return out
+}
+
+func writeGolden(t *testing.T, file string, msgs []proto.Message) {
+	t.Helper()
+
+	var g golden
+	var m jsonpb.Marshaler
+	for _, msg := range msgs {
+		s, err := m.MarshalToString(msg)
+		if err != nil {
+			t.Fatal(err)
+		}
+		raw := json.RawMessage(s)
+		switch msg.(type) {
+		case *gw.UplinkFrame:
+			g.UplinkFrames = append(g.UplinkFrames, raw)
+		case *gw.GatewayStats:
+			g.GatewayStats = raw
+		case *gw.DownlinkFrame:
+			g.DownlinkFrame = raw
+		case *gw.DownlinkTXAck:
+			g.DownlinkTXAck = raw
+		}
+	}
+
+	b, err := json.MarshalIndent(g, "", "\t")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(file, append(b, '\n'), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,222 @@
+package semtech
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// CompactTime implements the ISO 8601 'compact' time format (e.g.
+// "2013-03-31T16:21:17.528002Z").
+type CompactTime time.Time
+
+// MarshalJSON implements json.Marshaler.
+func (t CompactTime) MarshalJSON() ([]byte, error) {
+	return []byte(strconv.Quote(time.Time(t).UTC().Format(time.RFC3339Nano))), nil
+}
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (t *CompactTime) UnmarshalJSON(data []byte) error {
+	s, err := strconv.Unquote(string(data))
+	if err != nil {
+		return fmt.Errorf("semtech: invalid compact time: %s", data)
+	}
+	ts, err := time.Parse(time.RFC3339Nano, s)
+	if err != nil {
+		return fmt.Errorf("semtech: parse compact time error: %w", err)
+	}
+	*t = CompactTime(ts)
+	return nil
+}
+
+// ExpandedTime implements the 'expanded' time format as used by the stat
+// object (e.g. "2014-01-12 08:59:28 GMT").
+type ExpandedTime time.Time
+
+const expandedTimeFormat = "2006-01-02 15:04:05 MST"
+
+// MarshalJSON implements json.Marshaler.
+func (t ExpandedTime) MarshalJSON() ([]byte, error) {
+	return []byte(strconv.Quote(time.Time(t).In(time.FixedZone("GMT", 0)).Format(expandedTimeFormat))), nil
+}
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (t *ExpandedTime) UnmarshalJSON(data []byte) error {
+	s, err := strconv.Unquote(string(data))
+	if err != nil {
+		return fmt.Errorf("semtech: invalid expanded time: %s", data)
+	}
+	ts, err := time.Parse(expandedTimeFormat, s)
+	if err != nil {
+		return fmt.Errorf("semtech: parse expanded time error: %w", err)
+	}
+	*t = ExpandedTime(ts.UTC())
+	return nil
+}
+
+// DatR implements the data-rate, which is a string for LoRa (e.g.
+// "SF7BW125") and a number (bits per second) for FSK.
+type DatR struct {
+	LoRa string
+	FSK  uint32
+}
+
+// NewLoRaDatR returns the LoRa data-rate for the given spreading-factor and
+// bandwidth (kHz).
+func NewLoRaDatR(spreadingFactor, bandwidth uint32) DatR {
+	return DatR{LoRa: fmt.Sprintf("SF%dBW%d", spreadingFactor, bandwidth)}
+}
+
+// ParseLoRa returns the spreading-factor and bandwidth (kHz) of the LoRa
+// data-rate.
+func (d DatR) ParseLoRa() (spreadingFactor, bandwidth uint32, err error) {
+	if _, err := fmt.Sscanf(strings.ToUpper(d.LoRa), "SF%dBW%d", &spreadingFactor, &bandwidth); err != nil {
+		return 0, 0, fmt.Errorf("semtech: invalid lora data-rate: %q", d.LoRa)
+	}
+	return spreadingFactor, bandwidth, nil
+}
+
+// MarshalJSON implements json.Marshaler.
+func (d DatR) MarshalJSON() ([]byte, error) {
+	if d.LoRa != "" {
+		return []byte(strconv.Quote(d.LoRa)), nil
+	}
+	return []byte(strconv.FormatUint(uint64(d.FSK), 10)), nil
+}
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (d *DatR) UnmarshalJSON(data []byte) error {
+	*d = DatR{}
+	if strings.HasPrefix(string(data), `"`) {
+		return json.Unmarshal(data, &d.LoRa)
+	}
+	return json.Unmarshal(data, &d.FSK)
+}
+
+// RXPK contains a received packet.
+type RXPK struct {
+	// Time holds the UTC time of the packet RX (only when the gateway has a
+	// GPS module or synchronized time).
+	Time *CompactTime `json:"time,omitempty"`
+
+	// Tmms holds the GPS time of the packet RX, in milliseconds since GPS
+	// epoch.
+	Tmms *int64 `json:"tmms,omitempty"`
+
+	// Tmst holds the internal concentrator counter (microseconds).
+	Tmst uint32 `json:"tmst"`
+
+	// Freq holds the RX frequency (MHz).
+	Freq float64 `json:"freq"`
+
+	Brd  uint32 `json:"brd,omitempty"`
+	AESK uint8  `json:"aesk,omitempty"`
+	Chan uint8  `json:"chan"`
+	RFCh uint8  `json:"rfch"`
+
+	// Stat holds the CRC status (1 = OK, -1 = fail, 0 = no CRC).
+	Stat int8 `json:"stat"`
+
+	// Modu holds the modulation ("LORA" or "FSK").
+	Modu string `json:"modu"`
+
+	DatR DatR   `json:"datr"`
+	CodR string `json:"codr,omitempty"`
+	RSSI int16  `json:"rssi"`
+
+	// LSNR holds the LoRa SNR ratio (dB). It is only set for LoRa, as 0 dB
+	// is a valid SNR.
+	LSNR *float64 `json:"lsnr,omitempty"`
+
+	Size uint16 `json:"size"`
+	Data []byte `json:"data"`
+
+	// RSig holds the per antenna signal information (protocol version 2).
+	RSig []RSig `json:"rsig,omitempty"`
+}
+
+// RSig contains the signal information of a single antenna.
+type RSig struct {
+	Ant   uint8   `json:"ant"`
+	Chan  uint8   `json:"chan"`
+	RSSIC int16   `json:"rssic"`
+	RSSIS *int16  `json:"rssis,omitempty"`
+	LSNR  float64 `json:"lsnr"`
+
+	// ETime holds the encrypted fine timestamp.
+	ETime []byte `json:"etime,omitempty"`
+
+	// FTime holds the (decrypted) fine timestamp, in nanoseconds within the
+	// GPS second.
+	FTime *uint32 `json:"ftime,omitempty"`
+
+	// FOff holds the frequency offset (Hz).
+	FOff int32 `json:"foff,omitempty"`
+}
+
+// Stat contains the gateway status.
+type Stat struct {
+	Time ExpandedTime `json:"time"`
+	Lati *float64     `json:"lati,omitempty"`
+	Long *float64     `json:"long,omitempty"`
+	Alti *int32       `json:"alti,omitempty"`
+
+	// RXNb holds the number of radio packets received.
+	RXNb uint32 `json:"rxnb"`
+
+	// RXOK holds the number of radio packets received with a valid CRC.
+	RXOK uint32 `json:"rxok"`
+
+	// RXFW holds the number of radio packets forwarded. This is not part
+	// of gw.GatewayStats.
+	RXFW uint32 `json:"rxfw"`
+
+	// ACKR holds the percentage of upstream datagrams that were
+	// acknowledged. This is not part of gw.GatewayStats.
+	ACKR float64 `json:"ackr"`
+
+	// DWNb holds the number of downlink datagrams received.
+	DWNb uint32 `json:"dwnb"`
+
+	// TXNb holds the number of packets emitted.
+	TXNb uint32 `json:"txnb"`
+}
+
+// TXPK contains a packet to transmit.
+type TXPK struct {
+	// Imme sends the packet immediately (ignoring Tmst and Tmms).
+	Imme bool `json:"imme,omitempty"`
+
+	// Tmst sends the packet on the given internal concentrator counter.
+	Tmst *uint32 `json:"tmst,omitempty"`
+
+	// Tmms sends the packet at the given GPS time (milliseconds since GPS
+	// epoch).
+	Tmms *int64 `json:"tmms,omitempty"`
+
+	// Freq holds the TX frequency (MHz).
+	Freq float64 `json:"freq"`
+
+	RFCh uint8  `json:"rfch"`
+	Powe uint8  `json:"powe"`
+	Ant  uint8  `json:"ant,omitempty"`
+	Brd  uint32 `json:"brd,omitempty"`
+	Modu string `json:"modu"`
+	DatR DatR   `json:"datr"`
+	CodR string `json:"codr,omitempty"`
+
+	// FDev holds the FSK frequency deviation (Hz).
+	FDev uint16 `json:"fdev,omitempty"`
+
+	// IPol enables the LoRa polarization inversion.
+	IPol bool `json:"ipol"`
+
+	Prea uint16 `json:"prea,omitempty"`
+	Size uint16 `json:"size"`
+	Data []byte `json:"data"`
+
+	// NCRC disables the CRC.
+	NCRC bool `json:"ncrc,omitempty"`
+}
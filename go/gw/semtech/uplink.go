@@ -0,0 +1,366 @@
+package semtech
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/golang/protobuf/ptypes"
+
+	"github.com/brocaar/chirpstack-api/go/common"
+	"github.com/brocaar/chirpstack-api/go/gw"
+	"github.com/brocaar/chirpstack-api/go/gw/gpstime"
+	"github.com/brocaar/chirpstack-api/go/lorawan"
+)
+
+// Modulation names.
+const (
+	ModulationLoRa = "LORA"
+	ModulationFSK  = "FSK"
+)
+
+// UplinkFrames returns the uplink frames of the PUSH_DATA packet. When an
+// rxpk contains signal information of multiple antennas (rsig), an uplink
+// frame is returned for every antenna. Unless skipCRCCheck is set, packets
+// without a valid CRC are ignored.
+func (p PushDataPacket) UplinkFrames(skipCRCCheck bool) ([]*gw.UplinkFrame, error) {
+	var out []*gw.UplinkFrame
+
+	for i := range p.Payload.RXPK {
+		rxpk := &p.Payload.RXPK[i]
+		if !skipCRCCheck && rxpk.Stat != 1 {
+			continue
+		}
+
+		frames, err := uplinkFrames(p.GatewayMAC, rxpk)
+		if err != nil {
+			return nil, fmt.Errorf("semtech: rxpk %d: %w", i, err)
+		}
+		out = append(out, frames...)
+	}
+
+	return out, nil
+}
+
+// GatewayStats returns the gateway stats of the PUSH_DATA packet, or nil
+// when the packet does not contain a stat object.
+func (p PushDataPacket) GatewayStats() (*gw.GatewayStats, error) {
+	stat := p.Payload.Stat
+	if stat == nil {
+		return nil, nil
+	}
+
+	ts, err := ptypes.TimestampProto(time.Time(stat.Time))
+	if err != nil {
+		return nil, fmt.Errorf("semtech: stat time error: %w", err)
+	}
+
+	out := gw.GatewayStats{
+		GatewayId:           p.GatewayMAC.Bytes(),
+		Time:                ts,
+		RxPacketsReceived:   stat.RXNb,
+		RxPacketsReceivedOk: stat.RXOK,
+		TxPacketsReceived:   stat.DWNb,
+		TxPacketsEmitted:    stat.TXNb,
+	}
+
+	if stat.Lati != nil && stat.Long != nil {
+		out.Location = &common.Location{
+			Latitude:  *stat.Lati,
+			Longitude: *stat.Long,
+			Source:    common.LocationSource_GPS,
+		}
+		if stat.Alti != nil {
+			out.Location.Altitude = float64(*stat.Alti)
+		}
+	}
+
+	return &out, nil
+}
+
+// NewPushDataPacket returns a PUSH_DATA packet for the given uplink frames
+// and gateway stats (which may be nil). The gateway MAC is taken from the
+// frames or stats. With protocol version 2, the antenna and fine timestamp
+// are sent as rsig object.
+func NewPushDataPacket(protocolVersion uint8, randomToken uint16, frames []*gw.UplinkFrame, stats *gw.GatewayStats) (PushDataPacket, error) {
+	p := PushDataPacket{
+		ProtocolVersion: protocolVersion,
+		RandomToken:     randomToken,
+	}
+
+	var gatewayID []byte
+	for i, f := range frames {
+		if i == 0 {
+			gatewayID = f.GetRxInfo().GetGatewayId()
+		} else if string(f.GetRxInfo().GetGatewayId()) != string(gatewayID) {
+			return p, errors.New("semtech: all uplink frames must have the same gateway_id")
+		}
+
+		rxpk, err := newRXPK(protocolVersion, f)
+		if err != nil {
+			return p, fmt.Errorf("semtech: uplink frame %d: %w", i, err)
+		}
+		p.Payload.RXPK = append(p.Payload.RXPK, rxpk)
+	}
+
+	if stats != nil {
+		if gatewayID != nil && string(stats.GatewayId) != string(gatewayID) {
+			return p, errors.New("semtech: stats and uplink frames must have the same gateway_id")
+		}
+		gatewayID = stats.GatewayId
+
+		stat, err := newStat(stats)
+		if err != nil {
+			return p, err
+		}
+		p.Payload.Stat = &stat
+	}
+
+	mac, err := lorawan.EUI64FromBytes(gatewayID)
+	if err != nil {
+		return p, fmt.Errorf("semtech: gateway_id: %w", err)
+	}
+	p.GatewayMAC = mac
+
+	return p, nil
+}
+
+func uplinkFrames(mac lorawan.EUI64, rxpk *RXPK) ([]*gw.UplinkFrame, error) {
+	txInfo, err := uplinkTXInfo(rxpk)
+	if err != nil {
+		return nil, err
+	}
+
+	rxInfo := gw.UplinkRXInfo{
+		GatewayId: mac.Bytes(),
+		Rssi:      int32(rxpk.RSSI),
+		Channel:   uint32(rxpk.Chan),
+		RfChain:   uint32(rxpk.RFCh),
+		Board:     rxpk.Brd,
+		Context:   make([]byte, 4),
+	}
+	binary.BigEndian.PutUint32(rxInfo.Context, rxpk.Tmst)
+	if rxpk.LSNR != nil {
+		rxInfo.LoraSnr = *rxpk.LSNR
+	}
+
+	if rxpk.Time != nil {
+		if rxInfo.Time, err = ptypes.TimestampProto(time.Time(*rxpk.Time)); err != nil {
+			return nil, fmt.Errorf("time error: %w", err)
+		}
+	}
+	if rxpk.Tmms != nil {
+		rxInfo.TimeSinceGpsEpoch = ptypes.DurationProto(time.Duration(*rxpk.Tmms) * time.Millisecond)
+	}
+
+	if len(rxpk.RSig) == 0 {
+		return []*gw.UplinkFrame{{
+			PhyPayload: rxpk.Data,
+			TxInfo:     txInfo,
+			RxInfo:     &rxInfo,
+		}}, nil
+	}
+
+	var out []*gw.UplinkFrame
+	for _, rsig := range rxpk.RSig {
+		ri := proto.Clone(&rxInfo).(*gw.UplinkRXInfo)
+		ri.Antenna = uint32(rsig.Ant)
+		ri.Channel = uint32(rsig.Chan)
+		ri.Rssi = int32(rsig.RSSIC)
+		ri.LoraSnr = rsig.LSNR
+
+		switch {
+		case len(rsig.ETime) != 0:
+			ri.FineTimestampType = gw.FineTimestampType_ENCRYPTED
+			ri.FineTimestamp = &gw.UplinkRXInfo_EncryptedFineTimestamp{
+				EncryptedFineTimestamp: &gw.EncryptedFineTimestamp{
+					AesKeyIndex: uint32(rxpk.AESK),
+					EncryptedNs: rsig.ETime,
+				},
+			}
+		case rsig.FTime != nil && rxpk.Tmms != nil:
+			// The fine timestamp holds the nanoseconds within the GPS
+			// second of tmms.
+			gpsSecond := time.Duration(*rxpk.Tmms/1000) * time.Second
+			ts, err := ptypes.TimestampProto(gpstime.ToTime(gpsSecond + time.Duration(*rsig.FTime)))
+			if err != nil {
+				return nil, fmt.Errorf("fine timestamp error: %w", err)
+			}
+			ri.FineTimestampType = gw.FineTimestampType_PLAIN
+			ri.FineTimestamp = &gw.UplinkRXInfo_PlainFineTimestamp{
+				PlainFineTimestamp: &gw.PlainFineTimestamp{
+					Time: ts,
+				},
+			}
+		}
+
+		out = append(out, &gw.UplinkFrame{
+			PhyPayload: rxpk.Data,
+			TxInfo:     proto.Clone(txInfo).(*gw.UplinkTXInfo),
+			RxInfo:     ri,
+		})
+	}
+	return out, nil
+}
+
+func uplinkTXInfo(rxpk *RXPK) (*gw.UplinkTXInfo, error) {
+	txInfo := gw.UplinkTXInfo{
+		Frequency: mhzToHz(rxpk.Freq),
+	}
+
+	switch rxpk.Modu {
+	case ModulationLoRa:
+		sf, bw, err := rxpk.DatR.ParseLoRa()
+		if err != nil {
+			return nil, err
+		}
+		txInfo.Modulation = common.Modulation_LORA
+		txInfo.ModulationInfo = &gw.UplinkTXInfo_LoraModulationInfo{
+			LoraModulationInfo: &gw.LoRaModulationInfo{
+				Bandwidth:       bw,
+				SpreadingFactor: sf,
+				CodeRate:        rxpk.CodR,
+			},
+		}
+	case ModulationFSK:
+		txInfo.Modulation = common.Modulation_FSK
+		txInfo.ModulationInfo = &gw.UplinkTXInfo_FskModulationInfo{
+			FskModulationInfo: &gw.FSKModulationInfo{
+				Bitrate: rxpk.DatR.FSK,
+			},
+		}
+	default:
+		return nil, fmt.Errorf("invalid modulation: %q", rxpk.Modu)
+	}
+
+	return &txInfo, nil
+}
+
+func newRXPK(protocolVersion uint8, f *gw.UplinkFrame) (RXPK, error) {
+	txInfo := f.GetTxInfo()
+	rxInfo := f.GetRxInfo()
+	if txInfo == nil || rxInfo == nil {
+		return RXPK{}, errors.New("tx_info and rx_info must not be nil")
+	}
+
+	rxpk := RXPK{
+		Freq: hzToMHz(txInfo.Frequency),
+		Brd:  rxInfo.Board,
+		Chan: uint8(rxInfo.Channel),
+		RFCh: uint8(rxInfo.RfChain),
+		Stat: 1,
+		RSSI: int16(rxInfo.Rssi),
+		Size: uint16(len(f.PhyPayload)),
+		Data: f.PhyPayload,
+	}
+
+	if len(rxInfo.Context) == 4 {
+		rxpk.Tmst = binary.BigEndian.Uint32(rxInfo.Context)
+	}
+	if rxInfo.Time != nil {
+		t, err := ptypes.Timestamp(rxInfo.Time)
+		if err != nil {
+			return rxpk, fmt.Errorf("time error: %w", err)
+		}
+		ct := CompactTime(t)
+		rxpk.Time = &ct
+	}
+	if rxInfo.TimeSinceGpsEpoch != nil {
+		d, err := ptypes.Duration(rxInfo.TimeSinceGpsEpoch)
+		if err != nil {
+			return rxpk, fmt.Errorf("time_since_gps_epoch error: %w", err)
+		}
+		tmms := int64(d / time.Millisecond)
+		rxpk.Tmms = &tmms
+	}
+
+	switch txInfo.Modulation {
+	case common.Modulation_LORA:
+		modInfo := txInfo.GetLoraModulationInfo()
+		if modInfo == nil {
+			return rxpk, errors.New("lora_modulation_info must not be nil")
+		}
+		rxpk.Modu = ModulationLoRa
+		rxpk.DatR = NewLoRaDatR(modInfo.SpreadingFactor, modInfo.Bandwidth)
+		rxpk.CodR = modInfo.CodeRate
+		lsnr := rxInfo.LoraSnr
+		rxpk.LSNR = &lsnr
+	case common.Modulation_FSK:
+		modInfo := txInfo.GetFskModulationInfo()
+		if modInfo == nil {
+			return rxpk, errors.New("fsk_modulation_info must not be nil")
+		}
+		rxpk.Modu = ModulationFSK
+		rxpk.DatR = DatR{FSK: modInfo.Bitrate}
+	}
+
+	if protocolVersion == ProtocolVersion2 {
+		rsig := RSig{
+			Ant:   uint8(rxInfo.Antenna),
+			Chan:  uint8(rxInfo.Channel),
+			RSSIC: int16(rxInfo.Rssi),
+			LSNR:  rxInfo.LoraSnr,
+		}
+
+		switch ft := rxInfo.FineTimestamp.(type) {
+		case *gw.UplinkRXInfo_EncryptedFineTimestamp:
+			rxpk.AESK = uint8(ft.EncryptedFineTimestamp.GetAesKeyIndex())
+			rsig.ETime = ft.EncryptedFineTimestamp.GetEncryptedNs()
+		case *gw.UplinkRXInfo_PlainFineTimestamp:
+			t, err := ptypes.Timestamp(ft.PlainFineTimestamp.GetTime())
+			if err != nil {
+				return rxpk, fmt.Errorf("plain fine timestamp error: %w", err)
+			}
+			d := gpstime.FromTime(t)
+			ftime := uint32(d % time.Second)
+			rsig.FTime = &ftime
+			if rxpk.Tmms == nil {
+				tmms := int64(d / time.Millisecond)
+				rxpk.Tmms = &tmms
+			}
+		}
+
+		rxpk.RSig = []RSig{rsig}
+	}
+
+	return rxpk, nil
+}
+
+// newStat returns the stat object of the gateway stats. The rxfw and ackr
+// fields are not part of the gateway stats and are left zero.
+func newStat(stats *gw.GatewayStats) (Stat, error) {
+	stat := Stat{
+		RXNb: stats.RxPacketsReceived,
+		RXOK: stats.RxPacketsReceivedOk,
+		DWNb: stats.TxPacketsReceived,
+		TXNb: stats.TxPacketsEmitted,
+	}
+
+	if stats.Time != nil {
+		t, err := ptypes.Timestamp(stats.Time)
+		if err != nil {
+			return stat, fmt.Errorf("semtech: stats time error: %w", err)
+		}
+		stat.Time = ExpandedTime(t)
+	}
+
+	if loc := stats.Location; loc != nil {
+		lati, long, alti := loc.Latitude, loc.Longitude, int32(math.Round(loc.Altitude))
+		stat.Lati = &lati
+		stat.Long = &long
+		stat.Alti = &alti
+	}
+
+	return stat, nil
+}
+
+func mhzToHz(f float64) uint32 {
+	return uint32(math.Round(f * 1000000))
+}
+
+func hzToMHz(f uint32) float64 {
+	return float64(f) / 1000000
+}
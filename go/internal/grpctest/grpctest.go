@@ -0,0 +1,88 @@
+// Package grpctest serves gRPC services over an in-memory (bufconn) listener,
+// for use by the fake service implementations.
+package grpctest
+
+import (
+	"context"
+	"net"
+	"sync"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+const bufSize = 1024 * 1024
+
+// Server serves the registered services over an in-memory listener. The
+// gRPC server is started on the first Dial.
+type Server struct {
+	register func(*grpc.Server)
+
+	mu       sync.Mutex
+	listener *bufconn.Listener
+	server   *grpc.Server
+	conns    []*grpc.ClientConn
+	closed   bool
+}
+
+// NewServer returns a new Server. The register function is called once to
+// register the services with the gRPC server.
+func NewServer(register func(*grpc.Server)) *Server {
+	return &Server{
+		register: register,
+	}
+}
+
+// Dial returns a new connection to the in-memory server. The connection is
+// closed by Close.
+func (s *Server) Dial(ctx context.Context, opts ...grpc.DialOption) (*grpc.ClientConn, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.closed {
+		return nil, grpc.ErrServerStopped
+	}
+
+	if s.server == nil {
+		s.listener = bufconn.Listen(bufSize)
+		s.server = grpc.NewServer()
+		s.register(s.server)
+		go s.server.Serve(s.listener)
+	}
+
+	lis := s.listener
+	dialOpts := append([]grpc.DialOption{
+		grpc.WithInsecure(),
+		grpc.WithContextDialer(func(context.Context, string) (net.Conn, error) {
+			return lis.Dial()
+		}),
+	}, opts...)
+
+	conn, err := grpc.DialContext(ctx, "bufnet", dialOpts...)
+	if err != nil {
+		return nil, err
+	}
+	s.conns = append(s.conns, conn)
+	return conn, nil
+}
+
+// Close closes the connections returned by Dial and stops the server.
+func (s *Server) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.closed {
+		return nil
+	}
+	s.closed = true
+
+	for _, conn := range s.conns {
+		conn.Close()
+	}
+	s.conns = nil
+
+	if s.server != nil {
+		s.server.Stop()
+	}
+	return nil
+}
@@ -0,0 +1,62 @@
+package lorawan
+
+import (
+	"database/sql/driver"
+	"encoding/hex"
+)
+
+// AES128Key defines an AES128 key (e.g. a root or session key).
+type AES128Key [16]byte
+
+// ParseAES128Key parses the HEX encoded AES128 key (as used by the external
+// API).
+func ParseAES128Key(s string) (AES128Key, error) {
+	var k AES128Key
+	err := decodeHex(k[:], "AES128 key", s)
+	return k, err
+}
+
+// AES128KeyFromBytes returns the key for the given bytes (as used by the
+// ns, as, gw and integration API).
+func AES128KeyFromBytes(b []byte) (AES128Key, error) {
+	var k AES128Key
+	err := copyBytes(k[:], "AES128 key", b)
+	return k, err
+}
+
+// String returns the key as a lower-case HEX encoded string.
+func (k AES128Key) String() string {
+	return hex.EncodeToString(k[:])
+}
+
+// Bytes returns the key as a byte slice.
+func (k AES128Key) Bytes() []byte {
+	b := make([]byte, len(k))
+	copy(b, k[:])
+	return b
+}
+
+// IsZero returns true when all bytes of the key are zero.
+func (k AES128Key) IsZero() bool {
+	return k == AES128Key{}
+}
+
+// MarshalText implements encoding.TextMarshaler.
+func (k AES128Key) MarshalText() ([]byte, error) {
+	return []byte(k.String()), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler.
+func (k *AES128Key) UnmarshalText(text []byte) error {
+	return decodeHex(k[:], "AES128 key", string(text))
+}
+
+// Scan implements sql.Scanner.
+func (k *AES128Key) Scan(src interface{}) error {
+	return scan(k[:], "AES128 key", src)
+}
+
+// Value implements driver.Valuer.
+func (k AES128Key) Value() (driver.Value, error) {
+	return value(k[:])
+}
@@ -0,0 +1,129 @@
+package lorawan
+
+import (
+	"database/sql/driver"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+)
+
+// DevAddr defines the 32 bit device address.
+type DevAddr [4]byte
+
+// ParseDevAddr parses the HEX encoded DevAddr (as used by the external API).
+func ParseDevAddr(s string) (DevAddr, error) {
+	var a DevAddr
+	err := decodeHex(a[:], "DevAddr", s)
+	return a, err
+}
+
+// DevAddrFromBytes returns the DevAddr for the given bytes (as used by the
+// ns and as API).
+func DevAddrFromBytes(b []byte) (DevAddr, error) {
+	var a DevAddr
+	err := copyBytes(a[:], "DevAddr", b)
+	return a, err
+}
+
+// NewDevAddr returns the DevAddr for the given NetID and NwkAddr. It returns
+// an error when the NwkAddr does not fit the NetID type.
+func NewDevAddr(netID NetID, nwkAddr uint32) (DevAddr, error) {
+	typ := uint(netID.Type())
+	addrBits := netID.NwkAddrBits()
+	if nwkAddr >= 1<<addrBits {
+		return DevAddr{}, fmt.Errorf("lorawan: DevAddr: NwkAddr exceeds %d bits for NetID type %d", addrBits, typ)
+	}
+
+	// prefix: type times a 1 bit, followed by a 0 bit
+	prefix := uint32(0xff<<(8-typ)) & 0xff
+	v := prefix<<24 | netID.NwkID()<<addrBits | nwkAddr
+
+	var a DevAddr
+	binary.BigEndian.PutUint32(a[:], v)
+	return a, nil
+}
+
+// String returns the DevAddr as a lower-case HEX encoded string.
+func (a DevAddr) String() string {
+	return hex.EncodeToString(a[:])
+}
+
+// Bytes returns the DevAddr as a byte slice.
+func (a DevAddr) Bytes() []byte {
+	b := make([]byte, len(a))
+	copy(b, a[:])
+	return b
+}
+
+// IsZero returns true when all bytes of the DevAddr are zero.
+func (a DevAddr) IsZero() bool {
+	return a == DevAddr{}
+}
+
+// NetIDType returns the NetID type encoded by the DevAddr prefix, or -1 when
+// the prefix is invalid.
+func (a DevAddr) NetIDType() int {
+	for i := 0; i < 8; i++ {
+		if a[0]&(0x80>>uint(i)) == 0 {
+			return i
+		}
+	}
+	return -1
+}
+
+// NwkID returns the NwkID of the DevAddr.
+func (a DevAddr) NwkID() uint32 {
+	typ := a.NetIDType()
+	if typ < 0 {
+		return 0
+	}
+	return binary.BigEndian.Uint32(a[:]) >> nwkAddrBits(typ) & (1<<nwkIDBits[typ] - 1)
+}
+
+// NwkAddr returns the NwkAddr of the DevAddr.
+func (a DevAddr) NwkAddr() uint32 {
+	typ := a.NetIDType()
+	if typ < 0 {
+		return 0
+	}
+	return binary.BigEndian.Uint32(a[:]) & (1<<nwkAddrBits(typ) - 1)
+}
+
+// IsNetID returns true when the DevAddr prefix and NwkID match the given
+// NetID.
+func (a DevAddr) IsNetID(netID NetID) bool {
+	return a.NetIDType() == netID.Type() && a.NwkID() == netID.NwkID()
+}
+
+// Validate returns an error when the DevAddr does not belong to the given
+// NetID.
+func (a DevAddr) Validate(netID NetID) error {
+	if a.NetIDType() < 0 {
+		return errors.New("lorawan: DevAddr: invalid prefix")
+	}
+	if !a.IsNetID(netID) {
+		return fmt.Errorf("lorawan: DevAddr %s does not belong to NetID %s", a, netID)
+	}
+	return nil
+}
+
+// MarshalText implements encoding.TextMarshaler.
+func (a DevAddr) MarshalText() ([]byte, error) {
+	return []byte(a.String()), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler.
+func (a *DevAddr) UnmarshalText(text []byte) error {
+	return decodeHex(a[:], "DevAddr", string(text))
+}
+
+// Scan implements sql.Scanner.
+func (a *DevAddr) Scan(src interface{}) error {
+	return scan(a[:], "DevAddr", src)
+}
+
+// Value implements driver.Valuer.
+func (a DevAddr) Value() (driver.Value, error) {
+	return value(a[:])
+}
@@ -0,0 +1,61 @@
+package lorawan
+
+import (
+	"database/sql/driver"
+	"encoding/hex"
+)
+
+// EUI64 defines a 64 bit EUI (e.g. DevEUI, JoinEUI or gateway ID).
+type EUI64 [8]byte
+
+// ParseEUI64 parses the HEX encoded EUI64 (as used by the external API).
+func ParseEUI64(s string) (EUI64, error) {
+	var e EUI64
+	err := decodeHex(e[:], "EUI64", s)
+	return e, err
+}
+
+// EUI64FromBytes returns the EUI64 for the given bytes (as used by the
+// ns, as, gw and integration API).
+func EUI64FromBytes(b []byte) (EUI64, error) {
+	var e EUI64
+	err := copyBytes(e[:], "EUI64", b)
+	return e, err
+}
+
+// String returns the EUI64 as a lower-case HEX encoded string.
+func (e EUI64) String() string {
+	return hex.EncodeToString(e[:])
+}
+
+// Bytes returns the EUI64 as a byte slice.
+func (e EUI64) Bytes() []byte {
+	b := make([]byte, len(e))
+	copy(b, e[:])
+	return b
+}
+
+// IsZero returns true when all bytes of the EUI64 are zero.
+func (e EUI64) IsZero() bool {
+	return e == EUI64{}
+}
+
+// MarshalText implements encoding.TextMarshaler.
+func (e EUI64) MarshalText() ([]byte, error) {
+	return []byte(e.String()), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler.
+func (e *EUI64) UnmarshalText(text []byte) error {
+	return decodeHex(e[:], "EUI64", string(text))
+}
+
+// Scan implements sql.Scanner.
+func (e *EUI64) Scan(src interface{}) error {
+	return scan(e[:], "EUI64", src)
+}
+
+// Value implements driver.Valuer.
+func (e EUI64) Value() (driver.Value, error) {
+	return value(e[:])
+}
@@ -0,0 +1,56 @@
+// Package lorawan provides the LoRaWAN identifier and key types shared by the
+// API packages.
+//
+// The ns, as, gw and integration packages use the byte representation of
+// these values, the as/external/api package uses the HEX encoded string
+// representation. In both cases, the bytes are in the order in which they are
+// displayed (MSB first), which is the reverse of the order in which they are
+// encoded within LoRaWAN frames.
+package lorawan
+
+import (
+	"database/sql/driver"
+	"encoding/hex"
+	"fmt"
+)
+
+// decodeHex decodes the HEX encoded string into dst. The string may be upper
+// or lower case and must decode exactly into len(dst) bytes.
+func decodeHex(dst []byte, name, s string) error {
+	if len(s) != hex.EncodedLen(len(dst)) {
+		return fmt.Errorf("lorawan: %s: expected %d HEX characters, got %d", name, hex.EncodedLen(len(dst)), len(s))
+	}
+	if _, err := hex.Decode(dst, []byte(s)); err != nil {
+		return fmt.Errorf("lorawan: %s: %w", name, err)
+	}
+	return nil
+}
+
+// copyBytes copies b into dst and returns an error when the lengths differ.
+func copyBytes(dst []byte, name string, b []byte) error {
+	if len(b) != len(dst) {
+		return fmt.Errorf("lorawan: %s: expected %d bytes, got %d", name, len(dst), len(b))
+	}
+	copy(dst, b)
+	return nil
+}
+
+// scan implements sql.Scanner for the fixed-length byte types. Both the byte
+// and HEX encoded string representations are accepted.
+func scan(dst []byte, name string, src interface{}) error {
+	switch v := src.(type) {
+	case []byte:
+		return copyBytes(dst, name, v)
+	case string:
+		return decodeHex(dst, name, v)
+	default:
+		return fmt.Errorf("lorawan: %s: unsupported scan type %T", name, src)
+	}
+}
+
+// value implements driver.Valuer for the fixed-length byte types.
+func value(b []byte) (driver.Value, error) {
+	out := make([]byte, len(b))
+	copy(out, b)
+	return out, nil
+}
@@ -0,0 +1,256 @@
+package lorawan
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+)
+
+func TestEUI64(t *testing.T) {
+	e, err := ParseEUI64("0102030405060A0B")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if exp := (EUI64{0x01, 0x02, 0x03, 0x04, 0x05, 0x06, 0x0a, 0x0b}); e != exp {
+		t.Errorf("expected %v, got %v", exp, e)
+	}
+	if s := e.String(); s != "0102030405060a0b" {
+		t.Errorf("expected 0102030405060a0b, got %s", s)
+	}
+
+	b, err := EUI64FromBytes(e.Bytes())
+	if err != nil || b != e {
+		t.Errorf("bytes round trip: expected %s, got %s (%v)", e, b, err)
+	}
+	if e.IsZero() || !(EUI64{}).IsZero() {
+		t.Error("unexpected IsZero result")
+	}
+
+	for _, s := range []string{"", "01020304050607", "010203040506070809", "010203040506070g"} {
+		if _, err := ParseEUI64(s); err == nil {
+			t.Errorf("%q: expected error", s)
+		}
+	}
+	if _, err := EUI64FromBytes(make([]byte, 7)); err == nil {
+		t.Error("expected error for 7 bytes")
+	}
+}
+
+func TestDevAddr(t *testing.T) {
+	a, err := ParseDevAddr("26011234")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if exp := (DevAddr{0x26, 0x01, 0x12, 0x34}); a != exp {
+		t.Errorf("expected %v, got %v", exp, a)
+	}
+	if s := a.String(); s != "26011234" {
+		t.Errorf("expected 26011234, got %s", s)
+	}
+
+	b, err := DevAddrFromBytes(a.Bytes())
+	if err != nil || b != a {
+		t.Errorf("bytes round trip: expected %s, got %s (%v)", a, b, err)
+	}
+
+	if _, err := ParseDevAddr("260112"); err == nil {
+		t.Error("expected error for 3 bytes")
+	}
+	if _, err := DevAddrFromBytes(make([]byte, 5)); err == nil {
+		t.Error("expected error for 5 bytes")
+	}
+}
+
+func TestAES128Key(t *testing.T) {
+	k, err := ParseAES128Key("2B7E151628AED2A6ABF7158809CF4F3C")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s := k.String(); s != "2b7e151628aed2a6abf7158809cf4f3c" {
+		t.Errorf("expected 2b7e151628aed2a6abf7158809cf4f3c, got %s", s)
+	}
+
+	b, err := AES128KeyFromBytes(k.Bytes())
+	if err != nil || b != k {
+		t.Errorf("bytes round trip: expected %s, got %s (%v)", k, b, err)
+	}
+	if k.IsZero() || !(AES128Key{}).IsZero() {
+		t.Error("unexpected IsZero result")
+	}
+
+	if _, err := ParseAES128Key("2b7e151628aed2a6abf7158809cf4f"); err == nil {
+		t.Error("expected error for 15 bytes")
+	}
+	if _, err := AES128KeyFromBytes(make([]byte, 32)); err == nil {
+		t.Error("expected error for 32 bytes")
+	}
+}
+
+func TestTextAndJSON(t *testing.T) {
+	type ids struct {
+		DevEUI  EUI64     `json:"devEUI"`
+		DevAddr DevAddr   `json:"devAddr"`
+		NetID   NetID     `json:"netID"`
+		AppSKey AES128Key `json:"appSKey"`
+	}
+
+	in := ids{
+		DevEUI:  EUI64{0x01, 0x02, 0x03, 0x04, 0x05, 0x06, 0x07, 0x08},
+		DevAddr: DevAddr{0x26, 0x01, 0x12, 0x34},
+		NetID:   NetID{0x00, 0x00, 0x13},
+		AppSKey: AES128Key{0x2b, 0x7e, 0x15, 0x16, 0x28, 0xae, 0xd2, 0xa6, 0xab, 0xf7, 0x15, 0x88, 0x09, 0xcf, 0x4f, 0x3c},
+	}
+
+	b, err := json.Marshal(in)
+	if err != nil {
+		t.Fatal(err)
+	}
+	exp := `{"devEUI":"0102030405060708","devAddr":"26011234","netID":"000013","appSKey":"2b7e151628aed2a6abf7158809cf4f3c"}`
+	if string(b) != exp {
+		t.Errorf("expected %s, got %s", exp, b)
+	}
+
+	var out ids
+	if err := json.Unmarshal(b, &out); err != nil {
+		t.Fatal(err)
+	}
+	if out != in {
+		t.Errorf("expected %+v, got %+v", in, out)
+	}
+
+	if err := json.Unmarshal([]byte(`{"devEUI":"0102"}`), &out); err == nil {
+		t.Error("expected error for a short DevEUI")
+	}
+}
+
+func TestScanValue(t *testing.T) {
+	var e EUI64
+	if err := e.Scan([]byte{0x01, 0x02, 0x03, 0x04, 0x05, 0x06, 0x07, 0x08}); err != nil {
+		t.Fatal(err)
+	}
+	v, err := e.Value()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(v.([]byte), e[:]) {
+		t.Errorf("expected %x, got %x", e[:], v)
+	}
+
+	var n NetID
+	if err := n.Scan("000013"); err != nil || n != (NetID{0x00, 0x00, 0x13}) {
+		t.Errorf("expected 000013, got %s (%v)", n, err)
+	}
+	if err := n.Scan(13); err == nil {
+		t.Error("expected error for unsupported scan type")
+	}
+}
+
+func TestNetID(t *testing.T) {
+	tests := []struct {
+		netID       string
+		typ         int
+		id          uint32
+		nwkID       uint32
+		nwkAddrBits uint
+	}{
+		{"000013", 0, 0x13, 0x13, 25},
+		{"3fffff", 1, 0x1fffff, 0x3f, 24},
+		{"400005", 2, 0x05, 0x05, 20},
+		{"600020", 3, 0x20, 0x20, 17},
+		{"c01234", 6, 0x1234, 0x1234, 10},
+		{"fffffe", 7, 0x1ffffe, 0x1fffe, 7},
+	}
+
+	for _, test := range tests {
+		n, err := ParseNetID(test.netID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if n.Type() != test.typ || n.ID() != test.id || n.NwkID() != test.nwkID || n.NwkAddrBits() != test.nwkAddrBits {
+			t.Errorf("%s: expected type %d, ID %x, NwkID %x, NwkAddr bits %d, got %d, %x, %x, %d",
+				test.netID, test.typ, test.id, test.nwkID, test.nwkAddrBits, n.Type(), n.ID(), n.NwkID(), n.NwkAddrBits())
+		}
+
+		nn, err := NewNetID(test.typ, test.id)
+		if err != nil || nn != n {
+			t.Errorf("NewNetID(%d, %x): expected %s, got %s (%v)", test.typ, test.id, n, nn, err)
+		}
+	}
+
+	if _, err := NewNetID(8, 0); err == nil {
+		t.Error("expected error for type 8")
+	}
+	if _, err := NewNetID(0, 1<<21); err == nil {
+		t.Error("expected error for a 22 bit ID")
+	}
+	if _, err := NetIDFromBytes([]byte{0x00, 0x13}); err == nil {
+		t.Error("expected error for 2 bytes")
+	}
+}
+
+func TestNewDevAddr(t *testing.T) {
+	tests := []struct {
+		netID   NetID
+		nwkAddr uint32
+		devAddr DevAddr
+	}{
+		// type 0: prefix 0, 6 bit NwkID, 25 bit NwkAddr
+		{NetID{0x00, 0x00, 0x13}, 0x011234, DevAddr{0x26, 0x01, 0x12, 0x34}},
+		// type 3: prefix 1110, 11 bit NwkID, 17 bit NwkAddr
+		{NetID{0x60, 0x00, 0x20}, 0x1234, DevAddr{0xe0, 0x40, 0x12, 0x34}},
+		// type 7: prefix 11111110, 17 bit NwkID, 7 bit NwkAddr
+		{NetID{0xff, 0xff, 0xfe}, 0x7f, DevAddr{0xfe, 0xff, 0xff, 0x7f}},
+	}
+
+	for _, test := range tests {
+		a, err := NewDevAddr(test.netID, test.nwkAddr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if a != test.devAddr {
+			t.Errorf("NetID %s: expected %s, got %s", test.netID, test.devAddr, a)
+		}
+		if a.NetIDType() != test.netID.Type() || a.NwkID() != test.netID.NwkID() || a.NwkAddr() != test.nwkAddr {
+			t.Errorf("%s: expected type %d, NwkID %x, NwkAddr %x, got %d, %x, %x",
+				a, test.netID.Type(), test.netID.NwkID(), test.nwkAddr, a.NetIDType(), a.NwkID(), a.NwkAddr())
+		}
+		if !a.IsNetID(test.netID) || a.Validate(test.netID) != nil {
+			t.Errorf("%s: expected to belong to NetID %s", a, test.netID)
+		}
+
+		if _, err := NewDevAddr(test.netID, 1<<test.netID.NwkAddrBits()); err == nil {
+			t.Errorf("NetID %s: expected error for a NwkAddr exceeding %d bits", test.netID, test.netID.NwkAddrBits())
+		}
+	}
+}
+
+func TestDevAddrIsNetID(t *testing.T) {
+	tests := []struct {
+		devAddr DevAddr
+		netID   NetID
+		isNetID bool
+	}{
+		{DevAddr{0x26, 0x01, 0x12, 0x34}, NetID{0x00, 0x00, 0x13}, true},
+		{DevAddr{0x27, 0xff, 0xff, 0xff}, NetID{0x00, 0x00, 0x13}, true},
+		{DevAddr{0x28, 0x00, 0x00, 0x00}, NetID{0x00, 0x00, 0x13}, false},
+		// same NwkID, but a different NetID type
+		{DevAddr{0x26, 0x01, 0x12, 0x34}, NetID{0x20, 0x00, 0x13}, false},
+		{DevAddr{0xe0, 0x40, 0x12, 0x34}, NetID{0x60, 0x00, 0x20}, true},
+		{DevAddr{0xe0, 0x40, 0x12, 0x34}, NetID{0x60, 0x00, 0x21}, false},
+		// invalid prefix
+		{DevAddr{0xff, 0x00, 0x00, 0x00}, NetID{0xe0, 0x00, 0x00}, false},
+	}
+
+	for _, test := range tests {
+		if is := test.devAddr.IsNetID(test.netID); is != test.isNetID {
+			t.Errorf("%s, NetID %s: expected %t, got %t", test.devAddr, test.netID, test.isNetID, is)
+		}
+		if err := test.devAddr.Validate(test.netID); (err == nil) != test.isNetID {
+			t.Errorf("%s, NetID %s: unexpected Validate result: %v", test.devAddr, test.netID, err)
+		}
+	}
+
+	if typ := (DevAddr{0xff, 0x00, 0x00, 0x00}).NetIDType(); typ != -1 {
+		t.Errorf("expected NetID type -1 for an invalid prefix, got %d", typ)
+	}
+}
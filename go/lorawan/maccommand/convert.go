@@ -0,0 +1,89 @@
+package maccommand
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/brocaar/chirpstack-api/go/lorawan"
+	"github.com/brocaar/chirpstack-api/go/nc"
+	"github.com/brocaar/chirpstack-api/go/ns"
+)
+
+// FromHandleUplinkMACCommandRequest returns the (uplink) mac-commands of the
+// given request.
+func FromHandleUplinkMACCommandRequest(req *nc.HandleUplinkMACCommandRequest) ([]Command, error) {
+	return fromCommands(true, req.GetCid(), req.GetCommands())
+}
+
+// NewHandleUplinkMACCommandRequest returns the request for the given
+// (uplink) mac-commands, which must all have the same CID.
+func NewHandleUplinkMACCommandRequest(devEUI lorawan.EUI64, cmds []Command) (*nc.HandleUplinkMACCommandRequest, error) {
+	cid, b, err := toCommands(cmds)
+	if err != nil {
+		return nil, err
+	}
+	return &nc.HandleUplinkMACCommandRequest{
+		DevEui:   devEUI.Bytes(),
+		Cid:      uint32(cid),
+		Commands: b,
+	}, nil
+}
+
+// FromCreateMACCommandQueueItemRequest returns the (downlink) mac-commands of
+// the given request.
+func FromCreateMACCommandQueueItemRequest(req *ns.CreateMACCommandQueueItemRequest) ([]Command, error) {
+	return fromCommands(false, req.GetCid(), req.GetCommands())
+}
+
+// NewCreateMACCommandQueueItemRequest returns the request for the given
+// (downlink) mac-commands, which must all have the same CID.
+func NewCreateMACCommandQueueItemRequest(devEUI lorawan.EUI64, cmds []Command) (*ns.CreateMACCommandQueueItemRequest, error) {
+	cid, b, err := toCommands(cmds)
+	if err != nil {
+		return nil, err
+	}
+	return &ns.CreateMACCommandQueueItemRequest{
+		DevEui:   devEUI.Bytes(),
+		Cid:      uint32(cid),
+		Commands: b,
+	}, nil
+}
+
+// fromCommands decodes the commands, which must match the given CID.
+func fromCommands(uplink bool, cid uint32, commands [][]byte) ([]Command, error) {
+	if cid > 0xff {
+		return nil, fmt.Errorf("maccommand: invalid cid: %d", cid)
+	}
+
+	out := make([]Command, len(commands))
+	for i, b := range commands {
+		if err := out[i].Decode(uplink, b); err != nil {
+			return nil, fmt.Errorf("%w (command %d)", err, i)
+		}
+		if out[i].CID != CID(cid) {
+			return nil, fmt.Errorf("maccommand: command %d: cid %s does not match cid %s", i, out[i].CID, CID(cid))
+		}
+	}
+	return out, nil
+}
+
+// toCommands marshals the commands, which must all have the same CID.
+func toCommands(cmds []Command) (CID, [][]byte, error) {
+	if len(cmds) == 0 {
+		return 0, nil, errors.New("maccommand: at least one command expected")
+	}
+
+	cid := cmds[0].CID
+	out := make([][]byte, len(cmds))
+	for i, cmd := range cmds {
+		if cmd.CID != cid {
+			return 0, nil, fmt.Errorf("maccommand: command %d: cid %s does not match cid %s", i, cmd.CID, cid)
+		}
+		b, err := cmd.MarshalBinary()
+		if err != nil {
+			return 0, nil, err
+		}
+		out[i] = b
+	}
+	return cid, out, nil
+}
@@ -0,0 +1,105 @@
+package maccommand
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/golang/protobuf/proto"
+
+	"github.com/brocaar/chirpstack-api/go/lorawan"
+	"github.com/brocaar/chirpstack-api/go/nc"
+	"github.com/brocaar/chirpstack-api/go/ns"
+)
+
+var devEUI = lorawan.EUI64{1, 2, 3, 4, 5, 6, 7, 8}
+
+func TestHandleUplinkMACCommandRequest(t *testing.T) {
+	cmds := []Command{
+		{CID: LinkADR, Payload: &LinkADRAns{PowerACK: true, DataRateACK: true, ChannelMaskACK: true}},
+		{CID: LinkADR, Payload: &LinkADRAns{ChannelMaskACK: true}},
+	}
+	expected := &nc.HandleUplinkMACCommandRequest{
+		DevEui:   devEUI[:],
+		Cid:      3,
+		Commands: [][]byte{{0x03, 0x07}, {0x03, 0x01}},
+	}
+
+	req, err := NewHandleUplinkMACCommandRequest(devEUI, cmds)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !proto.Equal(req, expected) {
+		t.Errorf("expected %s, got %s", expected, req)
+	}
+
+	out, err := FromHandleUplinkMACCommandRequest(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(out, cmds) {
+		t.Errorf("expected %+v, got %+v", cmds, out)
+	}
+
+	// The commands are decoded as uplink: 4 bytes is a LinkADRReq.
+	req.Commands = [][]byte{{0x03, 0x52, 0x07, 0x00, 0x01}}
+	if _, err := FromHandleUplinkMACCommandRequest(req); err == nil {
+		t.Error("expected error for a downlink mac-command")
+	}
+}
+
+func TestCreateMACCommandQueueItemRequest(t *testing.T) {
+	cmds := []Command{
+		{CID: DevStatus},
+	}
+	expected := &ns.CreateMACCommandQueueItemRequest{
+		DevEui:   devEUI[:],
+		Cid:      6,
+		Commands: [][]byte{{0x06}},
+	}
+
+	req, err := NewCreateMACCommandQueueItemRequest(devEUI, cmds)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !proto.Equal(req, expected) {
+		t.Errorf("expected %s, got %s", expected, req)
+	}
+
+	out, err := FromCreateMACCommandQueueItemRequest(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(out, cmds) {
+		t.Errorf("expected %+v, got %+v", cmds, out)
+	}
+}
+
+func TestConvertErrors(t *testing.T) {
+	if _, err := NewCreateMACCommandQueueItemRequest(devEUI, nil); err == nil {
+		t.Error("expected error for no commands")
+	}
+	if _, err := NewCreateMACCommandQueueItemRequest(devEUI, []Command{{CID: DevStatus}, {CID: LinkCheck, Payload: &LinkCheckAns{}}}); err == nil {
+		t.Error("expected error for mixed cids")
+	}
+	if _, err := NewHandleUplinkMACCommandRequest(devEUI, []Command{{CID: LinkADR}}); err == nil {
+		t.Error("expected error for a missing payload")
+	}
+
+	tests := []struct {
+		name string
+		req  *ns.CreateMACCommandQueueItemRequest
+	}{
+		{"cid mismatch", &ns.CreateMACCommandQueueItemRequest{Cid: 2, Commands: [][]byte{{0x06}}}},
+		{"invalid cid", &ns.CreateMACCommandQueueItemRequest{Cid: 0x106, Commands: [][]byte{{0x06}}}},
+		{"empty command", &ns.CreateMACCommandQueueItemRequest{Cid: 6, Commands: [][]byte{{}}}},
+		{"truncated command", &ns.CreateMACCommandQueueItemRequest{Cid: 2, Commands: [][]byte{{0x02, 0x0a}}}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if cmds, err := FromCreateMACCommandQueueItemRequest(test.req); err == nil {
+				t.Errorf("expected error, got %+v", cmds)
+			}
+		})
+	}
+}
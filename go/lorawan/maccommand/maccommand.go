@@ -0,0 +1,299 @@
+// Package maccommand implements the LoRaWAN mac-commands, as carried by the
+// cid and commands fields of the nc.HandleUplinkMACCommandRequest and
+// ns.CreateMACCommandQueueItemRequest messages. Every item of commands holds
+// a single mac-command, starting with the CID byte.
+//
+// The payload of a mac-command depends on its direction. Uplink mac-commands
+// are sent by the device (e.g. LinkADRAns), downlink mac-commands by the
+// network-server (e.g. LinkADRReq). Mac-commands without payload (e.g.
+// DevStatusReq) have a nil Payload.
+package maccommand
+
+import (
+	"encoding"
+	"errors"
+	"fmt"
+	"reflect"
+)
+
+// CID defines the mac-command identifier.
+type CID byte
+
+// Mac-command identifiers.
+const (
+	Reset            CID = 0x01
+	LinkCheck        CID = 0x02
+	LinkADR          CID = 0x03
+	DutyCycle        CID = 0x04
+	RXParamSetup     CID = 0x05
+	DevStatus        CID = 0x06
+	NewChannel       CID = 0x07
+	RXTimingSetup    CID = 0x08
+	TXParamSetup     CID = 0x09
+	DLChannel        CID = 0x0A
+	Rekey            CID = 0x0B
+	ADRParamSetup    CID = 0x0C
+	DeviceTime       CID = 0x0D
+	ForceRejoin      CID = 0x0E
+	RejoinParamSetup CID = 0x0F
+	PingSlotInfo     CID = 0x10
+	PingSlotChannel  CID = 0x11
+	BeaconTiming     CID = 0x12
+	BeaconFreq       CID = 0x13
+	DeviceMode       CID = 0x20
+)
+
+// ProprietaryMin defines the first proprietary CID (0x80 - 0xFF).
+const ProprietaryMin CID = 0x80
+
+var cidNames = map[CID]string{
+	Reset:            "Reset",
+	LinkCheck:        "LinkCheck",
+	LinkADR:          "LinkADR",
+	DutyCycle:        "DutyCycle",
+	RXParamSetup:     "RXParamSetup",
+	DevStatus:        "DevStatus",
+	NewChannel:       "NewChannel",
+	RXTimingSetup:    "RXTimingSetup",
+	TXParamSetup:     "TXParamSetup",
+	DLChannel:        "DLChannel",
+	Rekey:            "Rekey",
+	ADRParamSetup:    "ADRParamSetup",
+	DeviceTime:       "DeviceTime",
+	ForceRejoin:      "ForceRejoin",
+	RejoinParamSetup: "RejoinParamSetup",
+	PingSlotInfo:     "PingSlotInfo",
+	PingSlotChannel:  "PingSlotChannel",
+	BeaconTiming:     "BeaconTiming",
+	BeaconFreq:       "BeaconFreq",
+	DeviceMode:       "DeviceMode",
+}
+
+// String implements fmt.Stringer.
+func (c CID) String() string {
+	if name, ok := cidNames[c]; ok {
+		return name
+	}
+	if c.IsProprietary() {
+		return fmt.Sprintf("Proprietary(0x%02x)", byte(c))
+	}
+	return fmt.Sprintf("CID(0x%02x)", byte(c))
+}
+
+// IsProprietary returns true for the proprietary CID range.
+func (c CID) IsProprietary() bool {
+	return c >= ProprietaryMin
+}
+
+// Errors.
+var (
+	ErrUnknownCID = errors.New("maccommand: unknown cid")
+	ErrNoCommand  = errors.New("maccommand: cid is not defined for this direction")
+)
+
+// Payload is implemented by the mac-command payloads.
+type Payload interface {
+	encoding.BinaryMarshaler
+	encoding.BinaryUnmarshaler
+}
+
+// payloadType defines the payload of a mac-command in a single direction.
+type payloadType struct {
+	size int
+
+	// new returns a new payload, nil for mac-commands without payload.
+	new func() Payload
+}
+
+// none defines a mac-command without payload.
+var none = &payloadType{}
+
+// payloadTypes holds the uplink and downlink payload types per CID. A nil
+// entry means the mac-command is not defined for that direction.
+var payloadTypes = map[CID][2]*payloadType{
+	Reset:            {sized(1, func() Payload { return &ResetInd{} }), sized(1, func() Payload { return &ResetConf{} })},
+	LinkCheck:        {none, sized(2, func() Payload { return &LinkCheckAns{} })},
+	LinkADR:          {sized(1, func() Payload { return &LinkADRAns{} }), sized(4, func() Payload { return &LinkADRReq{} })},
+	DutyCycle:        {none, sized(1, func() Payload { return &DutyCycleReq{} })},
+	RXParamSetup:     {sized(1, func() Payload { return &RXParamSetupAns{} }), sized(4, func() Payload { return &RXParamSetupReq{} })},
+	DevStatus:        {sized(2, func() Payload { return &DevStatusAns{} }), none},
+	NewChannel:       {sized(1, func() Payload { return &NewChannelAns{} }), sized(5, func() Payload { return &NewChannelReq{} })},
+	RXTimingSetup:    {none, sized(1, func() Payload { return &RXTimingSetupReq{} })},
+	TXParamSetup:     {none, sized(1, func() Payload { return &TXParamSetupReq{} })},
+	DLChannel:        {sized(1, func() Payload { return &DLChannelAns{} }), sized(4, func() Payload { return &DLChannelReq{} })},
+	Rekey:            {sized(1, func() Payload { return &RekeyInd{} }), sized(1, func() Payload { return &RekeyConf{} })},
+	ADRParamSetup:    {none, sized(1, func() Payload { return &ADRParamSetupReq{} })},
+	DeviceTime:       {none, sized(5, func() Payload { return &DeviceTimeAns{} })},
+	ForceRejoin:      {nil, sized(2, func() Payload { return &ForceRejoinReq{} })},
+	RejoinParamSetup: {sized(1, func() Payload { return &RejoinParamSetupAns{} }), sized(1, func() Payload { return &RejoinParamSetupReq{} })},
+	PingSlotInfo:     {sized(1, func() Payload { return &PingSlotInfoReq{} }), none},
+	PingSlotChannel:  {sized(1, func() Payload { return &PingSlotChannelAns{} }), sized(4, func() Payload { return &PingSlotChannelReq{} })},
+	BeaconTiming:     {none, sized(3, func() Payload { return &BeaconTimingAns{} })},
+	BeaconFreq:       {sized(1, func() Payload { return &BeaconFreqAns{} }), sized(3, func() Payload { return &BeaconFreqReq{} })},
+	DeviceMode:       {sized(1, func() Payload { return &DeviceModeInd{} }), sized(1, func() Payload { return &DeviceModeConf{} })},
+}
+
+// sized returns a payload type of the given size.
+func sized(size int, fn func() Payload) *payloadType {
+	return &payloadType{size: size, new: fn}
+}
+
+// lookup returns the payload type of the CID for the given direction.
+func lookup(uplink bool, cid CID) (*payloadType, error) {
+	types, ok := payloadTypes[cid]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownCID, cid)
+	}
+	pt := types[1]
+	if uplink {
+		pt = types[0]
+	}
+	if pt == nil {
+		return nil, fmt.Errorf("%w: %s", ErrNoCommand, cid)
+	}
+	return pt, nil
+}
+
+// PayloadSize returns the payload size of the given CID and direction. It
+// returns -1 for proprietary mac-commands, as their size is not known.
+func PayloadSize(uplink bool, cid CID) (int, error) {
+	if cid.IsProprietary() {
+		return -1, nil
+	}
+	pt, err := lookup(uplink, cid)
+	if err != nil {
+		return 0, err
+	}
+	return pt.size, nil
+}
+
+// Command defines a mac-command.
+type Command struct {
+	CID     CID
+	Payload Payload
+}
+
+// MarshalBinary marshals the mac-command, including the CID. The payload
+// must match the CID (in either direction).
+func (c Command) MarshalBinary() ([]byte, error) {
+	if err := c.validatePayload(); err != nil {
+		return nil, err
+	}
+
+	out := []byte{byte(c.CID)}
+	if c.Payload == nil {
+		return out, nil
+	}
+	b, err := c.Payload.MarshalBinary()
+	if err != nil {
+		return nil, fmt.Errorf("maccommand: %s: %w", c.CID, err)
+	}
+	return append(out, b...), nil
+}
+
+// validatePayload returns an error when the payload type does not match the
+// CID.
+func (c Command) validatePayload() error {
+	if c.CID.IsProprietary() {
+		if _, ok := c.Payload.(*Proprietary); c.Payload != nil && !ok {
+			return fmt.Errorf("maccommand: %s: expected *Proprietary payload, got %T", c.CID, c.Payload)
+		}
+		return nil
+	}
+
+	types, ok := payloadTypes[c.CID]
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrUnknownCID, c.CID)
+	}
+	for _, pt := range types {
+		if pt == nil {
+			continue
+		}
+		if pt.new == nil {
+			if c.Payload == nil {
+				return nil
+			}
+			continue
+		}
+		if c.Payload != nil && reflect.TypeOf(pt.new()) == reflect.TypeOf(c.Payload) {
+			return nil
+		}
+	}
+	return fmt.Errorf("maccommand: %s: invalid payload %T", c.CID, c.Payload)
+}
+
+// Decode decodes a single mac-command, including the CID, for the given
+// direction. The bytes must contain exactly one mac-command.
+func (c *Command) Decode(uplink bool, b []byte) error {
+	if len(b) == 0 {
+		return errors.New("maccommand: at least 1 byte expected")
+	}
+	cmd, n, err := decode(uplink, b)
+	if err != nil {
+		return err
+	}
+	if n != len(b) {
+		return fmt.Errorf("maccommand: %s: %d trailing bytes", cmd.CID, len(b)-n)
+	}
+	*c = cmd
+	return nil
+}
+
+// Decode decodes a sequence of mac-commands (e.g. FOpts) for the given
+// direction. As the size of proprietary mac-commands is not known, a
+// proprietary mac-command takes the remaining bytes.
+func Decode(uplink bool, b []byte) ([]Command, error) {
+	var out []Command
+	for len(b) != 0 {
+		cmd, n, err := decode(uplink, b)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, cmd)
+		b = b[n:]
+	}
+	return out, nil
+}
+
+// Encode encodes the sequence of mac-commands.
+func Encode(cmds []Command) ([]byte, error) {
+	var out []byte
+	for _, cmd := range cmds {
+		b, err := cmd.MarshalBinary()
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, b...)
+	}
+	return out, nil
+}
+
+// decode decodes the first mac-command of b and returns the number of bytes
+// used.
+func decode(uplink bool, b []byte) (Command, int, error) {
+	cid := CID(b[0])
+	if cid.IsProprietary() {
+		p := &Proprietary{}
+		if err := p.UnmarshalBinary(b[1:]); err != nil {
+			return Command{}, 0, err
+		}
+		return Command{CID: cid, Payload: p}, len(b), nil
+	}
+
+	pt, err := lookup(uplink, cid)
+	if err != nil {
+		return Command{}, 0, err
+	}
+	if len(b)-1 < pt.size {
+		return Command{}, 0, fmt.Errorf("maccommand: %s: expected %d payload bytes, got %d", cid, pt.size, len(b)-1)
+	}
+
+	cmd := Command{CID: cid}
+	if pt.new != nil {
+		cmd.Payload = pt.new()
+		if err := cmd.Payload.UnmarshalBinary(b[1 : 1+pt.size]); err != nil {
+			return Command{}, 0, fmt.Errorf("maccommand: %s: %w", cid, err)
+		}
+	}
+	return cmd, 1 + pt.size, nil
+}
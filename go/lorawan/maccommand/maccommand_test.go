@@ -0,0 +1,298 @@
+package maccommand
+
+import (
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func mustHex(t *testing.T, s string) []byte {
+	t.Helper()
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+// The expected bytes follow the examples and field layouts of the LoRaWAN
+// 1.0.3 and 1.1 specifications.
+func TestCommand(t *testing.T) {
+	tests := []struct {
+		name   string
+		uplink bool
+		bytes  string
+		cmd    Command
+	}{
+		// Uplink.
+		{"ResetInd", true, "0101", Command{CID: Reset, Payload: &ResetInd{DevLoRaWANVersion: 1}}},
+		{"LinkCheckReq", true, "02", Command{CID: LinkCheck}},
+		{"LinkADRAns", true, "0307", Command{CID: LinkADR, Payload: &LinkADRAns{PowerACK: true, DataRateACK: true, ChannelMaskACK: true}}},
+		{"LinkADRAns power nack", true, "0303", Command{CID: LinkADR, Payload: &LinkADRAns{DataRateACK: true, ChannelMaskACK: true}}},
+		{"DutyCycleAns", true, "04", Command{CID: DutyCycle}},
+		{"RXParamSetupAns", true, "0505", Command{CID: RXParamSetup, Payload: &RXParamSetupAns{RX1DROffsetACK: true, ChannelACK: true}}},
+		{"DevStatusAns", true, "06fe1f", Command{CID: DevStatus, Payload: &DevStatusAns{Battery: 254, Margin: 31}}},
+		{"DevStatusAns negative margin", true, "060020", Command{CID: DevStatus, Payload: &DevStatusAns{Battery: 0, Margin: -32}}},
+		{"NewChannelAns", true, "0702", Command{CID: NewChannel, Payload: &NewChannelAns{DataRateRangeOK: true}}},
+		{"RXTimingSetupAns", true, "08", Command{CID: RXTimingSetup}},
+		{"TXParamSetupAns", true, "09", Command{CID: TXParamSetup}},
+		{"DLChannelAns", true, "0a03", Command{CID: DLChannel, Payload: &DLChannelAns{UplinkFrequencyExists: true, ChannelFrequencyOK: true}}},
+		{"RekeyInd", true, "0b01", Command{CID: Rekey, Payload: &RekeyInd{DevLoRaWANVersion: 1}}},
+		{"ADRParamSetupAns", true, "0c", Command{CID: ADRParamSetup}},
+		{"DeviceTimeReq", true, "0d", Command{CID: DeviceTime}},
+		{"RejoinParamSetupAns", true, "0f01", Command{CID: RejoinParamSetup, Payload: &RejoinParamSetupAns{TimeOK: true}}},
+		{"PingSlotInfoReq", true, "1005", Command{CID: PingSlotInfo, Payload: &PingSlotInfoReq{Periodicity: 5}}},
+		{"PingSlotChannelAns", true, "1101", Command{CID: PingSlotChannel, Payload: &PingSlotChannelAns{ChannelFrequencyOK: true}}},
+		{"BeaconTimingReq", true, "12", Command{CID: BeaconTiming}},
+		{"BeaconFreqAns", true, "1301", Command{CID: BeaconFreq, Payload: &BeaconFreqAns{BeaconFrequencyOK: true}}},
+		{"DeviceModeInd", true, "2002", Command{CID: DeviceMode, Payload: &DeviceModeInd{Class: ClassC}}},
+
+		// Downlink.
+		{"ResetConf", false, "0101", Command{CID: Reset, Payload: &ResetConf{ServLoRaWANVersion: 1}}},
+		{"LinkCheckAns", false, "020a03", Command{CID: LinkCheck, Payload: &LinkCheckAns{Margin: 10, GwCnt: 3}}},
+		{
+			name:   "LinkADRReq",
+			uplink: false,
+			bytes:  "0352070001",
+			cmd: Command{CID: LinkADR, Payload: &LinkADRReq{
+				DataRate: 5,
+				TXPower:  2,
+				ChMask:   [16]bool{true, true, true},
+				NbTrans:  1,
+			}},
+		},
+		{
+			name:   "LinkADRReq channel-mask control",
+			uplink: false,
+			bytes:  "0330ff0f71",
+			cmd: Command{CID: LinkADR, Payload: &LinkADRReq{
+				DataRate:   3,
+				ChMask:     [16]bool{true, true, true, true, true, true, true, true, true, true, true, true},
+				ChMaskCntl: 7,
+				NbTrans:    1,
+			}},
+		},
+		{"DutyCycleReq", false, "040f", Command{CID: DutyCycle, Payload: &DutyCycleReq{MaxDCycle: 15}}},
+		{"RXParamSetupReq", false, "0513d2ad84", Command{CID: RXParamSetup, Payload: &RXParamSetupReq{RX1DROffset: 1, RX2DataRate: 3, Frequency: 869525000}}},
+		{"DevStatusReq", false, "06", Command{CID: DevStatus}},
+		{"NewChannelReq", false, "0703f87d8450", Command{CID: NewChannel, Payload: &NewChannelReq{ChIndex: 3, Frequency: 868300000, MaxDR: 5}}},
+		{"NewChannelReq disable", false, "070400000000", Command{CID: NewChannel, Payload: &NewChannelReq{ChIndex: 4}}},
+		{"RXTimingSetupReq", false, "0801", Command{CID: RXTimingSetup, Payload: &RXTimingSetupReq{Delay: 1}}},
+		{"TXParamSetupReq", false, "0935", Command{CID: TXParamSetup, Payload: &TXParamSetupReq{DownlinkDwellTime400ms: true, UplinkDwellTime400ms: true, MaxEIRP: 5}}},
+		{"DLChannelReq", false, "0a00287684", Command{CID: DLChannel, Payload: &DLChannelReq{ChIndex: 0, Frequency: 868100000}}},
+		{"RekeyConf", false, "0b01", Command{CID: Rekey, Payload: &RekeyConf{ServLoRaWANVersion: 1}}},
+		{"ADRParamSetupReq", false, "0c65", Command{CID: ADRParamSetup, Payload: &ADRParamSetupReq{LimitExp: 6, DelayExp: 5}}},
+		{"DeviceTimeAns", false, "0dd202964980", Command{CID: DeviceTime, Payload: &DeviceTimeAns{TimeSinceGPSEpoch: 1234567890*time.Second + 500*time.Millisecond}}},
+		{"ForceRejoinReq", false, "0e251a", Command{CID: ForceRejoin, Payload: &ForceRejoinReq{Period: 3, MaxRetries: 2, RejoinType: 2, DR: 5}}},
+		{"RejoinParamSetupReq", false, "0fa4", Command{CID: RejoinParamSetup, Payload: &RejoinParamSetupReq{MaxTimeN: 10, MaxCountN: 4}}},
+		{"PingSlotInfoAns", false, "10", Command{CID: PingSlotInfo}},
+		{"PingSlotChannelReq", false, "11d2ad8403", Command{CID: PingSlotChannel, Payload: &PingSlotChannelReq{Frequency: 869525000, DR: 3}}},
+		{"BeaconTimingAns", false, "12e80301", Command{CID: BeaconTiming, Payload: &BeaconTimingAns{Delay: 1000, Channel: 1}}},
+		{"BeaconFreqReq", false, "13d2ad84", Command{CID: BeaconFreq, Payload: &BeaconFreqReq{Frequency: 869525000}}},
+		{"DeviceModeConf", false, "2000", Command{CID: DeviceMode, Payload: &DeviceModeConf{Class: ClassA}}},
+
+		// Proprietary.
+		{"proprietary uplink", true, "800102", Command{CID: 0x80, Payload: &Proprietary{Bytes: []byte{0x01, 0x02}}}},
+		{"proprietary downlink", false, "ff", Command{CID: 0xff, Payload: &Proprietary{}}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			b := mustHex(t, test.bytes)
+
+			var cmd Command
+			if err := cmd.Decode(test.uplink, b); err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(cmd, test.cmd) {
+				t.Errorf("expected %+v, got %+v", test.cmd, cmd)
+			}
+
+			out, err := test.cmd.MarshalBinary()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(out, b) {
+				t.Errorf("expected %x, got %x", b, out)
+			}
+
+			size, err := PayloadSize(test.uplink, test.cmd.CID)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if exp := len(b) - 1; !test.cmd.CID.IsProprietary() && size != exp {
+				t.Errorf("expected payload size %d, got %d", exp, size)
+			}
+		})
+	}
+}
+
+func TestCommandDecodeErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		uplink bool
+		bytes  string
+		err    error
+	}{
+		{"empty", true, "", nil},
+		{"truncated LinkADRReq", false, "035207", nil},
+		{"truncated DevStatusAns", true, "06fe", nil},
+		{"trailing bytes", true, "0307ff", nil},
+		{"two commands", true, "0202", nil},
+		{"unknown cid", true, "30", ErrUnknownCID},
+		{"ForceRejoinReq uplink", true, "0e251a", ErrNoCommand},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var cmd Command
+			err := cmd.Decode(test.uplink, mustHex(t, test.bytes))
+			if err == nil {
+				t.Fatalf("expected error, got %+v", cmd)
+			}
+			if test.err != nil && !errors.Is(err, test.err) {
+				t.Errorf("expected %v, got %v", test.err, err)
+			}
+		})
+	}
+}
+
+func TestDecode(t *testing.T) {
+	tests := []struct {
+		name     string
+		uplink   bool
+		bytes    string
+		expected []Command
+		err      bool
+	}{
+		{
+			name:   "uplink FOpts",
+			uplink: true,
+			bytes:  "0307060020020d",
+			expected: []Command{
+				{CID: LinkADR, Payload: &LinkADRAns{PowerACK: true, DataRateACK: true, ChannelMaskACK: true}},
+				{CID: DevStatus, Payload: &DevStatusAns{Margin: -32}},
+				{CID: LinkCheck},
+				{CID: DeviceTime},
+			},
+		},
+		{
+			name:   "downlink FOpts",
+			uplink: false,
+			bytes:  "020a0306",
+			expected: []Command{
+				{CID: LinkCheck, Payload: &LinkCheckAns{Margin: 10, GwCnt: 3}},
+				{CID: DevStatus},
+			},
+		},
+		{
+			// A proprietary mac-command takes the remaining bytes.
+			name:   "proprietary",
+			uplink: true,
+			bytes:  "02800203",
+			expected: []Command{
+				{CID: LinkCheck},
+				{CID: 0x80, Payload: &Proprietary{Bytes: []byte{0x02, 0x03}}},
+			},
+		},
+		{
+			name:   "empty",
+			uplink: true,
+		},
+		{
+			name:   "truncated",
+			uplink: false,
+			bytes:  "020a03035207",
+			err:    true,
+		},
+		{
+			name:   "unknown cid",
+			uplink: true,
+			bytes:  "0230",
+			err:    true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			b := mustHex(t, test.bytes)
+
+			cmds, err := Decode(test.uplink, b)
+			if test.err {
+				if err == nil {
+					t.Fatalf("expected error, got %+v", cmds)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(cmds, test.expected) {
+				t.Errorf("expected %+v, got %+v", test.expected, cmds)
+			}
+
+			out, err := Encode(cmds)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(out, b) {
+				t.Errorf("expected %x, got %x", b, out)
+			}
+		})
+	}
+}
+
+func TestMarshalBinaryErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		cmd  Command
+	}{
+		{"unknown cid", Command{CID: 0x30}},
+		{"missing payload", Command{CID: LinkADR}},
+		{"unexpected payload", Command{CID: LinkCheck, Payload: &LinkADRAns{}}},
+		{"payload of other cid", Command{CID: LinkADR, Payload: &LinkCheckAns{}}},
+		{"non-proprietary payload", Command{CID: 0x80, Payload: &LinkADRAns{}}},
+		{"data-rate overflow", Command{CID: LinkADR, Payload: &LinkADRReq{DataRate: 16}}},
+		{"frequency step", Command{CID: NewChannel, Payload: &NewChannelReq{Frequency: 868100050}}},
+		{"frequency overflow", Command{CID: BeaconFreq, Payload: &BeaconFreqReq{Frequency: maxFrequency + 100}}},
+		{"margin overflow", Command{CID: DevStatus, Payload: &DevStatusAns{Margin: 32}}},
+		{"negative device time", Command{CID: DeviceTime, Payload: &DeviceTimeAns{TimeSinceGPSEpoch: -time.Second}}},
+		{"rejoin type overflow", Command{CID: ForceRejoin, Payload: &ForceRejoinReq{RejoinType: 8}}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if b, err := test.cmd.MarshalBinary(); err == nil {
+				t.Errorf("expected error, got %x", b)
+			}
+		})
+	}
+}
+
+func TestPayloadSize(t *testing.T) {
+	if size, err := PayloadSize(true, 0x80); err != nil || size != -1 {
+		t.Errorf("expected -1 for a proprietary cid, got %d (%v)", size, err)
+	}
+	if _, err := PayloadSize(true, ForceRejoin); !errors.Is(err, ErrNoCommand) {
+		t.Errorf("expected ErrNoCommand, got %v", err)
+	}
+	if _, err := PayloadSize(false, 0x30); !errors.Is(err, ErrUnknownCID) {
+		t.Errorf("expected ErrUnknownCID, got %v", err)
+	}
+}
+
+func TestCIDString(t *testing.T) {
+	for cid, expected := range map[CID]string{
+		LinkADR:    "LinkADR",
+		DeviceMode: "DeviceMode",
+		0x30:       "CID(0x30)",
+		0x80:       "Proprietary(0x80)",
+	} {
+		if s := cid.String(); s != expected {
+			t.Errorf("expected %s, got %s", expected, s)
+		}
+	}
+}
@@ -0,0 +1,867 @@
+package maccommand
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// maxFrequency defines the max. frequency (Hz) which can be encoded in the
+// 24 bit frequency fields (in steps of 100 Hz).
+const maxFrequency = (1<<24 - 1) * 100
+
+// putFrequency encodes the frequency (Hz) into b (3 bytes).
+func putFrequency(b []byte, freq uint32) error {
+	if freq%100 != 0 {
+		return fmt.Errorf("frequency %d must be a multiple of 100 Hz", freq)
+	}
+	if freq > maxFrequency {
+		return fmt.Errorf("frequency %d exceeds max. %d", freq, maxFrequency)
+	}
+	f := freq / 100
+	b[0], b[1], b[2] = byte(f), byte(f>>8), byte(f>>16)
+	return nil
+}
+
+// frequency decodes the frequency (Hz) from b (3 bytes).
+func frequency(b []byte) uint32 {
+	return (uint32(b[0]) | uint32(b[1])<<8 | uint32(b[2])<<16) * 100
+}
+
+// checkLen returns an error when b is not of the given length.
+func checkLen(b []byte, n int) error {
+	if len(b) != n {
+		return fmt.Errorf("expected %d bytes, got %d", n, len(b))
+	}
+	return nil
+}
+
+// checkMax returns an error when v exceeds max.
+func checkMax(name string, v, max uint8) error {
+	if v > max {
+		return fmt.Errorf("%s must be <= %d", name, max)
+	}
+	return nil
+}
+
+// bit returns 1 when v is true.
+func bit(v bool, pos uint) byte {
+	if v {
+		return 1 << pos
+	}
+	return 0
+}
+
+// ResetInd is sent by an ABP device (LoRaWAN 1.1) after a reset.
+type ResetInd struct {
+	// DevLoRaWANVersion holds the minor LoRaWAN version (1 = LoRaWAN 1.1).
+	DevLoRaWANVersion uint8
+}
+
+// MarshalBinary implements encoding.BinaryMarshaler.
+func (p ResetInd) MarshalBinary() ([]byte, error) {
+	if err := checkMax("dev_lorawan_version", p.DevLoRaWANVersion, 15); err != nil {
+		return nil, err
+	}
+	return []byte{p.DevLoRaWANVersion}, nil
+}
+
+// UnmarshalBinary implements encoding.BinaryUnmarshaler.
+func (p *ResetInd) UnmarshalBinary(b []byte) error {
+	if err := checkLen(b, 1); err != nil {
+		return err
+	}
+	p.DevLoRaWANVersion = b[0] & 0x0f
+	return nil
+}
+
+// ResetConf is the answer to ResetInd.
+type ResetConf struct {
+	// ServLoRaWANVersion holds the minor LoRaWAN version (1 = LoRaWAN 1.1).
+	ServLoRaWANVersion uint8
+}
+
+// MarshalBinary implements encoding.BinaryMarshaler.
+func (p ResetConf) MarshalBinary() ([]byte, error) {
+	if err := checkMax("serv_lorawan_version", p.ServLoRaWANVersion, 15); err != nil {
+		return nil, err
+	}
+	return []byte{p.ServLoRaWANVersion}, nil
+}
+
+// UnmarshalBinary implements encoding.BinaryUnmarshaler.
+func (p *ResetConf) UnmarshalBinary(b []byte) error {
+	if err := checkLen(b, 1); err != nil {
+		return err
+	}
+	p.ServLoRaWANVersion = b[0] & 0x0f
+	return nil
+}
+
+// LinkCheckAns is the answer to LinkCheckReq.
+type LinkCheckAns struct {
+	// Margin holds the link margin (dB) of the last received LinkCheckReq.
+	Margin uint8
+
+	// GwCnt holds the number of gateways which received the LinkCheckReq.
+	GwCnt uint8
+}
+
+// MarshalBinary implements encoding.BinaryMarshaler.
+func (p LinkCheckAns) MarshalBinary() ([]byte, error) {
+	if err := checkMax("margin", p.Margin, 254); err != nil {
+		return nil, err
+	}
+	return []byte{p.Margin, p.GwCnt}, nil
+}
+
+// UnmarshalBinary implements encoding.BinaryUnmarshaler.
+func (p *LinkCheckAns) UnmarshalBinary(b []byte) error {
+	if err := checkLen(b, 2); err != nil {
+		return err
+	}
+	p.Margin, p.GwCnt = b[0], b[1]
+	return nil
+}
+
+// LinkADRReq requests the device to change its data-rate, TX power,
+// channel-mask and number of transmissions.
+type LinkADRReq struct {
+	DataRate   uint8
+	TXPower    uint8
+	ChMask     [16]bool
+	ChMaskCntl uint8
+	NbTrans    uint8
+}
+
+// MarshalBinary implements encoding.BinaryMarshaler.
+func (p LinkADRReq) MarshalBinary() ([]byte, error) {
+	for _, f := range []struct {
+		name string
+		v    uint8
+		max  uint8
+	}{
+		{"data_rate", p.DataRate, 15},
+		{"tx_power", p.TXPower, 15},
+		{"ch_mask_cntl", p.ChMaskCntl, 7},
+		{"nb_trans", p.NbTrans, 15},
+	} {
+		if err := checkMax(f.name, f.v, f.max); err != nil {
+			return nil, err
+		}
+	}
+
+	var mask uint16
+	for i, on := range p.ChMask {
+		if on {
+			mask |= 1 << uint(i)
+		}
+	}
+
+	b := make([]byte, 4)
+	b[0] = p.DataRate<<4 | p.TXPower
+	binary.LittleEndian.PutUint16(b[1:3], mask)
+	b[3] = p.ChMaskCntl<<4 | p.NbTrans
+	return b, nil
+}
+
+// UnmarshalBinary implements encoding.BinaryUnmarshaler.
+func (p *LinkADRReq) UnmarshalBinary(b []byte) error {
+	if err := checkLen(b, 4); err != nil {
+		return err
+	}
+	p.DataRate = b[0] >> 4
+	p.TXPower = b[0] & 0x0f
+	mask := binary.LittleEndian.Uint16(b[1:3])
+	for i := range p.ChMask {
+		p.ChMask[i] = mask&(1<<uint(i)) != 0
+	}
+	p.ChMaskCntl = (b[3] >> 4) & 0x07
+	p.NbTrans = b[3] & 0x0f
+	return nil
+}
+
+// LinkADRAns is the answer to LinkADRReq.
+type LinkADRAns struct {
+	PowerACK       bool
+	DataRateACK    bool
+	ChannelMaskACK bool
+}
+
+// MarshalBinary implements encoding.BinaryMarshaler.
+func (p LinkADRAns) MarshalBinary() ([]byte, error) {
+	return []byte{bit(p.PowerACK, 2) | bit(p.DataRateACK, 1) | bit(p.ChannelMaskACK, 0)}, nil
+}
+
+// UnmarshalBinary implements encoding.BinaryUnmarshaler.
+func (p *LinkADRAns) UnmarshalBinary(b []byte) error {
+	if err := checkLen(b, 1); err != nil {
+		return err
+	}
+	p.PowerACK = b[0]&(1<<2) != 0
+	p.DataRateACK = b[0]&(1<<1) != 0
+	p.ChannelMaskACK = b[0]&1 != 0
+	return nil
+}
+
+// DutyCycleReq sets the max. aggregated duty-cycle of the device to
+// 1 / 2^MaxDCycle.
+type DutyCycleReq struct {
+	MaxDCycle uint8
+}
+
+// MarshalBinary implements encoding.BinaryMarshaler.
+func (p DutyCycleReq) MarshalBinary() ([]byte, error) {
+	if err := checkMax("max_dcycle", p.MaxDCycle, 15); err != nil {
+		return nil, err
+	}
+	return []byte{p.MaxDCycle}, nil
+}
+
+// UnmarshalBinary implements encoding.BinaryUnmarshaler.
+func (p *DutyCycleReq) UnmarshalBinary(b []byte) error {
+	if err := checkLen(b, 1); err != nil {
+		return err
+	}
+	p.MaxDCycle = b[0] & 0x0f
+	return nil
+}
+
+// RXParamSetupReq sets the RX1 data-rate offset and the RX2 data-rate and
+// frequency.
+type RXParamSetupReq struct {
+	RX1DROffset uint8
+	RX2DataRate uint8
+
+	// Frequency (Hz) of RX2.
+	Frequency uint32
+}
+
+// MarshalBinary implements encoding.BinaryMarshaler.
+func (p RXParamSetupReq) MarshalBinary() ([]byte, error) {
+	if err := checkMax("rx1_dr_offset", p.RX1DROffset, 7); err != nil {
+		return nil, err
+	}
+	if err := checkMax("rx2_data_rate", p.RX2DataRate, 15); err != nil {
+		return nil, err
+	}
+	b := make([]byte, 4)
+	b[0] = p.RX1DROffset<<4 | p.RX2DataRate
+	if err := putFrequency(b[1:], p.Frequency); err != nil {
+		return nil, err
+	}
+	return b, nil
+}
+
+// UnmarshalBinary implements encoding.BinaryUnmarshaler.
+func (p *RXParamSetupReq) UnmarshalBinary(b []byte) error {
+	if err := checkLen(b, 4); err != nil {
+		return err
+	}
+	p.RX1DROffset = (b[0] >> 4) & 0x07
+	p.RX2DataRate = b[0] & 0x0f
+	p.Frequency = frequency(b[1:])
+	return nil
+}
+
+// RXParamSetupAns is the answer to RXParamSetupReq.
+type RXParamSetupAns struct {
+	RX1DROffsetACK bool
+	RX2DataRateACK bool
+	ChannelACK     bool
+}
+
+// MarshalBinary implements encoding.BinaryMarshaler.
+func (p RXParamSetupAns) MarshalBinary() ([]byte, error) {
+	return []byte{bit(p.RX1DROffsetACK, 2) | bit(p.RX2DataRateACK, 1) | bit(p.ChannelACK, 0)}, nil
+}
+
+// UnmarshalBinary implements encoding.BinaryUnmarshaler.
+func (p *RXParamSetupAns) UnmarshalBinary(b []byte) error {
+	if err := checkLen(b, 1); err != nil {
+		return err
+	}
+	p.RX1DROffsetACK = b[0]&(1<<2) != 0
+	p.RX2DataRateACK = b[0]&(1<<1) != 0
+	p.ChannelACK = b[0]&1 != 0
+	return nil
+}
+
+// DevStatusAns is the answer to DevStatusReq.
+type DevStatusAns struct {
+	// Battery holds the battery level: 0 = external power source, 1 - 254 =
+	// battery level, 255 = unable to measure.
+	Battery uint8
+
+	// Margin holds the demodulation SNR margin (dB) of the last received
+	// DevStatusReq, -32 - 31.
+	Margin int8
+}
+
+// MarshalBinary implements encoding.BinaryMarshaler.
+func (p DevStatusAns) MarshalBinary() ([]byte, error) {
+	if p.Margin < -32 || p.Margin > 31 {
+		return nil, errors.New("margin must be between -32 and 31")
+	}
+	return []byte{p.Battery, byte(p.Margin) & 0x3f}, nil
+}
+
+// UnmarshalBinary implements encoding.BinaryUnmarshaler.
+func (p *DevStatusAns) UnmarshalBinary(b []byte) error {
+	if err := checkLen(b, 2); err != nil {
+		return err
+	}
+	p.Battery = b[0]
+
+	// 6 bit signed integer.
+	p.Margin = int8(b[1]<<2) >> 2
+	return nil
+}
+
+// NewChannelReq creates or modifies a channel.
+type NewChannelReq struct {
+	ChIndex uint8
+
+	// Frequency (Hz), 0 disables the channel.
+	Frequency uint32
+	MinDR     uint8
+	MaxDR     uint8
+}
+
+// MarshalBinary implements encoding.BinaryMarshaler.
+func (p NewChannelReq) MarshalBinary() ([]byte, error) {
+	if err := checkMax("min_dr", p.MinDR, 15); err != nil {
+		return nil, err
+	}
+	if err := checkMax("max_dr", p.MaxDR, 15); err != nil {
+		return nil, err
+	}
+	b := make([]byte, 5)
+	b[0] = p.ChIndex
+	if err := putFrequency(b[1:4], p.Frequency); err != nil {
+		return nil, err
+	}
+	b[4] = p.MaxDR<<4 | p.MinDR
+	return b, nil
+}
+
+// UnmarshalBinary implements encoding.BinaryUnmarshaler.
+func (p *NewChannelReq) UnmarshalBinary(b []byte) error {
+	if err := checkLen(b, 5); err != nil {
+		return err
+	}
+	p.ChIndex = b[0]
+	p.Frequency = frequency(b[1:4])
+	p.MaxDR = b[4] >> 4
+	p.MinDR = b[4] & 0x0f
+	return nil
+}
+
+// NewChannelAns is the answer to NewChannelReq.
+type NewChannelAns struct {
+	DataRateRangeOK bool
+	ChannelFreqOK   bool
+}
+
+// MarshalBinary implements encoding.BinaryMarshaler.
+func (p NewChannelAns) MarshalBinary() ([]byte, error) {
+	return []byte{bit(p.DataRateRangeOK, 1) | bit(p.ChannelFreqOK, 0)}, nil
+}
+
+// UnmarshalBinary implements encoding.BinaryUnmarshaler.
+func (p *NewChannelAns) UnmarshalBinary(b []byte) error {
+	if err := checkLen(b, 1); err != nil {
+		return err
+	}
+	p.DataRateRangeOK = b[0]&(1<<1) != 0
+	p.ChannelFreqOK = b[0]&1 != 0
+	return nil
+}
+
+// RXTimingSetupReq sets the RX1 delay (seconds, 0 means 1 second).
+type RXTimingSetupReq struct {
+	Delay uint8
+}
+
+// MarshalBinary implements encoding.BinaryMarshaler.
+func (p RXTimingSetupReq) MarshalBinary() ([]byte, error) {
+	if err := checkMax("delay", p.Delay, 15); err != nil {
+		return nil, err
+	}
+	return []byte{p.Delay}, nil
+}
+
+// UnmarshalBinary implements encoding.BinaryUnmarshaler.
+func (p *RXTimingSetupReq) UnmarshalBinary(b []byte) error {
+	if err := checkLen(b, 1); err != nil {
+		return err
+	}
+	p.Delay = b[0] & 0x0f
+	return nil
+}
+
+// TXParamSetupReq sets the dwell-time and max. EIRP of the device.
+type TXParamSetupReq struct {
+	DownlinkDwellTime400ms bool
+	UplinkDwellTime400ms   bool
+
+	// MaxEIRP holds the max. EIRP index (0 - 15), see the LoRaWAN
+	// specification for the dBm values.
+	MaxEIRP uint8
+}
+
+// MarshalBinary implements encoding.BinaryMarshaler.
+func (p TXParamSetupReq) MarshalBinary() ([]byte, error) {
+	if err := checkMax("max_eirp", p.MaxEIRP, 15); err != nil {
+		return nil, err
+	}
+	return []byte{bit(p.DownlinkDwellTime400ms, 5) | bit(p.UplinkDwellTime400ms, 4) | p.MaxEIRP}, nil
+}
+
+// UnmarshalBinary implements encoding.BinaryUnmarshaler.
+func (p *TXParamSetupReq) UnmarshalBinary(b []byte) error {
+	if err := checkLen(b, 1); err != nil {
+		return err
+	}
+	p.DownlinkDwellTime400ms = b[0]&(1<<5) != 0
+	p.UplinkDwellTime400ms = b[0]&(1<<4) != 0
+	p.MaxEIRP = b[0] & 0x0f
+	return nil
+}
+
+// DLChannelReq sets the downlink (RX1) frequency of a channel.
+type DLChannelReq struct {
+	ChIndex uint8
+
+	// Frequency (Hz).
+	Frequency uint32
+}
+
+// MarshalBinary implements encoding.BinaryMarshaler.
+func (p DLChannelReq) MarshalBinary() ([]byte, error) {
+	b := make([]byte, 4)
+	b[0] = p.ChIndex
+	if err := putFrequency(b[1:], p.Frequency); err != nil {
+		return nil, err
+	}
+	return b, nil
+}
+
+// UnmarshalBinary implements encoding.BinaryUnmarshaler.
+func (p *DLChannelReq) UnmarshalBinary(b []byte) error {
+	if err := checkLen(b, 4); err != nil {
+		return err
+	}
+	p.ChIndex = b[0]
+	p.Frequency = frequency(b[1:])
+	return nil
+}
+
+// DLChannelAns is the answer to DLChannelReq.
+type DLChannelAns struct {
+	UplinkFrequencyExists bool
+	ChannelFrequencyOK    bool
+}
+
+// MarshalBinary implements encoding.BinaryMarshaler.
+func (p DLChannelAns) MarshalBinary() ([]byte, error) {
+	return []byte{bit(p.UplinkFrequencyExists, 1) | bit(p.ChannelFrequencyOK, 0)}, nil
+}
+
+// UnmarshalBinary implements encoding.BinaryUnmarshaler.
+func (p *DLChannelAns) UnmarshalBinary(b []byte) error {
+	if err := checkLen(b, 1); err != nil {
+		return err
+	}
+	p.UplinkFrequencyExists = b[0]&(1<<1) != 0
+	p.ChannelFrequencyOK = b[0]&1 != 0
+	return nil
+}
+
+// RekeyInd is sent by an OTAA device (LoRaWAN 1.1) after activation.
+type RekeyInd struct {
+	// DevLoRaWANVersion holds the minor LoRaWAN version (1 = LoRaWAN 1.1).
+	DevLoRaWANVersion uint8
+}
+
+// MarshalBinary implements encoding.BinaryMarshaler.
+func (p RekeyInd) MarshalBinary() ([]byte, error) {
+	return ResetInd(p).MarshalBinary()
+}
+
+// UnmarshalBinary implements encoding.BinaryUnmarshaler.
+func (p *RekeyInd) UnmarshalBinary(b []byte) error {
+	return (*ResetInd)(p).UnmarshalBinary(b)
+}
+
+// RekeyConf is the answer to RekeyInd.
+type RekeyConf struct {
+	// ServLoRaWANVersion holds the minor LoRaWAN version (1 = LoRaWAN 1.1).
+	ServLoRaWANVersion uint8
+}
+
+// MarshalBinary implements encoding.BinaryMarshaler.
+func (p RekeyConf) MarshalBinary() ([]byte, error) {
+	return ResetConf(p).MarshalBinary()
+}
+
+// UnmarshalBinary implements encoding.BinaryUnmarshaler.
+func (p *RekeyConf) UnmarshalBinary(b []byte) error {
+	return (*ResetConf)(p).UnmarshalBinary(b)
+}
+
+// ADRParamSetupReq sets the ADR_ACK_LIMIT (2^LimitExp) and ADR_ACK_DELAY
+// (2^DelayExp) of the device.
+type ADRParamSetupReq struct {
+	LimitExp uint8
+	DelayExp uint8
+}
+
+// MarshalBinary implements encoding.BinaryMarshaler.
+func (p ADRParamSetupReq) MarshalBinary() ([]byte, error) {
+	if err := checkMax("limit_exp", p.LimitExp, 15); err != nil {
+		return nil, err
+	}
+	if err := checkMax("delay_exp", p.DelayExp, 15); err != nil {
+		return nil, err
+	}
+	return []byte{p.LimitExp<<4 | p.DelayExp}, nil
+}
+
+// UnmarshalBinary implements encoding.BinaryUnmarshaler.
+func (p *ADRParamSetupReq) UnmarshalBinary(b []byte) error {
+	if err := checkLen(b, 1); err != nil {
+		return err
+	}
+	p.LimitExp = b[0] >> 4
+	p.DelayExp = b[0] & 0x0f
+	return nil
+}
+
+// DeviceTimeAns is the answer to DeviceTimeReq.
+type DeviceTimeAns struct {
+	// TimeSinceGPSEpoch holds the time since GPS epoch, with a precision of
+	// 1/256 second.
+	TimeSinceGPSEpoch time.Duration
+}
+
+// MarshalBinary implements encoding.BinaryMarshaler.
+func (p DeviceTimeAns) MarshalBinary() ([]byte, error) {
+	if p.TimeSinceGPSEpoch < 0 {
+		return nil, errors.New("time_since_gps_epoch must not be negative")
+	}
+	secs := p.TimeSinceGPSEpoch / time.Second
+	if secs > 1<<32-1 {
+		return nil, errors.New("time_since_gps_epoch overflows 32 bit seconds")
+	}
+	frac := (p.TimeSinceGPSEpoch % time.Second) * 256 / time.Second
+
+	b := make([]byte, 5)
+	binary.LittleEndian.PutUint32(b, uint32(secs))
+	b[4] = byte(frac)
+	return b, nil
+}
+
+// UnmarshalBinary implements encoding.BinaryUnmarshaler.
+func (p *DeviceTimeAns) UnmarshalBinary(b []byte) error {
+	if err := checkLen(b, 5); err != nil {
+		return err
+	}
+	p.TimeSinceGPSEpoch = time.Duration(binary.LittleEndian.Uint32(b))*time.Second +
+		time.Duration(b[4])*time.Second/256
+	return nil
+}
+
+// ForceRejoinReq requests the device to send a rejoin-request.
+type ForceRejoinReq struct {
+	// Period defines the delay between retransmissions: 32 s * 2^Period.
+	Period     uint8
+	MaxRetries uint8
+	RejoinType uint8
+	DR         uint8
+}
+
+// MarshalBinary implements encoding.BinaryMarshaler.
+func (p ForceRejoinReq) MarshalBinary() ([]byte, error) {
+	for _, f := range []struct {
+		name string
+		v    uint8
+		max  uint8
+	}{
+		{"period", p.Period, 7},
+		{"max_retries", p.MaxRetries, 7},
+		{"rejoin_type", p.RejoinType, 7},
+		{"dr", p.DR, 15},
+	} {
+		if err := checkMax(f.name, f.v, f.max); err != nil {
+			return nil, err
+		}
+	}
+	v := uint16(p.Period)<<11 | uint16(p.MaxRetries)<<8 | uint16(p.RejoinType)<<4 | uint16(p.DR)
+	b := make([]byte, 2)
+	binary.LittleEndian.PutUint16(b, v)
+	return b, nil
+}
+
+// UnmarshalBinary implements encoding.BinaryUnmarshaler.
+func (p *ForceRejoinReq) UnmarshalBinary(b []byte) error {
+	if err := checkLen(b, 2); err != nil {
+		return err
+	}
+	v := binary.LittleEndian.Uint16(b)
+	p.Period = uint8(v>>11) & 0x07
+	p.MaxRetries = uint8(v>>8) & 0x07
+	p.RejoinType = uint8(v>>4) & 0x07
+	p.DR = uint8(v) & 0x0f
+	return nil
+}
+
+// RejoinParamSetupReq sets the periodic rejoin-request interval: every
+// 2^(MaxCountN+4) uplinks or 2^(MaxTimeN+10) seconds.
+type RejoinParamSetupReq struct {
+	MaxTimeN  uint8
+	MaxCountN uint8
+}
+
+// MarshalBinary implements encoding.BinaryMarshaler.
+func (p RejoinParamSetupReq) MarshalBinary() ([]byte, error) {
+	if err := checkMax("max_time_n", p.MaxTimeN, 15); err != nil {
+		return nil, err
+	}
+	if err := checkMax("max_count_n", p.MaxCountN, 15); err != nil {
+		return nil, err
+	}
+	return []byte{p.MaxTimeN<<4 | p.MaxCountN}, nil
+}
+
+// UnmarshalBinary implements encoding.BinaryUnmarshaler.
+func (p *RejoinParamSetupReq) UnmarshalBinary(b []byte) error {
+	if err := checkLen(b, 1); err != nil {
+		return err
+	}
+	p.MaxTimeN = b[0] >> 4
+	p.MaxCountN = b[0] & 0x0f
+	return nil
+}
+
+// RejoinParamSetupAns is the answer to RejoinParamSetupReq.
+type RejoinParamSetupAns struct {
+	TimeOK bool
+}
+
+// MarshalBinary implements encoding.BinaryMarshaler.
+func (p RejoinParamSetupAns) MarshalBinary() ([]byte, error) {
+	return []byte{bit(p.TimeOK, 0)}, nil
+}
+
+// UnmarshalBinary implements encoding.BinaryUnmarshaler.
+func (p *RejoinParamSetupAns) UnmarshalBinary(b []byte) error {
+	if err := checkLen(b, 1); err != nil {
+		return err
+	}
+	p.TimeOK = b[0]&1 != 0
+	return nil
+}
+
+// PingSlotInfoReq informs the network-server of the ping-slot periodicity
+// of the device: 2^Periodicity seconds.
+type PingSlotInfoReq struct {
+	Periodicity uint8
+}
+
+// MarshalBinary implements encoding.BinaryMarshaler.
+func (p PingSlotInfoReq) MarshalBinary() ([]byte, error) {
+	if err := checkMax("periodicity", p.Periodicity, 7); err != nil {
+		return nil, err
+	}
+	return []byte{p.Periodicity}, nil
+}
+
+// UnmarshalBinary implements encoding.BinaryUnmarshaler.
+func (p *PingSlotInfoReq) UnmarshalBinary(b []byte) error {
+	if err := checkLen(b, 1); err != nil {
+		return err
+	}
+	p.Periodicity = b[0] & 0x07
+	return nil
+}
+
+// PingSlotChannelReq sets the ping-slot frequency and data-rate.
+type PingSlotChannelReq struct {
+	// Frequency (Hz), 0 restores the default frequency.
+	Frequency uint32
+	DR        uint8
+}
+
+// MarshalBinary implements encoding.BinaryMarshaler.
+func (p PingSlotChannelReq) MarshalBinary() ([]byte, error) {
+	if err := checkMax("dr", p.DR, 15); err != nil {
+		return nil, err
+	}
+	b := make([]byte, 4)
+	if err := putFrequency(b[:3], p.Frequency); err != nil {
+		return nil, err
+	}
+	b[3] = p.DR
+	return b, nil
+}
+
+// UnmarshalBinary implements encoding.BinaryUnmarshaler.
+func (p *PingSlotChannelReq) UnmarshalBinary(b []byte) error {
+	if err := checkLen(b, 4); err != nil {
+		return err
+	}
+	p.Frequency = frequency(b[:3])
+	p.DR = b[3] & 0x0f
+	return nil
+}
+
+// PingSlotChannelAns is the answer to PingSlotChannelReq.
+type PingSlotChannelAns struct {
+	DataRateOK         bool
+	ChannelFrequencyOK bool
+}
+
+// MarshalBinary implements encoding.BinaryMarshaler.
+func (p PingSlotChannelAns) MarshalBinary() ([]byte, error) {
+	return []byte{bit(p.DataRateOK, 1) | bit(p.ChannelFrequencyOK, 0)}, nil
+}
+
+// UnmarshalBinary implements encoding.BinaryUnmarshaler.
+func (p *PingSlotChannelAns) UnmarshalBinary(b []byte) error {
+	if err := checkLen(b, 1); err != nil {
+		return err
+	}
+	p.DataRateOK = b[0]&(1<<1) != 0
+	p.ChannelFrequencyOK = b[0]&1 != 0
+	return nil
+}
+
+// BeaconTimingAns is the answer to BeaconTimingReq (LoRaWAN 1.0.x only).
+type BeaconTimingAns struct {
+	// Delay holds the delay until the next beacon, in units of 30 ms.
+	Delay   uint16
+	Channel uint8
+}
+
+// MarshalBinary implements encoding.BinaryMarshaler.
+func (p BeaconTimingAns) MarshalBinary() ([]byte, error) {
+	b := make([]byte, 3)
+	binary.LittleEndian.PutUint16(b, p.Delay)
+	b[2] = p.Channel
+	return b, nil
+}
+
+// UnmarshalBinary implements encoding.BinaryUnmarshaler.
+func (p *BeaconTimingAns) UnmarshalBinary(b []byte) error {
+	if err := checkLen(b, 3); err != nil {
+		return err
+	}
+	p.Delay = binary.LittleEndian.Uint16(b)
+	p.Channel = b[2]
+	return nil
+}
+
+// BeaconFreqReq sets the beacon frequency.
+type BeaconFreqReq struct {
+	// Frequency (Hz), 0 restores the default frequency.
+	Frequency uint32
+}
+
+// MarshalBinary implements encoding.BinaryMarshaler.
+func (p BeaconFreqReq) MarshalBinary() ([]byte, error) {
+	b := make([]byte, 3)
+	if err := putFrequency(b, p.Frequency); err != nil {
+		return nil, err
+	}
+	return b, nil
+}
+
+// UnmarshalBinary implements encoding.BinaryUnmarshaler.
+func (p *BeaconFreqReq) UnmarshalBinary(b []byte) error {
+	if err := checkLen(b, 3); err != nil {
+		return err
+	}
+	p.Frequency = frequency(b)
+	return nil
+}
+
+// BeaconFreqAns is the answer to BeaconFreqReq.
+type BeaconFreqAns struct {
+	BeaconFrequencyOK bool
+}
+
+// MarshalBinary implements encoding.BinaryMarshaler.
+func (p BeaconFreqAns) MarshalBinary() ([]byte, error) {
+	return []byte{bit(p.BeaconFrequencyOK, 0)}, nil
+}
+
+// UnmarshalBinary implements encoding.BinaryUnmarshaler.
+func (p *BeaconFreqAns) UnmarshalBinary(b []byte) error {
+	if err := checkLen(b, 1); err != nil {
+		return err
+	}
+	p.BeaconFrequencyOK = b[0]&1 != 0
+	return nil
+}
+
+// Device classes used by DeviceModeInd and DeviceModeConf.
+const (
+	ClassA uint8 = 0x00
+	ClassC uint8 = 0x02
+)
+
+// DeviceModeInd is sent by a device (LoRaWAN 1.1) to change its class.
+type DeviceModeInd struct {
+	Class uint8
+}
+
+// MarshalBinary implements encoding.BinaryMarshaler.
+func (p DeviceModeInd) MarshalBinary() ([]byte, error) {
+	return []byte{p.Class}, nil
+}
+
+// UnmarshalBinary implements encoding.BinaryUnmarshaler.
+func (p *DeviceModeInd) UnmarshalBinary(b []byte) error {
+	if err := checkLen(b, 1); err != nil {
+		return err
+	}
+	p.Class = b[0]
+	return nil
+}
+
+// DeviceModeConf is the answer to DeviceModeInd.
+type DeviceModeConf struct {
+	Class uint8
+}
+
+// MarshalBinary implements encoding.BinaryMarshaler.
+func (p DeviceModeConf) MarshalBinary() ([]byte, error) {
+	return []byte{p.Class}, nil
+}
+
+// UnmarshalBinary implements encoding.BinaryUnmarshaler.
+func (p *DeviceModeConf) UnmarshalBinary(b []byte) error {
+	if err := checkLen(b, 1); err != nil {
+		return err
+	}
+	p.Class = b[0]
+	return nil
+}
+
+// Proprietary holds the payload of a proprietary mac-command.
+type Proprietary struct {
+	Bytes []byte
+}
+
+// MarshalBinary implements encoding.BinaryMarshaler.
+func (p Proprietary) MarshalBinary() ([]byte, error) {
+	return append([]byte(nil), p.Bytes...), nil
+}
+
+// UnmarshalBinary implements encoding.BinaryUnmarshaler.
+func (p *Proprietary) UnmarshalBinary(b []byte) error {
+	p.Bytes = append([]byte(nil), b...)
+	return nil
+}
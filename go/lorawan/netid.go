@@ -0,0 +1,105 @@
+package lorawan
+
+import (
+	"database/sql/driver"
+	"encoding/hex"
+	"fmt"
+)
+
+// nwkIDBits defines per NetID type the number of NwkID bits, as defined by
+// the LoRaWAN Backend Interfaces specification.
+var nwkIDBits = [8]uint{6, 6, 9, 11, 12, 13, 15, 17}
+
+// NetID defines the 24 bit network identifier.
+type NetID [3]byte
+
+// ParseNetID parses the HEX encoded NetID.
+func ParseNetID(s string) (NetID, error) {
+	var n NetID
+	err := decodeHex(n[:], "NetID", s)
+	return n, err
+}
+
+// NetIDFromBytes returns the NetID for the given bytes.
+func NetIDFromBytes(b []byte) (NetID, error) {
+	var n NetID
+	err := copyBytes(n[:], "NetID", b)
+	return n, err
+}
+
+// NewNetID returns the NetID for the given type (0 - 7) and 21 bit ID.
+func NewNetID(typ int, id uint32) (NetID, error) {
+	if typ < 0 || typ > 7 {
+		return NetID{}, fmt.Errorf("lorawan: NetID: invalid type %d", typ)
+	}
+	if id >= 1<<21 {
+		return NetID{}, fmt.Errorf("lorawan: NetID: ID %d exceeds 21 bits", id)
+	}
+
+	v := uint32(typ)<<21 | id
+	return NetID{byte(v >> 16), byte(v >> 8), byte(v)}, nil
+}
+
+// String returns the NetID as a lower-case HEX encoded string.
+func (n NetID) String() string {
+	return hex.EncodeToString(n[:])
+}
+
+// Bytes returns the NetID as a byte slice.
+func (n NetID) Bytes() []byte {
+	b := make([]byte, len(n))
+	copy(b, n[:])
+	return b
+}
+
+// Type returns the NetID type (0 - 7).
+func (n NetID) Type() int {
+	return int(n[0] >> 5)
+}
+
+// ID returns the 21 bit ID of the NetID.
+func (n NetID) ID() uint32 {
+	return n.uint32() & (1<<21 - 1)
+}
+
+// NwkID returns the NwkID, which are the least significant bits of the ID.
+// The number of bits depends on the NetID type.
+func (n NetID) NwkID() uint32 {
+	return n.uint32() & (1<<nwkIDBits[n.Type()] - 1)
+}
+
+// NwkAddrBits returns the number of NwkAddr bits of the DevAddrs which
+// belong to the NetID. This depends on the NetID type.
+func (n NetID) NwkAddrBits() uint {
+	return nwkAddrBits(n.Type())
+}
+
+// MarshalText implements encoding.TextMarshaler.
+func (n NetID) MarshalText() ([]byte, error) {
+	return []byte(n.String()), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler.
+func (n *NetID) UnmarshalText(text []byte) error {
+	return decodeHex(n[:], "NetID", string(text))
+}
+
+// Scan implements sql.Scanner.
+func (n *NetID) Scan(src interface{}) error {
+	return scan(n[:], "NetID", src)
+}
+
+// Value implements driver.Valuer.
+func (n NetID) Value() (driver.Value, error) {
+	return value(n[:])
+}
+
+// nwkAddrBits returns the number of NwkAddr bits for the given NetID type.
+// These are the DevAddr bits which remain after the prefix and the NwkID.
+func nwkAddrBits(typ int) uint {
+	return 32 - uint(typ+1) - nwkIDBits[typ]
+}
+
+func (n NetID) uint32() uint32 {
+	return uint32(n[0])<<16 | uint32(n[1])<<8 | uint32(n[2])
+}
@@ -0,0 +1,332 @@
+package phypayload
+
+import (
+	"crypto/aes"
+	"crypto/subtle"
+	"encoding/binary"
+	"fmt"
+
+	"github.com/brocaar/chirpstack-api/go/lorawan"
+)
+
+// Keys contains the keys and frame parameters used for validating the MIC
+// and decrypting the payload. Keys which are not set (zero) are not used,
+// e.g. the MIC of a data message is not validated when the network session
+// (integrity) keys are not set.
+type Keys struct {
+	// LoRaWAN11 must be set for LoRaWAN 1.1 devices.
+	LoRaWAN11 bool
+
+	// NwkSKey holds the LoRaWAN 1.0.x network session key.
+	NwkSKey lorawan.AES128Key
+
+	// LoRaWAN 1.1 network session keys.
+	FNwkSIntKey lorawan.AES128Key
+	SNwkSIntKey lorawan.AES128Key
+	NwkSEncKey  lorawan.AES128Key
+
+	// AppSKey holds the application session key.
+	AppSKey lorawan.AES128Key
+
+	// FCntMSB holds the 16 MSB of the 32 bit frame-counter.
+	FCntMSB uint16
+
+	// ConfFCnt holds the frame-counter of the acknowledged confirmed frame,
+	// used by the LoRaWAN 1.1 MIC when the ACK bit is set.
+	ConfFCnt uint32
+
+	// TXDR and TXCh hold the data-rate and channel index of the uplink,
+	// used by the LoRaWAN 1.1 uplink MIC.
+	TXDR uint8
+	TXCh uint8
+
+	// NwkKey holds the network root key, used for the join-request MIC and
+	// the join-accept (LoRaWAN 1.0.x, or a join-accept in response to a
+	// join-request).
+	NwkKey lorawan.AES128Key
+
+	// JSIntKey and JSEncKey hold the LoRaWAN 1.1 join-server keys. The
+	// JSEncKey is used for decrypting a join-accept in response to a
+	// rejoin-request, the JSIntKey for the join-accept MIC when OptNeg is
+	// set and the rejoin-request type 1 MIC.
+	JSIntKey lorawan.AES128Key
+	JSEncKey lorawan.AES128Key
+
+	// RejoinType must be set when the join-accept is in response to a
+	// rejoin-request (LoRaWAN 1.1), nil means a join-request. It selects the
+	// JSEncKey for decrypting the join-accept and, together with JoinEUI and
+	// DevNonce (the RJcount0 or RJcount1 for a rejoin-request), is used for
+	// the join-accept MIC when OptNeg is set.
+	RejoinType *uint8
+	JoinEUI    lorawan.EUI64
+	DevNonce   uint16
+}
+
+// joinRequestType defines the JoinReqType of the join-accept MIC for a
+// join-request.
+const joinRequestType uint8 = 0xff
+
+// applyKeys validates the MIC and decrypts the payload.
+func (p *PHYPayload) applyKeys(k *Keys) error {
+	switch {
+	case p.JoinRequestPayload != nil:
+		p.validateMIC(k.NwkKey, p.raw[:len(p.raw)-4])
+	case p.RejoinRequestPayload != nil:
+		key := k.SNwkSIntKey
+		if p.RejoinRequestPayload.RejoinType == 1 {
+			key = k.JSIntKey
+		}
+		p.validateMIC(key, p.raw[:len(p.raw)-4])
+	case p.EncryptedPayload != nil:
+		return p.decryptJoinAccept(k)
+	case p.MACPayload != nil:
+		return p.applyDataKeys(k)
+	}
+	return nil
+}
+
+func (p *PHYPayload) decryptJoinAccept(k *Keys) error {
+	// A join-accept in response to a rejoin-request is encrypted using the
+	// JSEncKey (LoRaWAN 1.1).
+	key := k.NwkKey
+	if k.RejoinType != nil {
+		key = k.JSEncKey
+	}
+	if key.IsZero() {
+		return nil
+	}
+
+	block, err := aes.NewCipher(key[:])
+	if err != nil {
+		return fmt.Errorf("phypayload: new cipher error: %w", err)
+	}
+
+	// The join-accept is encrypted using AES decrypt, thus decrypted using
+	// AES encrypt.
+	b := make([]byte, len(p.EncryptedPayload))
+	for i := 0; i < len(b); i += block.BlockSize() {
+		block.Encrypt(b[i:], p.EncryptedPayload[i:])
+	}
+	p.decodeJoinAccept(b)
+
+	msg := append([]byte{p.raw[0]}, b[:len(b)-4]...)
+	if !p.JoinAcceptPayload.DLSettings.OptNeg {
+		p.validateMIC(k.NwkKey, msg)
+		return nil
+	}
+
+	joinReqType := joinRequestType
+	if k.RejoinType != nil {
+		joinReqType = *k.RejoinType
+	}
+	prefix := []byte{joinReqType}
+	for i := len(k.JoinEUI) - 1; i >= 0; i-- {
+		prefix = append(prefix, k.JoinEUI[i])
+	}
+	prefix = append(prefix, byte(k.DevNonce), byte(k.DevNonce>>8))
+	p.validateMIC(k.JSIntKey, append(prefix, msg...))
+	return nil
+}
+
+func (p *PHYPayload) applyDataKeys(k *Keys) error {
+	mp := p.MACPayload
+	uplink := p.MHDR.MType.IsUplink()
+	mp.FHDR.FCnt = uint32(k.FCntMSB)<<16 | mp.FHDR.FCnt
+	msg := p.raw[:len(p.raw)-4]
+
+	var confFCnt uint32
+	if mp.FHDR.FCtrl.ACK {
+		confFCnt = k.ConfFCnt
+	}
+
+	// MIC.
+	switch {
+	case !k.LoRaWAN11:
+		if !k.NwkSKey.IsZero() {
+			b0 := micBlock(uplink, mp.FHDR.DevAddr, mp.FHDR.FCnt, len(msg), 0, 0, 0)
+			p.setMICValid(cmac(k.NwkSKey, append(b0, msg...))[:4])
+		}
+	case uplink:
+		if !k.FNwkSIntKey.IsZero() && !k.SNwkSIntKey.IsZero() {
+			b0 := micBlock(uplink, mp.FHDR.DevAddr, mp.FHDR.FCnt, len(msg), 0, 0, 0)
+			b1 := micBlock(uplink, mp.FHDR.DevAddr, mp.FHDR.FCnt, len(msg), confFCnt, k.TXDR, k.TXCh)
+			cmacF := cmac(k.FNwkSIntKey, append(b0, msg...))
+			cmacS := cmac(k.SNwkSIntKey, append(b1, msg...))
+			p.setMICValid(append(cmacS[:2], cmacF[:2]...))
+		}
+	default:
+		if !k.SNwkSIntKey.IsZero() {
+			b0 := micBlock(uplink, mp.FHDR.DevAddr, mp.FHDR.FCnt, len(msg), confFCnt, 0, 0)
+			p.setMICValid(cmac(k.SNwkSIntKey, append(b0, msg...))[:4])
+		}
+	}
+
+	// FOpts (LoRaWAN 1.1). As defined by the LoRaWAN 1.1 errata, byte 4 of
+	// the A block holds 0x01 for uplink and NFCntDown, 0x02 for AFCntDown
+	// (downlink with FPort > 0) and the block counter starts at 1.
+	if k.LoRaWAN11 {
+		mp.foptsEncrypted = len(mp.FHDR.FOpts) != 0
+		if mp.foptsEncrypted && !k.NwkSEncKey.IsZero() {
+			a := aBlock(uplink, mp.FHDR.DevAddr, mp.FHDR.FCnt)
+			a[4] = 0x01
+			if !uplink && mp.FPort != nil && *mp.FPort > 0 {
+				a[4] = 0x02
+			}
+			b, err := cipherStream(k.NwkSEncKey, a, 1, mp.FHDR.FOpts)
+			if err != nil {
+				return err
+			}
+			mp.FHDR.FOpts = b
+			mp.foptsEncrypted = false
+			mp.Decrypted = true
+		}
+	}
+
+	// FRMPayload.
+	if mp.FPort != nil {
+		key := k.AppSKey
+		if *mp.FPort == 0 {
+			key = k.NwkSKey
+			if k.LoRaWAN11 {
+				key = k.NwkSEncKey
+			}
+		}
+		if !key.IsZero() {
+			b, err := cipherStream(key, aBlock(uplink, mp.FHDR.DevAddr, mp.FHDR.FCnt), 1, mp.FRMPayload)
+			if err != nil {
+				return err
+			}
+			mp.FRMPayload = b
+			mp.frmDecrypted = true
+			mp.Decrypted = true
+		}
+	}
+
+	return nil
+}
+
+// validateMIC validates the MIC using the CMAC of msg, when the key is set.
+func (p *PHYPayload) validateMIC(key lorawan.AES128Key, msg []byte) {
+	if key.IsZero() {
+		return
+	}
+	p.setMICValid(cmac(key, msg)[:4])
+}
+
+func (p *PHYPayload) setMICValid(mic []byte) {
+	valid := subtle.ConstantTimeCompare(mic, p.MIC) == 1
+	p.MICValid = &valid
+}
+
+// micBlock returns the B0 (confFCnt, txDR and txCh 0) or B1 block of the
+// data message MIC.
+func micBlock(uplink bool, devAddr lorawan.DevAddr, fCnt uint32, msgLen int, confFCnt uint32, txDR, txCh uint8) []byte {
+	b := make([]byte, 16)
+	b[0] = 0x49
+	binary.LittleEndian.PutUint16(b[1:3], uint16(confFCnt))
+	b[3] = txDR
+	b[4] = txCh
+	if !uplink {
+		b[5] = 0x01
+	}
+	b[6], b[7], b[8], b[9] = devAddr[3], devAddr[2], devAddr[1], devAddr[0]
+	binary.LittleEndian.PutUint32(b[10:14], fCnt)
+	b[15] = byte(msgLen)
+	return b
+}
+
+// aBlock returns the A block of the payload encryption, without the block
+// counter.
+func aBlock(uplink bool, devAddr lorawan.DevAddr, fCnt uint32) []byte {
+	a := make([]byte, 16)
+	a[0] = 0x01
+	if !uplink {
+		a[5] = 0x01
+	}
+	a[6], a[7], a[8], a[9] = devAddr[3], devAddr[2], devAddr[1], devAddr[0]
+	binary.LittleEndian.PutUint32(a[10:14], fCnt)
+	return a
+}
+
+// cipherStream encrypts or decrypts b using the LoRaWAN AES-CTR like
+// scheme, using the A block a with the block counter starting at the given
+// value.
+func cipherStream(key lorawan.AES128Key, a []byte, counter byte, b []byte) ([]byte, error) {
+	block, err := aes.NewCipher(key[:])
+	if err != nil {
+		return nil, fmt.Errorf("phypayload: new cipher error: %w", err)
+	}
+
+	out := make([]byte, len(b))
+	s := make([]byte, 16)
+	for i := 0; i < len(b); i += 16 {
+		a[15] = counter
+		counter++
+		block.Encrypt(s, a)
+		for j := i; j < len(b) && j < i+16; j++ {
+			out[j] = b[j] ^ s[j-i]
+		}
+	}
+	return out, nil
+}
+
+// cmac returns the AES-CMAC (RFC 4493) of msg.
+func cmac(key lorawan.AES128Key, msg []byte) []byte {
+	block, err := aes.NewCipher(key[:])
+	if err != nil {
+		// aes.NewCipher only fails on invalid key sizes.
+		panic(err)
+	}
+
+	// Subkeys.
+	k1 := make([]byte, 16)
+	block.Encrypt(k1, k1)
+	k1 = shiftSubkey(k1)
+	k2 := shiftSubkey(k1)
+
+	n := (len(msg) + 15) / 16
+	complete := n != 0 && len(msg)%16 == 0
+	if n == 0 {
+		n = 1
+	}
+
+	last := make([]byte, 16)
+	copy(last, msg[(n-1)*16:])
+	if complete {
+		xor(last, k1)
+	} else {
+		last[len(msg)-(n-1)*16] = 0x80
+		xor(last, k2)
+	}
+
+	x := make([]byte, 16)
+	for i := 0; i < n-1; i++ {
+		xor(x, msg[i*16:(i+1)*16])
+		block.Encrypt(x, x)
+	}
+	xor(x, last)
+	block.Encrypt(x, x)
+	return x
+}
+
+// shiftSubkey returns b shifted left by one bit, xor-ed with the CMAC
+// constant when the MSB was set.
+func shiftSubkey(b []byte) []byte {
+	out := make([]byte, len(b))
+	for i := range b {
+		out[i] = b[i] << 1
+		if i+1 < len(b) {
+			out[i] |= b[i+1] >> 7
+		}
+	}
+	if b[0]&0x80 != 0 {
+		out[len(out)-1] ^= 0x87
+	}
+	return out
+}
+
+func xor(dst, src []byte) {
+	for i := range dst {
+		dst[i] ^= src[i]
+	}
+}
@@ -0,0 +1,474 @@
+package phypayload
+
+import (
+	"bytes"
+	"encoding/hex"
+	"reflect"
+	"testing"
+
+	"github.com/brocaar/chirpstack-api/go/lorawan"
+)
+
+func mustHEX(t *testing.T, s string) []byte {
+	t.Helper()
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+func mustKey(t *testing.T, s string) lorawan.AES128Key {
+	t.Helper()
+	k, err := lorawan.ParseAES128Key(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return k
+}
+
+// TestCMAC tests the AES-CMAC implementation using the RFC 4493 test
+// vectors.
+func TestCMAC(t *testing.T) {
+	key := mustKey(t, "2b7e151628aed2a6abf7158809cf4f3c")
+	msg := "6bc1bee22e409f96e93d7e117393172aae2d8a571e03ac9c9eb76fac45af8e5130c81c46a35ce411e5fbc1191a0a52eff69f2445df4f9b17ad2b417be66c3710"
+
+	tests := []struct {
+		len int
+		mac string
+	}{
+		{0, "bb1d6929e95937287fa37d129b756746"},
+		{16, "070a16b46b4d4144f79bdd9dd04a287c"},
+		{40, "dfa66747de9ae63030ca32611497c827"},
+		{64, "51f0bebf7e3b9d92fc49741779363cfe"},
+	}
+
+	for _, test := range tests {
+		m := mustHEX(t, msg)[:test.len]
+		if mac := hex.EncodeToString(cmac(key, m)); mac != test.mac {
+			t.Errorf("len %d: expected %s, got %s", test.len, test.mac, mac)
+		}
+	}
+}
+
+// Keys used by the test vectors.
+const (
+	nwkSKey     = "44024241ed4ce9a68c6a8bc055233fd3"
+	appSKey     = "ec925802ae430ca77fd3dd73cb2cc588"
+	fNwkSIntKey = "00112233445566778899aabbccddeeff"
+	sNwkSIntKey = "ffeeddccbbaa99887766554433221100"
+	nwkSEncKey  = "0f0e0d0c0b0a09080706050403020100"
+	appSKey11   = "a0a1a2a3a4a5a6a7a8a9aaabacadaeaf"
+	jsIntKey    = "1f1e1d1c1b1a19181716151413121110"
+	jsEncKey    = "2f2e2d2c2b2a29282726252423222120"
+)
+
+// TestDecodeKeys tests the MIC validation and decryption using known
+// frames. Except for the first (published) LoRaWAN 1.0 uplink, the frames
+// have been constructed using an independent AES and AES-CMAC
+// implementation (OpenSSL).
+func TestDecodeKeys(t *testing.T) {
+	valid, invalid := true, false
+
+	keys10 := func() *Keys {
+		return &Keys{
+			NwkSKey: mustKey(t, nwkSKey),
+			AppSKey: mustKey(t, appSKey),
+			NwkKey:  mustKey(t, nwkSKey),
+		}
+	}
+	keys11 := func() *Keys {
+		return &Keys{
+			LoRaWAN11:   true,
+			FNwkSIntKey: mustKey(t, fNwkSIntKey),
+			SNwkSIntKey: mustKey(t, sNwkSIntKey),
+			NwkSEncKey:  mustKey(t, nwkSEncKey),
+			AppSKey:     mustKey(t, appSKey11),
+			NwkKey:      mustKey(t, nwkSKey),
+			JSIntKey:    mustKey(t, jsIntKey),
+			JSEncKey:    mustKey(t, jsEncKey),
+			JoinEUI:     lorawan.EUI64{0x01, 0x02, 0x03, 0x04, 0x05, 0x06, 0x07, 0x08},
+			DevNonce:    0x0102,
+		}
+	}
+	with := func(k *Keys, fn func(*Keys)) *Keys {
+		fn(k)
+		return k
+	}
+	rejoinType0, rejoinType2 := uint8(0), uint8(2)
+	rejoinJoinAccept := &JoinAcceptPayload{
+		JoinNonce:  0x000102,
+		HomeNetID:  lorawan.NetID{0x00, 0x00, 0x13},
+		DevAddr:    lorawan.DevAddr{0x26, 0x01, 0x12, 0x34},
+		DLSettings: DLSettings{OptNeg: true, RX1DROffset: 1, RX2DataRate: 3},
+		RXDelay:    1,
+	}
+
+	tests := []struct {
+		name     string
+		phy      string
+		keys     *Keys
+		micValid *bool
+
+		// Data messages.
+		fCnt       uint32
+		fOpts      string
+		frmPayload string
+		cids       []string
+
+		// Join messages.
+		joinRequest   *JoinRequestPayload
+		joinAccept    *JoinAcceptPayload
+		rejoinRequest *RejoinRequestPayload
+	}{
+		{
+			name:       "1.0 uplink",
+			phy:        "40f17dbe4900020001954378762b11ff0d",
+			keys:       keys10(),
+			micValid:   &valid,
+			fCnt:       2,
+			frmPayload: "74657374",
+		},
+		{
+			name:       "1.0 uplink without keys",
+			phy:        "40f17dbe4900020001954378762b11ff0d",
+			fCnt:       2,
+			frmPayload: "95437876",
+		},
+		{
+			name: "1.0 uplink invalid NwkSKey",
+			phy:  "40f17dbe4900020001954378762b11ff0d",
+			keys: with(keys10(), func(k *Keys) {
+				k.NwkSKey[0] ^= 0xff
+			}),
+			micValid:   &invalid,
+			fCnt:       2,
+			frmPayload: "74657374",
+		},
+		{
+			name:       "1.0 downlink",
+			phy:        "6004030201330500020a03016b5dd782ec37f7e5b3",
+			keys:       keys10(),
+			micValid:   &valid,
+			fCnt:       5,
+			fOpts:      "020a03",
+			frmPayload: hex.EncodeToString([]byte("hello")),
+			cids:       []string{"LinkCheck"},
+		},
+		{
+			name:     "1.0 join-request",
+			phy:      "000807060504030201010203040506070802011643dccf",
+			keys:     keys10(),
+			micValid: &valid,
+			joinRequest: &JoinRequestPayload{
+				JoinEUI:  lorawan.EUI64{0x01, 0x02, 0x03, 0x04, 0x05, 0x06, 0x07, 0x08},
+				DevEUI:   lorawan.EUI64{0x08, 0x07, 0x06, 0x05, 0x04, 0x03, 0x02, 0x01},
+				DevNonce: 0x0102,
+			},
+		},
+		{
+			name:     "1.0 join-accept",
+			phy:      "20053c460cd4dbabd293a2f639d3f0b01f6dfed2b65ec854f7537aeb6837e9b48d",
+			keys:     keys10(),
+			micValid: &valid,
+			joinAccept: &JoinAcceptPayload{
+				JoinNonce:  0x010203,
+				HomeNetID:  lorawan.NetID{0x00, 0x00, 0x13},
+				DevAddr:    lorawan.DevAddr{0x26, 0x01, 0x12, 0x34},
+				DLSettings: DLSettings{RX1DROffset: 1, RX2DataRate: 3},
+				RXDelay:    1,
+				CFList:     HEXBytes(mustHEX(t, "184f84e85684b85e84886684586e8400")),
+			},
+		},
+		{
+			name: "1.1 uplink",
+			phy:  "8004030201a50500301582bfc202fa619bdd464fd258cbd110c698ecfd7e89741c9456da6550",
+			keys: with(keys11(), func(k *Keys) {
+				k.FCntMSB = 1
+				k.ConfFCnt = 7
+				k.TXDR = 5
+				k.TXCh = 2
+			}),
+			micValid:   &valid,
+			fCnt:       0x00010005,
+			fOpts:      "030706ff3e",
+			frmPayload: hex.EncodeToString([]byte("0123456789abcdefghij")),
+			cids:       []string{"LinkADR", "DevStatus"},
+		},
+		{
+			// The ConfFCnt, TXDR and TXCh are only part of the B1 block,
+			// thus of the SNwkSIntKey half of the MIC.
+			name: "1.1 uplink invalid B1",
+			phy:  "8004030201a50500301582bfc202fa619bdd464fd258cbd110c698ecfd7e89741c9456da6550",
+			keys: with(keys11(), func(k *Keys) {
+				k.FCntMSB = 1
+				k.ConfFCnt = 7
+				k.TXDR = 5
+				k.TXCh = 3
+			}),
+			micValid:   &invalid,
+			fCnt:       0x00010005,
+			fOpts:      "030706ff3e",
+			frmPayload: hex.EncodeToString([]byte("0123456789abcdefghij")),
+			cids:       []string{"LinkADR", "DevStatus"},
+		},
+		{
+			name: "1.1 uplink invalid FNwkSIntKey",
+			phy:  "8004030201a50500301582bfc202fa619bdd464fd258cbd110c698ecfd7e89741c9456da6550",
+			keys: with(keys11(), func(k *Keys) {
+				k.FCntMSB = 1
+				k.ConfFCnt = 7
+				k.TXDR = 5
+				k.TXCh = 2
+				k.FNwkSIntKey[15] ^= 0x01
+			}),
+			micValid:   &invalid,
+			fCnt:       0x00010005,
+			fOpts:      "030706ff3e",
+			frmPayload: hex.EncodeToString([]byte("0123456789abcdefghij")),
+			cids:       []string{"LinkADR", "DevStatus"},
+		},
+		{
+			name: "1.1 uplink without NwkSEncKey",
+			phy:  "8004030201a50500301582bfc202fa619bdd464fd258cbd110c698ecfd7e89741c9456da6550",
+			keys: with(keys11(), func(k *Keys) {
+				k.FCntMSB = 1
+				k.ConfFCnt = 7
+				k.TXDR = 5
+				k.TXCh = 2
+				k.NwkSEncKey = lorawan.AES128Key{}
+			}),
+			micValid:   &valid,
+			fCnt:       0x00010005,
+			fOpts:      "301582bfc2",
+			frmPayload: hex.EncodeToString([]byte("0123456789abcdefghij")),
+		},
+		{
+			name: "1.1 downlink",
+			phy:  "600403020123090001af1003bd73da5c9022bfc8374f812f",
+			keys: with(keys11(), func(k *Keys) {
+				k.ConfFCnt = 0x0102
+			}),
+			micValid:   &valid,
+			fCnt:       9,
+			fOpts:      "020a03",
+			frmPayload: hex.EncodeToString([]byte("downlink")),
+			cids:       []string{"LinkCheck"},
+		},
+		{
+			name:       "1.1 downlink FPort 0",
+			phy:        "6004030201000a000025051fc1b5c445",
+			keys:       keys11(),
+			micValid:   &valid,
+			fCnt:       10,
+			frmPayload: "020a03",
+			cids:       []string{"LinkCheck"},
+		},
+		{
+			name:     "1.1 join-request",
+			phy:      "000807060504030201010203040506070802011643dccf",
+			keys:     keys11(),
+			micValid: &valid,
+			joinRequest: &JoinRequestPayload{
+				JoinEUI:  lorawan.EUI64{0x01, 0x02, 0x03, 0x04, 0x05, 0x06, 0x07, 0x08},
+				DevEUI:   lorawan.EUI64{0x08, 0x07, 0x06, 0x05, 0x04, 0x03, 0x02, 0x01},
+				DevNonce: 0x0102,
+			},
+		},
+		{
+			name:     "1.1 join-accept",
+			phy:      "2043738183e5749b07418c69819441e483",
+			keys:     keys11(),
+			micValid: &valid,
+			joinAccept: &JoinAcceptPayload{
+				JoinNonce:  0x000102,
+				HomeNetID:  lorawan.NetID{0x00, 0x00, 0x13},
+				DevAddr:    lorawan.DevAddr{0x26, 0x01, 0x12, 0x34},
+				DLSettings: DLSettings{OptNeg: true, RX1DROffset: 1, RX2DataRate: 3},
+				RXDelay:    1,
+			},
+		},
+		{
+			name: "1.1 join-accept invalid DevNonce",
+			phy:  "2043738183e5749b07418c69819441e483",
+			keys: with(keys11(), func(k *Keys) {
+				k.DevNonce = 0x0103
+			}),
+			micValid: &invalid,
+			joinAccept: &JoinAcceptPayload{
+				JoinNonce:  0x000102,
+				HomeNetID:  lorawan.NetID{0x00, 0x00, 0x13},
+				DevAddr:    lorawan.DevAddr{0x26, 0x01, 0x12, 0x34},
+				DLSettings: DLSettings{OptNeg: true, RX1DROffset: 1, RX2DataRate: 3},
+				RXDelay:    1,
+			},
+		},
+		{
+			// Encrypted using the JSEncKey, the MIC uses the rejoin type
+			// and RJcount0 instead of the DevNonce.
+			name: "1.1 join-accept rejoin type 0",
+			phy:  "200bd9ffa2d4c8b6afea3d343b68ed1c01",
+			keys: with(keys11(), func(k *Keys) {
+				k.RejoinType = &rejoinType0
+				k.DevNonce = 5
+			}),
+			micValid:   &valid,
+			joinAccept: rejoinJoinAccept,
+		},
+		{
+			name: "1.1 join-accept rejoin type 2",
+			phy:  "20355ea2193dcd84853de42388b3b0c65d",
+			keys: with(keys11(), func(k *Keys) {
+				k.RejoinType = &rejoinType2
+				k.DevNonce = 5
+			}),
+			micValid:   &valid,
+			joinAccept: rejoinJoinAccept,
+		},
+		{
+			name: "1.1 join-accept invalid rejoin type",
+			phy:  "200bd9ffa2d4c8b6afea3d343b68ed1c01",
+			keys: with(keys11(), func(k *Keys) {
+				k.RejoinType = &rejoinType2
+				k.DevNonce = 5
+			}),
+			micValid:   &invalid,
+			joinAccept: rejoinJoinAccept,
+		},
+		{
+			name:     "1.1 rejoin-request type 0",
+			phy:      "c000130000080706050403020105004fdde013",
+			keys:     keys11(),
+			micValid: &valid,
+			rejoinRequest: &RejoinRequestPayload{
+				RejoinType: 0,
+				NetID:      &lorawan.NetID{0x00, 0x00, 0x13},
+				DevEUI:     lorawan.EUI64{0x01, 0x02, 0x03, 0x04, 0x05, 0x06, 0x07, 0x08},
+				RJCount:    5,
+			},
+		},
+		{
+			name: "1.1 rejoin-request type 0 invalid SNwkSIntKey",
+			phy:  "c000130000080706050403020105004fdde013",
+			keys: with(keys11(), func(k *Keys) {
+				k.SNwkSIntKey[0] ^= 0x01
+			}),
+			micValid: &invalid,
+			rejoinRequest: &RejoinRequestPayload{
+				RejoinType: 0,
+				NetID:      &lorawan.NetID{0x00, 0x00, 0x13},
+				DevEUI:     lorawan.EUI64{0x01, 0x02, 0x03, 0x04, 0x05, 0x06, 0x07, 0x08},
+				RJCount:    5,
+			},
+		},
+		{
+			name:     "1.1 rejoin-request type 1",
+			phy:      "c00108070605040302010807060504030201070051e09164",
+			keys:     keys11(),
+			micValid: &valid,
+			rejoinRequest: &RejoinRequestPayload{
+				RejoinType: 1,
+				JoinEUI:    &lorawan.EUI64{0x01, 0x02, 0x03, 0x04, 0x05, 0x06, 0x07, 0x08},
+				DevEUI:     lorawan.EUI64{0x01, 0x02, 0x03, 0x04, 0x05, 0x06, 0x07, 0x08},
+				RJCount:    7,
+			},
+		},
+		{
+			// Type 1 uses the JSIntKey, not the SNwkSIntKey.
+			name: "1.1 rejoin-request type 1 invalid JSIntKey",
+			phy:  "c00108070605040302010807060504030201070051e09164",
+			keys: with(keys11(), func(k *Keys) {
+				k.JSIntKey[0] ^= 0x01
+			}),
+			micValid: &invalid,
+			rejoinRequest: &RejoinRequestPayload{
+				RejoinType: 1,
+				JoinEUI:    &lorawan.EUI64{0x01, 0x02, 0x03, 0x04, 0x05, 0x06, 0x07, 0x08},
+				DevEUI:     lorawan.EUI64{0x01, 0x02, 0x03, 0x04, 0x05, 0x06, 0x07, 0x08},
+				RJCount:    7,
+			},
+		},
+		{
+			name:     "1.1 rejoin-request type 2",
+			phy:      "c00213000008070605040302010500a8c29c54",
+			keys:     keys11(),
+			micValid: &valid,
+			rejoinRequest: &RejoinRequestPayload{
+				RejoinType: 2,
+				NetID:      &lorawan.NetID{0x00, 0x00, 0x13},
+				DevEUI:     lorawan.EUI64{0x01, 0x02, 0x03, 0x04, 0x05, 0x06, 0x07, 0x08},
+				RJCount:    5,
+			},
+		},
+		{
+			name: "1.1 rejoin-request without keys",
+			phy:  "c00213000008070605040302010500a8c29c54",
+			rejoinRequest: &RejoinRequestPayload{
+				RejoinType: 2,
+				NetID:      &lorawan.NetID{0x00, 0x00, 0x13},
+				DevEUI:     lorawan.EUI64{0x01, 0x02, 0x03, 0x04, 0x05, 0x06, 0x07, 0x08},
+				RJCount:    5,
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			phy := mustHEX(t, test.phy)
+			p, err := Decode(phy, test.keys)
+			if err != nil {
+				t.Fatalf("decode error: %s", err)
+			}
+
+			switch {
+			case test.micValid == nil && p.MICValid != nil:
+				t.Errorf("expected no MIC validation, got %t", *p.MICValid)
+			case test.micValid != nil && p.MICValid == nil:
+				t.Errorf("expected MIC validation")
+			case test.micValid != nil && *test.micValid != *p.MICValid:
+				t.Errorf("expected MICValid %t, got %t", *test.micValid, *p.MICValid)
+			}
+
+			switch {
+			case test.joinRequest != nil:
+				if !reflect.DeepEqual(test.joinRequest, p.JoinRequestPayload) {
+					t.Errorf("expected join-request %+v, got %+v", test.joinRequest, p.JoinRequestPayload)
+				}
+			case test.joinAccept != nil:
+				if !reflect.DeepEqual(test.joinAccept, p.JoinAcceptPayload) {
+					t.Errorf("expected join-accept %+v, got %+v", test.joinAccept, p.JoinAcceptPayload)
+				}
+			case test.rejoinRequest != nil:
+				if !reflect.DeepEqual(test.rejoinRequest, p.RejoinRequestPayload) {
+					t.Errorf("expected rejoin-request %+v, got %+v", test.rejoinRequest, p.RejoinRequestPayload)
+				}
+			default:
+				mp := p.MACPayload
+				if mp == nil {
+					t.Fatal("expected mac-payload")
+				}
+				if mp.FHDR.FCnt != test.fCnt {
+					t.Errorf("expected FCnt %d, got %d", test.fCnt, mp.FHDR.FCnt)
+				}
+				if fOpts := hex.EncodeToString(mp.FHDR.FOpts); fOpts != test.fOpts {
+					t.Errorf("expected FOpts %s, got %s", test.fOpts, fOpts)
+				}
+				if frm := hex.EncodeToString(mp.FRMPayload); frm != test.frmPayload {
+					t.Errorf("expected FRMPayload %s, got %s", test.frmPayload, frm)
+				}
+				var cids []string
+				for _, cmd := range mp.MACCommands {
+					cids = append(cids, cmd.CID)
+				}
+				if !reflect.DeepEqual(test.cids, cids) {
+					t.Errorf("expected mac-commands %v, got %v", test.cids, cids)
+				}
+			}
+
+			// The input must not be modified.
+			if !bytes.Equal(phy, mustHEX(t, test.phy)) {
+				t.Error("input has been modified")
+			}
+		})
+	}
+}
@@ -0,0 +1,197 @@
+package phypayload
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/brocaar/chirpstack-api/go/lorawan"
+)
+
+// asPHYPayload implements the phy_payload_json of the application-server
+// frame logs. The macPayload depends on the message type.
+type asPHYPayload struct {
+	MHDR       MHDR            `json:"mhdr"`
+	MACPayload json.RawMessage `json:"macPayload"`
+	MIC        HEXBytes        `json:"mic"`
+}
+
+// asPayload holds an item of the fOpts and frmPayload lists, which is either
+// a data payload (bytes) or a mac-command (cid and payload).
+type asPayload struct {
+	Bytes   []byte          `json:"bytes"`
+	CID     string          `json:"cid"`
+	Payload json.RawMessage `json:"payload"`
+}
+
+type asMACPayload struct {
+	FHDR struct {
+		DevAddr lorawan.DevAddr `json:"devAddr"`
+		FCtrl   FCtrl           `json:"fCtrl"`
+		FCnt    uint32          `json:"fCnt"`
+		FOpts   []asPayload     `json:"fOpts"`
+	} `json:"fhdr"`
+	FPort      *uint8      `json:"fPort"`
+	FRMPayload []asPayload `json:"frmPayload"`
+}
+
+// asJoinAcceptPayload holds the decrypted join-accept. The cFlist is
+// omitted, as it is not HEX encoded.
+type asJoinAcceptPayload struct {
+	JoinNonce  uint32          `json:"joinNonce"`
+	HomeNetID  lorawan.NetID   `json:"homeNetID"`
+	DevAddr    lorawan.DevAddr `json:"devAddr"`
+	DLSettings DLSettings      `json:"dlSettings"`
+	RXDelay    uint8           `json:"rxDelay"`
+}
+
+type asRejoinRequestPayload struct {
+	RejoinType uint8          `json:"rejoinType"`
+	NetID      *lorawan.NetID `json:"netID"`
+	JoinEUI    *lorawan.EUI64 `json:"joinEUI"`
+	DevEUI     lorawan.EUI64  `json:"devEUI"`
+	RJCount0   uint16         `json:"rjCount0"`
+	RJCount1   uint16         `json:"rjCount1"`
+}
+
+// DecodeJSON decodes the phy_payload_json of the application-server frame
+// logs. As the raw PHYPayload is not available, the MIC can not be validated
+// and the payload can not be decrypted. Mac-commands are decoded by CID
+// only, their payload and the raw FOpts are not available. The CFList of a
+// join-accept is not decoded.
+func DecodeJSON(s string) (*PHYPayload, error) {
+	var as asPHYPayload
+	if err := json.Unmarshal([]byte(s), &as); err != nil {
+		return nil, fmt.Errorf("phypayload: unmarshal json error: %w", err)
+	}
+
+	p := PHYPayload{
+		MHDR: as.MHDR,
+		MIC:  as.MIC,
+	}
+
+	var err error
+	switch p.MHDR.MType {
+	case JoinRequest:
+		var jr JoinRequestPayload
+		err = json.Unmarshal(as.MACPayload, &jr)
+		p.JoinRequestPayload = &jr
+	case JoinAccept:
+		err = p.decodeJoinAcceptJSON(as.MACPayload)
+	case UnconfirmedDataUp, UnconfirmedDataDown, ConfirmedDataUp, ConfirmedDataDown:
+		err = p.decodeMACPayloadJSON(as.MACPayload)
+	case RejoinRequest:
+		err = p.decodeRejoinRequestJSON(as.MACPayload)
+	case Proprietary:
+		var pl asPayload
+		err = json.Unmarshal(as.MACPayload, &pl)
+		p.ProprietaryPayload = HEXBytes(pl.Bytes)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("phypayload: unmarshal %s json error: %w", p.MHDR.MType, err)
+	}
+
+	return &p, nil
+}
+
+// decodeJoinAcceptJSON decodes the join-accept, which is either encrypted
+// (bytes, without the encrypted MIC) or decrypted.
+func (p *PHYPayload) decodeJoinAcceptJSON(b json.RawMessage) error {
+	var pl asPayload
+	if err := json.Unmarshal(b, &pl); err != nil {
+		return err
+	}
+	if pl.Bytes != nil {
+		p.EncryptedPayload = HEXBytes(append(pl.Bytes, p.MIC...))
+		p.MIC = nil
+		return nil
+	}
+
+	var ja asJoinAcceptPayload
+	if err := json.Unmarshal(b, &ja); err != nil {
+		return err
+	}
+	p.JoinAcceptPayload = &JoinAcceptPayload{
+		JoinNonce:  ja.JoinNonce,
+		HomeNetID:  ja.HomeNetID,
+		DevAddr:    ja.DevAddr,
+		DLSettings: ja.DLSettings,
+		RXDelay:    ja.RXDelay,
+	}
+	return nil
+}
+
+func (p *PHYPayload) decodeMACPayloadJSON(b json.RawMessage) error {
+	var as asMACPayload
+	if err := json.Unmarshal(b, &as); err != nil {
+		return err
+	}
+
+	mp := MACPayload{
+		FHDR: FHDR{
+			DevAddr: as.FHDR.DevAddr,
+			FCtrl:   as.FHDR.FCtrl,
+			FCnt:    as.FHDR.FCnt,
+		},
+		FPort: as.FPort,
+	}
+
+	// Encrypted (LoRaWAN 1.1) FOpts are logged as bytes.
+	for _, pl := range as.FHDR.FOpts {
+		if pl.CID == "" {
+			mp.FHDR.FOpts = append(mp.FHDR.FOpts, pl.Bytes...)
+			continue
+		}
+		mp.MACCommands = append(mp.MACCommands, MACCommand{CID: asCIDName(pl.CID)})
+	}
+	mp.FHDR.FCtrl.FOptsLen = uint8(len(mp.FHDR.FOpts))
+
+	// The FRMPayload holds mac-commands in case of a decrypted FPort 0.
+	for _, pl := range as.FRMPayload {
+		if pl.CID == "" {
+			mp.FRMPayload = append(mp.FRMPayload, pl.Bytes...)
+			continue
+		}
+		mp.MACCommands = append(mp.MACCommands, MACCommand{CID: asCIDName(pl.CID)})
+		mp.Decrypted = true
+	}
+
+	p.MACPayload = &mp
+	return nil
+}
+
+func (p *PHYPayload) decodeRejoinRequestJSON(b json.RawMessage) error {
+	var as asRejoinRequestPayload
+	if err := json.Unmarshal(b, &as); err != nil {
+		return err
+	}
+
+	rj := RejoinRequestPayload{
+		RejoinType: as.RejoinType,
+		DevEUI:     as.DevEUI,
+	}
+	switch as.RejoinType {
+	case 0, 2:
+		rj.NetID = as.NetID
+		rj.RJCount = as.RJCount0
+	case 1:
+		rj.JoinEUI = as.JoinEUI
+		rj.RJCount = as.RJCount1
+	default:
+		return fmt.Errorf("invalid rejoin type: %d", as.RejoinType)
+	}
+
+	p.RejoinRequestPayload = &rj
+	return nil
+}
+
+// asCIDName returns the CID name as used by MACCommand. The frame logs
+// include the direction in the name (e.g. LinkADRReq).
+func asCIDName(s string) string {
+	for _, suffix := range []string{"Req", "Ans", "Ind", "Conf"} {
+		if strings.HasSuffix(s, suffix) {
+			return strings.TrimSuffix(s, suffix)
+		}
+	}
+	return s
+}
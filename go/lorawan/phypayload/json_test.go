@@ -0,0 +1,179 @@
+package phypayload
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/brocaar/chirpstack-api/go/lorawan"
+)
+
+func TestDecodeJSON(t *testing.T) {
+	fPort0, fPort1 := uint8(0), uint8(1)
+
+	tests := []struct {
+		name     string
+		json     string
+		expected *PHYPayload
+	}{
+		{
+			name: "uplink",
+			json: `{"mhdr":{"mType":"UnconfirmedDataUp","major":"LoRaWANR1"},"macPayload":{"fhdr":{"devAddr":"01020304","fCtrl":{"adr":true,"adrAckReq":false,"ack":false,"fPending":false,"classB":false},"fCnt":5,"fOpts":[{"cid":"LinkADRAns","payload":{"channelMaskAck":true,"dataRateAck":true,"powerAck":true}},{"cid":"DevStatusAns","payload":{"battery":255,"margin":-2}}]},"fPort":1,"frmPayload":[{"bytes":"dGVzdA=="}]},"mic":"2b11ff0d"}`,
+			expected: &PHYPayload{
+				MHDR: MHDR{MType: UnconfirmedDataUp, Major: LoRaWANR1},
+				MACPayload: &MACPayload{
+					FHDR: FHDR{
+						DevAddr: lorawan.DevAddr{0x01, 0x02, 0x03, 0x04},
+						FCtrl:   FCtrl{ADR: true},
+						FCnt:    5,
+					},
+					FPort:       &fPort1,
+					FRMPayload:  HEXBytes("test"),
+					MACCommands: []MACCommand{{CID: "LinkADR"}, {CID: "DevStatus"}},
+				},
+				MIC: HEXBytes{0x2b, 0x11, 0xff, 0x0d},
+			},
+		},
+		{
+			name: "downlink encrypted fopts",
+			json: `{"mhdr":{"mType":"ConfirmedDataDown","major":"LoRaWANR1"},"macPayload":{"fhdr":{"devAddr":"01020304","fCtrl":{"adr":false,"adrAckReq":false,"ack":true,"fPending":true,"classB":false},"fCnt":9,"fOpts":[{"bytes":"rxAD"}]},"fPort":null,"frmPayload":null},"mic":"8374f812"}`,
+			expected: &PHYPayload{
+				MHDR: MHDR{MType: ConfirmedDataDown, Major: LoRaWANR1},
+				MACPayload: &MACPayload{
+					FHDR: FHDR{
+						DevAddr: lorawan.DevAddr{0x01, 0x02, 0x03, 0x04},
+						FCtrl:   FCtrl{ACK: true, FPending: true, FOptsLen: 3},
+						FCnt:    9,
+						FOpts:   HEXBytes{0xaf, 0x10, 0x03},
+					},
+				},
+				MIC: HEXBytes{0x83, 0x74, 0xf8, 0x12},
+			},
+		},
+		{
+			name: "downlink fport 0",
+			json: `{"mhdr":{"mType":"UnconfirmedDataDown","major":"LoRaWANR1"},"macPayload":{"fhdr":{"devAddr":"01020304","fCtrl":{"adr":false,"adrAckReq":false,"ack":false,"fPending":false,"classB":false},"fCnt":10,"fOpts":null},"fPort":0,"frmPayload":[{"cid":"LinkCheckReq","payload":{"margin":10,"gwCnt":3}}]},"mic":"1fc1b5c4"}`,
+			expected: &PHYPayload{
+				MHDR: MHDR{MType: UnconfirmedDataDown, Major: LoRaWANR1},
+				MACPayload: &MACPayload{
+					FHDR: FHDR{
+						DevAddr: lorawan.DevAddr{0x01, 0x02, 0x03, 0x04},
+						FCnt:    10,
+					},
+					FPort:       &fPort0,
+					Decrypted:   true,
+					MACCommands: []MACCommand{{CID: "LinkCheck"}},
+				},
+				MIC: HEXBytes{0x1f, 0xc1, 0xb5, 0xc4},
+			},
+		},
+		{
+			name: "join-request",
+			json: `{"mhdr":{"mType":"JoinRequest","major":"LoRaWANR1"},"macPayload":{"joinEUI":"0102030405060708","devEUI":"0807060504030201","devNonce":258},"mic":"1643dccf"}`,
+			expected: &PHYPayload{
+				MHDR: MHDR{MType: JoinRequest, Major: LoRaWANR1},
+				JoinRequestPayload: &JoinRequestPayload{
+					JoinEUI:  lorawan.EUI64{0x01, 0x02, 0x03, 0x04, 0x05, 0x06, 0x07, 0x08},
+					DevEUI:   lorawan.EUI64{0x08, 0x07, 0x06, 0x05, 0x04, 0x03, 0x02, 0x01},
+					DevNonce: 0x0102,
+				},
+				MIC: HEXBytes{0x16, 0x43, 0xdc, 0xcf},
+			},
+		},
+		{
+			// The encrypted MIC is part of the EncryptedPayload.
+			name: "encrypted join-accept",
+			json: `{"mhdr":{"mType":"JoinAccept","major":"LoRaWANR1"},"macPayload":{"bytes":"Q3OBg+V0mwdBjGmB"},"mic":"9441e483"}`,
+			expected: &PHYPayload{
+				MHDR:             MHDR{MType: JoinAccept, Major: LoRaWANR1},
+				EncryptedPayload: HEXBytes(mustHEX(t, "43738183e5749b07418c69819441e483")),
+			},
+		},
+		{
+			name: "join-accept",
+			json: `{"mhdr":{"mType":"JoinAccept","major":"LoRaWANR1"},"macPayload":{"joinNonce":258,"homeNetID":"000013","devAddr":"26011234","dlSettings":{"optNeg":true,"rx2DataRate":3,"rx1DROffset":1},"rxDelay":1,"cFlist":null},"mic":"01020304"}`,
+			expected: &PHYPayload{
+				MHDR: MHDR{MType: JoinAccept, Major: LoRaWANR1},
+				JoinAcceptPayload: &JoinAcceptPayload{
+					JoinNonce:  0x000102,
+					HomeNetID:  lorawan.NetID{0x00, 0x00, 0x13},
+					DevAddr:    lorawan.DevAddr{0x26, 0x01, 0x12, 0x34},
+					DLSettings: DLSettings{OptNeg: true, RX1DROffset: 1, RX2DataRate: 3},
+					RXDelay:    1,
+				},
+				MIC: HEXBytes{0x01, 0x02, 0x03, 0x04},
+			},
+		},
+		{
+			name: "rejoin-request type 0",
+			json: `{"mhdr":{"mType":"RejoinRequest","major":"LoRaWANR1"},"macPayload":{"rejoinType":0,"netID":"000013","devEUI":"0102030405060708","rjCount0":5},"mic":"4fdde013"}`,
+			expected: &PHYPayload{
+				MHDR: MHDR{MType: RejoinRequest, Major: LoRaWANR1},
+				RejoinRequestPayload: &RejoinRequestPayload{
+					RejoinType: 0,
+					NetID:      &lorawan.NetID{0x00, 0x00, 0x13},
+					DevEUI:     lorawan.EUI64{0x01, 0x02, 0x03, 0x04, 0x05, 0x06, 0x07, 0x08},
+					RJCount:    5,
+				},
+				MIC: HEXBytes{0x4f, 0xdd, 0xe0, 0x13},
+			},
+		},
+		{
+			name: "rejoin-request type 1",
+			json: `{"mhdr":{"mType":"RejoinRequest","major":"LoRaWANR1"},"macPayload":{"rejoinType":1,"joinEUI":"0102030405060708","devEUI":"0102030405060708","rjCount1":7},"mic":"51e09164"}`,
+			expected: &PHYPayload{
+				MHDR: MHDR{MType: RejoinRequest, Major: LoRaWANR1},
+				RejoinRequestPayload: &RejoinRequestPayload{
+					RejoinType: 1,
+					JoinEUI:    &lorawan.EUI64{0x01, 0x02, 0x03, 0x04, 0x05, 0x06, 0x07, 0x08},
+					DevEUI:     lorawan.EUI64{0x01, 0x02, 0x03, 0x04, 0x05, 0x06, 0x07, 0x08},
+					RJCount:    7,
+				},
+				MIC: HEXBytes{0x51, 0xe0, 0x91, 0x64},
+			},
+		},
+		{
+			name: "proprietary",
+			json: `{"mhdr":{"mType":"Proprietary","major":"LoRaWANR1"},"macPayload":{"bytes":"AQID"},"mic":""}`,
+			expected: &PHYPayload{
+				MHDR:               MHDR{MType: Proprietary, Major: LoRaWANR1},
+				ProprietaryPayload: HEXBytes{0x01, 0x02, 0x03},
+				MIC:                HEXBytes{},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			p, err := DecodeJSON(test.json)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(test.expected, p) {
+				t.Errorf("expected %+v, got %+v", test.expected, p)
+			}
+		})
+	}
+}
+
+func TestDecodeJSONErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		json string
+	}{
+		{"invalid json", `{"mhdr":`},
+		{"invalid mtype", `{"mhdr":{"mType":"Unknown","major":"LoRaWANR1"}}`},
+		{"invalid major", `{"mhdr":{"mType":"JoinRequest","major":"LoRaWANR2"}}`},
+		{"invalid mic", `{"mhdr":{"mType":"JoinRequest","major":"LoRaWANR1"},"mic":"xyz"}`},
+		{"invalid dev_eui", `{"mhdr":{"mType":"JoinRequest","major":"LoRaWANR1"},"macPayload":{"devEUI":"0102"}}`},
+		{"invalid rejoin type", `{"mhdr":{"mType":"RejoinRequest","major":"LoRaWANR1"},"macPayload":{"rejoinType":3}}`},
+		{"invalid frmpayload", `{"mhdr":{"mType":"UnconfirmedDataUp","major":"LoRaWANR1"},"macPayload":{"frmPayload":{"bytes":"AQID"}}}`},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if p, err := DecodeJSON(test.json); err == nil {
+				t.Errorf("expected error, got %+v", p)
+			}
+		})
+	}
+}
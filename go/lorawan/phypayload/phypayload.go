@@ -0,0 +1,436 @@
+// Package phypayload decodes LoRaWAN PHYPayloads (e.g. the phy_payload of
+// the gw.UplinkFrame and gw.DownlinkFrame messages) into typed structs with
+// a stable JSON representation:
+//
+//	p, err := phypayload.Decode(frame.PhyPayload, nil)
+//	...
+//	b, err := json.Marshal(p)
+//
+// When keys are given, the MIC is validated and the FRMPayload (and in case
+// of LoRaWAN 1.1 the FOpts) and the join-accept payload are decrypted.
+//
+// The JSON representation is not the phy_payload_json of the
+// application-server frame logs. That format holds every payload type in
+// macPayload, the FRMPayload as a list of {"bytes": <base64>} objects and
+// the FOpts as a list of mac-commands. Here, every payload type has its own
+// field, byte fields are HEX encoded and the decoded mac-commands are kept
+// in macCommands, next to the raw FOpts and FRMPayload. DecodeJSON decodes
+// the phy_payload_json of the frame logs into a PHYPayload.
+package phypayload
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+
+	"github.com/brocaar/chirpstack-api/go/lorawan"
+	"github.com/brocaar/chirpstack-api/go/lorawan/maccommand"
+)
+
+// MType defines the message type.
+type MType byte
+
+// Message types.
+const (
+	JoinRequest MType = iota
+	JoinAccept
+	UnconfirmedDataUp
+	UnconfirmedDataDown
+	ConfirmedDataUp
+	ConfirmedDataDown
+	RejoinRequest
+	Proprietary
+)
+
+var mTypeNames = [...]string{
+	"JoinRequest",
+	"JoinAccept",
+	"UnconfirmedDataUp",
+	"UnconfirmedDataDown",
+	"ConfirmedDataUp",
+	"ConfirmedDataDown",
+	"RejoinRequest",
+	"Proprietary",
+}
+
+// String implements fmt.Stringer.
+func (m MType) String() string {
+	if int(m) < len(mTypeNames) {
+		return mTypeNames[m]
+	}
+	return fmt.Sprintf("MType(%d)", byte(m))
+}
+
+// MarshalText implements encoding.TextMarshaler.
+func (m MType) MarshalText() ([]byte, error) {
+	return []byte(m.String()), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler.
+func (m *MType) UnmarshalText(text []byte) error {
+	for i, name := range mTypeNames {
+		if name == string(text) {
+			*m = MType(i)
+			return nil
+		}
+	}
+	return fmt.Errorf("phypayload: invalid mtype: %s", text)
+}
+
+// IsUplink returns true for the uplink message types.
+func (m MType) IsUplink() bool {
+	switch m {
+	case JoinRequest, UnconfirmedDataUp, ConfirmedDataUp, RejoinRequest:
+		return true
+	}
+	return false
+}
+
+// Major defines the major version of the data message.
+type Major byte
+
+// LoRaWANR1 defines the LoRaWAN R1 major version.
+const LoRaWANR1 Major = 0
+
+// MarshalText implements encoding.TextMarshaler.
+func (m Major) MarshalText() ([]byte, error) {
+	if m == LoRaWANR1 {
+		return []byte("LoRaWANR1"), nil
+	}
+	return []byte(fmt.Sprintf("Major(%d)", byte(m))), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler.
+func (m *Major) UnmarshalText(text []byte) error {
+	if string(text) != "LoRaWANR1" {
+		return fmt.Errorf("phypayload: invalid major: %s", text)
+	}
+	*m = LoRaWANR1
+	return nil
+}
+
+// HEXBytes implements a HEX encoded byte slice.
+type HEXBytes []byte
+
+// MarshalText implements encoding.TextMarshaler.
+func (h HEXBytes) MarshalText() ([]byte, error) {
+	return []byte(hex.EncodeToString(h)), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler.
+func (h *HEXBytes) UnmarshalText(text []byte) error {
+	b, err := hex.DecodeString(string(text))
+	if err != nil {
+		return fmt.Errorf("phypayload: decode hex error: %w", err)
+	}
+	*h = b
+	return nil
+}
+
+// MHDR contains the MAC header.
+type MHDR struct {
+	MType MType `json:"mType"`
+	Major Major `json:"major"`
+}
+
+// PHYPayload contains the decoded PHYPayload. Depending on the message type,
+// one of the payload fields is set. A join-accept which could not be
+// decrypted is kept in EncryptedPayload (including the encrypted MIC).
+type PHYPayload struct {
+	MHDR                 MHDR                  `json:"mhdr"`
+	MACPayload           *MACPayload           `json:"macPayload,omitempty"`
+	JoinRequestPayload   *JoinRequestPayload   `json:"joinRequestPayload,omitempty"`
+	JoinAcceptPayload    *JoinAcceptPayload    `json:"joinAcceptPayload,omitempty"`
+	RejoinRequestPayload *RejoinRequestPayload `json:"rejoinRequestPayload,omitempty"`
+	ProprietaryPayload   HEXBytes              `json:"proprietaryPayload,omitempty"`
+	EncryptedPayload     HEXBytes              `json:"encryptedPayload,omitempty"`
+	MIC                  HEXBytes              `json:"mic,omitempty"`
+
+	// MICValid is set when the MIC has been validated.
+	MICValid *bool `json:"micValid,omitempty"`
+
+	// raw holds the raw PHYPayload.
+	raw []byte
+}
+
+// FCtrl contains the frame control field. FPending is only used by downlink,
+// ClassB only by uplink frames.
+type FCtrl struct {
+	ADR       bool  `json:"adr"`
+	ADRACKReq bool  `json:"adrAckReq"`
+	ACK       bool  `json:"ack"`
+	FPending  bool  `json:"fPending"`
+	ClassB    bool  `json:"classB"`
+	FOptsLen  uint8 `json:"fOptsLen"`
+}
+
+// FHDR contains the frame header.
+type FHDR struct {
+	DevAddr lorawan.DevAddr `json:"devAddr"`
+	FCtrl   FCtrl           `json:"fCtrl"`
+
+	// FCnt holds the frame-counter. The frame only contains the 16 LSB, the
+	// MSB are set from the Keys when decoding with keys.
+	FCnt  uint32   `json:"fCnt"`
+	FOpts HEXBytes `json:"fOpts"`
+}
+
+// MACCommand contains a decoded mac-command.
+type MACCommand struct {
+	CID     string             `json:"cid"`
+	Payload maccommand.Payload `json:"payload,omitempty"`
+}
+
+// MACPayload contains the payload of a data message.
+type MACPayload struct {
+	FHDR       FHDR     `json:"fhdr"`
+	FPort      *uint8   `json:"fPort"`
+	FRMPayload HEXBytes `json:"frmPayload"`
+
+	// Decrypted is set when the FRMPayload (and FOpts) have been decrypted.
+	Decrypted bool `json:"decrypted,omitempty"`
+
+	// MACCommands holds the mac-commands of the FOpts or of the FRMPayload
+	// (FPort 0), when these could be decoded.
+	MACCommands []MACCommand `json:"macCommands,omitempty"`
+
+	// foptsEncrypted is set for encrypted (LoRaWAN 1.1) FOpts.
+	foptsEncrypted bool
+
+	// frmDecrypted is set when the FRMPayload has been decrypted.
+	frmDecrypted bool
+}
+
+// JoinRequestPayload contains the join-request payload.
+type JoinRequestPayload struct {
+	JoinEUI  lorawan.EUI64 `json:"joinEUI"`
+	DevEUI   lorawan.EUI64 `json:"devEUI"`
+	DevNonce uint16        `json:"devNonce"`
+}
+
+// DLSettings contains the downlink settings of the join-accept.
+type DLSettings struct {
+	OptNeg      bool  `json:"optNeg"`
+	RX1DROffset uint8 `json:"rx1DROffset"`
+	RX2DataRate uint8 `json:"rx2DataRate"`
+}
+
+// JoinAcceptPayload contains the (decrypted) join-accept payload.
+type JoinAcceptPayload struct {
+	JoinNonce  uint32          `json:"joinNonce"`
+	HomeNetID  lorawan.NetID   `json:"homeNetID"`
+	DevAddr    lorawan.DevAddr `json:"devAddr"`
+	DLSettings DLSettings      `json:"dlSettings"`
+	RXDelay    uint8           `json:"rxDelay"`
+	CFList     HEXBytes        `json:"cfList,omitempty"`
+}
+
+// RejoinRequestPayload contains the rejoin-request payload. For type 0 and 2
+// NetID is set and RJCount holds RJcount0, for type 1 JoinEUI is set and
+// RJCount holds RJcount1.
+type RejoinRequestPayload struct {
+	RejoinType uint8          `json:"rejoinType"`
+	NetID      *lorawan.NetID `json:"netID,omitempty"`
+	JoinEUI    *lorawan.EUI64 `json:"joinEUI,omitempty"`
+	DevEUI     lorawan.EUI64  `json:"devEUI"`
+	RJCount    uint16         `json:"rjCount"`
+}
+
+// Decode decodes the PHYPayload. When keys is not nil, the MIC is validated
+// (for the keys which are set) and the payload is decrypted.
+func Decode(b []byte, keys *Keys) (*PHYPayload, error) {
+	if len(b) < 1 {
+		return nil, errors.New("phypayload: at least 1 byte expected")
+	}
+
+	p := PHYPayload{
+		MHDR: MHDR{
+			MType: MType(b[0] >> 5),
+			Major: Major(b[0] & 0x03),
+		},
+		raw: append([]byte(nil), b...),
+	}
+
+	var err error
+	switch p.MHDR.MType {
+	case JoinRequest:
+		err = p.decodeJoinRequest()
+	case JoinAccept:
+		if len(b) != 17 && len(b) != 33 {
+			return nil, fmt.Errorf("phypayload: join-accept must be 17 or 33 bytes, got %d", len(b))
+		}
+		p.EncryptedPayload = HEXBytes(p.raw[1:])
+	case UnconfirmedDataUp, UnconfirmedDataDown, ConfirmedDataUp, ConfirmedDataDown:
+		err = p.decodeMACPayload()
+	case RejoinRequest:
+		err = p.decodeRejoinRequest()
+	case Proprietary:
+		p.ProprietaryPayload = HEXBytes(p.raw[1:])
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if keys != nil {
+		if err := p.applyKeys(keys); err != nil {
+			return nil, err
+		}
+	}
+
+	if p.MACPayload != nil {
+		p.MACPayload.decodeMACCommands(p.MHDR.MType.IsUplink())
+	}
+
+	return &p, nil
+}
+
+// decodeMACCommands decodes the mac-commands of the FRMPayload (FPort 0)
+// when decrypted, else of the FOpts when not encrypted. Without keys, the
+// FOpts are assumed to be plain (as is the case for LoRaWAN 1.0.x). Invalid
+// mac-commands are ignored, the raw bytes are always available.
+func (mp *MACPayload) decodeMACCommands(uplink bool) {
+	var b []byte
+	switch {
+	case mp.FPort != nil && *mp.FPort == 0:
+		if !mp.frmDecrypted {
+			return
+		}
+		b = mp.FRMPayload
+	case !mp.foptsEncrypted:
+		b = mp.FHDR.FOpts
+	}
+
+	cmds, err := maccommand.Decode(uplink, b)
+	if err != nil {
+		return
+	}
+	for _, cmd := range cmds {
+		mp.MACCommands = append(mp.MACCommands, MACCommand{
+			CID:     cmd.CID.String(),
+			Payload: cmd.Payload,
+		})
+	}
+}
+
+func (p *PHYPayload) decodeJoinRequest() error {
+	b := p.raw
+	if len(b) != 23 {
+		return fmt.Errorf("phypayload: join-request must be 23 bytes, got %d", len(b))
+	}
+	p.JoinRequestPayload = &JoinRequestPayload{
+		JoinEUI:  eui64(b[1:9]),
+		DevEUI:   eui64(b[9:17]),
+		DevNonce: binary.LittleEndian.Uint16(b[17:19]),
+	}
+	p.MIC = HEXBytes(b[19:])
+	return nil
+}
+
+func (p *PHYPayload) decodeRejoinRequest() error {
+	b := p.raw
+	if len(b) < 2 {
+		return errors.New("phypayload: rejoin-request must be at least 2 bytes")
+	}
+
+	rj := RejoinRequestPayload{RejoinType: b[1]}
+	switch rj.RejoinType {
+	case 0, 2:
+		if len(b) != 19 {
+			return fmt.Errorf("phypayload: rejoin-request type %d must be 19 bytes, got %d", rj.RejoinType, len(b))
+		}
+		netID := lorawan.NetID{b[4], b[3], b[2]}
+		rj.NetID = &netID
+		rj.DevEUI = eui64(b[5:13])
+		rj.RJCount = binary.LittleEndian.Uint16(b[13:15])
+	case 1:
+		if len(b) != 24 {
+			return fmt.Errorf("phypayload: rejoin-request type 1 must be 24 bytes, got %d", len(b))
+		}
+		joinEUI := eui64(b[2:10])
+		rj.JoinEUI = &joinEUI
+		rj.DevEUI = eui64(b[10:18])
+		rj.RJCount = binary.LittleEndian.Uint16(b[18:20])
+	default:
+		return fmt.Errorf("phypayload: invalid rejoin type: %d", rj.RejoinType)
+	}
+
+	p.RejoinRequestPayload = &rj
+	p.MIC = HEXBytes(b[len(b)-4:])
+	return nil
+}
+
+func (p *PHYPayload) decodeMACPayload() error {
+	b := p.raw
+	if len(b) < 12 {
+		return fmt.Errorf("phypayload: data message must be at least 12 bytes, got %d", len(b))
+	}
+	uplink := p.MHDR.MType.IsUplink()
+
+	var mp MACPayload
+	mp.FHDR.DevAddr = lorawan.DevAddr{b[4], b[3], b[2], b[1]}
+
+	fCtrl := b[5]
+	mp.FHDR.FCtrl = FCtrl{
+		ADR:      fCtrl&0x80 != 0,
+		ACK:      fCtrl&0x20 != 0,
+		FOptsLen: fCtrl & 0x0f,
+	}
+	if uplink {
+		mp.FHDR.FCtrl.ADRACKReq = fCtrl&0x40 != 0
+		mp.FHDR.FCtrl.ClassB = fCtrl&0x10 != 0
+	} else {
+		mp.FHDR.FCtrl.FPending = fCtrl&0x10 != 0
+	}
+	mp.FHDR.FCnt = uint32(binary.LittleEndian.Uint16(b[6:8]))
+
+	payload := b[8 : len(b)-4]
+	n := int(mp.FHDR.FCtrl.FOptsLen)
+	if len(payload) < n {
+		return fmt.Errorf("phypayload: fopts_len %d exceeds payload", n)
+	}
+	mp.FHDR.FOpts = HEXBytes(payload[:n])
+	payload = payload[n:]
+
+	if len(payload) != 0 {
+		fPort := payload[0]
+		if fPort == 0 && n != 0 {
+			return errors.New("phypayload: fport 0 must not be combined with fopts")
+		}
+		mp.FPort = &fPort
+		mp.FRMPayload = HEXBytes(payload[1:])
+	}
+
+	p.MACPayload = &mp
+	p.MIC = HEXBytes(b[len(b)-4:])
+	return nil
+}
+
+func (p *PHYPayload) decodeJoinAccept(b []byte) {
+	ja := JoinAcceptPayload{
+		JoinNonce: uint32(b[0]) | uint32(b[1])<<8 | uint32(b[2])<<16,
+		HomeNetID: lorawan.NetID{b[5], b[4], b[3]},
+		DevAddr:   lorawan.DevAddr{b[9], b[8], b[7], b[6]},
+		DLSettings: DLSettings{
+			OptNeg:      b[10]&0x80 != 0,
+			RX1DROffset: (b[10] >> 4) & 0x07,
+			RX2DataRate: b[10] & 0x0f,
+		},
+		RXDelay: b[11],
+	}
+	if len(b) == 32 {
+		ja.CFList = HEXBytes(b[12:28])
+	}
+	p.JoinAcceptPayload = &ja
+	p.EncryptedPayload = nil
+	p.MIC = HEXBytes(b[len(b)-4:])
+}
+
+// eui64 returns the EUI64 of the given (little-endian) bytes.
+func eui64(b []byte) lorawan.EUI64 {
+	var out lorawan.EUI64
+	for i := range out {
+		out[i] = b[len(out)-1-i]
+	}
+	return out
+}
@@ -0,0 +1,31 @@
+package phypayload
+
+import (
+	"testing"
+)
+
+func TestDecodeErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		phy  string
+	}{
+		{"empty", ""},
+		{"join-request length", "0008070605040302010102030405060708020116"},
+		{"join-accept length", "2043738183e5749b07418c69819441e4"},
+		{"data length", "40f17dbe490002000195"},
+		{"fopts length", "40f17dbe4905020001954378"},
+		{"rejoin-request length", "c0"},
+		{"rejoin-request type 0 length", "c0001300000807060504030201054fdde013"},
+		{"rejoin-request type 1 length", "c001080706050403020108070605040302010751e09164"},
+		{"rejoin-request type 2 length", "c00213000008070605040302010500a8c29c5400"},
+		{"rejoin-request invalid type", "c003130000080706050403020105004fdde013"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if p, err := Decode(mustHEX(t, test.phy), nil); err == nil {
+				t.Errorf("expected error, got %+v", p)
+			}
+		})
+	}
+}
@@ -0,0 +1,182 @@
+// Package sessionkeys derives the LoRaWAN OTAA session keys from the root
+// keys (api.DeviceKeys) and the join parameters, e.g. to reproduce an OTAA
+// session offline:
+//
+//	da, err := sessionkeys.DeviceActivation(deviceKeys, sessionkeys.Join{
+//		DevAddr:   devAddr,
+//		NetID:     netID,
+//		JoinNonce: joinNonce,
+//		DevNonce:  devNonce,
+//	})
+//
+// For LoRaWAN 1.0.x, the NwkSKey is returned as the SNwkSIntKey, FNwkSIntKey
+// and NwkSEncKey, as is done by the network-server.
+package sessionkeys
+
+import (
+	"crypto/aes"
+	"fmt"
+
+	"github.com/brocaar/chirpstack-api/go/as/external/api"
+	"github.com/brocaar/chirpstack-api/go/lorawan"
+)
+
+// Key derivation prefixes.
+const (
+	prefixNwkSKey     = 0x01
+	prefixFNwkSIntKey = 0x01
+	prefixAppSKey     = 0x02
+	prefixSNwkSIntKey = 0x03
+	prefixNwkSEncKey  = 0x04
+	prefixJSEncKey    = 0x05
+	prefixJSIntKey    = 0x06
+)
+
+// maxJoinNonce defines the max. value of the 24 bit JoinNonce (AppNonce).
+const maxJoinNonce = 1<<24 - 1
+
+// Keys contains the session keys.
+type Keys struct {
+	AppSKey     lorawan.AES128Key
+	FNwkSIntKey lorawan.AES128Key
+	SNwkSIntKey lorawan.AES128Key
+	NwkSEncKey  lorawan.AES128Key
+}
+
+// DeriveLoRaWAN10 derives the LoRaWAN 1.0.x session keys from the AppKey,
+// the NetID, the JoinNonce (AppNonce) and the DevNonce.
+func DeriveLoRaWAN10(appKey lorawan.AES128Key, netID lorawan.NetID, joinNonce uint32, devNonce uint16) (Keys, error) {
+	if joinNonce > maxJoinNonce {
+		return Keys{}, fmt.Errorf("sessionkeys: JoinNonce %d exceeds 24 bits", joinNonce)
+	}
+
+	b := joinBlock(joinNonce, reverse(netID[:]), devNonce)
+	nwkSKey := derive(appKey, prefixNwkSKey, b)
+	return Keys{
+		AppSKey:     derive(appKey, prefixAppSKey, b),
+		FNwkSIntKey: nwkSKey,
+		SNwkSIntKey: nwkSKey,
+		NwkSEncKey:  nwkSKey,
+	}, nil
+}
+
+// DeriveLoRaWAN11 derives the LoRaWAN 1.1 session keys from the NwkKey and
+// AppKey, the JoinEUI, the JoinNonce and the DevNonce.
+func DeriveLoRaWAN11(nwkKey, appKey lorawan.AES128Key, joinEUI lorawan.EUI64, joinNonce uint32, devNonce uint16) (Keys, error) {
+	if joinNonce > maxJoinNonce {
+		return Keys{}, fmt.Errorf("sessionkeys: JoinNonce %d exceeds 24 bits", joinNonce)
+	}
+
+	b := joinBlock(joinNonce, reverse(joinEUI[:]), devNonce)
+	return Keys{
+		AppSKey:     derive(appKey, prefixAppSKey, b),
+		FNwkSIntKey: derive(nwkKey, prefixFNwkSIntKey, b),
+		SNwkSIntKey: derive(nwkKey, prefixSNwkSIntKey, b),
+		NwkSEncKey:  derive(nwkKey, prefixNwkSEncKey, b),
+	}, nil
+}
+
+// JSEncKey derives the LoRaWAN 1.1 join-server encryption key, used for
+// encrypting the join-accept in response to a rejoin-request.
+func JSEncKey(nwkKey lorawan.AES128Key, devEUI lorawan.EUI64) lorawan.AES128Key {
+	return derive(nwkKey, prefixJSEncKey, reverse(devEUI[:]))
+}
+
+// JSIntKey derives the LoRaWAN 1.1 join-server integrity key, used for the
+// join-accept MIC (when OptNeg is set) and the rejoin-request type 1 MIC.
+func JSIntKey(nwkKey lorawan.AES128Key, devEUI lorawan.EUI64) lorawan.AES128Key {
+	return derive(nwkKey, prefixJSIntKey, reverse(devEUI[:]))
+}
+
+// Join contains the join parameters.
+type Join struct {
+	// LoRaWAN11 must be set for LoRaWAN 1.1 devices.
+	LoRaWAN11 bool
+
+	// DevAddr holds the assigned device address.
+	DevAddr lorawan.DevAddr
+
+	// NetID holds the NetID of the join-accept (LoRaWAN 1.0.x).
+	NetID lorawan.NetID
+
+	// JoinEUI holds the JoinEUI of the join-request (LoRaWAN 1.1).
+	JoinEUI lorawan.EUI64
+
+	// JoinNonce holds the 24 bit JoinNonce (AppNonce) of the join-accept.
+	JoinNonce uint32
+
+	// DevNonce holds the DevNonce of the join-request.
+	DevNonce uint16
+}
+
+// DeviceActivation derives the session keys from the device keys and
+// returns the resulting device-activation. For LoRaWAN 1.0.x devices, the
+// nwk_key field holds the AppKey. The frame-counters are left zero.
+func DeviceActivation(dk *api.DeviceKeys, j Join) (*api.DeviceActivation, error) {
+	devEUI, err := lorawan.ParseEUI64(dk.GetDevEui())
+	if err != nil {
+		return nil, fmt.Errorf("sessionkeys: dev_eui: %w", err)
+	}
+	nwkKey, err := lorawan.ParseAES128Key(dk.GetNwkKey())
+	if err != nil {
+		return nil, fmt.Errorf("sessionkeys: nwk_key: %w", err)
+	}
+
+	var keys Keys
+	if j.LoRaWAN11 {
+		appKey, err := lorawan.ParseAES128Key(dk.GetAppKey())
+		if err != nil {
+			return nil, fmt.Errorf("sessionkeys: app_key: %w", err)
+		}
+		keys, err = DeriveLoRaWAN11(nwkKey, appKey, j.JoinEUI, j.JoinNonce, j.DevNonce)
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		keys, err = DeriveLoRaWAN10(nwkKey, j.NetID, j.JoinNonce, j.DevNonce)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return &api.DeviceActivation{
+		DevEui:      devEUI.String(),
+		DevAddr:     j.DevAddr.String(),
+		AppSKey:     keys.AppSKey.String(),
+		NwkSEncKey:  keys.NwkSEncKey.String(),
+		SNwkSIntKey: keys.SNwkSIntKey.String(),
+		FNwkSIntKey: keys.FNwkSIntKey.String(),
+	}, nil
+}
+
+// joinBlock returns the JoinNonce | NetID or JoinEUI | DevNonce part of the
+// key derivation block. The id must be in LoRaWAN (little endian) byte order.
+func joinBlock(joinNonce uint32, id []byte, devNonce uint16) []byte {
+	b := []byte{byte(joinNonce), byte(joinNonce >> 8), byte(joinNonce >> 16)}
+	b = append(b, id...)
+	return append(b, byte(devNonce), byte(devNonce>>8))
+}
+
+// derive returns aes128_encrypt(key, prefix | b | pad16).
+func derive(key lorawan.AES128Key, prefix byte, b []byte) lorawan.AES128Key {
+	block, err := aes.NewCipher(key[:])
+	if err != nil {
+		// aes.NewCipher only fails on invalid key sizes.
+		panic(err)
+	}
+
+	var in, out lorawan.AES128Key
+	in[0] = prefix
+	copy(in[1:], b)
+	block.Encrypt(out[:], in[:])
+	return out
+}
+
+// reverse returns b in reversed byte order.
+func reverse(b []byte) []byte {
+	out := make([]byte, len(b))
+	for i := range b {
+		out[len(b)-1-i] = b[i]
+	}
+	return out
+}
@@ -0,0 +1,192 @@
+package sessionkeys
+
+import (
+	"testing"
+
+	"github.com/golang/protobuf/proto"
+
+	"github.com/brocaar/chirpstack-api/go/as/external/api"
+	"github.com/brocaar/chirpstack-api/go/lorawan"
+)
+
+// The expected keys have been computed using an independent AES
+// implementation (OpenSSL), from the key derivation blocks as defined by the
+// LoRaWAN 1.0.x and 1.1 specifications. All multi-byte inputs are
+// asymmetric, so that a wrong byte order results in different keys.
+var (
+	testAppKey10  = mustKey("2b7e151628aed2a6abf7158809cf4f3c")
+	testNwkKey11  = mustKey("000102030405060708090a0b0c0d0e0f")
+	testAppKey11  = mustKey("f0e0d0c0b0a090807060504030201000")
+	testNetID     = lorawan.NetID{0x01, 0x02, 0x03}
+	testJoinEUI   = lorawan.EUI64{0x01, 0x02, 0x03, 0x04, 0x05, 0x06, 0x07, 0x08}
+	testDevEUI    = lorawan.EUI64{0x11, 0x12, 0x13, 0x14, 0x15, 0x16, 0x17, 0x18}
+	testDevAddr   = lorawan.DevAddr{0x26, 0x01, 0x12, 0x34}
+	testJoinNonce = uint32(0x040506)
+	testDevNonce  = uint16(0x0708)
+)
+
+func mustKey(s string) lorawan.AES128Key {
+	k, err := lorawan.ParseAES128Key(s)
+	if err != nil {
+		panic(err)
+	}
+	return k
+}
+
+func TestDeriveLoRaWAN10(t *testing.T) {
+	keys, err := DeriveLoRaWAN10(testAppKey10, testNetID, testJoinNonce, testDevNonce)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	nwkSKey := mustKey("f94f5030bef7e7e81c77f83dd5c055bd")
+	expected := Keys{
+		AppSKey:     mustKey("ee373f9c549a59f111d6c67edfcc6d96"),
+		FNwkSIntKey: nwkSKey,
+		SNwkSIntKey: nwkSKey,
+		NwkSEncKey:  nwkSKey,
+	}
+	if keys != expected {
+		t.Errorf("expected %+v, got %+v", expected, keys)
+	}
+}
+
+func TestDeriveLoRaWAN11(t *testing.T) {
+	keys, err := DeriveLoRaWAN11(testNwkKey11, testAppKey11, testJoinEUI, testJoinNonce, testDevNonce)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := Keys{
+		AppSKey:     mustKey("0d966a58ddf8c591869f922b87ff3b64"),
+		FNwkSIntKey: mustKey("2b0fa2e5d921f208bdfed1edf6102b8b"),
+		SNwkSIntKey: mustKey("ac9db671c87dc91b246426cdef77f0c8"),
+		NwkSEncKey:  mustKey("c4ace70633ffbaf62cefced8518d8d30"),
+	}
+	if keys != expected {
+		t.Errorf("expected %+v, got %+v", expected, keys)
+	}
+}
+
+func TestJSKeys(t *testing.T) {
+	if k, exp := JSEncKey(testNwkKey11, testDevEUI), mustKey("a707769478ca7ed2252fba09787a9184"); k != exp {
+		t.Errorf("JSEncKey: expected %s, got %s", exp, k)
+	}
+	if k, exp := JSIntKey(testNwkKey11, testDevEUI), mustKey("af078f296000f5abf50fce6ae67693c0"); k != exp {
+		t.Errorf("JSIntKey: expected %s, got %s", exp, k)
+	}
+}
+
+func TestJoinNonce(t *testing.T) {
+	for _, joinNonce := range []uint32{maxJoinNonce, maxJoinNonce + 1} {
+		expectErr := joinNonce > maxJoinNonce
+
+		_, err := DeriveLoRaWAN10(testAppKey10, testNetID, joinNonce, testDevNonce)
+		if (err != nil) != expectErr {
+			t.Errorf("LoRaWAN 1.0, JoinNonce %d: unexpected error: %v", joinNonce, err)
+		}
+		_, err = DeriveLoRaWAN11(testNwkKey11, testAppKey11, testJoinEUI, joinNonce, testDevNonce)
+		if (err != nil) != expectErr {
+			t.Errorf("LoRaWAN 1.1, JoinNonce %d: unexpected error: %v", joinNonce, err)
+		}
+	}
+}
+
+func TestDeviceActivation(t *testing.T) {
+	tests := []struct {
+		name     string
+		keys     *api.DeviceKeys
+		join     Join
+		expected *api.DeviceActivation
+		err      bool
+	}{
+		{
+			name: "LoRaWAN 1.0",
+			keys: &api.DeviceKeys{
+				DevEui: testDevEUI.String(),
+				NwkKey: testAppKey10.String(),
+			},
+			join: Join{
+				DevAddr:   testDevAddr,
+				NetID:     testNetID,
+				JoinNonce: testJoinNonce,
+				DevNonce:  testDevNonce,
+			},
+			expected: &api.DeviceActivation{
+				DevEui:      "1112131415161718",
+				DevAddr:     "26011234",
+				AppSKey:     "ee373f9c549a59f111d6c67edfcc6d96",
+				NwkSEncKey:  "f94f5030bef7e7e81c77f83dd5c055bd",
+				SNwkSIntKey: "f94f5030bef7e7e81c77f83dd5c055bd",
+				FNwkSIntKey: "f94f5030bef7e7e81c77f83dd5c055bd",
+			},
+		},
+		{
+			name: "LoRaWAN 1.1",
+			keys: &api.DeviceKeys{
+				DevEui: testDevEUI.String(),
+				NwkKey: testNwkKey11.String(),
+				AppKey: testAppKey11.String(),
+			},
+			join: Join{
+				LoRaWAN11: true,
+				DevAddr:   testDevAddr,
+				JoinEUI:   testJoinEUI,
+				JoinNonce: testJoinNonce,
+				DevNonce:  testDevNonce,
+			},
+			expected: &api.DeviceActivation{
+				DevEui:      "1112131415161718",
+				DevAddr:     "26011234",
+				AppSKey:     "0d966a58ddf8c591869f922b87ff3b64",
+				NwkSEncKey:  "c4ace70633ffbaf62cefced8518d8d30",
+				SNwkSIntKey: "ac9db671c87dc91b246426cdef77f0c8",
+				FNwkSIntKey: "2b0fa2e5d921f208bdfed1edf6102b8b",
+			},
+		},
+		{
+			name: "LoRaWAN 1.1 without app_key",
+			keys: &api.DeviceKeys{
+				DevEui: testDevEUI.String(),
+				NwkKey: testNwkKey11.String(),
+			},
+			join: Join{LoRaWAN11: true},
+			err:  true,
+		},
+		{
+			name: "invalid dev_eui",
+			keys: &api.DeviceKeys{
+				DevEui: "0102",
+				NwkKey: testAppKey10.String(),
+			},
+			err: true,
+		},
+		{
+			name: "JoinNonce exceeds 24 bits",
+			keys: &api.DeviceKeys{
+				DevEui: testDevEUI.String(),
+				NwkKey: testAppKey10.String(),
+			},
+			join: Join{JoinNonce: maxJoinNonce + 1},
+			err:  true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			da, err := DeviceActivation(test.keys, test.join)
+			if test.err {
+				if err == nil {
+					t.Fatal("expected error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !proto.Equal(test.expected, da) {
+				t.Errorf("expected %s, got %s", test.expected, da)
+			}
+		})
+	}
+}
@@ -0,0 +1,128 @@
+package usage
+
+import (
+	"bufio"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/brocaar/chirpstack-api/go/lorawan"
+	"github.com/brocaar/chirpstack-api/go/nc"
+)
+
+// metric defines a counter metric, derived from Counters.
+type metric struct {
+	name  string
+	help  string
+	value func(Counters) float64
+}
+
+var metrics = []metric{
+	{"frames_total", "Number of frames.", func(c Counters) float64 { return float64(c.Frames) }},
+	{"phy_payload_bytes_total", "Number of PHYPayload bytes.", func(c Counters) float64 { return float64(c.PHYPayloadBytes) }},
+	{"mac_command_bytes_total", "Number of mac-command bytes.", func(c Counters) float64 { return float64(c.MACCommandBytes) }},
+	{"application_payload_bytes_total", "Number of application payload bytes.", func(c Counters) float64 { return float64(c.ApplicationPayloadBytes) }},
+	{"airtime_seconds_total", "Time-on-air in seconds.", func(c Counters) float64 { return c.Airtime.Seconds() }},
+}
+
+// series holds the usage of a single object.
+type series struct {
+	id    string
+	usage Usage
+}
+
+// ServeHTTP writes the counters in the Prometheus text exposition format.
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	devices := eui64Series(s.devices)
+	gateways := eui64Series(s.gateways)
+	var multicastGroups []series
+	for id, u := range s.multicastGroups {
+		multicastGroups = append(multicastGroups, series{id: hex.EncodeToString([]byte(id)), usage: u.copy()})
+	}
+	sortSeries(multicastGroups)
+	macCommands := make(map[string]map[uint32]uint64, len(s.macCommands))
+	for id, m := range s.macCommands {
+		cp := make(map[uint32]uint64, len(m))
+		for k, v := range m {
+			cp[k] = v
+		}
+		macCommands[id.String()] = cp
+	}
+	s.mu.Unlock()
+
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	bw := bufio.NewWriter(w)
+
+	s.writeUsage(bw, "device", "dev_eui", devices)
+	s.writeUsage(bw, "gateway", "gateway_id", gateways)
+	s.writeUsage(bw, "multicast_group", "multicast_group_id", multicastGroups)
+
+	name := s.metricName("device_mac_commands_total")
+	fmt.Fprintf(bw, "# HELP %s Number of received uplink mac-commands.\n", name)
+	fmt.Fprintf(bw, "# TYPE %s counter\n", name)
+	var ids []string
+	for id := range macCommands {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	for _, id := range ids {
+		var cids []uint32
+		for cid := range macCommands[id] {
+			cids = append(cids, cid)
+		}
+		sort.Slice(cids, func(i, j int) bool { return cids[i] < cids[j] })
+		for _, cid := range cids {
+			fmt.Fprintf(bw, "%s{dev_eui=%q,cid=\"%d\"} %d\n", name, id, cid, macCommands[id][cid])
+		}
+	}
+
+	bw.Flush()
+}
+
+// writeUsage writes the usage metrics of the given series.
+func (s *Server) writeUsage(w *bufio.Writer, subsystem, label string, ss []series) {
+	for _, m := range metrics {
+		name := s.metricName(subsystem + "_" + m.name)
+		fmt.Fprintf(w, "# HELP %s %s\n", name, m.help)
+		fmt.Fprintf(w, "# TYPE %s counter\n", name)
+
+		for _, se := range ss {
+			var mTypes []nc.MType
+			for mType := range se.usage {
+				mTypes = append(mTypes, mType)
+			}
+			sort.Slice(mTypes, func(i, j int) bool { return mTypes[i] < mTypes[j] })
+
+			for _, mType := range mTypes {
+				fmt.Fprintf(w, "%s{%s=%q,mtype=%q} %s\n", name, label, se.id, mType, strconv.FormatFloat(m.value(se.usage[mType]), 'f', -1, 64))
+			}
+		}
+	}
+}
+
+// metricName returns the metric name prefixed by the namespace.
+func (s *Server) metricName(name string) string {
+	if s.namespace == "" {
+		return name
+	}
+	return strings.TrimSuffix(s.namespace, "_") + "_" + name
+}
+
+// eui64Series returns the sorted series of the given usage. The caller must
+// hold the lock.
+func eui64Series(m map[lorawan.EUI64]Usage) []series {
+	out := make([]series, 0, len(m))
+	for id, u := range m {
+		out = append(out, series{id: id.String(), usage: u.copy()})
+	}
+	sortSeries(out)
+	return out
+}
+
+func sortSeries(ss []series) {
+	sort.Slice(ss, func(i, j int) bool { return ss[i].id < ss[j].id })
+}
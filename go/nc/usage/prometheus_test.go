@@ -0,0 +1,129 @@
+package usage
+
+import (
+	"io/ioutil"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestServeHTTP(t *testing.T) {
+	s := newTestServer(t, WithNamespace("lora_"))
+
+	rec := httptest.NewRecorder()
+	s.ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+
+	if ct := rec.Header().Get("Content-Type"); ct != "text/plain; version=0.0.4; charset=utf-8" {
+		t.Errorf("unexpected content-type: %s", ct)
+	}
+	body, err := ioutil.ReadAll(rec.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(body) != expectedMetrics {
+		t.Errorf("expected:\n%s\ngot:\n%s", expectedMetrics, body)
+	}
+}
+
+const expectedMetrics = `# HELP lora_device_frames_total Number of frames.
+# TYPE lora_device_frames_total counter
+lora_device_frames_total{dev_eui="0102030405060708",mtype="UNCONFIRMED_DATA_UP"} 1
+lora_device_frames_total{dev_eui="0102030405060708",mtype="UNCONFIRMED_DATA_DOWN"} 1
+lora_device_frames_total{dev_eui="0102030405060708",mtype="CONFIRMED_DATA_UP"} 1
+# HELP lora_device_phy_payload_bytes_total Number of PHYPayload bytes.
+# TYPE lora_device_phy_payload_bytes_total counter
+lora_device_phy_payload_bytes_total{dev_eui="0102030405060708",mtype="UNCONFIRMED_DATA_UP"} 13
+lora_device_phy_payload_bytes_total{dev_eui="0102030405060708",mtype="UNCONFIRMED_DATA_DOWN"} 13
+lora_device_phy_payload_bytes_total{dev_eui="0102030405060708",mtype="CONFIRMED_DATA_UP"} 13
+# HELP lora_device_mac_command_bytes_total Number of mac-command bytes.
+# TYPE lora_device_mac_command_bytes_total counter
+lora_device_mac_command_bytes_total{dev_eui="0102030405060708",mtype="UNCONFIRMED_DATA_UP"} 2
+lora_device_mac_command_bytes_total{dev_eui="0102030405060708",mtype="UNCONFIRMED_DATA_DOWN"} 3
+lora_device_mac_command_bytes_total{dev_eui="0102030405060708",mtype="CONFIRMED_DATA_UP"} 0
+# HELP lora_device_application_payload_bytes_total Number of application payload bytes.
+# TYPE lora_device_application_payload_bytes_total counter
+lora_device_application_payload_bytes_total{dev_eui="0102030405060708",mtype="UNCONFIRMED_DATA_UP"} 4
+lora_device_application_payload_bytes_total{dev_eui="0102030405060708",mtype="UNCONFIRMED_DATA_DOWN"} 0
+lora_device_application_payload_bytes_total{dev_eui="0102030405060708",mtype="CONFIRMED_DATA_UP"} 5
+# HELP lora_device_airtime_seconds_total Time-on-air in seconds.
+# TYPE lora_device_airtime_seconds_total counter
+lora_device_airtime_seconds_total{dev_eui="0102030405060708",mtype="UNCONFIRMED_DATA_UP"} 0.046336
+lora_device_airtime_seconds_total{dev_eui="0102030405060708",mtype="UNCONFIRMED_DATA_DOWN"} 0.041216
+lora_device_airtime_seconds_total{dev_eui="0102030405060708",mtype="CONFIRMED_DATA_UP"} 0.046336
+# HELP lora_gateway_frames_total Number of frames.
+# TYPE lora_gateway_frames_total counter
+lora_gateway_frames_total{gateway_id="0101010101010101",mtype="JOIN_REQUEST"} 1
+lora_gateway_frames_total{gateway_id="0101010101010101",mtype="UNCONFIRMED_DATA_UP"} 1
+lora_gateway_frames_total{gateway_id="0101010101010101",mtype="UNCONFIRMED_DATA_DOWN"} 1
+lora_gateway_frames_total{gateway_id="0101010101010101",mtype="CONFIRMED_DATA_UP"} 1
+lora_gateway_frames_total{gateway_id="0202020202020202",mtype="UNCONFIRMED_DATA_UP"} 1
+lora_gateway_frames_total{gateway_id="0202020202020202",mtype="UNCONFIRMED_DATA_DOWN"} 1
+lora_gateway_frames_total{gateway_id="0202020202020202",mtype="CONFIRMED_DATA_UP"} 1
+# HELP lora_gateway_phy_payload_bytes_total Number of PHYPayload bytes.
+# TYPE lora_gateway_phy_payload_bytes_total counter
+lora_gateway_phy_payload_bytes_total{gateway_id="0101010101010101",mtype="JOIN_REQUEST"} 23
+lora_gateway_phy_payload_bytes_total{gateway_id="0101010101010101",mtype="UNCONFIRMED_DATA_UP"} 13
+lora_gateway_phy_payload_bytes_total{gateway_id="0101010101010101",mtype="UNCONFIRMED_DATA_DOWN"} 13
+lora_gateway_phy_payload_bytes_total{gateway_id="0101010101010101",mtype="CONFIRMED_DATA_UP"} 13
+lora_gateway_phy_payload_bytes_total{gateway_id="0202020202020202",mtype="UNCONFIRMED_DATA_UP"} 13
+lora_gateway_phy_payload_bytes_total{gateway_id="0202020202020202",mtype="UNCONFIRMED_DATA_DOWN"} 13
+lora_gateway_phy_payload_bytes_total{gateway_id="0202020202020202",mtype="CONFIRMED_DATA_UP"} 13
+# HELP lora_gateway_mac_command_bytes_total Number of mac-command bytes.
+# TYPE lora_gateway_mac_command_bytes_total counter
+lora_gateway_mac_command_bytes_total{gateway_id="0101010101010101",mtype="JOIN_REQUEST"} 0
+lora_gateway_mac_command_bytes_total{gateway_id="0101010101010101",mtype="UNCONFIRMED_DATA_UP"} 2
+lora_gateway_mac_command_bytes_total{gateway_id="0101010101010101",mtype="UNCONFIRMED_DATA_DOWN"} 3
+lora_gateway_mac_command_bytes_total{gateway_id="0101010101010101",mtype="CONFIRMED_DATA_UP"} 0
+lora_gateway_mac_command_bytes_total{gateway_id="0202020202020202",mtype="UNCONFIRMED_DATA_UP"} 2
+lora_gateway_mac_command_bytes_total{gateway_id="0202020202020202",mtype="UNCONFIRMED_DATA_DOWN"} 0
+lora_gateway_mac_command_bytes_total{gateway_id="0202020202020202",mtype="CONFIRMED_DATA_UP"} 0
+# HELP lora_gateway_application_payload_bytes_total Number of application payload bytes.
+# TYPE lora_gateway_application_payload_bytes_total counter
+lora_gateway_application_payload_bytes_total{gateway_id="0101010101010101",mtype="JOIN_REQUEST"} 0
+lora_gateway_application_payload_bytes_total{gateway_id="0101010101010101",mtype="UNCONFIRMED_DATA_UP"} 4
+lora_gateway_application_payload_bytes_total{gateway_id="0101010101010101",mtype="UNCONFIRMED_DATA_DOWN"} 0
+lora_gateway_application_payload_bytes_total{gateway_id="0101010101010101",mtype="CONFIRMED_DATA_UP"} 5
+lora_gateway_application_payload_bytes_total{gateway_id="0202020202020202",mtype="UNCONFIRMED_DATA_UP"} 4
+lora_gateway_application_payload_bytes_total{gateway_id="0202020202020202",mtype="UNCONFIRMED_DATA_DOWN"} 8
+lora_gateway_application_payload_bytes_total{gateway_id="0202020202020202",mtype="CONFIRMED_DATA_UP"} 5
+# HELP lora_gateway_airtime_seconds_total Time-on-air in seconds.
+# TYPE lora_gateway_airtime_seconds_total counter
+lora_gateway_airtime_seconds_total{gateway_id="0101010101010101",mtype="JOIN_REQUEST"} 0
+lora_gateway_airtime_seconds_total{gateway_id="0101010101010101",mtype="UNCONFIRMED_DATA_UP"} 0.046336
+lora_gateway_airtime_seconds_total{gateway_id="0101010101010101",mtype="UNCONFIRMED_DATA_DOWN"} 0.041216
+lora_gateway_airtime_seconds_total{gateway_id="0101010101010101",mtype="CONFIRMED_DATA_UP"} 0.046336
+lora_gateway_airtime_seconds_total{gateway_id="0202020202020202",mtype="UNCONFIRMED_DATA_UP"} 0.046336
+lora_gateway_airtime_seconds_total{gateway_id="0202020202020202",mtype="UNCONFIRMED_DATA_DOWN"} 0.041216
+lora_gateway_airtime_seconds_total{gateway_id="0202020202020202",mtype="CONFIRMED_DATA_UP"} 0.046336
+# HELP lora_multicast_group_frames_total Number of frames.
+# TYPE lora_multicast_group_frames_total counter
+lora_multicast_group_frames_total{multicast_group_id="0102030405060708090a0b0c0d0e0f10",mtype="UNCONFIRMED_DATA_DOWN"} 1
+# HELP lora_multicast_group_phy_payload_bytes_total Number of PHYPayload bytes.
+# TYPE lora_multicast_group_phy_payload_bytes_total counter
+lora_multicast_group_phy_payload_bytes_total{multicast_group_id="0102030405060708090a0b0c0d0e0f10",mtype="UNCONFIRMED_DATA_DOWN"} 13
+# HELP lora_multicast_group_mac_command_bytes_total Number of mac-command bytes.
+# TYPE lora_multicast_group_mac_command_bytes_total counter
+lora_multicast_group_mac_command_bytes_total{multicast_group_id="0102030405060708090a0b0c0d0e0f10",mtype="UNCONFIRMED_DATA_DOWN"} 0
+# HELP lora_multicast_group_application_payload_bytes_total Number of application payload bytes.
+# TYPE lora_multicast_group_application_payload_bytes_total counter
+lora_multicast_group_application_payload_bytes_total{multicast_group_id="0102030405060708090a0b0c0d0e0f10",mtype="UNCONFIRMED_DATA_DOWN"} 8
+# HELP lora_multicast_group_airtime_seconds_total Time-on-air in seconds.
+# TYPE lora_multicast_group_airtime_seconds_total counter
+lora_multicast_group_airtime_seconds_total{multicast_group_id="0102030405060708090a0b0c0d0e0f10",mtype="UNCONFIRMED_DATA_DOWN"} 0.041216
+# HELP lora_device_mac_commands_total Number of received uplink mac-commands.
+# TYPE lora_device_mac_commands_total counter
+lora_device_mac_commands_total{dev_eui="0102030405060708",cid="2"} 1
+lora_device_mac_commands_total{dev_eui="0102030405060708",cid="3"} 2
+`
+
+func TestMetricName(t *testing.T) {
+	for namespace, expected := range map[string]string{
+		"":     "frames_total",
+		"nc":   "nc_frames_total",
+		"nc_":  "nc_frames_total",
+		"lora": "lora_frames_total",
+	} {
+		if got := NewServer(WithNamespace(namespace)).metricName("frames_total"); got != expected {
+			t.Errorf("namespace %q: expected %s, got %s", namespace, expected, got)
+		}
+	}
+}
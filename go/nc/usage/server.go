@@ -0,0 +1,311 @@
+// Package usage implements a nc.NetworkControllerServiceServer which
+// aggregates the uplink and downlink meta-data into usage counters per
+// device, gateway and multicast-group, by message type. The counters can be
+// queried through the Server methods or be scraped by Prometheus, as the
+// Server implements http.Handler:
+//
+//	srv := usage.NewServer()
+//	nc.RegisterNetworkControllerServiceServer(grpcServer, srv)
+//	http.Handle("/metrics", srv)
+package usage
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/golang/protobuf/ptypes/empty"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/brocaar/chirpstack-api/go/gw/airtime"
+	"github.com/brocaar/chirpstack-api/go/lorawan"
+	"github.com/brocaar/chirpstack-api/go/nc"
+)
+
+var _ nc.NetworkControllerServiceServer = (*Server)(nil)
+
+// DefaultNamespace defines the default Prometheus metric namespace.
+const DefaultNamespace = "nc"
+
+// Counters contains the usage counters.
+type Counters struct {
+	Frames                  uint64
+	PHYPayloadBytes         uint64
+	MACCommandBytes         uint64
+	ApplicationPayloadBytes uint64
+
+	// Airtime holds the total time-on-air. Frames for which the airtime
+	// can not be calculated (e.g. missing modulation info) do not add to it.
+	Airtime time.Duration
+}
+
+// add adds the given counters.
+func (c *Counters) add(o Counters) {
+	c.Frames += o.Frames
+	c.PHYPayloadBytes += o.PHYPayloadBytes
+	c.MACCommandBytes += o.MACCommandBytes
+	c.ApplicationPayloadBytes += o.ApplicationPayloadBytes
+	c.Airtime += o.Airtime
+}
+
+// Usage contains the counters by message type.
+type Usage map[nc.MType]Counters
+
+// Total returns the sum of the counters of all message types.
+func (u Usage) Total() Counters {
+	var out Counters
+	for _, c := range u {
+		out.add(c)
+	}
+	return out
+}
+
+// add adds the counters to the given message type.
+func (u Usage) add(mType nc.MType, c Counters) {
+	cur := u[mType]
+	cur.add(c)
+	u[mType] = cur
+}
+
+// copy returns a copy of the usage.
+func (u Usage) copy() Usage {
+	out := make(Usage, len(u))
+	for k, v := range u {
+		out[k] = v
+	}
+	return out
+}
+
+// Option configures the Server.
+type Option func(*Server)
+
+// WithNamespace sets the Prometheus metric namespace (default "nc").
+func WithNamespace(namespace string) Option {
+	return func(s *Server) {
+		s.namespace = namespace
+	}
+}
+
+// Server implements the usage aggregating nc.NetworkControllerServiceServer.
+type Server struct {
+	namespace string
+
+	mu              sync.Mutex
+	devices         map[lorawan.EUI64]Usage
+	gateways        map[lorawan.EUI64]Usage
+	multicastGroups map[string]Usage
+	macCommands     map[lorawan.EUI64]map[uint32]uint64
+}
+
+// NewServer returns a new Server.
+func NewServer(opts ...Option) *Server {
+	s := &Server{
+		namespace: DefaultNamespace,
+	}
+	for _, o := range opts {
+		o(s)
+	}
+	s.Reset()
+	return s
+}
+
+// HandleUplinkMetaData adds the uplink to the counters of the device and of
+// every gateway which received it.
+func (s *Server) HandleUplinkMetaData(ctx context.Context, req *nc.HandleUplinkMetaDataRequest) (*empty.Empty, error) {
+	devEUI, hasDevEUI, err := optionalEUI64("dev_eui", req.GetDevEui())
+	if err != nil {
+		return nil, err
+	}
+
+	c := Counters{
+		Frames:                  1,
+		PHYPayloadBytes:         uint64(req.GetPhyPayloadByteCount()),
+		MACCommandBytes:         uint64(req.GetMacCommandByteCount()),
+		ApplicationPayloadBytes: uint64(req.GetApplicationPayloadByteCount()),
+	}
+	if txInfo := req.GetTxInfo(); txInfo != nil {
+		if d, err := airtime.Airtime(txInfo, int(req.GetPhyPayloadByteCount())); err == nil {
+			c.Airtime = d
+		}
+	}
+
+	// A gateway can report the same uplink multiple times (e.g. multiple
+	// antennas), it is only counted once.
+	var gatewayIDs []lorawan.EUI64
+	seen := make(map[lorawan.EUI64]struct{})
+	for _, rx := range req.GetRxInfo() {
+		gatewayID, err := lorawan.EUI64FromBytes(rx.GetGatewayId())
+		if err != nil {
+			return nil, status.Errorf(codes.InvalidArgument, "rx_info gateway_id: %s", err)
+		}
+		if _, ok := seen[gatewayID]; !ok {
+			seen[gatewayID] = struct{}{}
+			gatewayIDs = append(gatewayIDs, gatewayID)
+		}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if hasDevEUI {
+		usage(s.devices, devEUI).add(req.GetMessageType(), c)
+	}
+	for _, id := range gatewayIDs {
+		usage(s.gateways, id).add(req.GetMessageType(), c)
+	}
+
+	return &empty.Empty{}, nil
+}
+
+// HandleDownlinkMetaData adds the downlink to the counters of the device or
+// multicast-group and of the gateway used for the transmission.
+func (s *Server) HandleDownlinkMetaData(ctx context.Context, req *nc.HandleDownlinkMetaDataRequest) (*empty.Empty, error) {
+	devEUI, hasDevEUI, err := optionalEUI64("dev_eui", req.GetDevEui())
+	if err != nil {
+		return nil, err
+	}
+	gatewayID, hasGatewayID, err := optionalEUI64("tx_info gateway_id", req.GetTxInfo().GetGatewayId())
+	if err != nil {
+		return nil, err
+	}
+
+	c := Counters{
+		Frames:                  1,
+		PHYPayloadBytes:         uint64(req.GetPhyPayloadByteCount()),
+		MACCommandBytes:         uint64(req.GetMacCommandByteCount()),
+		ApplicationPayloadBytes: uint64(req.GetApplicationPayloadByteCount()),
+	}
+	if txInfo := req.GetTxInfo(); txInfo != nil {
+		if d, err := airtime.Airtime(txInfo, int(req.GetPhyPayloadByteCount())); err == nil {
+			c.Airtime = d
+		}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if hasDevEUI {
+		usage(s.devices, devEUI).add(req.GetMessageType(), c)
+	}
+	if len(req.GetMulticastGroupId()) != 0 {
+		key := string(req.GetMulticastGroupId())
+		if s.multicastGroups[key] == nil {
+			s.multicastGroups[key] = make(Usage)
+		}
+		s.multicastGroups[key].add(req.GetMessageType(), c)
+	}
+	if hasGatewayID {
+		usage(s.gateways, gatewayID).add(req.GetMessageType(), c)
+	}
+
+	return &empty.Empty{}, nil
+}
+
+// HandleUplinkMACCommand counts the received mac-commands per device and
+// CID.
+func (s *Server) HandleUplinkMACCommand(ctx context.Context, req *nc.HandleUplinkMACCommandRequest) (*empty.Empty, error) {
+	devEUI, err := lorawan.EUI64FromBytes(req.GetDevEui())
+	if err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "dev_eui: %s", err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.macCommands[devEUI] == nil {
+		s.macCommands[devEUI] = make(map[uint32]uint64)
+	}
+	s.macCommands[devEUI][req.GetCid()] += uint64(len(req.GetCommands()))
+
+	return &empty.Empty{}, nil
+}
+
+// Device returns the usage of the given device.
+func (s *Server) Device(devEUI lorawan.EUI64) Usage {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.devices[devEUI].copy()
+}
+
+// Gateway returns the usage of the given gateway.
+func (s *Server) Gateway(gatewayID lorawan.EUI64) Usage {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.gateways[gatewayID].copy()
+}
+
+// MulticastGroup returns the downlink usage of the given multicast-group.
+func (s *Server) MulticastGroup(multicastGroupID []byte) Usage {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.multicastGroups[string(multicastGroupID)].copy()
+}
+
+// MACCommands returns the number of received mac-commands of the given
+// device, by CID.
+func (s *Server) MACCommands(devEUI lorawan.EUI64) map[uint32]uint64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	out := make(map[uint32]uint64, len(s.macCommands[devEUI]))
+	for k, v := range s.macCommands[devEUI] {
+		out[k] = v
+	}
+	return out
+}
+
+// Devices returns the usage of all devices.
+func (s *Server) Devices() map[lorawan.EUI64]Usage {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return copyAll(s.devices)
+}
+
+// Gateways returns the usage of all gateways.
+func (s *Server) Gateways() map[lorawan.EUI64]Usage {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return copyAll(s.gateways)
+}
+
+// Reset resets all counters.
+func (s *Server) Reset() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.devices = make(map[lorawan.EUI64]Usage)
+	s.gateways = make(map[lorawan.EUI64]Usage)
+	s.multicastGroups = make(map[string]Usage)
+	s.macCommands = make(map[lorawan.EUI64]map[uint32]uint64)
+}
+
+// usage returns the usage for the given ID, creating it when needed.
+func usage(m map[lorawan.EUI64]Usage, id lorawan.EUI64) Usage {
+	u, ok := m[id]
+	if !ok {
+		u = make(Usage)
+		m[id] = u
+	}
+	return u
+}
+
+func copyAll(m map[lorawan.EUI64]Usage) map[lorawan.EUI64]Usage {
+	out := make(map[lorawan.EUI64]Usage, len(m))
+	for k, v := range m {
+		out[k] = v.copy()
+	}
+	return out
+}
+
+// optionalEUI64 returns the EUI64 of b, when set.
+func optionalEUI64(field string, b []byte) (lorawan.EUI64, bool, error) {
+	if len(b) == 0 {
+		return lorawan.EUI64{}, false, nil
+	}
+	id, err := lorawan.EUI64FromBytes(b)
+	if err != nil {
+		return id, false, status.Errorf(codes.InvalidArgument, "%s: %s", field, err)
+	}
+	return id, true, nil
+}
@@ -0,0 +1,264 @@
+package usage
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/brocaar/chirpstack-api/go/common"
+	"github.com/brocaar/chirpstack-api/go/gw"
+	"github.com/brocaar/chirpstack-api/go/lorawan"
+	"github.com/brocaar/chirpstack-api/go/nc"
+)
+
+var (
+	devEUI           = lorawan.EUI64{1, 2, 3, 4, 5, 6, 7, 8}
+	gatewayID1       = lorawan.EUI64{1, 1, 1, 1, 1, 1, 1, 1}
+	gatewayID2       = lorawan.EUI64{2, 2, 2, 2, 2, 2, 2, 2}
+	multicastGroupID = []byte{1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15, 16}
+)
+
+// Time-on-air of a 13 byte SF7 125 kHz uplink (with CRC) and downlink
+// (without CRC).
+const (
+	uplinkAirtime   = 46336 * time.Microsecond
+	downlinkAirtime = 41216 * time.Microsecond
+)
+
+func uplinkTXInfo() *gw.UplinkTXInfo {
+	return &gw.UplinkTXInfo{
+		Frequency:  868100000,
+		Modulation: common.Modulation_LORA,
+		ModulationInfo: &gw.UplinkTXInfo_LoraModulationInfo{
+			LoraModulationInfo: &gw.LoRaModulationInfo{
+				SpreadingFactor: 7,
+				Bandwidth:       125,
+				CodeRate:        "4/5",
+			},
+		},
+	}
+}
+
+func downlinkTXInfo(gatewayID lorawan.EUI64) *gw.DownlinkTXInfo {
+	return &gw.DownlinkTXInfo{
+		GatewayId:  gatewayID[:],
+		Frequency:  868100000,
+		Modulation: common.Modulation_LORA,
+		ModulationInfo: &gw.DownlinkTXInfo_LoraModulationInfo{
+			LoraModulationInfo: &gw.LoRaModulationInfo{
+				SpreadingFactor: 7,
+				Bandwidth:       125,
+				CodeRate:        "4/5",
+			},
+		},
+	}
+}
+
+func rxInfo(gatewayIDs ...lorawan.EUI64) []*gw.UplinkRXInfo {
+	var out []*gw.UplinkRXInfo
+	for i := range gatewayIDs {
+		out = append(out, &gw.UplinkRXInfo{GatewayId: gatewayIDs[i][:]})
+	}
+	return out
+}
+
+// newTestServer returns a server which has handled the following frames:
+//
+//   - an unconfirmed uplink, received twice by gateway 1 and by gateway 2
+//   - a confirmed uplink, received by gateway 1 and 2
+//   - a join-request (no DevEUI and tx-info), received by gateway 1
+//   - an unconfirmed downlink to the device, through gateway 1
+//   - an unconfirmed multicast downlink, through gateway 2
+//   - two uplink mac-command requests
+func newTestServer(t *testing.T, opts ...Option) *Server {
+	t.Helper()
+
+	s := NewServer(opts...)
+	ctx := context.Background()
+
+	uplinks := []*nc.HandleUplinkMetaDataRequest{
+		{
+			DevEui:                      devEUI[:],
+			TxInfo:                      uplinkTXInfo(),
+			RxInfo:                      rxInfo(gatewayID1, gatewayID1, gatewayID2),
+			PhyPayloadByteCount:         13,
+			MacCommandByteCount:         2,
+			ApplicationPayloadByteCount: 4,
+			MessageType:                 nc.MType_UNCONFIRMED_DATA_UP,
+		},
+		{
+			DevEui:                      devEUI[:],
+			TxInfo:                      uplinkTXInfo(),
+			RxInfo:                      rxInfo(gatewayID2, gatewayID1),
+			PhyPayloadByteCount:         13,
+			ApplicationPayloadByteCount: 5,
+			MessageType:                 nc.MType_CONFIRMED_DATA_UP,
+		},
+		{
+			RxInfo:              rxInfo(gatewayID1),
+			PhyPayloadByteCount: 23,
+			MessageType:         nc.MType_JOIN_REQUEST,
+		},
+	}
+	for _, req := range uplinks {
+		if _, err := s.HandleUplinkMetaData(ctx, req); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	downlinks := []*nc.HandleDownlinkMetaDataRequest{
+		{
+			DevEui:              devEUI[:],
+			TxInfo:              downlinkTXInfo(gatewayID1),
+			PhyPayloadByteCount: 13,
+			MacCommandByteCount: 3,
+			MessageType:         nc.MType_UNCONFIRMED_DATA_DOWN,
+		},
+		{
+			MulticastGroupId:            multicastGroupID,
+			TxInfo:                      downlinkTXInfo(gatewayID2),
+			PhyPayloadByteCount:         13,
+			ApplicationPayloadByteCount: 8,
+			MessageType:                 nc.MType_UNCONFIRMED_DATA_DOWN,
+		},
+	}
+	for _, req := range downlinks {
+		if _, err := s.HandleDownlinkMetaData(ctx, req); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	macCommands := []*nc.HandleUplinkMACCommandRequest{
+		{DevEui: devEUI[:], Cid: 2, Commands: [][]byte{{}}},
+		{DevEui: devEUI[:], Cid: 3, Commands: [][]byte{{0x07}, {0x07}}},
+	}
+	for _, req := range macCommands {
+		if _, err := s.HandleUplinkMACCommand(ctx, req); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	return s
+}
+
+func TestServer(t *testing.T) {
+	s := newTestServer(t)
+
+	unconfirmedUp := Counters{Frames: 1, PHYPayloadBytes: 13, MACCommandBytes: 2, ApplicationPayloadBytes: 4, Airtime: uplinkAirtime}
+	confirmedUp := Counters{Frames: 1, PHYPayloadBytes: 13, ApplicationPayloadBytes: 5, Airtime: uplinkAirtime}
+	joinRequest := Counters{Frames: 1, PHYPayloadBytes: 23}
+	deviceDown := Counters{Frames: 1, PHYPayloadBytes: 13, MACCommandBytes: 3, Airtime: downlinkAirtime}
+	multicastDown := Counters{Frames: 1, PHYPayloadBytes: 13, ApplicationPayloadBytes: 8, Airtime: downlinkAirtime}
+
+	tests := []struct {
+		name     string
+		usage    Usage
+		expected Usage
+	}{
+		{
+			name:  "device",
+			usage: s.Device(devEUI),
+			expected: Usage{
+				nc.MType_UNCONFIRMED_DATA_UP:   unconfirmedUp,
+				nc.MType_CONFIRMED_DATA_UP:     confirmedUp,
+				nc.MType_UNCONFIRMED_DATA_DOWN: deviceDown,
+			},
+		},
+		{
+			// The duplicate rx-info of the first uplink is counted once.
+			name:  "gateway 1",
+			usage: s.Gateway(gatewayID1),
+			expected: Usage{
+				nc.MType_UNCONFIRMED_DATA_UP:   unconfirmedUp,
+				nc.MType_CONFIRMED_DATA_UP:     confirmedUp,
+				nc.MType_JOIN_REQUEST:          joinRequest,
+				nc.MType_UNCONFIRMED_DATA_DOWN: deviceDown,
+			},
+		},
+		{
+			name:  "gateway 2",
+			usage: s.Gateway(gatewayID2),
+			expected: Usage{
+				nc.MType_UNCONFIRMED_DATA_UP:   unconfirmedUp,
+				nc.MType_CONFIRMED_DATA_UP:     confirmedUp,
+				nc.MType_UNCONFIRMED_DATA_DOWN: multicastDown,
+			},
+		},
+		{
+			name:  "multicast-group",
+			usage: s.MulticastGroup(multicastGroupID),
+			expected: Usage{
+				nc.MType_UNCONFIRMED_DATA_DOWN: multicastDown,
+			},
+		},
+		{
+			name:     "unknown device",
+			usage:    s.Device(lorawan.EUI64{}),
+			expected: Usage{},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if !reflect.DeepEqual(test.usage, test.expected) {
+				t.Errorf("expected %+v, got %+v", test.expected, test.usage)
+			}
+		})
+	}
+
+	total := s.Gateway(gatewayID1).Total()
+	if exp := (Counters{Frames: 4, PHYPayloadBytes: 62, MACCommandBytes: 5, ApplicationPayloadBytes: 9, Airtime: 2*uplinkAirtime + downlinkAirtime}); total != exp {
+		t.Errorf("expected total %+v, got %+v", exp, total)
+	}
+
+	if devices := s.Devices(); len(devices) != 1 || !reflect.DeepEqual(devices[devEUI], s.Device(devEUI)) {
+		t.Errorf("unexpected devices: %+v", devices)
+	}
+	if gateways := s.Gateways(); len(gateways) != 2 {
+		t.Errorf("expected 2 gateways, got %d", len(gateways))
+	}
+	if exp := map[uint32]uint64{2: 1, 3: 2}; !reflect.DeepEqual(s.MACCommands(devEUI), exp) {
+		t.Errorf("expected mac-commands %v, got %v", exp, s.MACCommands(devEUI))
+	}
+
+	// The returned usage is a copy.
+	s.Device(devEUI)[nc.MType_UNCONFIRMED_DATA_UP] = Counters{}
+	if s.Device(devEUI)[nc.MType_UNCONFIRMED_DATA_UP] != unconfirmedUp {
+		t.Error("expected the device usage to be unchanged")
+	}
+
+	s.Reset()
+	if len(s.Devices()) != 0 || len(s.Gateways()) != 0 || len(s.MulticastGroup(multicastGroupID)) != 0 || len(s.MACCommands(devEUI)) != 0 {
+		t.Error("expected no usage after reset")
+	}
+}
+
+func TestServerInvalidArgument(t *testing.T) {
+	s := NewServer()
+	ctx := context.Background()
+
+	_, err := s.HandleUplinkMetaData(ctx, &nc.HandleUplinkMetaDataRequest{DevEui: []byte{1, 2, 3}})
+	if status.Code(err) != codes.InvalidArgument {
+		t.Errorf("uplink dev_eui: expected InvalidArgument, got %v", err)
+	}
+	_, err = s.HandleUplinkMetaData(ctx, &nc.HandleUplinkMetaDataRequest{RxInfo: []*gw.UplinkRXInfo{{GatewayId: []byte{1}}}})
+	if status.Code(err) != codes.InvalidArgument {
+		t.Errorf("uplink gateway_id: expected InvalidArgument, got %v", err)
+	}
+	_, err = s.HandleDownlinkMetaData(ctx, &nc.HandleDownlinkMetaDataRequest{TxInfo: &gw.DownlinkTXInfo{GatewayId: []byte{1}}})
+	if status.Code(err) != codes.InvalidArgument {
+		t.Errorf("downlink gateway_id: expected InvalidArgument, got %v", err)
+	}
+	_, err = s.HandleUplinkMACCommand(ctx, &nc.HandleUplinkMACCommandRequest{})
+	if status.Code(err) != codes.InvalidArgument {
+		t.Errorf("mac-command dev_eui: expected InvalidArgument, got %v", err)
+	}
+
+	if len(s.Gateways()) != 0 {
+		t.Error("expected no usage for invalid requests")
+	}
+}
@@ -0,0 +1,367 @@
+package nstest
+
+import (
+	"context"
+	"sort"
+	"strings"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/golang/protobuf/ptypes/empty"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/brocaar/chirpstack-api/go/ns"
+)
+
+// CreateDevice creates the given device. The device-profile, service-profile
+// and routing-profile must exist.
+func (s *Server) CreateDevice(ctx context.Context, req *ns.CreateDeviceRequest) (*empty.Empty, error) {
+	d := req.GetDevice()
+	if d == nil {
+		return nil, missing("device")
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.validateDevice(d); err != nil {
+		return nil, err
+	}
+	if err := create(s.devices, "device", d.DevEui, d); err != nil {
+		return nil, err
+	}
+	return &empty.Empty{}, nil
+}
+
+// GetDevice returns the device for the given DevEUI.
+func (s *Server) GetDevice(ctx context.Context, req *ns.GetDeviceRequest) (*ns.GetDeviceResponse, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	e, err := get(s.devices, "device", req.GetDevEui())
+	if err != nil {
+		return nil, err
+	}
+	return &ns.GetDeviceResponse{
+		Device:    proto.Clone(e.msg).(*ns.Device),
+		CreatedAt: toTimestamp(e.createdAt),
+		UpdatedAt: toTimestamp(e.updatedAt),
+	}, nil
+}
+
+// UpdateDevice updates the given device. The device-profile, service-profile
+// and routing-profile must exist.
+func (s *Server) UpdateDevice(ctx context.Context, req *ns.UpdateDeviceRequest) (*empty.Empty, error) {
+	d := req.GetDevice()
+	if d == nil {
+		return nil, missing("device")
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.validateDevice(d); err != nil {
+		return nil, err
+	}
+	if err := update(s.devices, "device", d.DevEui, d); err != nil {
+		return nil, err
+	}
+	return &empty.Empty{}, nil
+}
+
+// DeleteDevice deletes the device for the given DevEUI, including its
+// activation, device-queue and multicast-group memberships.
+func (s *Server) DeleteDevice(ctx context.Context, req *ns.DeleteDeviceRequest) (*empty.Empty, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	devEUI := req.GetDevEui()
+	if _, err := get(s.devices, "device", devEUI); err != nil {
+		return nil, err
+	}
+
+	key := string(devEUI)
+	delete(s.devices, key)
+	delete(s.activations, key)
+	delete(s.deviceQueues, key)
+	delete(s.macCommands, key)
+	for _, devices := range s.multicastDevices {
+		delete(devices, key)
+	}
+
+	return &empty.Empty{}, nil
+}
+
+// ActivateDevice stores the activation of the given device, replacing any
+// previous activation and flushing the device-queue.
+func (s *Server) ActivateDevice(ctx context.Context, req *ns.ActivateDeviceRequest) (*empty.Empty, error) {
+	da := req.GetDeviceActivation()
+	if da == nil {
+		return nil, missing("device_activation")
+	}
+	if err := checkID("device_activation.dev_addr", da.DevAddr, 4); err != nil {
+		return nil, err
+	}
+	for _, key := range []struct {
+		field string
+		key   []byte
+	}{
+		{"device_activation.s_nwk_s_int_key", da.SNwkSIntKey},
+		{"device_activation.f_nwk_s_int_key", da.FNwkSIntKey},
+		{"device_activation.nwk_s_enc_key", da.NwkSEncKey},
+	} {
+		if err := checkID(key.field, key.key, 16); err != nil {
+			return nil, err
+		}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, err := get(s.devices, "device", da.DevEui); err != nil {
+		return nil, err
+	}
+
+	s.activations[string(da.DevEui)] = proto.Clone(da).(*ns.DeviceActivation)
+	delete(s.deviceQueues, string(da.DevEui))
+
+	return &empty.Empty{}, nil
+}
+
+// DeactivateDevice removes the activation of the given device and flushes
+// its device-queue.
+func (s *Server) DeactivateDevice(ctx context.Context, req *ns.DeactivateDeviceRequest) (*empty.Empty, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	devEUI := req.GetDevEui()
+	if _, err := get(s.devices, "device", devEUI); err != nil {
+		return nil, err
+	}
+
+	delete(s.activations, string(devEUI))
+	delete(s.deviceQueues, string(devEUI))
+
+	return &empty.Empty{}, nil
+}
+
+// GetDeviceActivation returns the activation of the given device.
+func (s *Server) GetDeviceActivation(ctx context.Context, req *ns.GetDeviceActivationRequest) (*ns.GetDeviceActivationResponse, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	da, err := s.activation(req.GetDevEui())
+	if err != nil {
+		return nil, err
+	}
+	return &ns.GetDeviceActivationResponse{
+		DeviceActivation: proto.Clone(da).(*ns.DeviceActivation),
+	}, nil
+}
+
+// CreateDeviceQueueItem adds the given item to the device-queue. The device
+// must be activated and the frame-counter must not be lower than the next
+// downlink frame-counter.
+func (s *Server) CreateDeviceQueueItem(ctx context.Context, req *ns.CreateDeviceQueueItemRequest) (*empty.Empty, error) {
+	item := req.GetItem()
+	if item == nil {
+		return nil, missing("item")
+	}
+	if item.FPort == 0 {
+		return nil, status.Error(codes.InvalidArgument, "item.f_port must be > 0")
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	fCnt, err := s.nextDownlinkFCnt(item.DevEui)
+	if err != nil {
+		return nil, err
+	}
+	if item.FCnt < fCnt {
+		return nil, status.Errorf(codes.InvalidArgument, "item.f_cnt must be >= %d", fCnt)
+	}
+
+	key := string(item.DevEui)
+	queue := append(s.deviceQueues[key], proto.Clone(item).(*ns.DeviceQueueItem))
+	sort.SliceStable(queue, func(i, j int) bool {
+		return queue[i].FCnt < queue[j].FCnt
+	})
+	s.deviceQueues[key] = queue
+
+	return &empty.Empty{}, nil
+}
+
+// FlushDeviceQueueForDevEUI removes all items from the device-queue.
+func (s *Server) FlushDeviceQueueForDevEUI(ctx context.Context, req *ns.FlushDeviceQueueForDevEUIRequest) (*empty.Empty, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	devEUI := req.GetDevEui()
+	if _, err := get(s.devices, "device", devEUI); err != nil {
+		return nil, err
+	}
+
+	delete(s.deviceQueues, string(devEUI))
+	return &empty.Empty{}, nil
+}
+
+// GetDeviceQueueItemsForDevEUI returns the device-queue items, ordered by
+// frame-counter.
+func (s *Server) GetDeviceQueueItemsForDevEUI(ctx context.Context, req *ns.GetDeviceQueueItemsForDevEUIRequest) (*ns.GetDeviceQueueItemsForDevEUIResponse, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	devEUI := req.GetDevEui()
+	if _, err := get(s.devices, "device", devEUI); err != nil {
+		return nil, err
+	}
+
+	var resp ns.GetDeviceQueueItemsForDevEUIResponse
+	for _, item := range s.deviceQueues[string(devEUI)] {
+		resp.Items = append(resp.Items, proto.Clone(item).(*ns.DeviceQueueItem))
+	}
+	return &resp, nil
+}
+
+// GetNextDownlinkFCntForDevEUI returns the frame-counter to use for the next
+// device-queue item. This takes the items already in the queue into account.
+func (s *Server) GetNextDownlinkFCntForDevEUI(ctx context.Context, req *ns.GetNextDownlinkFCntForDevEUIRequest) (*ns.GetNextDownlinkFCntForDevEUIResponse, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	fCnt, err := s.nextDownlinkFCnt(req.GetDevEui())
+	if err != nil {
+		return nil, err
+	}
+	return &ns.GetNextDownlinkFCntForDevEUIResponse{FCnt: fCnt}, nil
+}
+
+// CreateMACCommandQueueItem records the given mac-command block. Use
+// MACCommandQueueItems to inspect the recorded items.
+func (s *Server) CreateMACCommandQueueItem(ctx context.Context, req *ns.CreateMACCommandQueueItemRequest) (*empty.Empty, error) {
+	if len(req.GetCommands()) == 0 {
+		return nil, status.Error(codes.InvalidArgument, "commands must not be empty")
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, err := get(s.devices, "device", req.DevEui); err != nil {
+		return nil, err
+	}
+
+	key := string(req.DevEui)
+	s.macCommands[key] = append(s.macCommands[key], proto.Clone(req).(*ns.CreateMACCommandQueueItemRequest))
+	return &empty.Empty{}, nil
+}
+
+// SendProprietaryPayload records the given proprietary payload. Use
+// ProprietaryPayloads to inspect the recorded payloads.
+func (s *Server) SendProprietaryPayload(ctx context.Context, req *ns.SendProprietaryPayloadRequest) (*empty.Empty, error) {
+	if len(req.GetGatewayMacs()) == 0 {
+		return nil, status.Error(codes.InvalidArgument, "gateway_macs must not be empty")
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, id := range req.GatewayMacs {
+		if _, err := get(s.gateways, "gateway", id); err != nil {
+			return nil, err
+		}
+	}
+
+	s.proprietaryPayloads = append(s.proprietaryPayloads, proto.Clone(req).(*ns.SendProprietaryPayloadRequest))
+	return &empty.Empty{}, nil
+}
+
+// MACCommandQueueItems returns the mac-command queue items created for the
+// given device, in the order in which they were created.
+func (s *Server) MACCommandQueueItems(devEUI []byte) []*ns.CreateMACCommandQueueItemRequest {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var out []*ns.CreateMACCommandQueueItemRequest
+	for _, item := range s.macCommands[string(devEUI)] {
+		out = append(out, proto.Clone(item).(*ns.CreateMACCommandQueueItemRequest))
+	}
+	return out
+}
+
+// ProprietaryPayloads returns the proprietary payloads sent, in the order in
+// which they were sent.
+func (s *Server) ProprietaryPayloads() []*ns.SendProprietaryPayloadRequest {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var out []*ns.SendProprietaryPayloadRequest
+	for _, req := range s.proprietaryPayloads {
+		out = append(out, proto.Clone(req).(*ns.SendProprietaryPayloadRequest))
+	}
+	return out
+}
+
+// validateDevice validates the device and the objects it refers to. The
+// caller must hold the lock.
+func (s *Server) validateDevice(d *ns.Device) error {
+	if err := checkEUI64("device.dev_eui", d.DevEui); err != nil {
+		return err
+	}
+	for _, ref := range []struct {
+		field string
+		kind  string
+		id    []byte
+		m     map[string]*entry
+	}{
+		{"device.device_profile_id", "device-profile", d.DeviceProfileId, s.deviceProfiles},
+		{"device.service_profile_id", "service-profile", d.ServiceProfileId, s.serviceProfiles},
+		{"device.routing_profile_id", "routing-profile", d.RoutingProfileId, s.routingProfiles},
+	} {
+		if err := checkUUID(ref.field, ref.id); err != nil {
+			return err
+		}
+		if _, err := get(ref.m, ref.kind, ref.id); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// activation returns the activation of the given device. The caller must
+// hold the lock.
+func (s *Server) activation(devEUI []byte) (*ns.DeviceActivation, error) {
+	if _, err := get(s.devices, "device", devEUI); err != nil {
+		return nil, err
+	}
+	da, ok := s.activations[string(devEUI)]
+	if !ok {
+		return nil, status.Errorf(codes.FailedPrecondition, "device %x is not activated", devEUI)
+	}
+	return da, nil
+}
+
+// nextDownlinkFCnt returns the next downlink frame-counter of the device.
+// For LoRaWAN 1.1 devices this is the AFCntDown, for LoRaWAN 1.0 devices the
+// NFCntDown. The caller must hold the lock.
+func (s *Server) nextDownlinkFCnt(devEUI []byte) (uint32, error) {
+	da, err := s.activation(devEUI)
+	if err != nil {
+		return 0, err
+	}
+
+	fCnt := da.NFCntDown
+	d := s.devices[string(devEUI)].msg.(*ns.Device)
+	if e, ok := s.deviceProfiles[string(d.DeviceProfileId)]; ok {
+		if strings.HasPrefix(e.msg.(*ns.DeviceProfile).MacVersion, "1.1") {
+			fCnt = da.AFCntDown
+		}
+	}
+
+	if queue := s.deviceQueues[string(devEUI)]; len(queue) != 0 {
+		if last := queue[len(queue)-1].FCnt + 1; last > fCnt {
+			fCnt = last
+		}
+	}
+	return fCnt, nil
+}
@@ -0,0 +1,142 @@
+package nstest
+
+import (
+	"context"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/golang/protobuf/ptypes"
+	"github.com/golang/protobuf/ptypes/empty"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/brocaar/chirpstack-api/go/ns"
+)
+
+// CreateGateway creates the given gateway. The routing-profile and, when
+// set, the gateway-profile must exist.
+func (s *Server) CreateGateway(ctx context.Context, req *ns.CreateGatewayRequest) (*empty.Empty, error) {
+	gw := req.GetGateway()
+	if gw == nil {
+		return nil, missing("gateway")
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.validateGateway(gw); err != nil {
+		return nil, err
+	}
+	if err := create(s.gateways, "gateway", gw.Id, gw); err != nil {
+		return nil, err
+	}
+	return &empty.Empty{}, nil
+}
+
+// GetGateway returns the gateway for the given ID.
+func (s *Server) GetGateway(ctx context.Context, req *ns.GetGatewayRequest) (*ns.GetGatewayResponse, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	e, err := get(s.gateways, "gateway", req.GetId())
+	if err != nil {
+		return nil, err
+	}
+	return &ns.GetGatewayResponse{
+		Gateway:   proto.Clone(e.msg).(*ns.Gateway),
+		CreatedAt: toTimestamp(e.createdAt),
+		UpdatedAt: toTimestamp(e.updatedAt),
+	}, nil
+}
+
+// UpdateGateway updates the given gateway. The routing-profile and, when
+// set, the gateway-profile must exist.
+func (s *Server) UpdateGateway(ctx context.Context, req *ns.UpdateGatewayRequest) (*empty.Empty, error) {
+	gw := req.GetGateway()
+	if gw == nil {
+		return nil, missing("gateway")
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.validateGateway(gw); err != nil {
+		return nil, err
+	}
+	if err := update(s.gateways, "gateway", gw.Id, gw); err != nil {
+		return nil, err
+	}
+	return &empty.Empty{}, nil
+}
+
+// DeleteGateway deletes the gateway for the given ID, including its stats.
+func (s *Server) DeleteGateway(ctx context.Context, req *ns.DeleteGatewayRequest) (*empty.Empty, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	id := req.GetId()
+	if _, err := get(s.gateways, "gateway", id); err != nil {
+		return nil, err
+	}
+
+	delete(s.gateways, string(id))
+	delete(s.gatewayStats, string(id))
+	return &empty.Empty{}, nil
+}
+
+// GetGatewayStats returns the stats set using SetGatewayStats which are
+// within the requested time range. The aggregation interval is not applied.
+func (s *Server) GetGatewayStats(ctx context.Context, req *ns.GetGatewayStatsRequest) (*ns.GetGatewayStatsResponse, error) {
+	start, err := ptypes.Timestamp(req.GetStartTimestamp())
+	if err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "start_timestamp: %s", err)
+	}
+	end, err := ptypes.Timestamp(req.GetEndTimestamp())
+	if err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "end_timestamp: %s", err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, err := get(s.gateways, "gateway", req.GatewayId); err != nil {
+		return nil, err
+	}
+
+	var resp ns.GetGatewayStatsResponse
+	for _, st := range s.gatewayStats[string(req.GatewayId)] {
+		ts, err := ptypes.Timestamp(st.Timestamp)
+		if err != nil || ts.Before(start) || ts.After(end) {
+			continue
+		}
+		resp.Result = append(resp.Result, proto.Clone(st).(*ns.GatewayStats))
+	}
+	return &resp, nil
+}
+
+// SetGatewayStats sets the stats returned by GetGatewayStats for the given
+// gateway.
+func (s *Server) SetGatewayStats(gatewayID []byte, stats []*ns.GatewayStats) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var out []*ns.GatewayStats
+	for _, st := range stats {
+		out = append(out, proto.Clone(st).(*ns.GatewayStats))
+	}
+	s.gatewayStats[string(gatewayID)] = out
+}
+
+// validateGateway validates the gateway and the objects it refers to. The
+// caller must hold the lock.
+func (s *Server) validateGateway(gw *ns.Gateway) error {
+	if err := checkEUI64("gateway.id", gw.Id); err != nil {
+		return err
+	}
+	if err := checkUUID("gateway.routing_profile_id", gw.RoutingProfileId); err != nil {
+		return err
+	}
+	if _, err := get(s.routingProfiles, "routing-profile", gw.RoutingProfileId); err != nil {
+		return err
+	}
+	return exists(s.gatewayProfiles, "gateway-profile", gw.GatewayProfileId)
+}
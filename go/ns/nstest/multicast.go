@@ -0,0 +1,250 @@
+package nstest
+
+import (
+	"context"
+	"sort"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/golang/protobuf/ptypes/empty"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/brocaar/chirpstack-api/go/ns"
+)
+
+// CreateMulticastGroup creates the given multicast-group. A random ID is
+// assigned when the ID is not set. The service-profile and routing-profile
+// must exist.
+func (s *Server) CreateMulticastGroup(ctx context.Context, req *ns.CreateMulticastGroupRequest) (*ns.CreateMulticastGroupResponse, error) {
+	mg := req.GetMulticastGroup()
+	if mg == nil {
+		return nil, missing("multicast_group")
+	}
+	mg = proto.Clone(mg).(*ns.MulticastGroup)
+	if len(mg.Id) == 0 {
+		mg.Id = newID()
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.validateMulticastGroup(mg); err != nil {
+		return nil, err
+	}
+	if err := create(s.multicastGroups, "multicast-group", mg.Id, mg); err != nil {
+		return nil, err
+	}
+	return &ns.CreateMulticastGroupResponse{Id: mg.Id}, nil
+}
+
+// GetMulticastGroup returns the multicast-group for the given ID.
+func (s *Server) GetMulticastGroup(ctx context.Context, req *ns.GetMulticastGroupRequest) (*ns.GetMulticastGroupResponse, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	e, err := get(s.multicastGroups, "multicast-group", req.GetId())
+	if err != nil {
+		return nil, err
+	}
+	return &ns.GetMulticastGroupResponse{
+		MulticastGroup: proto.Clone(e.msg).(*ns.MulticastGroup),
+		CreatedAt:      toTimestamp(e.createdAt),
+		UpdatedAt:      toTimestamp(e.updatedAt),
+	}, nil
+}
+
+// UpdateMulticastGroup updates the given multicast-group. The
+// service-profile and routing-profile must exist.
+func (s *Server) UpdateMulticastGroup(ctx context.Context, req *ns.UpdateMulticastGroupRequest) (*empty.Empty, error) {
+	mg := req.GetMulticastGroup()
+	if mg == nil {
+		return nil, missing("multicast_group")
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.validateMulticastGroup(mg); err != nil {
+		return nil, err
+	}
+	if err := update(s.multicastGroups, "multicast-group", mg.Id, mg); err != nil {
+		return nil, err
+	}
+	return &empty.Empty{}, nil
+}
+
+// DeleteMulticastGroup deletes the multicast-group for the given ID,
+// including its device memberships and queue.
+func (s *Server) DeleteMulticastGroup(ctx context.Context, req *ns.DeleteMulticastGroupRequest) (*empty.Empty, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	id := req.GetId()
+	if _, err := get(s.multicastGroups, "multicast-group", id); err != nil {
+		return nil, err
+	}
+
+	delete(s.multicastGroups, string(id))
+	delete(s.multicastDevices, string(id))
+	delete(s.multicastQueues, string(id))
+	return &empty.Empty{}, nil
+}
+
+// AddDeviceToMulticastGroup adds the device to the multicast-group.
+func (s *Server) AddDeviceToMulticastGroup(ctx context.Context, req *ns.AddDeviceToMulticastGroupRequest) (*empty.Empty, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, err := get(s.devices, "device", req.GetDevEui()); err != nil {
+		return nil, err
+	}
+	if _, err := get(s.multicastGroups, "multicast-group", req.GetMulticastGroupId()); err != nil {
+		return nil, err
+	}
+
+	key := string(req.MulticastGroupId)
+	if s.multicastDevices[key] == nil {
+		s.multicastDevices[key] = make(map[string]struct{})
+	}
+	if _, ok := s.multicastDevices[key][string(req.DevEui)]; ok {
+		return nil, status.Errorf(codes.AlreadyExists, "device %x is already in multicast-group %x", req.DevEui, req.MulticastGroupId)
+	}
+	s.multicastDevices[key][string(req.DevEui)] = struct{}{}
+
+	return &empty.Empty{}, nil
+}
+
+// RemoveDeviceFromMulticastGroup removes the device from the
+// multicast-group.
+func (s *Server) RemoveDeviceFromMulticastGroup(ctx context.Context, req *ns.RemoveDeviceFromMulticastGroupRequest) (*empty.Empty, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, err := get(s.multicastGroups, "multicast-group", req.GetMulticastGroupId()); err != nil {
+		return nil, err
+	}
+
+	devices := s.multicastDevices[string(req.MulticastGroupId)]
+	if _, ok := devices[string(req.GetDevEui())]; !ok {
+		return nil, status.Errorf(codes.NotFound, "device %x is not in multicast-group %x", req.DevEui, req.MulticastGroupId)
+	}
+	delete(devices, string(req.DevEui))
+
+	return &empty.Empty{}, nil
+}
+
+// EnqueueMulticastQueueItem adds the given item to the multicast-group
+// queue. The frame-counter must not be lower than the frame-counter of the
+// multicast-group, which is incremented to the frame-counter of the item + 1.
+func (s *Server) EnqueueMulticastQueueItem(ctx context.Context, req *ns.EnqueueMulticastQueueItemRequest) (*empty.Empty, error) {
+	item := req.GetMulticastQueueItem()
+	if item == nil {
+		return nil, missing("multicast_queue_item")
+	}
+	if item.FPort == 0 {
+		return nil, status.Error(codes.InvalidArgument, "multicast_queue_item.f_port must be > 0")
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	e, err := get(s.multicastGroups, "multicast-group", item.MulticastGroupId)
+	if err != nil {
+		return nil, err
+	}
+
+	mg := e.msg.(*ns.MulticastGroup)
+	if item.FCnt < mg.FCnt {
+		return nil, status.Errorf(codes.InvalidArgument, "multicast_queue_item.f_cnt must be >= %d", mg.FCnt)
+	}
+	mg.FCnt = item.FCnt + 1
+
+	key := string(item.MulticastGroupId)
+	queue := append(s.multicastQueues[key], proto.Clone(item).(*ns.MulticastQueueItem))
+	sort.SliceStable(queue, func(i, j int) bool {
+		return queue[i].FCnt < queue[j].FCnt
+	})
+	s.multicastQueues[key] = queue
+
+	return &empty.Empty{}, nil
+}
+
+// FlushMulticastQueueForMulticastGroup removes all items from the
+// multicast-group queue.
+func (s *Server) FlushMulticastQueueForMulticastGroup(ctx context.Context, req *ns.FlushMulticastQueueForMulticastGroupRequest) (*empty.Empty, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	id := req.GetMulticastGroupId()
+	if _, err := get(s.multicastGroups, "multicast-group", id); err != nil {
+		return nil, err
+	}
+
+	delete(s.multicastQueues, string(id))
+	return &empty.Empty{}, nil
+}
+
+// GetMulticastQueueItemsForMulticastGroup returns the multicast-group queue
+// items, ordered by frame-counter.
+func (s *Server) GetMulticastQueueItemsForMulticastGroup(ctx context.Context, req *ns.GetMulticastQueueItemsForMulticastGroupRequest) (*ns.GetMulticastQueueItemsForMulticastGroupResponse, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	id := req.GetMulticastGroupId()
+	if _, err := get(s.multicastGroups, "multicast-group", id); err != nil {
+		return nil, err
+	}
+
+	var resp ns.GetMulticastQueueItemsForMulticastGroupResponse
+	for _, item := range s.multicastQueues[string(id)] {
+		resp.MulticastQueueItems = append(resp.MulticastQueueItems, proto.Clone(item).(*ns.MulticastQueueItem))
+	}
+	return &resp, nil
+}
+
+// MulticastGroupDevices returns the DevEUIs of the devices in the given
+// multicast-group, in sorted order.
+func (s *Server) MulticastGroupDevices(multicastGroupID []byte) [][]byte {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var out [][]byte
+	for devEUI := range s.multicastDevices[string(multicastGroupID)] {
+		out = append(out, []byte(devEUI))
+	}
+	sort.Slice(out, func(i, j int) bool {
+		return string(out[i]) < string(out[j])
+	})
+	return out
+}
+
+// validateMulticastGroup validates the multicast-group and the objects it
+// refers to. The caller must hold the lock.
+func (s *Server) validateMulticastGroup(mg *ns.MulticastGroup) error {
+	if err := checkUUID("multicast_group.id", mg.Id); err != nil {
+		return err
+	}
+	if err := checkID("multicast_group.mc_addr", mg.McAddr, 4); err != nil {
+		return err
+	}
+	if err := checkID("multicast_group.mc_nwk_s_key", mg.McNwkSKey, 16); err != nil {
+		return err
+	}
+	for _, ref := range []struct {
+		field string
+		kind  string
+		id    []byte
+		m     map[string]*entry
+	}{
+		{"multicast_group.service_profile_id", "service-profile", mg.ServiceProfileId, s.serviceProfiles},
+		{"multicast_group.routing_profile_id", "routing-profile", mg.RoutingProfileId, s.routingProfiles},
+	} {
+		if err := checkUUID(ref.field, ref.id); err != nil {
+			return err
+		}
+		if _, err := get(ref.m, ref.kind, ref.id); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,346 @@
+package nstest
+
+import (
+	"context"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/golang/protobuf/ptypes/empty"
+
+	"github.com/brocaar/chirpstack-api/go/ns"
+)
+
+// CreateServiceProfile creates the given service-profile. A random ID is
+// assigned when the ID is not set.
+func (s *Server) CreateServiceProfile(ctx context.Context, req *ns.CreateServiceProfileRequest) (*ns.CreateServiceProfileResponse, error) {
+	sp := req.GetServiceProfile()
+	if sp == nil {
+		return nil, missing("service_profile")
+	}
+	sp = copyServiceProfile(sp)
+	if len(sp.Id) == 0 {
+		sp.Id = newID()
+	}
+	if err := checkUUID("service_profile.id", sp.Id); err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := create(s.serviceProfiles, "service-profile", sp.Id, sp); err != nil {
+		return nil, err
+	}
+	return &ns.CreateServiceProfileResponse{Id: sp.Id}, nil
+}
+
+// GetServiceProfile returns the service-profile for the given ID.
+func (s *Server) GetServiceProfile(ctx context.Context, req *ns.GetServiceProfileRequest) (*ns.GetServiceProfileResponse, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	e, err := get(s.serviceProfiles, "service-profile", req.GetId())
+	if err != nil {
+		return nil, err
+	}
+	return &ns.GetServiceProfileResponse{
+		ServiceProfile: copyServiceProfile(e.msg.(*ns.ServiceProfile)),
+		CreatedAt:      toTimestamp(e.createdAt),
+		UpdatedAt:      toTimestamp(e.updatedAt),
+	}, nil
+}
+
+// UpdateServiceProfile updates the given service-profile.
+func (s *Server) UpdateServiceProfile(ctx context.Context, req *ns.UpdateServiceProfileRequest) (*empty.Empty, error) {
+	sp := req.GetServiceProfile()
+	if sp == nil {
+		return nil, missing("service_profile")
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := update(s.serviceProfiles, "service-profile", sp.Id, sp); err != nil {
+		return nil, err
+	}
+	return &empty.Empty{}, nil
+}
+
+// DeleteServiceProfile deletes the service-profile for the given ID. It
+// fails when the service-profile is used by a device or multicast-group.
+func (s *Server) DeleteServiceProfile(ctx context.Context, req *ns.DeleteServiceProfileRequest) (*empty.Empty, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	id := req.GetId()
+	if _, err := get(s.serviceProfiles, "service-profile", id); err != nil {
+		return nil, err
+	}
+	for _, e := range s.devices {
+		if string(e.msg.(*ns.Device).ServiceProfileId) == string(id) {
+			return nil, inUse("service-profile", id, "devices")
+		}
+	}
+	for _, e := range s.multicastGroups {
+		if string(e.msg.(*ns.MulticastGroup).ServiceProfileId) == string(id) {
+			return nil, inUse("service-profile", id, "multicast-groups")
+		}
+	}
+
+	delete(s.serviceProfiles, string(id))
+	return &empty.Empty{}, nil
+}
+
+// CreateRoutingProfile creates the given routing-profile. A random ID is
+// assigned when the ID is not set.
+func (s *Server) CreateRoutingProfile(ctx context.Context, req *ns.CreateRoutingProfileRequest) (*ns.CreateRoutingProfileResponse, error) {
+	rp := req.GetRoutingProfile()
+	if rp == nil {
+		return nil, missing("routing_profile")
+	}
+	rp = copyRoutingProfile(rp)
+	if len(rp.Id) == 0 {
+		rp.Id = newID()
+	}
+	if err := checkUUID("routing_profile.id", rp.Id); err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := create(s.routingProfiles, "routing-profile", rp.Id, rp); err != nil {
+		return nil, err
+	}
+	return &ns.CreateRoutingProfileResponse{Id: rp.Id}, nil
+}
+
+// GetRoutingProfile returns the routing-profile for the given ID.
+func (s *Server) GetRoutingProfile(ctx context.Context, req *ns.GetRoutingProfileRequest) (*ns.GetRoutingProfileResponse, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	e, err := get(s.routingProfiles, "routing-profile", req.GetId())
+	if err != nil {
+		return nil, err
+	}
+	return &ns.GetRoutingProfileResponse{
+		RoutingProfile: copyRoutingProfile(e.msg.(*ns.RoutingProfile)),
+		CreatedAt:      toTimestamp(e.createdAt),
+		UpdatedAt:      toTimestamp(e.updatedAt),
+	}, nil
+}
+
+// UpdateRoutingProfile updates the given routing-profile.
+func (s *Server) UpdateRoutingProfile(ctx context.Context, req *ns.UpdateRoutingProfileRequest) (*empty.Empty, error) {
+	rp := req.GetRoutingProfile()
+	if rp == nil {
+		return nil, missing("routing_profile")
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := update(s.routingProfiles, "routing-profile", rp.Id, rp); err != nil {
+		return nil, err
+	}
+	return &empty.Empty{}, nil
+}
+
+// DeleteRoutingProfile deletes the routing-profile for the given ID. It
+// fails when the routing-profile is used by a device, gateway or
+// multicast-group.
+func (s *Server) DeleteRoutingProfile(ctx context.Context, req *ns.DeleteRoutingProfileRequest) (*empty.Empty, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	id := req.GetId()
+	if _, err := get(s.routingProfiles, "routing-profile", id); err != nil {
+		return nil, err
+	}
+	for _, e := range s.devices {
+		if string(e.msg.(*ns.Device).RoutingProfileId) == string(id) {
+			return nil, inUse("routing-profile", id, "devices")
+		}
+	}
+	for _, e := range s.gateways {
+		if string(e.msg.(*ns.Gateway).RoutingProfileId) == string(id) {
+			return nil, inUse("routing-profile", id, "gateways")
+		}
+	}
+	for _, e := range s.multicastGroups {
+		if string(e.msg.(*ns.MulticastGroup).RoutingProfileId) == string(id) {
+			return nil, inUse("routing-profile", id, "multicast-groups")
+		}
+	}
+
+	delete(s.routingProfiles, string(id))
+	return &empty.Empty{}, nil
+}
+
+// CreateDeviceProfile creates the given device-profile. A random ID is
+// assigned when the ID is not set.
+func (s *Server) CreateDeviceProfile(ctx context.Context, req *ns.CreateDeviceProfileRequest) (*ns.CreateDeviceProfileResponse, error) {
+	dp := req.GetDeviceProfile()
+	if dp == nil {
+		return nil, missing("device_profile")
+	}
+	dp = copyDeviceProfile(dp)
+	if len(dp.Id) == 0 {
+		dp.Id = newID()
+	}
+	if err := checkUUID("device_profile.id", dp.Id); err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := create(s.deviceProfiles, "device-profile", dp.Id, dp); err != nil {
+		return nil, err
+	}
+	return &ns.CreateDeviceProfileResponse{Id: dp.Id}, nil
+}
+
+// GetDeviceProfile returns the device-profile for the given ID.
+func (s *Server) GetDeviceProfile(ctx context.Context, req *ns.GetDeviceProfileRequest) (*ns.GetDeviceProfileResponse, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	e, err := get(s.deviceProfiles, "device-profile", req.GetId())
+	if err != nil {
+		return nil, err
+	}
+	return &ns.GetDeviceProfileResponse{
+		DeviceProfile: copyDeviceProfile(e.msg.(*ns.DeviceProfile)),
+		CreatedAt:     toTimestamp(e.createdAt),
+		UpdatedAt:     toTimestamp(e.updatedAt),
+	}, nil
+}
+
+// UpdateDeviceProfile updates the given device-profile.
+func (s *Server) UpdateDeviceProfile(ctx context.Context, req *ns.UpdateDeviceProfileRequest) (*empty.Empty, error) {
+	dp := req.GetDeviceProfile()
+	if dp == nil {
+		return nil, missing("device_profile")
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := update(s.deviceProfiles, "device-profile", dp.Id, dp); err != nil {
+		return nil, err
+	}
+	return &empty.Empty{}, nil
+}
+
+// DeleteDeviceProfile deletes the device-profile for the given ID. It fails
+// when the device-profile is used by a device.
+func (s *Server) DeleteDeviceProfile(ctx context.Context, req *ns.DeleteDeviceProfileRequest) (*empty.Empty, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	id := req.GetId()
+	if _, err := get(s.deviceProfiles, "device-profile", id); err != nil {
+		return nil, err
+	}
+	for _, e := range s.devices {
+		if string(e.msg.(*ns.Device).DeviceProfileId) == string(id) {
+			return nil, inUse("device-profile", id, "devices")
+		}
+	}
+
+	delete(s.deviceProfiles, string(id))
+	return &empty.Empty{}, nil
+}
+
+// CreateGatewayProfile creates the given gateway-profile. A random ID is
+// assigned when the ID is not set.
+func (s *Server) CreateGatewayProfile(ctx context.Context, req *ns.CreateGatewayProfileRequest) (*ns.CreateGatewayProfileResponse, error) {
+	gp := req.GetGatewayProfile()
+	if gp == nil {
+		return nil, missing("gateway_profile")
+	}
+	gp = copyGatewayProfile(gp)
+	if len(gp.Id) == 0 {
+		gp.Id = newID()
+	}
+	if err := checkUUID("gateway_profile.id", gp.Id); err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := create(s.gatewayProfiles, "gateway-profile", gp.Id, gp); err != nil {
+		return nil, err
+	}
+	return &ns.CreateGatewayProfileResponse{Id: gp.Id}, nil
+}
+
+// GetGatewayProfile returns the gateway-profile for the given ID.
+func (s *Server) GetGatewayProfile(ctx context.Context, req *ns.GetGatewayProfileRequest) (*ns.GetGatewayProfileResponse, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	e, err := get(s.gatewayProfiles, "gateway-profile", req.GetId())
+	if err != nil {
+		return nil, err
+	}
+	return &ns.GetGatewayProfileResponse{
+		GatewayProfile: copyGatewayProfile(e.msg.(*ns.GatewayProfile)),
+		CreatedAt:      toTimestamp(e.createdAt),
+		UpdatedAt:      toTimestamp(e.updatedAt),
+	}, nil
+}
+
+// UpdateGatewayProfile updates the given gateway-profile.
+func (s *Server) UpdateGatewayProfile(ctx context.Context, req *ns.UpdateGatewayProfileRequest) (*empty.Empty, error) {
+	gp := req.GetGatewayProfile()
+	if gp == nil {
+		return nil, missing("gateway_profile")
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := update(s.gatewayProfiles, "gateway-profile", gp.Id, gp); err != nil {
+		return nil, err
+	}
+	return &empty.Empty{}, nil
+}
+
+// DeleteGatewayProfile deletes the gateway-profile for the given ID. It fails
+// when the gateway-profile is used by a gateway.
+func (s *Server) DeleteGatewayProfile(ctx context.Context, req *ns.DeleteGatewayProfileRequest) (*empty.Empty, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	id := req.GetId()
+	if _, err := get(s.gatewayProfiles, "gateway-profile", id); err != nil {
+		return nil, err
+	}
+	for _, e := range s.gateways {
+		if string(e.msg.(*ns.Gateway).GatewayProfileId) == string(id) {
+			return nil, inUse("gateway-profile", id, "gateways")
+		}
+	}
+
+	delete(s.gatewayProfiles, string(id))
+	return &empty.Empty{}, nil
+}
+
+func copyServiceProfile(sp *ns.ServiceProfile) *ns.ServiceProfile {
+	return proto.Clone(sp).(*ns.ServiceProfile)
+}
+
+func copyRoutingProfile(rp *ns.RoutingProfile) *ns.RoutingProfile {
+	return proto.Clone(rp).(*ns.RoutingProfile)
+}
+
+func copyDeviceProfile(dp *ns.DeviceProfile) *ns.DeviceProfile {
+	return proto.Clone(dp).(*ns.DeviceProfile)
+}
+
+func copyGatewayProfile(gp *ns.GatewayProfile) *ns.GatewayProfile {
+	return proto.Clone(gp).(*ns.GatewayProfile)
+}
@@ -0,0 +1,284 @@
+// Package nstest provides an in-memory implementation of the
+// NetworkServerService for testing code which uses the
+// ns.NetworkServerServiceClient.
+//
+// The Server keeps its state in memory and enforces basic referential
+// integrity. Errors are returned using the gRPC status codes used by the
+// network-server:
+//
+//   - InvalidArgument when a required field is missing or has an invalid length
+//   - NotFound when an object, or an object it refers to, does not exist
+//   - AlreadyExists when an object with the same ID already exists
+//   - FailedPrecondition when deleting an object which is still referred to, or
+//     when a device must be activated first
+//
+// The Server can be registered with any grpc.Server, or be served over an
+// in-memory listener using Dial:
+//
+//	srv := nstest.NewServer()
+//	defer srv.Close()
+//
+//	client, err := srv.Client(ctx)
+package nstest
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/binary"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/golang/protobuf/ptypes"
+	"github.com/golang/protobuf/ptypes/empty"
+	"github.com/golang/protobuf/ptypes/timestamp"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/brocaar/chirpstack-api/go/common"
+	"github.com/brocaar/chirpstack-api/go/internal/grpctest"
+	"github.com/brocaar/chirpstack-api/go/lorawan"
+	"github.com/brocaar/chirpstack-api/go/ns"
+)
+
+var _ ns.NetworkServerServiceServer = (*Server)(nil)
+
+// Option configures the Server.
+type Option func(*Server)
+
+// WithVersion sets the version and region returned by GetVersion (default
+// "nstest" and EU868).
+func WithVersion(version string, region common.Region) Option {
+	return func(s *Server) {
+		s.version = version
+		s.region = region
+	}
+}
+
+// WithNetID sets the NetID used by GetRandomDevAddr (default 000000).
+func WithNetID(netID lorawan.NetID) Option {
+	return func(s *Server) {
+		s.netID = netID
+	}
+}
+
+// entry holds a stored object together with its timestamps.
+type entry struct {
+	msg       proto.Message
+	createdAt time.Time
+	updatedAt time.Time
+}
+
+// Server implements an in-memory ns.NetworkServerServiceServer.
+type Server struct {
+	version string
+	region  common.Region
+	netID   lorawan.NetID
+
+	grpc *grpctest.Server
+
+	mu sync.Mutex
+
+	// The maps are keyed by the string of the object ID bytes.
+	serviceProfiles  map[string]*entry
+	routingProfiles  map[string]*entry
+	deviceProfiles   map[string]*entry
+	gatewayProfiles  map[string]*entry
+	devices          map[string]*entry
+	activations      map[string]*ns.DeviceActivation
+	deviceQueues     map[string][]*ns.DeviceQueueItem
+	macCommands      map[string][]*ns.CreateMACCommandQueueItemRequest
+	gateways         map[string]*entry
+	gatewayStats     map[string][]*ns.GatewayStats
+	multicastGroups  map[string]*entry
+	multicastDevices map[string]map[string]struct{}
+	multicastQueues  map[string][]*ns.MulticastQueueItem
+
+	proprietaryPayloads []*ns.SendProprietaryPayloadRequest
+
+	gatewayFrameSubs map[string][]chan *ns.StreamFrameLogsForGatewayResponse
+	deviceFrameSubs  map[string][]chan *ns.StreamFrameLogsForDeviceResponse
+}
+
+// NewServer returns a new, empty Server.
+func NewServer(opts ...Option) *Server {
+	s := &Server{
+		version: "nstest",
+		region:  common.Region_EU868,
+
+		serviceProfiles:  make(map[string]*entry),
+		routingProfiles:  make(map[string]*entry),
+		deviceProfiles:   make(map[string]*entry),
+		gatewayProfiles:  make(map[string]*entry),
+		devices:          make(map[string]*entry),
+		activations:      make(map[string]*ns.DeviceActivation),
+		deviceQueues:     make(map[string][]*ns.DeviceQueueItem),
+		macCommands:      make(map[string][]*ns.CreateMACCommandQueueItemRequest),
+		gateways:         make(map[string]*entry),
+		gatewayStats:     make(map[string][]*ns.GatewayStats),
+		multicastGroups:  make(map[string]*entry),
+		multicastDevices: make(map[string]map[string]struct{}),
+		multicastQueues:  make(map[string][]*ns.MulticastQueueItem),
+
+		gatewayFrameSubs: make(map[string][]chan *ns.StreamFrameLogsForGatewayResponse),
+		deviceFrameSubs:  make(map[string][]chan *ns.StreamFrameLogsForDeviceResponse),
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	s.grpc = grpctest.NewServer(func(gs *grpc.Server) {
+		ns.RegisterNetworkServerServiceServer(gs, s)
+	})
+
+	return s
+}
+
+// Dial returns a connection to the Server, served over an in-memory
+// listener.
+func (s *Server) Dial(ctx context.Context, opts ...grpc.DialOption) (*grpc.ClientConn, error) {
+	return s.grpc.Dial(ctx, opts...)
+}
+
+// Client returns a NetworkServerService client connected to the Server.
+func (s *Server) Client(ctx context.Context, opts ...grpc.DialOption) (ns.NetworkServerServiceClient, error) {
+	conn, err := s.Dial(ctx, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return ns.NewNetworkServerServiceClient(conn), nil
+}
+
+// Close closes the connections returned by Dial and stops serving.
+func (s *Server) Close() error {
+	return s.grpc.Close()
+}
+
+// GetRandomDevAddr returns a random DevAddr matching the configured NetID.
+func (s *Server) GetRandomDevAddr(ctx context.Context, req *empty.Empty) (*ns.GetRandomDevAddrResponse, error) {
+	var b [4]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+
+	nwkAddr := binary.BigEndian.Uint32(b[:]) & (1<<s.netID.NwkAddrBits() - 1)
+	devAddr, err := lorawan.NewDevAddr(s.netID, nwkAddr)
+	if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+
+	return &ns.GetRandomDevAddrResponse{
+		DevAddr: devAddr.Bytes(),
+	}, nil
+}
+
+// GetVersion returns the configured version and region.
+func (s *Server) GetVersion(ctx context.Context, req *empty.Empty) (*ns.GetVersionResponse, error) {
+	return &ns.GetVersionResponse{
+		Version: s.version,
+		Region:  s.region,
+	}, nil
+}
+
+// newID returns a random (version 4) UUID.
+func newID() []byte {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		panic(err)
+	}
+	b[6] = (b[6] & 0x0f) | 0x40
+	b[8] = (b[8] & 0x3f) | 0x80
+	return b
+}
+
+// checkID returns an InvalidArgument error when the ID does not have the
+// expected length.
+func checkID(name string, id []byte, size int) error {
+	if len(id) != size {
+		return status.Errorf(codes.InvalidArgument, "%s must be %d bytes, got %d", name, size, len(id))
+	}
+	return nil
+}
+
+// checkUUID returns an InvalidArgument error when the ID is not a UUID.
+func checkUUID(name string, id []byte) error {
+	return checkID(name, id, 16)
+}
+
+// checkEUI64 returns an InvalidArgument error when the ID is not an EUI64.
+func checkEUI64(name string, id []byte) error {
+	return checkID(name, id, 8)
+}
+
+// missing returns an InvalidArgument error for a missing field.
+func missing(name string) error {
+	return status.Errorf(codes.InvalidArgument, "%s must not be nil", name)
+}
+
+func notFound(kind string, id []byte) error {
+	return status.Errorf(codes.NotFound, "%s %x does not exist", kind, id)
+}
+
+func alreadyExists(kind string, id []byte) error {
+	return status.Errorf(codes.AlreadyExists, "%s %x already exists", kind, id)
+}
+
+func inUse(kind string, id []byte, by string) error {
+	return status.Errorf(codes.FailedPrecondition, "%s %x is still used by one or more %s", kind, id, by)
+}
+
+// create stores a copy of msg under id.
+func create(m map[string]*entry, kind string, id []byte, msg proto.Message) error {
+	if _, ok := m[string(id)]; ok {
+		return alreadyExists(kind, id)
+	}
+	now := time.Now()
+	m[string(id)] = &entry{
+		msg:       proto.Clone(msg),
+		createdAt: now,
+		updatedAt: now,
+	}
+	return nil
+}
+
+// get returns the stored entry for id.
+func get(m map[string]*entry, kind string, id []byte) (*entry, error) {
+	e, ok := m[string(id)]
+	if !ok {
+		return nil, notFound(kind, id)
+	}
+	return e, nil
+}
+
+// update replaces the object stored under id by a copy of msg.
+func update(m map[string]*entry, kind string, id []byte, msg proto.Message) error {
+	e, ok := m[string(id)]
+	if !ok {
+		return notFound(kind, id)
+	}
+	e.msg = proto.Clone(msg)
+	e.updatedAt = time.Now()
+	return nil
+}
+
+// exists returns a NotFound error when id is set, but does not exist in m.
+// An unset (empty) id is accepted.
+func exists(m map[string]*entry, kind string, id []byte) error {
+	if len(id) == 0 {
+		return nil
+	}
+	if _, ok := m[string(id)]; !ok {
+		return notFound(kind, id)
+	}
+	return nil
+}
+
+func toTimestamp(t time.Time) *timestamp.Timestamp {
+	ts, err := ptypes.TimestampProto(t)
+	if err != nil {
+		panic(fmt.Sprintf("nstest: invalid timestamp: %s", err))
+	}
+	return ts
+}
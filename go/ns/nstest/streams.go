@@ -0,0 +1,123 @@
+package nstest
+
+import (
+	"github.com/golang/protobuf/proto"
+
+	"github.com/brocaar/chirpstack-api/go/ns"
+)
+
+// streamBufferSize defines the number of frames buffered per subscriber.
+// Frames are dropped when the buffer of a subscriber is full.
+const streamBufferSize = 100
+
+// StreamFrameLogsForGateway streams the frames published using
+// PublishGatewayFrameLog for the given gateway, until the client cancels the
+// stream.
+func (s *Server) StreamFrameLogsForGateway(req *ns.StreamFrameLogsForGatewayRequest, stream ns.NetworkServerService_StreamFrameLogsForGatewayServer) error {
+	key := string(req.GetGatewayId())
+	c := make(chan *ns.StreamFrameLogsForGatewayResponse, streamBufferSize)
+
+	s.mu.Lock()
+	if _, err := get(s.gateways, "gateway", req.GetGatewayId()); err != nil {
+		s.mu.Unlock()
+		return err
+	}
+	s.gatewayFrameSubs[key] = append(s.gatewayFrameSubs[key], c)
+	s.mu.Unlock()
+
+	defer func() {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		subs := s.gatewayFrameSubs[key]
+		for i := range subs {
+			if subs[i] == c {
+				s.gatewayFrameSubs[key] = append(subs[:i], subs[i+1:]...)
+				break
+			}
+		}
+	}()
+
+	for {
+		select {
+		case resp := <-c:
+			if err := stream.Send(resp); err != nil {
+				return err
+			}
+		case <-stream.Context().Done():
+			return nil
+		}
+	}
+}
+
+// StreamFrameLogsForDevice streams the frames published using
+// PublishDeviceFrameLog for the given device, until the client cancels the
+// stream.
+func (s *Server) StreamFrameLogsForDevice(req *ns.StreamFrameLogsForDeviceRequest, stream ns.NetworkServerService_StreamFrameLogsForDeviceServer) error {
+	key := string(req.GetDevEui())
+	c := make(chan *ns.StreamFrameLogsForDeviceResponse, streamBufferSize)
+
+	s.mu.Lock()
+	if _, err := get(s.devices, "device", req.GetDevEui()); err != nil {
+		s.mu.Unlock()
+		return err
+	}
+	s.deviceFrameSubs[key] = append(s.deviceFrameSubs[key], c)
+	s.mu.Unlock()
+
+	defer func() {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		subs := s.deviceFrameSubs[key]
+		for i := range subs {
+			if subs[i] == c {
+				s.deviceFrameSubs[key] = append(subs[:i], subs[i+1:]...)
+				break
+			}
+		}
+	}()
+
+	for {
+		select {
+		case resp := <-c:
+			if err := stream.Send(resp); err != nil {
+				return err
+			}
+		case <-stream.Context().Done():
+			return nil
+		}
+	}
+}
+
+// PublishGatewayFrameLog sends the frame to the open frame-log streams of
+// the given gateway. It returns the number of streams the frame was sent to.
+func (s *Server) PublishGatewayFrameLog(gatewayID []byte, frame *ns.StreamFrameLogsForGatewayResponse) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var n int
+	for _, c := range s.gatewayFrameSubs[string(gatewayID)] {
+		select {
+		case c <- proto.Clone(frame).(*ns.StreamFrameLogsForGatewayResponse):
+			n++
+		default:
+		}
+	}
+	return n
+}
+
+// PublishDeviceFrameLog sends the frame to the open frame-log streams of the
+// given device. It returns the number of streams the frame was sent to.
+func (s *Server) PublishDeviceFrameLog(devEUI []byte, frame *ns.StreamFrameLogsForDeviceResponse) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var n int
+	for _, c := range s.deviceFrameSubs[string(devEUI)] {
+		select {
+		case c <- proto.Clone(frame).(*ns.StreamFrameLogsForDeviceResponse):
+			n++
+		default:
+		}
+	}
+	return n
+}
@@ -0,0 +1,47 @@
+package ns
+
+import (
+	"github.com/brocaar/chirpstack-api/go/common"
+	"github.com/brocaar/chirpstack-api/go/validation"
+)
+
+// Validate validates the device-profile against the Regional Parameters of
+// its rf_region. It returns validation.Errors when one or more fields are
+// invalid.
+func (m *DeviceProfile) Validate() error {
+	return validation.ValidateDeviceProfile(validation.DeviceProfile{
+		RFRegion:           m.GetRfRegion(),
+		MACVersion:         m.GetMacVersion(),
+		RegParamsRevision:  m.GetRegParamsRevision(),
+		RXDelay1:           m.GetRxDelay_1(),
+		RXDROffset1:        m.GetRxDrOffset_1(),
+		RXDataRate2:        m.GetRxDatarate_2(),
+		RXFreq2:            m.GetRxFreq_2(),
+		FactoryPresetFreqs: m.GetFactoryPresetFreqs(),
+		SupportsClassB:     m.GetSupportsClassB(),
+		PingSlotPeriod:     m.GetPingSlotPeriod(),
+		PingSlotDR:         m.GetPingSlotDr(),
+		PingSlotFreq:       m.GetPingSlotFreq(),
+	})
+}
+
+// Validate validates the service-profile. It returns validation.Errors when
+// one or more fields are invalid.
+func (m *ServiceProfile) Validate() error {
+	return validation.ValidateServiceProfile(m.validationServiceProfile())
+}
+
+// ValidateForRegion validates the service-profile, including that dr_min and
+// dr_max are valid uplink data-rates of the given region.
+func (m *ServiceProfile) ValidateForRegion(region common.Region) error {
+	return validation.ValidateServiceProfileForRegion(m.validationServiceProfile(), region)
+}
+
+func (m *ServiceProfile) validationServiceProfile() validation.ServiceProfile {
+	return validation.ServiceProfile{
+		DRMin:            m.GetDrMin(),
+		DRMax:            m.GetDrMax(),
+		TargetPER:        m.GetTargetPer(),
+		DevStatusReqFreq: m.GetDevStatusReqFreq(),
+	}
+}
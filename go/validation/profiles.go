@@ -0,0 +1,172 @@
+package validation
+
+import (
+	"fmt"
+
+	"github.com/brocaar/chirpstack-api/go/band"
+	"github.com/brocaar/chirpstack-api/go/common"
+)
+
+// MACVersions contains the supported LoRaWAN MAC versions.
+var MACVersions = []string{"1.0.0", "1.0.1", "1.0.2", "1.0.3", "1.0.4", "1.1.0"}
+
+// RegParamsRevisions contains the supported Regional Parameters revisions.
+var RegParamsRevisions = []string{"A", "B", "RP002-1.0.0", "RP002-1.0.1", "RP002-1.0.2", "RP002-1.0.3"}
+
+// DeviceProfile contains the device-profile fields which are validated. It
+// is shared by the api and ns DeviceProfile messages.
+type DeviceProfile struct {
+	RFRegion           string
+	MACVersion         string
+	RegParamsRevision  string
+	RXDelay1           uint32
+	RXDROffset1        uint32
+	RXDataRate2        uint32
+	RXFreq2            uint32
+	FactoryPresetFreqs []uint32
+	SupportsClassB     bool
+	PingSlotPeriod     uint32
+	PingSlotDR         uint32
+	PingSlotFreq       uint32
+}
+
+// ValidateDeviceProfile validates the device-profile. It returns Errors
+// when one or more fields are invalid. The region dependent fields are only
+// validated when the RF region is valid.
+//
+// A zero RX2 data-rate, RX2 frequency and ping-slot frequency means that the
+// region default is used (e.g. DR8 as RX2 data-rate for US915, in which DR0
+// is an uplink-only data-rate).
+func ValidateDeviceProfile(dp DeviceProfile) error {
+	var c collector
+
+	var b *band.Band
+	if region, ok := common.Region_value[dp.RFRegion]; ok {
+		b, _ = band.Get(common.Region(region))
+	} else {
+		c.add("rf_region", "unknown region %q", dp.RFRegion)
+	}
+
+	if !contains(MACVersions, dp.MACVersion) {
+		c.add("mac_version", "unsupported MAC version %q", dp.MACVersion)
+	}
+	if !contains(RegParamsRevisions, dp.RegParamsRevision) {
+		c.add("reg_params_revision", "unsupported Regional Parameters revision %q", dp.RegParamsRevision)
+	}
+	if dp.RXDelay1 > 15 {
+		c.add("rx_delay_1", "must be <= 15, got %d", dp.RXDelay1)
+	}
+	if dp.RXDROffset1 > 7 {
+		c.add("rx_dr_offset_1", "must be <= 7, got %d", dp.RXDROffset1)
+	}
+	if dp.SupportsClassB && !validPingSlotPeriod(dp.PingSlotPeriod) {
+		c.add("ping_slot_period", "must be 32 * 2^k (k = 0 - 7) when class-b is supported, got %d", dp.PingSlotPeriod)
+	}
+
+	if b != nil {
+		if dp.RXDataRate2 != 0 {
+			checkDownlinkDR(&c, b, "rx_datarate_2", dp.RXDataRate2)
+		}
+		if dp.RXFreq2 != 0 {
+			checkFrequency(&c, b, "rx_freq_2", dp.RXFreq2)
+		}
+		for i, f := range dp.FactoryPresetFreqs {
+			checkFrequency(&c, b, fmt.Sprintf("factory_preset_freqs[%d]", i), f)
+		}
+		if dp.SupportsClassB {
+			checkDownlinkDR(&c, b, "ping_slot_dr", dp.PingSlotDR)
+			if dp.PingSlotFreq != 0 {
+				checkFrequency(&c, b, "ping_slot_freq", dp.PingSlotFreq)
+			}
+		}
+	}
+
+	return c.err()
+}
+
+// ServiceProfile contains the service-profile fields which are validated. It
+// is shared by the api and ns ServiceProfile messages.
+type ServiceProfile struct {
+	DRMin            uint32
+	DRMax            uint32
+	TargetPER        uint32
+	DevStatusReqFreq uint32
+}
+
+// ValidateServiceProfile validates the service-profile. It returns Errors
+// when one or more fields are invalid.
+func ValidateServiceProfile(sp ServiceProfile) error {
+	var c collector
+	checkServiceProfile(&c, sp)
+	return c.err()
+}
+
+// ValidateServiceProfileForRegion validates the service-profile, including
+// that the data-rate range exists within the given region.
+func ValidateServiceProfileForRegion(sp ServiceProfile, region common.Region) error {
+	var c collector
+	checkServiceProfile(&c, sp)
+
+	b, err := band.Get(region)
+	if err != nil {
+		return err
+	}
+	checkUplinkDR(&c, b, "dr_min", sp.DRMin)
+	checkUplinkDR(&c, b, "dr_max", sp.DRMax)
+
+	return c.err()
+}
+
+func checkServiceProfile(c *collector, sp ServiceProfile) {
+	if sp.DRMax > 15 {
+		c.add("dr_max", "must be <= 15, got %d", sp.DRMax)
+	}
+	if sp.DRMin > sp.DRMax {
+		c.add("dr_min", "must be <= dr_max (%d), got %d", sp.DRMax, sp.DRMin)
+	}
+	if sp.TargetPER > 100 {
+		c.add("target_per", "must be <= 100, got %d", sp.TargetPER)
+	}
+	if sp.DevStatusReqFreq > 1440 {
+		c.add("dev_status_req_freq", "must be <= 1440, got %d", sp.DevStatusReqFreq)
+	}
+}
+
+func checkDownlinkDR(c *collector, b *band.Band, field string, dr uint32) {
+	d, err := b.GetDataRate(int(dr))
+	if err != nil || !d.Downlink {
+		c.add(field, "data-rate %d is not a valid downlink data-rate for %s", dr, b.Region)
+	}
+}
+
+func checkUplinkDR(c *collector, b *band.Band, field string, dr uint32) {
+	d, err := b.GetDataRate(int(dr))
+	if err != nil || !d.Uplink {
+		c.add(field, "data-rate %d is not a valid uplink data-rate for %s", dr, b.Region)
+	}
+}
+
+func checkFrequency(c *collector, b *band.Band, field string, freq uint32) {
+	if !b.ContainsFrequency(freq) {
+		c.add(field, "frequency %d is not within the %s band (%d - %d)", freq, b.Region, b.MinFrequency, b.MaxFrequency)
+	}
+}
+
+// validPingSlotPeriod returns true for the periods 32 * 2^k, k = 0 - 7.
+func validPingSlotPeriod(p uint32) bool {
+	for k := uint(0); k <= 7; k++ {
+		if p == 32<<k {
+			return true
+		}
+	}
+	return false
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,275 @@
+package validation
+
+import (
+	"sort"
+	"testing"
+
+	"github.com/brocaar/chirpstack-api/go/common"
+)
+
+// fields returns the sorted field names of the validation errors.
+func fields(t *testing.T, err error) []string {
+	t.Helper()
+
+	if err == nil {
+		return nil
+	}
+	errs, ok := err.(Errors)
+	if !ok {
+		t.Fatalf("expected Errors, got %T: %s", err, err)
+	}
+
+	var out []string
+	for _, fe := range errs {
+		out = append(out, fe.Field)
+	}
+	sort.Strings(out)
+	return out
+}
+
+func equalFields(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func TestValidateDeviceProfile(t *testing.T) {
+	valid := func(region string) DeviceProfile {
+		return DeviceProfile{
+			RFRegion:          region,
+			MACVersion:        "1.0.3",
+			RegParamsRevision: "B",
+			RXDelay1:          1,
+		}
+	}
+
+	tests := []struct {
+		name   string
+		dp     func() DeviceProfile
+		fields []string
+	}{
+		{
+			name: "EU868 defaults",
+			dp:   func() DeviceProfile { return valid("EU868") },
+		},
+		{
+			name: "US915 defaults",
+			dp:   func() DeviceProfile { return valid("US915") },
+		},
+		{
+			name: "AU915 defaults",
+			dp:   func() DeviceProfile { return valid("AU915") },
+		},
+		{
+			name: "EU868 RX2 and factory preset frequencies",
+			dp: func() DeviceProfile {
+				dp := valid("EU868")
+				dp.RXDataRate2 = 3
+				dp.RXFreq2 = 869525000
+				dp.FactoryPresetFreqs = []uint32{868100000, 868300000, 868500000}
+				return dp
+			},
+		},
+		{
+			name: "EU868 RX2 frequency outside the band",
+			dp: func() DeviceProfile {
+				dp := valid("EU868")
+				dp.RXFreq2 = 923300000
+				dp.FactoryPresetFreqs = []uint32{868100000, 902300000}
+				return dp
+			},
+			fields: []string{"factory_preset_freqs[1]", "rx_freq_2"},
+		},
+		{
+			name: "US915 RX2 frequency outside the band",
+			dp: func() DeviceProfile {
+				dp := valid("US915")
+				dp.RXDataRate2 = 8
+				dp.RXFreq2 = 869525000
+				return dp
+			},
+			fields: []string{"rx_freq_2"},
+		},
+		{
+			name: "EU868 undefined RX2 data-rate",
+			dp: func() DeviceProfile {
+				dp := valid("EU868")
+				dp.RXDataRate2 = 8
+				return dp
+			},
+			fields: []string{"rx_datarate_2"},
+		},
+		{
+			name: "US915 uplink-only RX2 data-rate",
+			dp: func() DeviceProfile {
+				dp := valid("US915")
+				dp.RXDataRate2 = 3
+				return dp
+			},
+			fields: []string{"rx_datarate_2"},
+		},
+		{
+			name: "US915 RFU RX2 data-rate",
+			dp: func() DeviceProfile {
+				dp := valid("US915")
+				dp.RXDataRate2 = 5
+				return dp
+			},
+			fields: []string{"rx_datarate_2"},
+		},
+		{
+			name: "AS923 class-b",
+			dp: func() DeviceProfile {
+				dp := valid("AS923")
+				dp.SupportsClassB = true
+				dp.PingSlotPeriod = 128
+				dp.PingSlotDR = 3
+				dp.PingSlotFreq = 923400000
+				return dp
+			},
+		},
+		{
+			name: "EU868 class-b without ping-slot period",
+			dp: func() DeviceProfile {
+				dp := valid("EU868")
+				dp.SupportsClassB = true
+				return dp
+			},
+			fields: []string{"ping_slot_period"},
+		},
+		{
+			name: "US915 class-b without ping-slot fields",
+			dp: func() DeviceProfile {
+				dp := valid("US915")
+				dp.SupportsClassB = true
+				return dp
+			},
+			fields: []string{"ping_slot_dr", "ping_slot_period"},
+		},
+		{
+			name: "US915 class-b ping-slot outside the band",
+			dp: func() DeviceProfile {
+				dp := valid("US915")
+				dp.SupportsClassB = true
+				dp.PingSlotPeriod = 100
+				dp.PingSlotDR = 8
+				dp.PingSlotFreq = 869525000
+				return dp
+			},
+			fields: []string{"ping_slot_freq", "ping_slot_period"},
+		},
+		{
+			name: "class-b fields are ignored when not supported",
+			dp: func() DeviceProfile {
+				dp := valid("US915")
+				dp.PingSlotFreq = 869525000
+				return dp
+			},
+		},
+		{
+			name: "invalid generic fields",
+			dp: func() DeviceProfile {
+				return DeviceProfile{
+					RFRegion:          "XX123",
+					MACVersion:        "1.2",
+					RegParamsRevision: "C",
+					RXDelay1:          16,
+					RXDROffset1:       8,
+					RXFreq2:           1,
+				}
+			},
+			fields: []string{"mac_version", "reg_params_revision", "rf_region", "rx_delay_1", "rx_dr_offset_1"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got := fields(t, ValidateDeviceProfile(test.dp()))
+			if !equalFields(got, test.fields) {
+				t.Errorf("expected errors for %v, got %v", test.fields, got)
+			}
+		})
+	}
+}
+
+func TestValidateServiceProfile(t *testing.T) {
+	tests := []struct {
+		name   string
+		sp     ServiceProfile
+		region common.Region
+		fields []string
+	}{
+		{
+			name:   "EU868",
+			sp:     ServiceProfile{DRMin: 0, DRMax: 5, TargetPER: 10},
+			region: common.Region_EU868,
+		},
+		{
+			name:   "dr_min > dr_max",
+			sp:     ServiceProfile{DRMin: 5, DRMax: 3},
+			region: common.Region_EU868,
+			fields: []string{"dr_min"},
+		},
+		{
+			name:   "EU868 undefined data-rate",
+			sp:     ServiceProfile{DRMin: 0, DRMax: 8},
+			region: common.Region_EU868,
+			fields: []string{"dr_max"},
+		},
+		{
+			name:   "US915",
+			sp:     ServiceProfile{DRMin: 0, DRMax: 4},
+			region: common.Region_US915,
+		},
+		{
+			name:   "US915 downlink-only data-rate",
+			sp:     ServiceProfile{DRMin: 0, DRMax: 8},
+			region: common.Region_US915,
+			fields: []string{"dr_max"},
+		},
+		{
+			name:   "AU915 RFU data-rate",
+			sp:     ServiceProfile{DRMin: 7, DRMax: 7},
+			region: common.Region_AU915,
+			fields: []string{"dr_max", "dr_min"},
+		},
+		{
+			name:   "out of range",
+			sp:     ServiceProfile{DRMin: 0, DRMax: 16, TargetPER: 101, DevStatusReqFreq: 1441},
+			region: common.Region_EU868,
+			fields: []string{"dev_status_req_freq", "dr_max", "dr_max", "target_per"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got := fields(t, ValidateServiceProfileForRegion(test.sp, test.region))
+			if !equalFields(got, test.fields) {
+				t.Errorf("expected errors for %v, got %v", test.fields, got)
+			}
+		})
+	}
+}
+
+func TestErrors(t *testing.T) {
+	err := ValidateServiceProfile(ServiceProfile{DRMin: 5, DRMax: 3, TargetPER: 101})
+	errs, ok := err.(Errors)
+	if !ok {
+		t.Fatalf("expected Errors, got %T", err)
+	}
+	if exp := "validation: dr_min: must be <= dr_max (3), got 5, target_per: must be <= 100, got 101"; errs.Error() != exp {
+		t.Errorf("expected %q, got %q", exp, errs.Error())
+	}
+	if fe := errs.Field("target_per"); fe == nil || fe.Message != "must be <= 100, got 101" {
+		t.Errorf("unexpected target_per error: %v", fe)
+	}
+	if fe := errs.Field("dr_max"); fe != nil {
+		t.Errorf("expected no dr_max error, got %s", fe)
+	}
+}
@@ -0,0 +1,67 @@
+// Package validation validates device-profiles and service-profiles against
+// the LoRaWAN Regional Parameters of the selected region. It implements the
+// Validate methods of the api and ns DeviceProfile and ServiceProfile
+// messages.
+package validation
+
+import (
+	"fmt"
+	"strings"
+)
+
+// FieldError describes an invalid field.
+type FieldError struct {
+	// Field holds the (proto) field name, e.g. "rx_freq_2". Repeated fields
+	// include the index, e.g. "factory_preset_freqs[1]".
+	Field string
+
+	// Message describes why the field is invalid.
+	Message string
+}
+
+// Error implements the error interface.
+func (e *FieldError) Error() string {
+	return e.Field + ": " + e.Message
+}
+
+// Errors contains the field errors of a validation.
+type Errors []*FieldError
+
+// Error implements the error interface.
+func (e Errors) Error() string {
+	msgs := make([]string, len(e))
+	for i, fe := range e {
+		msgs[i] = fe.Error()
+	}
+	return "validation: " + strings.Join(msgs, ", ")
+}
+
+// Field returns the error of the given field, or nil.
+func (e Errors) Field(field string) *FieldError {
+	for _, fe := range e {
+		if fe.Field == field {
+			return fe
+		}
+	}
+	return nil
+}
+
+// collector collects field errors.
+type collector struct {
+	errs Errors
+}
+
+func (c *collector) add(field, format string, a ...interface{}) {
+	c.errs = append(c.errs, &FieldError{
+		Field:   field,
+		Message: fmt.Sprintf(format, a...),
+	})
+}
+
+// err returns the collected errors, or nil when there are none.
+func (c *collector) err() error {
+	if len(c.errs) == 0 {
+		return nil
+	}
+	return c.errs
+}